@@ -114,3 +114,51 @@ func WriteToDB(samplePairs <-chan *SamplePair, sqliteDB string) {
 		panic(err)
 	}
 }
+
+// ReadFromDB reads the sample pairs written by WriteToDB, with the vectors
+// of their columns.
+func ReadFromDB(sqliteDB string) []*SamplePair {
+	db, err := sql.Open("sqlite3", sqliteDB)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	vecs := make(map[string][]float64)
+	rows, err := db.Query(fmt.Sprintf(`SELECT table_id, column_index, vec FROM %s;`, VecTableName))
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		var tableID string
+		var columnIndex int
+		var binVec []byte
+		if err := rows.Scan(&tableID, &columnIndex, &binVec); err != nil {
+			panic(err)
+		}
+		vec, err := embedding.BytesToVec(binVec, ByteOrder)
+		if err != nil {
+			panic(err)
+		}
+		vecs[fmt.Sprintf("%s_%d", tableID, columnIndex)] = vec
+	}
+	rows.Close()
+	rows, err = db.Query(fmt.Sprintf(`SELECT table_id1, column_index1, table_id2, column_index2, label FROM %s;`, ColumnPairTableName))
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+	pairs := make([]*SamplePair, 0)
+	for rows.Next() {
+		p := &SamplePair{}
+		if err := rows.Scan(&p.TableID1, &p.ColumnIndex1, &p.TableID2, &p.ColumnIndex2, &p.Label); err != nil {
+			panic(err)
+		}
+		id1, id2 := p.ColumnIDs()
+		p.Vec1, p.Vec2 = vecs[id1], vecs[id2]
+		pairs = append(pairs, p)
+	}
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+	return pairs
+}
@@ -1,6 +1,7 @@
 package benchmarkserver
 
 import (
+	"context"
 	"log"
 	"math"
 	"time"
@@ -26,8 +27,13 @@ type alignment struct {
 	//semsetCDF       opendata.CDF
 	//nlCDF           opendata.CDF
 	attCDFs           map[string]opendata.CDF
+	measures          []opendata.UnionabilityMeasure
 	domainDir         string
 	perturbationDelta float64
+	// the alignment strategy of the query
+	strategy string
+	// the query columns of the search
+	columns columnSelection
 }
 
 type embDomain struct {
@@ -59,6 +65,8 @@ type Pair struct {
 	Percentile             opendata.Percentile //between 0 and 1
 	//Measure                string
 	Measure []string
+	// scores of the measures in Measure
+	Scores map[string]float64
 }
 
 type CUnionableVector struct {
@@ -73,25 +81,26 @@ type CUnionableVector struct {
 	sketchedCandidateColsNum int
 }
 
-func alignTables(queryTable, candidateTable, domainDir string, attCDFs map[string]opendata.CDF, tableCDF map[int]opendata.CDF, perturbationDelta float64) CUnionableVector {
+func alignTables(ctx context.Context, queryTable, candidateTable, domainDir string, measures []opendata.UnionabilityMeasure, attCDFs map[string]opendata.CDF, tableCDF map[int]opendata.CDF, perturbationDelta float64, strategy string, columns columnSelection) CUnionableVector {
 	log.Printf("processing candidate table %s.", candidateTable)
 	var result CUnionableVector
 	cUnionabilityScores := make([]float64, 0)
 	cUnionabilityPercentiles := make([]opendata.Percentile, 0)
-	queryTextDomains := getTextDomains(queryTable, domainDir)
-	candTextDomains := getTextDomains(candidateTable, domainDir)
-	partialAlign := make(map[string](*counter.Counter))
-	reverseAlign := make(map[string](*counter.Counter))
-	partialAlign[candidateTable] = counter.NewCounter()
-	reverseAlign[queryTable] = counter.NewCounter()
+	// the columns of the types scored by the measures
+	colTypes := measureColumnTypes(measures)
+	queryTextDomains := columns.filter(getDomains(queryTable, domainDir, colTypes...))
+	candTextDomains := getDomains(candidateTable, domainDir, colTypes...)
 	sketchedCandColumns := make(map[int]bool)
 	sketchedQueryColumns := make(map[int]bool)
 	maxC := int(math.Min(float64(len(candTextDomains)), float64(len(queryTextDomains))))
-	alignment := make([]Pair, 0)
-	batch := pqueuespan.NewTopKQueue(len(queryTextDomains) * len(candTextDomains))
+	pairs := make([]Pair, 0)
 	for _, qindex := range queryTextDomains {
+		// the search was cancelled
+		if ctx.Err() != nil {
+			break
+		}
 		for _, cindex := range candTextDomains {
-			p := getAttUnionabilityPair(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta)
+			p := getAttUnionabilityPair(queryTable, candidateTable, qindex, cindex, measures, attCDFs, perturbationDelta)
 			//p := getOneMeasureAttUnionabilityPair(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta, "set")
 			//p := getOneMeasureAttUnionabilityPair(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta, "sem")
 			//p := getOneMeasureAttUnionabilityPair(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta, "semset")
@@ -103,33 +112,29 @@ func alignTables(queryTable, candidateTable, domainDir string, attCDFs map[strin
 				sketchedQueryColumns[qindex] = true
 			}
 			if p.Percentile.Value != 0.0 {
-				//batch.Push(p, p.Percentile)
-				batch.Push(p, p.Percentile.ValueMinus, p.Percentile.ValuePlus)
-				//batch.Push(p, p.Percentile.Value, p.Percentile.Value)
+				pairs = append(pairs, p)
 			}
 		}
 	}
-	pairs, _, _ := batch.Descending()
-	for i := range pairs {
-		pair := pairs[i].(Pair)
-		if partialAlign[candidateTable].Has(pair.CandColIndex) || reverseAlign[queryTable].Has(pair.QueryColIndex) {
-			// because we are using priority queue
-			continue
-		}
-		partialAlign[candidateTable].Update(pair.CandColIndex)
-		reverseAlign[queryTable].Update(pair.QueryColIndex)
-		alignment = append(alignment, pair)
+	alignment := alignSelected(pairs, strategy, columns)
+	if len(alignment) > maxC {
+		alignment = alignment[:maxC]
+	}
+	numMust := columns.numMustAlign()
+	for _, pair := range alignment {
+		score := weightedScore(pair, columns)
 		if len(cUnionabilityScores) == 0 {
-			cUnionabilityScores = append(cUnionabilityScores, pair.Percentile.Value)
+			cUnionabilityScores = append(cUnionabilityScores, score)
 		} else {
-			cUnionabilityScores = append(cUnionabilityScores, cUnionabilityScores[len(cUnionabilityScores)-1]*pair.Percentile.Value)
+			cUnionabilityScores = append(cUnionabilityScores, cUnionabilityScores[len(cUnionabilityScores)-1]*score)
+		}
+		// a c-alignment without all the must-align columns is not a result
+		if len(cUnionabilityPercentiles) < numMust-1 {
+			cUnionabilityPercentiles = append(cUnionabilityPercentiles, opendata.Percentile{})
+			continue
 		}
 		cUnionabilityPercentiles = append(cUnionabilityPercentiles, opendata.GetPerturbedPercentile(tableCDF[len(cUnionabilityPercentiles)+1], cUnionabilityScores[len(cUnionabilityScores)-1], perturbationDelta))
 		//cUnionabilityPercentiles = append(cUnionabilityPercentiles, getPercentile(tableCDF[len(cUnionabilityPercentiles)+1], cUnionabilityScores[len(cUnionabilityScores)-1]))
-		// When we get c unique column alignments for a candidate table
-		if partialAlign[candidateTable].Unique() == maxC {
-			break
-		}
 	}
 	//maxC = len(cUnionabilityPercentiles)
 	// if no alignment found for k = number of query columns
@@ -144,7 +149,11 @@ func alignTables(queryTable, candidateTable, domainDir string, attCDFs map[strin
 	//inds := make([]int, len(s))
 	// ascending sort
 	//floats.Argsort(s, inds)
-	_, bestC := opendata.SortPercentiles(cUnionabilityPercentiles)
+	// no c-alignment if no selected query column has a scored type
+	bestC := -1
+	if len(cUnionabilityPercentiles) != 0 {
+		_, bestC = opendata.SortPercentiles(cUnionabilityPercentiles)
+	}
 	//_, bestC := opendata.PickC(cUnionabilityPercentiles)
 	result = CUnionableVector{
 		queryTable:     queryTable,
@@ -162,36 +171,35 @@ func alignTables(queryTable, candidateTable, domainDir string, attCDFs map[strin
 	return result
 }
 
-func getOneMeasureAttUnionabilityPair(queryTable, candidateTable string, qindex, cindex int, attCDFs map[string]opendata.CDF, perturbationDelta float64, measure string) Pair {
-	uScore, uPercentile, uMeasures := opendata.GetOneMeasureAttUnionabilityPercentile(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta, measure)
-	p := Pair{
-		CandTableID:   candidateTable,
-		CandColIndex:  cindex,
-		QueryColIndex: qindex,
-		Sim:           uScore,
-		Percentile:    uPercentile,
-		//Measure:       measure,
-		Measure: []string{measure},
+// AlignTables aligns the columns of a candidate table with the columns of a
+// query table, both in the domain dir, like the combined search does with
+// the alignment strategy.
+func AlignTables(ctx context.Context, queryTable, candidateTable, domainDir string, measures []opendata.UnionabilityMeasure, attCDFs map[string]opendata.CDF, tableCDF map[int]opendata.CDF, strategy string) SearchResult {
+	a := alignTables(ctx, queryTable, candidateTable, domainDir, measures, attCDFs, tableCDF, perturbationDelta, strategy, nil)
+	return SearchResult{
+		CandidateTableID:         candidateTable,
+		Alignment:                a.alignment,
+		K:                        len(a.percentiles),
+		CUnionabilityScores:      a.scores,
+		CUnionabilityPercentiles: a.percentiles,
+		MaxC:                     a.maxC,
+		BestC:                    a.bestC,
+		SketchedQueryColsNum:     a.sketchedQueryColsNum,
+		SketchedCandidateColsNum: a.sketchedCandidateColsNum,
 	}
-	for _, m := range uMeasures {
-		if m == "set" {
-			p.Hypergeometric = uScore
-		}
-		if m == "sem" {
-			p.OntologyHypergeometric = uScore
-		}
-		if m == "semset" {
-			p.SemSet = uScore
-		}
-		if m == "nl" {
-			p.Cosine = uScore
-		}
-	}
-	return p
 }
 
-func getAttUnionabilityPair(queryTable, candidateTable string, qindex, cindex int, attCDFs map[string]opendata.CDF, perturbationDelta float64) Pair {
-	uScore, uPercentile, uMeasures := opendata.GetAttUnionabilityPercentile(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta)
+func getOneMeasureAttUnionabilityPair(od *opendata.Repository, queryTable, candidateTable string, qindex, cindex int, attCDFs map[string]opendata.CDF, perturbationDelta float64, measure string) Pair {
+	uScore, uPercentile, uMeasures := od.GetOneMeasureAttUnionabilityPercentile(queryTable, candidateTable, qindex, cindex, attCDFs, perturbationDelta, measure)
+	return newAttUnionabilityPair(candidateTable, qindex, cindex, uScore, uPercentile, uMeasures)
+}
+
+func getAttUnionabilityPair(queryTable, candidateTable string, qindex, cindex int, measures []opendata.UnionabilityMeasure, attCDFs map[string]opendata.CDF, perturbationDelta float64) Pair {
+	uScore, uPercentile, uMeasures := opendata.GetAttUnionabilityPercentile(queryTable, candidateTable, qindex, cindex, measures, attCDFs, perturbationDelta)
+	return newAttUnionabilityPair(candidateTable, qindex, cindex, uScore, uPercentile, uMeasures)
+}
+
+func newAttUnionabilityPair(candidateTable string, qindex, cindex int, uScore float64, uPercentile opendata.Percentile, uMeasures []string) Pair {
 	p := Pair{
 		CandTableID:   candidateTable,
 		CandColIndex:  cindex,
@@ -200,20 +208,28 @@ func getAttUnionabilityPair(queryTable, candidateTable string, qindex, cindex in
 		Percentile:    uPercentile,
 		//Measure:       uMeasures[0],
 		Measure: uMeasures,
+		Scores:  make(map[string]float64),
 	}
 	for _, m := range uMeasures {
-		if m == "set" {
-			p.Hypergeometric = uScore
-		}
-		if m == "sem" {
-			p.OntologyHypergeometric = uScore
-		}
-		if m == "semset" {
-			p.SemSet = uScore
-		}
-		if m == "nl" {
-			p.Cosine = uScore
-		}
+		p.Scores[m] = uScore
 	}
+	p.setLegacyScores()
 	return p
 }
+
+// setLegacyScores copies the scores of the built-in measures to the fields
+// of the pair read by the experiments.
+func (p *Pair) setLegacyScores() {
+	for m, score := range p.Scores {
+		switch m {
+		case "set":
+			p.Hypergeometric = score
+		case "sem":
+			p.OntologyHypergeometric = score
+		case "semset":
+			p.SemSet = score
+		case "nl":
+			p.Cosine = score
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package benchmarkserver
+
+import (
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func Test_newAttUnionabilityPair(t *testing.T) {
+	p := newAttUnionabilityPair("cand", 0, 1, 0.7, opendata.Percentile{}, []string{"set", "nl"})
+	if p.Scores["set"] != 0.7 || p.Scores["nl"] != 0.7 {
+		t.Fatalf("scores %v", p.Scores)
+	}
+	// the experiments read the scores of the built-in measures from the fields
+	if p.Hypergeometric != 0.7 || p.Cosine != 0.7 || p.OntologyHypergeometric != 0 {
+		t.Fatalf("pair %+v", p)
+	}
+}
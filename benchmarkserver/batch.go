@@ -0,0 +1,181 @@
+package benchmarkserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	defaultBatchWorkers = 4
+	maxBatchWorkers     = 32
+	// the size limit of the body of a batch, larger batches are split by
+	// the clients
+	maxBatchSize = maxQueryCSVSize
+)
+
+// BatchQueryRequest asks for the results of many query tables, searched by
+// at most Workers queries at the same time.
+type BatchQueryRequest struct {
+	Queries []CombinedQueryRequest `json:"queries"`
+	Workers int                    `json:"workers"`
+}
+
+// BatchQueryResult is the results of a query of a batch, or the reason the
+// query was not run.
+type BatchQueryResult struct {
+	QueryTableID string        `json:"querytableid"`
+	Result       []QueryResult `json:"result"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// scoreCache keeps the attribute unionability of the column pairs aligned
+// by the queries of a batch, so that a pair is scored once per measure.
+type scoreCache struct {
+	lock   sync.RWMutex
+	scores map[string]float64
+}
+
+func newScoreCache() *scoreCache {
+	return &scoreCache{
+		scores: make(map[string]float64),
+	}
+}
+
+type cachedMeasure struct {
+	opendata.UnionabilityMeasure
+	cache *scoreCache
+}
+
+func (m *cachedMeasure) Unionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	key := fmt.Sprintf("%s %s:%d %s:%d", m.Name(), queryTable, queryIndex, candidateTable, candIndex)
+	m.cache.lock.RLock()
+	u, ok := m.cache.scores[key]
+	m.cache.lock.RUnlock()
+	if ok {
+		return u
+	}
+	u = m.UnionabilityMeasure.Unionability(queryTable, candidateTable, queryIndex, candIndex)
+	m.cache.lock.Lock()
+	m.cache.scores[key] = u
+	m.cache.lock.Unlock()
+	return u
+}
+
+func (c *scoreCache) wrap(measures []opendata.UnionabilityMeasure) []opendata.UnionabilityMeasure {
+	cached := make([]opendata.UnionabilityMeasure, len(measures))
+	for i, m := range measures {
+		cached[i] = &cachedMeasure{m, c}
+	}
+	return cached
+}
+
+type scoreCacheKey struct{}
+
+// withScoreCache shares the cache with the searches run with the context.
+func withScoreCache(ctx context.Context, cache *scoreCache) context.Context {
+	return context.WithValue(ctx, scoreCacheKey{}, cache)
+}
+
+// alignmentMeasures returns the measures that align candidate tables,
+// through the score cache of the context if it has one.
+func alignmentMeasures(ctx context.Context, measures []Measure) []opendata.UnionabilityMeasure {
+	ms := toOpendataMeasures(measures)
+	if cache, ok := ctx.Value(scoreCacheKey{}).(*scoreCache); ok {
+		return cache.wrap(ms)
+	}
+	return ms
+}
+
+func validQuery(query CombinedQueryRequest) bool {
+	return query.N > 0 && validAlignment(query.Alignment) && validDiversify(query.Diversify, query.Lambda) && validColumns(query.Columns) && validVectorColumns(query) && query.Probes >= 0
+}
+
+// SearchBatch runs the queries with a pool of workers that share the scores
+// of column pairs, at least one worker. The results are in the order of the
+// queries.
+func (s *CombinedServer) SearchBatch(ctx context.Context, queries []CombinedQueryRequest, workers int) []BatchQueryResult {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx = withScoreCache(ctx, newScoreCache())
+	results := make([]BatchQueryResult, len(queries))
+	next := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				query := queries[i]
+				results[i].QueryTableID = query.QueryTableID
+				results[i].Result = make([]QueryResult, 0)
+				if !validQuery(query) {
+					results[i].Error = "invalid query"
+					continue
+				}
+				for result := range s.search(ctx, query) {
+					union := s.toUnion(result)
+					union.QueryTableID = query.QueryTableID
+					results[i].Result = append(results[i].Result, QueryResult{TableUnion: union})
+				}
+				if ctx.Err() != nil {
+					results[i].Error = ctx.Err().Error()
+				}
+			}
+		}()
+	}
+	for i := range queries {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	// the queries not run before the batch was cancelled
+	for i := range results {
+		if results[i].Result == nil {
+			results[i].QueryTableID = queries[i].QueryTableID
+			results[i].Result = make([]QueryResult, 0)
+			results[i].Error = ctx.Err().Error()
+		}
+	}
+	return results
+}
+
+// batchQueryHandler searches for the unionable tables of many query tables
+// and returns the results per query table. The batches larger than
+// maxBatchSize are rejected with 413.
+func (s *CombinedServer) batchQueryHandler(c *gin.Context) {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxBatchSize+1))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > maxBatchSize {
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	var request BatchQueryRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+		return
+	}
+	if request.Workers == 0 {
+		request.Workers = defaultBatchWorkers
+	}
+	if request.Workers < 0 || request.Workers > maxBatchWorkers {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	results := s.SearchBatch(c.Request.Context(), request.Queries, request.Workers)
+	c.JSON(http.StatusOK, gin.H{"result": results})
+}
@@ -0,0 +1,107 @@
+package benchmarkserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/gin-gonic/gin"
+)
+
+func Test_scoreCache(t *testing.T) {
+	calls := 0
+	m := opendata.NewMeasure("count", "text", "", "", nil, func(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+		calls += 1
+		return 0.5
+	})
+	cache := newScoreCache()
+	ctx := withScoreCache(context.Background(), cache)
+	for i := 0; i < 2; i++ {
+		measures := alignmentMeasures(ctx, []Measure{AlignMeasure(m)})
+		if u := measures[0].Unionability("q.csv", "c.csv", 0, 1); u != 0.5 {
+			t.Errorf("unionability is %f", u)
+		}
+	}
+	alignmentMeasures(ctx, []Measure{AlignMeasure(m)})[0].Unionability("q.csv", "c.csv", 1, 1)
+	if calls != 2 {
+		t.Errorf("%d calls instead of 2", calls)
+	}
+	// no cache without a batch
+	alignmentMeasures(context.Background(), []Measure{AlignMeasure(m)})[0].Unionability("q.csv", "c.csv", 0, 1)
+	if calls != 3 {
+		t.Errorf("%d calls instead of 3", calls)
+	}
+}
+
+func Test_SearchBatch(t *testing.T) {
+	s := &CombinedServer{}
+	lambda := 2.0
+	queries := []CombinedQueryRequest{
+		{QueryTableID: "a.csv", N: 0},
+		{QueryTableID: "b.csv", N: 10, Alignment: "best"},
+		{QueryTableID: "c.csv", N: 10, Lambda: &lambda},
+	}
+	// a batch without workers is run by one worker
+	for _, workers := range []int{2, 0} {
+		results := s.SearchBatch(context.Background(), queries, workers)
+		if len(results) != 3 {
+			t.Fatalf("%d results", len(results))
+		}
+		for i, r := range results {
+			if r.QueryTableID != queries[i].QueryTableID || r.Error == "" || len(r.Result) != 0 {
+				t.Errorf("result of %s is %v", queries[i].QueryTableID, r)
+			}
+		}
+	}
+}
+
+func Test_QueryBatch(t *testing.T) {
+	defer func(size int64) { maxBatchSize = size }(maxBatchSize)
+	// two queries per batch
+	query, err := json.Marshal(&CombinedQueryRequest{QueryTableID: "a.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxBatchSize = int64(2*len(query) + batchOverhead + 2)
+	s := &CombinedServer{}
+	batches := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		batches += 1
+	})
+	router.POST("/query-batch", s.batchQueryHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	// the batches larger than the limit are rejected
+	resp, err := http.Post(server.URL+"/query-batch", "application/json", bytes.NewReader(make([]byte, maxBatchSize+1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d of a large batch", resp.StatusCode)
+	}
+	// and split by the client
+	batches = 0
+	client := &CombinedClient{host: server.URL, cli: &http.Client{}}
+	queries := make([]CombinedQueryRequest, 0)
+	for _, tableID := range []string{"a.csv", "b.csv", "c.csv", "d.csv", "e.csv"} {
+		queries = append(queries, CombinedQueryRequest{QueryTableID: tableID})
+	}
+	results, err := client.QueryBatch(queries, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(queries) || batches != 3 {
+		t.Fatalf("%d results in %d batches", len(results), batches)
+	}
+	for i, r := range results {
+		if r.QueryTableID != queries[i].QueryTableID || r.Error != "invalid query" {
+			t.Errorf("result of %s is %v", queries[i].QueryTableID, r)
+		}
+	}
+}
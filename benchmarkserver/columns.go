@@ -0,0 +1,225 @@
+package benchmarkserver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QueryColumn selects a column of the query table for the search.
+type QueryColumn struct {
+	// the index of the column in the query table
+	Index int `json:"index"`
+	// the exponent of the percentile of the column in the c-unionability
+	// score, 1 if zero
+	Weight float64 `json:"weight"`
+	// the column has to be in the alignment of every result
+	MustAlign bool `json:"mustalign"`
+}
+
+// columnSelection maps the selected query columns to their options. The nil
+// selection selects every column with weight 1.
+type columnSelection map[int]QueryColumn
+
+func newColumnSelection(columns []QueryColumn) columnSelection {
+	if len(columns) == 0 {
+		return nil
+	}
+	sel := make(columnSelection)
+	for _, c := range columns {
+		if c.Weight == 0.0 {
+			c.Weight = 1.0
+		}
+		sel[c.Index] = c
+	}
+	return sel
+}
+
+func validColumns(columns []QueryColumn) bool {
+	seen := make(map[int]bool)
+	for _, c := range columns {
+		if c.Index < 0 || c.Weight < 0.0 || seen[c.Index] {
+			return false
+		}
+		seen[c.Index] = true
+	}
+	return true
+}
+
+func (sel columnSelection) selected(index int) bool {
+	if sel == nil {
+		return true
+	}
+	_, ok := sel[index]
+	return ok
+}
+
+func (sel columnSelection) weight(index int) float64 {
+	if c, ok := sel[index]; ok {
+		return c.Weight
+	}
+	return 1.0
+}
+
+func (sel columnSelection) mustAlign(index int) bool {
+	return sel[index].MustAlign
+}
+
+func (sel columnSelection) numMustAlign() int {
+	n := 0
+	for _, c := range sel {
+		if c.MustAlign {
+			n += 1
+		}
+	}
+	return n
+}
+
+// filter returns the selected columns of indices.
+func (sel columnSelection) filter(indices []int) []int {
+	if sel == nil {
+		return indices
+	}
+	selected := make([]int, 0)
+	for _, i := range indices {
+		if sel.selected(i) {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// alignSelected aligns the must-align query columns first and then the
+// other columns with the remaining candidate columns. The must-align pairs
+// come first in the alignment. It returns nil if a must-align column cannot
+// be aligned.
+func alignSelected(pairs []Pair, strategy string, sel columnSelection) []Pair {
+	numMust := sel.numMustAlign()
+	if numMust == 0 {
+		return AlignPairs(pairs, strategy)
+	}
+	mustPairs := make([]Pair, 0)
+	for _, p := range pairs {
+		if sel.mustAlign(p.QueryColIndex) {
+			mustPairs = append(mustPairs, p)
+		}
+	}
+	alignment := AlignPairs(mustPairs, strategy)
+	if len(alignment) < numMust {
+		return nil
+	}
+	candAligned := make(map[int]bool)
+	for _, p := range alignment {
+		candAligned[p.CandColIndex] = true
+	}
+	rest := make([]Pair, 0)
+	for _, p := range pairs {
+		if !sel.mustAlign(p.QueryColIndex) && !candAligned[p.CandColIndex] {
+			rest = append(rest, p)
+		}
+	}
+	return append(alignment, AlignPairs(rest, strategy)...)
+}
+
+// weightedScore is the percentile of an aligned pair raised to the weight
+// of its query column, so that a column of weight 2 counts twice in the
+// c-unionability product.
+func weightedScore(pair Pair, sel columnSelection) float64 {
+	w := sel.weight(pair.QueryColIndex)
+	if w == 1.0 {
+		return pair.Percentile.Value
+	}
+	return math.Pow(pair.Percentile.Value, w)
+}
+
+// validVectorColumns checks that a query selecting columns gives the query
+// column of each of its vectors.
+func validVectorColumns(query CombinedQueryRequest) bool {
+	if len(query.Columns) == 0 {
+		return true
+	}
+	return len(query.SetColumns) == len(query.SetVecs) &&
+		len(query.WSetColumns) == len(query.WSetVecs) &&
+		len(query.NlColumns) == len(query.NlMeans) &&
+		len(query.NumColumns) == len(query.NumVecs)
+}
+
+// queryColumn returns the query column of the i-th query vector, or i if the
+// columns of the vectors are not given.
+func queryColumn(columns []int, i int) int {
+	if i < len(columns) {
+		return columns[i]
+	}
+	return i
+}
+
+// parseQueryColumns parses the query columns of a CSV query, given as
+// comma-separated column indexes, the weights of the columns in the same
+// order and the indexes of the must-align columns, e.g. columns=0,3,5,
+// weights=1,2,1 and mustalign=3. The must-align columns are selected even if
+// they are not in columns.
+func parseQueryColumns(columns, weights, mustAlign string) ([]QueryColumn, error) {
+	indexes, err := parseInts(columns)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := parseFloats(weights)
+	if err != nil {
+		return nil, err
+	}
+	if len(ws) != 0 && len(ws) != len(indexes) {
+		return nil, fmt.Errorf("%d weights for %d columns", len(ws), len(indexes))
+	}
+	must, err := parseInts(mustAlign)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]QueryColumn, len(indexes))
+	position := make(map[int]int)
+	for i, index := range indexes {
+		selected[i].Index = index
+		if len(ws) != 0 {
+			selected[i].Weight = ws[i]
+		}
+		position[index] = i
+	}
+	for _, index := range must {
+		if i, ok := position[index]; ok {
+			selected[i].MustAlign = true
+			continue
+		}
+		selected = append(selected, QueryColumn{Index: index, MustAlign: true})
+	}
+	return selected, nil
+}
+
+func parseInts(list string) ([]int, error) {
+	ints := make([]int, 0)
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
+
+func parseFloats(list string) ([]float64, error) {
+	floats := make([]float64, 0)
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		floats = append(floats, f)
+	}
+	return floats, nil
+}
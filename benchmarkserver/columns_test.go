@@ -0,0 +1,90 @@
+package benchmarkserver
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_alignSelected(t *testing.T) {
+	// greedy takes (0, 0), unless query column 1 must be aligned
+	pairs := []Pair{
+		testPair(0, 0, 0.9),
+		testPair(1, 0, 0.5),
+		testPair(0, 1, 0.2),
+	}
+	alignment := alignSelected(pairs, GreedyAlignment, nil)
+	if len(alignment) != 1 || alignment[0].QueryColIndex != 0 {
+		t.Errorf("alignment is %v", alignment)
+	}
+	sel := newColumnSelection([]QueryColumn{{Index: 0}, {Index: 1, MustAlign: true}})
+	alignment = alignSelected(pairs, GreedyAlignment, sel)
+	if len(alignment) != 2 || alignment[0].QueryColIndex != 1 || alignment[0].CandColIndex != 0 ||
+		alignment[1].QueryColIndex != 0 || alignment[1].CandColIndex != 1 {
+		t.Errorf("must-align alignment is %v", alignment)
+	}
+	// no alignment if a must-align column cannot be aligned
+	sel = newColumnSelection([]QueryColumn{{Index: 2, MustAlign: true}})
+	if alignment := alignSelected(pairs, OptimalAlignment, sel); alignment != nil {
+		t.Errorf("alignment without column 2 is %v", alignment)
+	}
+}
+
+func Test_columnSelection(t *testing.T) {
+	var all columnSelection
+	if !all.selected(7) || all.weight(7) != 1.0 || all.numMustAlign() != 0 {
+		t.Error("the nil selection does not select every column")
+	}
+	sel := newColumnSelection([]QueryColumn{{Index: 1}, {Index: 3, Weight: 2.0}})
+	if sel.selected(0) || !sel.selected(1) || sel.weight(1) != 1.0 {
+		t.Errorf("selection is %v", sel)
+	}
+	if f := sel.filter([]int{0, 1, 2, 3}); len(f) != 2 || f[0] != 1 || f[1] != 3 {
+		t.Errorf("selected columns are %v", f)
+	}
+	if s := weightedScore(testPair(3, 0, 0.5), sel); math.Abs(s-0.25) > 1e-9 {
+		t.Errorf("weighted score is %f", s)
+	}
+	if validColumns([]QueryColumn{{Index: 1}, {Index: 1}}) || validColumns([]QueryColumn{{Index: 0, Weight: -1.0}}) {
+		t.Error("invalid columns are accepted")
+	}
+}
+
+func Test_validVectorColumns(t *testing.T) {
+	query := CombinedQueryRequest{
+		SetVecs: [][]uint64{{1}, {2}},
+		NlMeans: [][]float64{{1.0}},
+	}
+	if !validVectorColumns(query) {
+		t.Error("query without a selection is rejected")
+	}
+	query.Columns = []QueryColumn{{Index: 2}}
+	if validVectorColumns(query) {
+		t.Error("selection without the columns of the vectors is accepted")
+	}
+	// the nl vector is of the second text column
+	query.SetColumns = []int{0, 2}
+	query.NlColumns = []int{2}
+	if !validVectorColumns(query) {
+		t.Error("selection with the columns of the vectors is rejected")
+	}
+	if queryColumn(query.NlColumns, 0) != 2 || queryColumn(nil, 1) != 1 {
+		t.Error("bad query column of a vector")
+	}
+}
+
+func Test_parseQueryColumns(t *testing.T) {
+	columns, err := parseQueryColumns("0, 3", "1,2", "3,5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 3 || columns[1].Weight != 2.0 || !columns[1].MustAlign ||
+		columns[2].Index != 5 || !columns[2].MustAlign || columns[0].MustAlign {
+		t.Errorf("columns are %v", columns)
+	}
+	if _, err := parseQueryColumns("0,1", "2", ""); err == nil {
+		t.Error("weights of the wrong length are accepted")
+	}
+	if columns, err := parseQueryColumns("", "", ""); err != nil || len(columns) != 0 {
+		t.Errorf("empty columns are %v", columns)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
@@ -12,21 +13,15 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/ekzhu/datatable"
 	fasttext "github.com/ekzhu/go-fasttext"
 )
 
-var (
-	opendataDirUS = "/home/ekzhu/OPENDATA"
-	queryDir      = "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/csvfiles/"
-	domainDir     = "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/domains"
-	opendataDir   = "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/csvfiles"
-	//queryDir = "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only"
-	//domainDir     = "/home/fnargesian/TABLE_UNION_OUTPUT/domains"
-	//opendataDir   = "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only"
-)
-
+// The query tables of the clients are CSV files of the opendata dir of a
+// repository, their sketches are read from its domains.
 type CombinedClient struct {
+	od       *opendata.Repository
 	ft       *fasttext.FastText
 	host     string
 	cli      *http.Client
@@ -35,8 +30,9 @@ type CombinedClient struct {
 	numHash  int
 }
 
-func NewCombinedClient(ft *fasttext.FastText, host string, numHash int) (*CombinedClient, error) {
+func NewCombinedClient(od *opendata.Repository, ft *fasttext.FastText, host string, numHash int) (*CombinedClient, error) {
 	return &CombinedClient{
+		od:       od,
 		ft:       ft,
 		host:     host,
 		cli:      &http.Client{},
@@ -80,22 +76,101 @@ func (c *CombinedClient) mkReq(queryRequest CombinedQueryRequest) QueryResponse
 	return queryResponse
 }
 
-func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
-	queryRawFilename := strings.Replace(queryCSVFilename, queryDir, "", -1)
-	results := make([]QueryResult, 0)
+// QueryBatch sends many query requests to /query-batch, split into batches
+// under the size limit of the server. The results are in the order of the
+// queries.
+func (c *CombinedClient) QueryBatch(queries []CombinedQueryRequest, workers int) ([]BatchQueryResult, error) {
+	results := make([]BatchQueryResult, 0, len(queries))
+	batch := make([]json.RawMessage, 0)
+	size := 0
+	for _, query := range queries {
+		data, err := json.Marshal(&query)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)+batchOverhead) > maxBatchSize {
+			return nil, fmt.Errorf("query %s is larger than the batch size limit", query.QueryTableID)
+		}
+		if len(batch) > 0 && int64(size+len(data)+batchOverhead) > maxBatchSize {
+			batchResults, err := c.postBatch(batch, workers)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, batchResults...)
+			batch = batch[:0]
+			size = 0
+		}
+		batch = append(batch, data)
+		// the separating comma
+		size += len(data) + 1
+	}
+	if len(batch) > 0 {
+		batchResults, err := c.postBatch(batch, workers)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batchResults...)
+	}
+	return results, nil
+}
+
+// the size of a batch request without its queries
+const batchOverhead = 64
+
+func (c *CombinedClient) postBatch(queries []json.RawMessage, workers int) ([]BatchQueryResult, error) {
+	request := struct {
+		Queries []json.RawMessage `json:"queries"`
+		Workers int               `json:"workers"`
+	}{queries, workers}
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(&request); err != nil {
+		return nil, err
+	}
+	resp, err := c.cli.Post(c.host+"/query-batch", "application/json", buf)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("batch query failed with status %d", resp.StatusCode)
+	}
+	var response struct {
+		Result []BatchQueryResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if len(response.Result) != len(queries) {
+		return nil, fmt.Errorf("%d results of a batch of %d queries", len(response.Result), len(queries))
+	}
+	return response.Result, nil
+}
+
+// clientQuery is the query request of a CSV file and its headers.
+type clientQuery struct {
+	request     CombinedQueryRequest
+	headers     []string
+	textHeaders []string
+}
+
+// Reads the sketches of the text columns of a CSV file of the opendata dir
+// into a query request. It returns false if the file has no text columns.
+func (c *CombinedClient) readQueryTable(queryCSVFilename string, n int) (clientQuery, bool) {
+	queryRawFilename := tableIDOf(c.od, queryCSVFilename)
 	f, err := os.Open(queryCSVFilename)
 	if err != nil {
-		return results
+		return clientQuery{}, false
 	}
 	defer f.Close()
 	reader := csv.NewReader(f)
-	queryTable, err := datatable.FromCSV(reader)
-	queryHeaders := queryTable.GetRow(0)
+	table, err := datatable.FromCSV(reader)
 	if err != nil {
-		return results
+		return clientQuery{}, false
 	}
+	queryHeaders := table.GetRow(0)
 	// Create signatures
 	setVecs := make([][]uint64, 0)
+	wsetVecs := make([][]uint64, 0)
 	ontVecs := make([][]uint64, 0)
 	noOntVecs := make([][]uint64, 0)
 	nlMeans := make([][]float64, 0)
@@ -104,23 +179,33 @@ func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
 	setCards := make([]int, 0)
 	ontCards := make([]int, 0)
 	noOntCards := make([]int, 0)
+	// the query column of each vector
+	setColumns := make([]int, 0)
+	wsetColumns := make([]int, 0)
+	nlColumns := make([]int, 0)
 	queryTextHeaders := make([]string, 0)
 	textToAllHeaders := make(map[int]int)
-	for i := 0; i < queryTable.NumCol(); i++ {
-		col := queryTable.GetColumn(i)
+	for i := 0; i < table.NumCol(); i++ {
+		col := table.GetColumn(i)
 		if classifyValues(col) == "text" {
-			nlMean, nlCovar, err1 := getDomainEmbMeanCovar(queryRawFilename, i)
-			ontVec, noOntVec, _, ontCard, noOntCard, _, err2 := getAttributeOntologyData(queryRawFilename, i, c.numHash)
+			nlMean, nlCovar, err1 := getDomainEmbMeanCovar(c.od, queryRawFilename, i)
+			ontVec, noOntVec, _, ontCard, noOntCard, _, err2 := getAttributeOntologyData(c.od, queryRawFilename, i, c.numHash)
 			//setVec := opendata.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
-			setVec, err3 := getAttributeMinhash(queryRawFilename, i, c.numHash)
+			setVec, err3 := getAttributeMinhash(c.od, queryRawFilename, i, c.numHash)
 			if err1 == nil && len(nlMean) != 0 && len(nlCovar) != 0 && !containsNan(nlCovar) && !containsNan(nlMean) {
 				nlMeans = append(nlMeans, nlMean)
 				nlCovars = append(nlCovars, nlCovar)
 				nlCards = append(nlCards, len(col))
+				nlColumns = append(nlColumns, i)
 			}
 			if len(setVec) != 0 && err3 == nil {
 				setVecs = append(setVecs, setVec)
 				setCards = append(setCards, getCardinality(col))
+				setColumns = append(setColumns, i)
+			}
+			if wsetVec, err := getAttributeWeightedMinhash(c.od, queryRawFilename, i, c.numHash); err == nil {
+				wsetVecs = append(wsetVecs, wsetVec)
+				wsetColumns = append(wsetColumns, i)
 			}
 			if len(ontVec) != 0 && err2 == nil {
 				ontVecs = append(ontVecs, ontVec)
@@ -135,11 +220,24 @@ func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
 	// the query is empty
 	if len(setVecs) == 0 {
 		log.Printf("Query %s does not contain text attributes.", queryCSVFilename)
+		return clientQuery{}, false
+	}
+	queryTableID := queryRawFilename
+	return clientQuery{
+		request:     CombinedQueryRequest{SetVecs: setVecs, WSetVecs: wsetVecs, OntVecs: ontVecs, NoOntVecs: noOntVecs, NlMeans: nlMeans, NlCovars: nlCovars, NlCards: nlCards, SetCards: setCards, OntCards: ontCards, NoOntCards: noOntCards, SetColumns: setColumns, WSetColumns: wsetColumns, NlColumns: nlColumns, N: n, QueryTableID: queryTableID},
+		headers:     queryHeaders,
+		textHeaders: queryTextHeaders,
+	}, true
+}
+
+func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
+	results := make([]QueryResult, 0)
+	query, ok := c.readQueryTable(queryCSVFilename, n)
+	if !ok {
 		return results
 	}
 	// Query server
-	queryTableID := strings.Replace(queryCSVFilename, queryDir, "", -1)
-	resp := c.mkReq(CombinedQueryRequest{SetVecs: setVecs, OntVecs: ontVecs, NoOntVecs: noOntVecs, NlMeans: nlMeans, NlCovars: nlCovars, NlCards: nlCards, SetCards: setCards, OntCards: ontCards, NoOntCards: noOntCards, N: n, QueryTableID: queryTableID})
+	resp := c.mkReq(query.request)
 	// Process results
 	if resp.Result == nil || len(resp.Result) == 0 {
 		log.Printf("No result found for %s.", queryCSVFilename)
@@ -148,8 +246,8 @@ func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
 	for _, result := range resp.Result {
 		log.Printf("query: %s", queryCSVFilename)
 		log.Printf("candidate: %s", result.TableUnion.CandTableID)
-		result.TableUnion.QueryHeader = queryHeaders
-		result.TableUnion.QueryTextHeader = queryTextHeaders
+		result.TableUnion.QueryHeader = query.headers
+		result.TableUnion.QueryTextHeader = query.textHeaders
 		// Retrive header index
 		/*
 			log.Printf("table unionability scores: %v", result.TableUnion.CUnionabilityScores)
@@ -164,3 +262,44 @@ func (c *CombinedClient) Query(queryCSVFilename string, n int) []QueryResult {
 	}
 	return results
 }
+
+// QueryTables queries with many CSV files of the opendata dir through
+// QueryBatch. The results are in the order of the files, the files
+// without text columns have none.
+func (c *CombinedClient) QueryTables(queryCSVFilenames []string, n, workers int) ([][]QueryResult, error) {
+	results := make([][]QueryResult, len(queryCSVFilenames))
+	queries := make([]clientQuery, 0)
+	// the file of each query
+	files := make([]int, 0)
+	for i, queryCSVFilename := range queryCSVFilenames {
+		results[i] = make([]QueryResult, 0)
+		if query, ok := c.readQueryTable(queryCSVFilename, n); ok {
+			queries = append(queries, query)
+			files = append(files, i)
+		}
+	}
+	requests := make([]CombinedQueryRequest, len(queries))
+	for i, query := range queries {
+		requests[i] = query.request
+	}
+	batchResults, err := c.QueryBatch(requests, workers)
+	if err != nil {
+		return nil, err
+	}
+	for i, batchResult := range batchResults {
+		if batchResult.Error != "" {
+			log.Printf("Error in querying %s: %s", queryCSVFilenames[files[i]], batchResult.Error)
+		}
+		for _, result := range batchResult.Result {
+			result.TableUnion.QueryHeader = queries[i].headers
+			result.TableUnion.QueryTextHeader = queries[i].textHeaders
+			results[files[i]] = append(results[files[i]], result)
+		}
+	}
+	return results, nil
+}
+
+// Returns the table ID of a CSV file of the opendata dir of a repository.
+func tableIDOf(od *opendata.Repository, filename string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(filename, od.OpendataDir), "/")
+}
@@ -1,6 +1,7 @@
 package benchmarkserver
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
@@ -9,7 +10,6 @@ import (
 	"github.com/fnargesian/pqueuespan"
 	_ "github.com/mattn/go-sqlite3"
 
-	"github.com/RJMillerLab/table-union/minhashlsh"
 	"github.com/RJMillerLab/table-union/opendata"
 )
 
@@ -22,7 +22,7 @@ type tablePair struct {
 	candidateTable string
 }
 
-func initCAlignment(N int, tableCDF map[int]opendata.CDF, attCDFs map[string]opendata.CDF, domainDir string, perturbationDelta float64) alignment {
+func initCAlignment(N int, tableCDF map[int]opendata.CDF, attCDFs map[string]opendata.CDF, domainDir string, perturbationDelta float64, measures []opendata.UnionabilityMeasure, strategy string, columns columnSelection) alignment {
 	return alignment{
 		completedTables:   counter.NewCounter(),
 		partialAlign:      make(map[string](*counter.Counter)),
@@ -32,166 +32,56 @@ func initCAlignment(N int, tableCDF map[int]opendata.CDF, attCDFs map[string]ope
 		startTime:         time.Now(),
 		tableCDF:          tableCDF,
 		attCDFs:           attCDFs,
+		measures:          measures,
 		domainDir:         domainDir,
 		perturbationDelta: perturbationDelta,
+		strategy:          strategy,
+		columns:           columns,
 	}
 }
 
-func (server *CombinedServer) CombinedOrderAll(nlMeans, nlCovars [][]float64, setVecs, noOntVecs, ontVecs [][]uint64, N int, noOntCards, ontCards, nlCards, setCards []int, queryTableID string) <-chan SearchResult {
+// CombinedOrderAll searches for the top-N unionable tables. The candidate
+// columns of every measure of the server are ranked by their percentiles.
+// The search stops when ctx is cancelled, and the result channel is closed.
+func (server *CombinedServer) CombinedOrderAll(ctx context.Context, query CombinedQueryRequest) <-chan SearchResult {
 	var numBatches int
+	N := query.N
+	queryTableID := query.QueryTableID
 	results := make(chan SearchResult)
 	log.Printf("search queryTableID: %s", queryTableID)
-	ontSigs := make([]minhashlsh.Signature, len(ontVecs))
-	noOntSigs := make([]minhashlsh.Signature, len(noOntVecs))
-	setSigs := make([]minhashlsh.Signature, len(setVecs))
-	// cast the type of query columns to Signature
-	for i := 0; i < len(ontVecs); i++ {
-		ontSigs[i] = minhashlsh.Signature(ontVecs[i])
-	}
-	for i := 0; i < len(noOntVecs); i++ {
-		noOntSigs[i] = minhashlsh.Signature(noOntVecs[i])
-	}
-	for i := 0; i < len(setVecs); i++ {
-		setSigs[i] = minhashlsh.Signature(setVecs[i])
-	}
-	alignment := initCAlignment(N, server.tableCDF, server.attCDFs, server.seti.domainDir, server.perturbationDelta)
+	columns := newColumnSelection(query.Columns)
+	alignment := initCAlignment(N, server.tableCDF, server.attCDFs, server.seti.domainDir, server.perturbationDelta, alignmentMeasures(ctx, server.measures), query.Alignment, columns)
 	//reduceQueue := pqueue.NewTopKQueue(batchSize)
 	reduceQueue := pqueuespan.NewTopKQueue(batchSize)
 	reduceBatch := make(chan Pair)
-	done1 := make(chan struct{})
-	done2 := make(chan struct{})
-	done3 := make(chan struct{})
-	done4 := make(chan struct{})
+	// cancelled by the client or once enough tables are aligned
+	ctx, cancel := context.WithCancel(ctx)
 	wg := &sync.WaitGroup{}
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		if len(nlMeans) == 0 {
-			return
+	for _, m := range server.measures {
+		candidates := m.Candidates(ctx, query)
+		if candidates == nil {
+			continue
 		}
-		for pair := range server.nli.lsh.QueryPlus(nlMeans, done3) {
-			tableID, columnIndex := fromColumnID(pair.CandidateKey)
-			e := getColumnPairPlus(tableID, server.seti.domainDir, columnIndex, pair.QueryIndex, nlMeans[pair.QueryIndex], nlCovars[pair.QueryIndex], nlCards[pair.QueryIndex])
-			//e.Percentile = getPercentile(server.attCDFs["nl"], e.Sim)
-			e.Percentile = opendata.GetPerturbedPercentile(server.attCDFs["nl"], e.Sim, server.perturbationDelta)
-			//e.Measure = "nl"
-			if e.Percentile.Value != 0.0 {
-				//reduceBatch <- e
-				select {
-				case reduceBatch <- e:
-				case <-done3:
-					return
+		wg.Add(1)
+		go func(m Measure, candidates <-chan Pair) {
+			defer wg.Done()
+			attCDF := server.attCDFs[m.Name()]
+			for e := range candidates {
+				if !columns.selected(e.QueryColIndex) {
+					continue
 				}
-			}
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if len(setVecs) == 0 {
-			return
-		}
-		for pair := range server.seti.lsh.QueryPlus(setSigs, done4) {
-			tableID, columnIndex := fromColumnID(pair.CandidateKey)
-			e := getColumnPairJaccardPlus(tableID, server.seti.domainDir, columnIndex, pair.QueryIndex, server.seti.numHash, setVecs, setCards[pair.QueryIndex])
-			//e.Percentile = getPercentile(server.attCDFs["set"], e.Sim)
-			e.Percentile = opendata.GetPerturbedPercentile(server.attCDFs["set"], e.Sim, server.perturbationDelta)
-			//e.Measure = "set"
-			if e.Percentile.Value != 0.0 {
-				//reduceBatch <- e
-				select {
-				case reduceBatch <- e:
-				case <-done4:
-					return
+				e.Percentile = opendata.GetPerturbedPercentile(attCDF, e.Sim, server.perturbationDelta)
+				if e.Percentile.Value == 0.0 {
+					continue
 				}
-			}
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if len(noOntVecs) == 0 {
-			return
-		}
-		/*
-			for pair := range server.semseti.lsh.QueryPlus(noOntSigs, done1) {
-				tableID, columnIndex := fromColumnID(pair.CandidateKey)
-				e := getColumnPairOntJaccardPlus(tableID, server.semseti.domainDir, columnIndex, pair.QueryIndex, server.semseti.numHash, ontVecs[pair.QueryIndex], noOntVecs[pair.QueryIndex], ontCards[pair.QueryIndex], noOntCards[pair.QueryIndex])
-				p2 := opendata.GetPerturbedPercentile(server.attCDFs["semset"], e.Sim, server.perturbationDelta)
-				if p2.Value != 0.0 {
-					e.Percentile = p2
-					//e.Measure = "semset"
-					//reduceBatch <- e
-					select {
-					case reduceBatch <- e:
-					case <-done1:
-						return
-					}
-				}
-			}
-		*/
-	}()
-	go func() {
-		defer wg.Done()
-		if len(ontVecs) == 0 {
-			return
-		}
-		/*
-		for pair := range server.semi.lsh.QueryPlus(ontSigs, done2) {
-			tableID, columnIndex := fromColumnID(pair.CandidateKey)
-			e := getColumnPairOntJaccardPlus(tableID, server.semi.domainDir, columnIndex, pair.QueryIndex, server.semi.numHash, ontVecs[pair.QueryIndex], noOntVecs[pair.QueryIndex], ontCards[pair.QueryIndex], noOntCards[pair.QueryIndex])
-			//p1 := getPercentile(server.attCDFs["sem"], e.Sim)
-			//e := getColumnPairSem(tableID, server.semi.domainDir, columnIndex, pair.QueryIndex, server.semi.numHash, ontVecs[pair.QueryIndex], noOntVecs[pair.QueryIndex], ontCards[pair.QueryIndex], noOntCards[pair.QueryIndex])
-			//p1 := opendata.GetPerturbedPercentile(server.attCDFs["sem"], e.Sim, server.perturbationDelta)
-			//if p1.Value != 0.0 {
-			//	//reduceBatch <- e
-			//	select {
-			//	case reduceBatch <- e:
-			//	case <-done2:
-			//		return
-			//	}
-			//}
-			/*
-				if e.Sim != e.OntologyHypergeometric {
-					p1 := opendata.GetPerturbedPercentile(server.attCDFs["sem"], e.Sim, server.perturbationDelta)
-					if p1.Value != 0.0 {
-						e.Percentile = p1
-						//e.Measure = "sem"
-						//reduceBatch <- e
-						select {
-						case reduceBatch <- e:
-						case <-done2:
-							return
-						}
-					}
-					//p2 := getPercentile(server.attCDFs["semset"], e.Sim)
-					p2 := opendata.GetPerturbedPercentile(server.attCDFs["semset"], e.Sim, server.perturbationDelta)
-
-					if p2.Value != 0.0 {
-						e.Percentile = p2
-						//e.Measure = "semset"
-						//reduceBatch <- e
-						select {
-						case reduceBatch <- e:
-						case <-done2:
-							return
-						}
-					}
-				} else {
-			*/
-			p1 := opendata.GetPerturbedPercentile(server.attCDFs["sem"], e.Sim, server.perturbationDelta)
-			if p1.Value != 0.0 {
-				e.Percentile = p1
-				//e.Measure = "sem"
-				//reduceBatch <- e
 				select {
 				case reduceBatch <- e:
-				case <-done2:
+				case <-ctx.Done():
 					return
 				}
 			}
-			//}
-		}
-		*/
-	}()
+		}(m, candidates)
+	}
 	wwg := &sync.WaitGroup{}
 	wwg.Add(1)
 	go func() {
@@ -206,20 +96,14 @@ func (server *CombinedServer) CombinedOrderAll(nlMeans, nlCovars [][]float64, se
 				// checking if we have processed too many batches
 				if numBatches > 2 {
 					log.Printf("enough searching")
-					close(done1)
-					close(done2)
-					close(done3)
-					close(done4)
+					cancel()
 					wwg.Done()
 					return
 				}
 				log.Printf("numBatches: %d", numBatches)
 				numBatches += 1
-				if finished := alignment.processPairsCombined(reduceQueue, results, queryTableID); finished {
-					close(done1)
-					close(done2)
-					close(done3)
-					close(done4)
+				if finished := alignment.processPairsCombined(ctx, reduceQueue, results, queryTableID); finished {
+					cancel()
 					wwg.Done()
 					return
 				}
@@ -227,13 +111,10 @@ func (server *CombinedServer) CombinedOrderAll(nlMeans, nlCovars [][]float64, se
 				reduceQueue = pqueuespan.NewTopKQueue(batchSize)
 			}
 		}
-		if reduceQueue.Size() != 0 {
-			alignment.processPairsCombined(reduceQueue, results, queryTableID)
+		if reduceQueue.Size() != 0 && ctx.Err() == nil {
+			alignment.processPairsCombined(ctx, reduceQueue, results, queryTableID)
 		} //
-		close(done1)
-		close(done2)
-		close(done3)
-		close(done4)
+		cancel()
 		//}
 		wwg.Done()
 	}()
@@ -251,7 +132,7 @@ func (server *CombinedServer) CombinedOrderAll(nlMeans, nlCovars [][]float64, se
 }
 
 //func (a alignment) processPairsCombined(reduceQueue *pqueue.TopKQueue, out chan<- SearchResult, queryTableID string) bool {
-func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out chan<- SearchResult, queryTableID string) bool {
+func (a alignment) processPairsCombined(ctx context.Context, reduceQueue *pqueuespan.TopKQueue, out chan<- SearchResult, queryTableID string) bool {
 	//cAlignmentQueue := pqueue.NewTopKQueue(a.n)
 	cAlignmentQueue := pqueuespan.NewTopKQueue(a.n)
 	alignedTables := make(chan SearchResult)
@@ -268,7 +149,11 @@ func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out c
 			if a.hasCompleted(pair.CandTableID) {
 				continue
 			}
-			tablesToAlign <- pair.CandTableID
+			select {
+			case tablesToAlign <- pair.CandTableID:
+			case <-ctx.Done():
+				return
+			}
 			a.completedTables.Update(pair.CandTableID)
 			if a.completedTables.Unique() == a.n {
 				return
@@ -280,7 +165,13 @@ func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out c
 		go func(int) {
 			for tp := range tablesToAlign {
 				candTableID := tp
-				cAlignment := alignTables(queryTableID, candTableID, a.domainDir, a.attCDFs, a.tableCDF, a.perturbationDelta)
+				if ctx.Err() != nil {
+					continue
+				}
+				cAlignment := alignTables(ctx, queryTableID, candTableID, a.domainDir, a.measures, a.attCDFs, a.tableCDF, a.perturbationDelta, a.strategy, a.columns)
+				if ctx.Err() != nil {
+					continue
+				}
 				result := SearchResult{
 					CandidateTableID:         candTableID,
 					Alignment:                cAlignment.alignment,
@@ -304,7 +195,7 @@ func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out c
 	go func() {
 		for result := range alignedTables {
 			//cAlignmentQueue.Push(result, result.CUnionabilityPercentiles[result.BestC-1])
-			if result.CUnionabilityPercentiles[result.BestC-1].Value != 0.0 {
+			if result.BestC > 0 && result.CUnionabilityPercentiles[result.BestC-1].Value != 0.0 {
 				cAlignmentQueue.Push(result, result.CUnionabilityPercentiles[result.BestC-1].ValueMinus, result.CUnionabilityPercentiles[result.BestC-1].ValuePlus)
 			}
 			//cAlignmentQueue.Push(result, result.CUnionabilityPercentiles[result.BestC-1].Value, result.CUnionabilityPercentiles[result.BestC-1].Value)
@@ -327,7 +218,12 @@ func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out c
 			result.Duration = float64(time.Now().Sub(a.startTime)) / float64(1000000)
 			result.N = i
 			//	seen[result.CandidateTableID] = true
-			out <- result
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				wwg.Done()
+				return
+			}
 			//}
 		}
 		wwg.Done()
@@ -335,5 +231,5 @@ func (a alignment) processPairsCombined(reduceQueue *pqueuespan.TopKQueue, out c
 	wg.Wait()
 	close(alignedTables)
 	wwg.Wait()
-	return a.completedTables.Unique() == a.n
+	return a.completedTables.Unique() == a.n || ctx.Err() != nil
 }
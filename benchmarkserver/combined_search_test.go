@@ -0,0 +1,53 @@
+package benchmarkserver
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func Test_CombinedOrderAllCancel(t *testing.T) {
+	root, err := ioutil.TempDir("", "repository")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := path.Join(root, "domains")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index, queryRequest := buildTestJaccardIndex(t, dir, 2*batchSize)
+	// every score is in the top decile
+	cdf := opendata.CDF{Histogram: []opendata.Bin{{UpperBound: 1.0, Percentile: 0.9}}}
+	s := &CombinedServer{
+		od:                index.od,
+		seti:              index,
+		measures:          []Measure{NewSetMeasure(index.od, index)},
+		attCDFs:           map[string]opendata.CDF{"set": cdf},
+		tableCDF:          map[int]opendata.CDF{1: cdf},
+		perturbationDelta: perturbationDelta,
+	}
+	queryRequest.N = 10
+	before := runtime.NumGoroutine()
+	// the client leaves after the first result
+	ctx, cancel := context.WithCancel(context.Background())
+	results := s.CombinedOrderAll(ctx, queryRequest)
+	if _, ok := <-results; !ok {
+		t.Fatal("no result found")
+	}
+	cancel()
+	for range results {
+	}
+	waitGoroutines(t, before)
+	// the request is cancelled before the search starts
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	for range s.CombinedOrderAll(ctx, queryRequest) {
+	}
+	waitGoroutines(t, before)
+}
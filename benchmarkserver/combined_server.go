@@ -1,11 +1,19 @@
 package benchmarkserver
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/gin-gonic/gin"
@@ -13,9 +21,11 @@ import (
 
 var (
 	perturbationDelta = 0.1
+	maxQueryCSVSize   = int64(1 << 26)
 )
 
 type CombinedServer struct {
+	od *opendata.Repository
 	// U_set index
 	seti *JaccardUnionIndex
 	// U_semset and U_sem indexes
@@ -27,10 +37,21 @@ type CombinedServer struct {
 	tableCDF          map[int]opendata.CDF
 	attCDFs           map[string]opendata.CDF
 	perturbationDelta float64
+	// measures used to find and align candidate tables
+	measures []Measure
+	// containment index of the text columns for join search
+	joini *JoinIndex
+	// builds the sketches of uploaded query tables
+	sketcher *TableSketcher
+	// the raw CSV files of the indexed tables, for their union
+	tableDir string
+	// serializes online insertion and deletion of tables
+	tablesLock sync.Mutex
 }
 
 type CombinedQueryRequest struct {
 	SetVecs      [][]uint64  `json:"settable"`
+	WSetVecs     [][]uint64  `json:"wsettable"`
 	OntVecs      [][]uint64  `json:"onttable"`
 	NoOntVecs    [][]uint64  `json:"noonttable"`
 	NlMeans      [][]float64 `json:"nlmean"`
@@ -40,21 +61,45 @@ type CombinedQueryRequest struct {
 	OntCards     []int       `json:"ontcard"`
 	NoOntCards   []int       `json:"noontcard"`
 	NlCards      []int       `json:"nlcard"`
+	NumVecs      [][]uint64  `json:"numtable"`
+	NumSketches  [][]float64 `json:"numsketch"`
 	QueryTableID string      `json:"querytableid"`
+	// greedy (default), optimal or optimal-log
+	Alignment string `json:"alignment"`
+	// none (default), dataset or mmr, with the weight of relevance for mmr,
+	// 0.7 if not set
+	Diversify string   `json:"diversify"`
+	Lambda    *float64 `json:"lambda,omitempty"`
+	// the query columns of the search with their weights, all the columns
+	// with weight 1 if empty
+	Columns []QueryColumn `json:"columns"`
+	// the query column of each vector of SetVecs, WSetVecs, NlMeans and
+	// NumVecs, required to select query columns
+	SetColumns  []int `json:"setcolumns"`
+	WSetColumns []int `json:"wsetcolumns"`
+	NlColumns   []int `json:"nlcolumns"`
+	NumColumns  []int `json:"numcolumns"`
+	// the number of neighbouring buckets probed in each table of the nl
+	// index, 0 to only shrink the hash keys
+	Probes int `json:"probes"`
 }
 
-func NewCombinedServer(seti, semi, semseti *JaccardUnionIndex, nli *UnionIndex) *CombinedServer {
-	setCDF, semCDF, semsetCDF, nlCDF, tableCDF := opendata.LoadCDF()
-	attCDFs := make(map[string]opendata.CDF)
-	attCDFs["set"] = setCDF
-	attCDFs["sem"] = semCDF
-	attCDFs["semset"] = semsetCDF
-	attCDFs["nl"] = nlCDF
+// NewCombinedServer creates a server of the tables of a repository, with
+// the CDFs of its measures.
+func NewCombinedServer(od *opendata.Repository, seti, semi, semseti *JaccardUnionIndex, nli *UnionIndex) *CombinedServer {
+	attCDFs, tableCDF := od.LoadCDF()
 	s := &CombinedServer{
+		od:      od,
 		seti:    seti,
 		semi:    semi,
 		semseti: semseti,
 		nli:     nli,
+		// the order of measures breaks ties in alignments
+		measures: []Measure{
+			NewNlMeasure(od, nli),
+			NewSetMeasure(od, seti),
+			AlignMeasure(od.GetMeasure("sem")),
+		},
 		//semCDF:    semCDF,
 		//setCDF:    setCDF,
 		//semsetCDF: semsetCDF,
@@ -65,6 +110,12 @@ func NewCombinedServer(seti, semi, semseti *JaccardUnionIndex, nli *UnionIndex)
 		perturbationDelta: perturbationDelta,
 	}
 	s.router.POST("/query", s.queryHandler)
+	s.router.POST("/query-csv", s.queryCSVHandler)
+	s.router.POST("/query-batch", s.batchQueryHandler)
+	s.router.POST("/tables", s.insertTableHandler)
+	s.router.DELETE("/tables/*id", s.deleteTableHandler)
+	s.router.POST("/union", s.unionHandler)
+	s.router.POST("/join", s.joinHandler)
 	log.Printf("New combined server for experiments.")
 	return s
 }
@@ -77,6 +128,43 @@ func (s *CombinedServer) Close() error {
 	return nil
 }
 
+// AddMeasure adds a measure to the search and alignment of candidate tables.
+// The CDF of the measure is loaded if it is not loaded yet.
+func (s *CombinedServer) AddMeasure(m Measure) {
+	if _, ok := s.attCDFs[m.Name()]; !ok {
+		s.attCDFs[m.Name()] = s.od.LoadMeasureCDF(m)
+	}
+	s.measures = append(s.measures, m)
+}
+
+// RemoveMeasure removes the measure with the given name from the search and
+// alignment of candidate tables, e.g. to replace set with wset.
+func (s *CombinedServer) RemoveMeasure(name string) {
+	measures := make([]Measure, 0, len(s.measures))
+	for _, m := range s.measures {
+		if m.Name() != name {
+			measures = append(measures, m)
+		}
+	}
+	s.measures = measures
+}
+
+// SetJoinIndex enables join search with a built index.
+func (s *CombinedServer) SetJoinIndex(joini *JoinIndex) {
+	s.joini = joini
+}
+
+// SetTableDir sets the directory of the raw CSV files of the indexed tables,
+// read by the union of tables.
+func (s *CombinedServer) SetTableDir(dir string) {
+	s.tableDir = dir
+}
+
+// SetSketcher enables querying with raw CSV tables.
+func (s *CombinedServer) SetSketcher(sketcher *TableSketcher) {
+	s.sketcher = sketcher
+}
+
 func (s *CombinedServer) queryHandler(c *gin.Context) {
 	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, 1048576))
 	if err != nil {
@@ -88,31 +176,138 @@ func (s *CombinedServer) queryHandler(c *gin.Context) {
 		c.AbortWithStatus(http.StatusUnprocessableEntity)
 		return
 	}
+	if !validAlignment(queryRequest.Alignment) || !validDiversify(queryRequest.Diversify, queryRequest.Lambda) || !validColumns(queryRequest.Columns) || !validVectorColumns(queryRequest) || queryRequest.Probes < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
 	// Query index
-	searchResults := make([]QueryResult, 0)
-	queryResults := s.CombinedOrderAll(queryRequest.NlMeans, queryRequest.NlCovars, queryRequest.SetVecs, queryRequest.NoOntVecs, queryRequest.OntVecs, queryRequest.N, queryRequest.NoOntCards, queryRequest.OntCards, queryRequest.NlCards, queryRequest.SetCards, queryRequest.QueryTableID)
+	queryResults := s.search(c.Request.Context(), queryRequest)
+	w := newResultWriter(c)
 	for result := range queryResults {
-		union := Union{
-			CandTableID:              result.CandidateTableID,
-			CandHeader:               getHeaders(result.CandidateTableID, s.seti.domainDir),
-			CandTextHeader:           getTextHeaders(result.CandidateTableID, s.seti.domainDir),
-			Alignment:                result.Alignment,
-			N:                        result.N,
-			Duration:                 result.Duration,
-			CUnionabilityScores:      result.CUnionabilityScores,
-			CUnionabilityPercentiles: result.CUnionabilityPercentiles,
-			BestC:                    result.BestC,
-			SketchedQueryColsNum:     result.SketchedQueryColsNum,
-			SketchedCandidateColsNum: result.SketchedCandidateColsNum,
-			C: result.C,
+		if err := w.Write(QueryResult{TableUnion: s.toUnion(result)}); err != nil {
+			log.Printf("Error in writing results: %s", err.Error())
 		}
+	}
+	w.Close()
+}
 
-		searchResults = append(searchResults, QueryResult{
-			TableUnion: union,
-		})
+// queryCSVHandler sketches an uploaded CSV table, given as the "file" field
+// of a multipart form or as the request body, and queries with it.
+func (s *CombinedServer) queryCSVHandler(c *gin.Context) {
+	if s.sketcher == nil {
+		c.AbortWithStatus(http.StatusNotImplemented)
+		return
 	}
-	response := QueryResponse{
-		Result: searchResults,
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil || n < 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	strategy := c.DefaultQuery("alignment", GreedyAlignment)
+	diversify := c.Query("diversify")
+	var lambda *float64
+	if value := c.Query("lambda"); value != "" {
+		l, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		lambda = &l
+	}
+	if !validAlignment(strategy) || !validDiversify(diversify, lambda) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	probes, err := strconv.Atoi(c.DefaultQuery("probes", "0"))
+	if err != nil || probes < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	columns, err := parseQueryColumns(c.Query("columns"), c.Query("weights"), c.Query("mustalign"))
+	if err != nil || !validColumns(columns) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	name, records, status := readCSVTable(c, c.DefaultQuery("name", "query.csv"))
+	if status != http.StatusOK {
+		c.AbortWithStatus(status)
+		return
+	}
+	headers := records[0]
+	// the sketches of the query table are kept until the search is done
+	queryTableID := path.Join("uploads", fmt.Sprintf("%d-%s", time.Now().UnixNano(), path.Base(name)))
+	defer os.RemoveAll(path.Join(s.seti.domainDir, queryTableID))
+	queryRequest, err := s.sketcher.Sketch(headers, records[1:], s.seti.domainDir, queryTableID)
+	if err != nil {
+		log.Printf("Error in sketching %s: %s", queryTableID, err.Error())
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	w := newResultWriter(c)
+	defer w.Close()
+	if len(queryRequest.SetVecs) == 0 && len(queryRequest.NumVecs) == 0 {
+		log.Printf("Query %s does not contain text or numeric attributes.", queryTableID)
+		return
+	}
+	queryTextHeaders := make([]string, 0)
+	for _, index := range getTextDomains(queryTableID, s.seti.domainDir) {
+		queryTextHeaders = append(queryTextHeaders, headers[index])
+	}
+	queryRequest.N = n
+	queryRequest.Alignment = strategy
+	queryRequest.Diversify = diversify
+	queryRequest.Lambda = lambda
+	queryRequest.Columns = columns
+	queryRequest.Probes = probes
+	queryResults := s.search(c.Request.Context(), queryRequest)
+	for result := range queryResults {
+		union := s.toUnion(result)
+		union.QueryHeader = headers
+		union.QueryTextHeader = queryTextHeaders
+		if err := w.Write(QueryResult{TableUnion: union}); err != nil {
+			log.Printf("Error in writing results: %s", err.Error())
+		}
+	}
+}
+
+// readCSVTable reads a CSV table from the "file" field of a multipart form
+// or from the request body. The name of the uploaded file replaces name.
+func readCSVTable(c *gin.Context, name string) (string, [][]string, int) {
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			return name, nil, http.StatusBadRequest
+		}
+		defer file.Close()
+		body = file
+		name = header.Filename
+	}
+	reader := csv.NewReader(io.LimitReader(body, maxQueryCSVSize))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) < 2 {
+		return name, nil, http.StatusUnprocessableEntity
+	}
+	return name, records, http.StatusOK
+}
+
+func (s *CombinedServer) toUnion(result SearchResult) Union {
+	return Union{
+		CandTableID:              result.CandidateTableID,
+		CandHeader:               getHeaders(s.od, result.CandidateTableID, s.seti.domainDir),
+		CandTextHeader:           getTextHeaders(s.od, result.CandidateTableID, s.seti.domainDir),
+		Alignment:                result.Alignment,
+		N:                        result.N,
+		Duration:                 result.Duration,
+		CUnionabilityScores:      result.CUnionabilityScores,
+		CUnionabilityPercentiles: result.CUnionabilityPercentiles,
+		BestC:                    result.BestC,
+		SketchedQueryColsNum:     result.SketchedQueryColsNum,
+		SketchedCandidateColsNum: result.SketchedCandidateColsNum,
+		C:                        result.C,
+		Dataset:                  result.Dataset,
+		Siblings:                 result.Siblings,
 	}
-	c.JSON(http.StatusOK, response)
 }
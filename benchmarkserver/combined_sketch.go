@@ -0,0 +1,147 @@
+package benchmarkserver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+// TableSketcher builds the domains and sketches of a raw query table on the
+// server, so clients without the fastText and YAGO databases can query.
+type TableSketcher struct {
+	od *opendata.Repository
+}
+
+// NewTableSketcher creates a sketcher saving the sketches of the measures
+// of a repository, leaving out its stop values. The nl, header and
+// ontology sketches are skipped unless the fastText embeddings and the
+// YAGO database are set on the repository.
+func NewTableSketcher(od *opendata.Repository) *TableSketcher {
+	return &TableSketcher{od: od}
+}
+
+// Sketch saves the domains of a table and their sketches to domainDir/tableID
+// in the layout of the offline pipeline, and returns the query request
+// for the table. The caller removes the directory when done.
+func (sk *TableSketcher) Sketch(headers []string, rows [][]string, domainDir, tableID string) (CombinedQueryRequest, error) {
+	queryRequest := CombinedQueryRequest{QueryTableID: tableID}
+	tableDir := path.Join(domainDir, tableID)
+	if err := os.MkdirAll(tableDir, 0755); err != nil {
+		return queryRequest, err
+	}
+	if err := writeLines(path.Join(tableDir, "index"), headers); err != nil {
+		return queryRequest, err
+	}
+	numHash := sk.od.MinhashSize()
+	types := make([]string, 0)
+	for i := range headers {
+		// same filter as the domain extraction of the pipeline
+		values := make([]string, 0)
+		for _, row := range rows {
+			if i < len(row) && len(strings.TrimSpace(row[i])) > 2 {
+				values = append(values, row[i])
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		sample := values
+		if len(sample) > 100 {
+			sample = sample[:100]
+		}
+		colType := classifyValues(sample)
+		types = append(types, fmt.Sprintf("%d %s", i, colType))
+		if colType != "text" && colType != "numeric" {
+			continue
+		}
+		if err := writeLines(path.Join(tableDir, fmt.Sprintf("%d.values", i)), values); err != nil {
+			return queryRequest, err
+		}
+		setCard := getCardinality(values)
+		if colType == "text" {
+			if err := writeLines(path.Join(tableDir, fmt.Sprintf("%d.card", i)), []string{fmt.Sprint(setCard)}); err != nil {
+				return queryRequest, err
+			}
+		}
+		if err := sk.od.SketchColumn(values, tableDir, i, colType); err != nil {
+			return queryRequest, err
+		}
+		if colType == "numeric" {
+			numVec, err := sk.od.ReadMinhashSignature(path.Join(tableDir, fmt.Sprintf("%d.num-minhash", i)), numHash)
+			if err != nil {
+				continue
+			}
+			sketch, err := sk.od.ReadNumericSketch(path.Join(tableDir, fmt.Sprintf("%d.num-sketch", i)))
+			if err != nil {
+				return queryRequest, err
+			}
+			queryRequest.NumVecs = append(queryRequest.NumVecs, numVec)
+			queryRequest.NumSketches = append(queryRequest.NumSketches, sketch.Vec())
+			queryRequest.NumColumns = append(queryRequest.NumColumns, i)
+			continue
+		}
+		setVec, err := sk.od.ReadMinhashSignature(getMinhashFilename(tableID, domainDir, i), numHash)
+		if err != nil {
+			return queryRequest, err
+		}
+		wsetVec, err := sk.od.ReadMinhashSignature(path.Join(tableDir, fmt.Sprintf("%d.wminhash", i)), numHash)
+		if err != nil {
+			return queryRequest, err
+		}
+		queryRequest.SetVecs = append(queryRequest.SetVecs, setVec)
+		queryRequest.WSetVecs = append(queryRequest.WSetVecs, wsetVec)
+		queryRequest.SetCards = append(queryRequest.SetCards, setCard)
+		queryRequest.SetColumns = append(queryRequest.SetColumns, i)
+		queryRequest.WSetColumns = append(queryRequest.WSetColumns, i)
+		if m := sk.od.GetMeasure("nl"); m != nil && sk.od.CanSketch(m) {
+			mean, merr := embedding.ReadVecFromDisk(path.Join(tableDir, fmt.Sprintf("%d.ft-mean", i)), ByteOrder)
+			covar, cerr := embedding.ReadVecFromDisk(path.Join(tableDir, fmt.Sprintf("%d.ft-covar", i)), ByteOrder)
+			if merr == nil && cerr == nil {
+				queryRequest.NlMeans = append(queryRequest.NlMeans, mean)
+				queryRequest.NlCovars = append(queryRequest.NlCovars, covar)
+				queryRequest.NlCards = append(queryRequest.NlCards, len(values))
+				queryRequest.NlColumns = append(queryRequest.NlColumns, i)
+			} else {
+				log.Printf("No embedding representation found for %s.%d.", tableID, i)
+			}
+		}
+		if m := sk.od.GetMeasure("sem"); m != nil && sk.od.CanSketch(m) {
+			ontVec, err := sk.od.ReadMinhashSignature(getOntMinhashFilename(tableID, domainDir, i), numHash)
+			if err != nil {
+				return queryRequest, err
+			}
+			noOntVec, err := sk.od.ReadMinhashSignature(getUnannotatedMinhashFilename(tableID, domainDir, i), numHash)
+			if err != nil {
+				return queryRequest, err
+			}
+			noOntCard, ontCard := getOntDomainCardinality(sk.od, tableID, domainDir, i)
+			queryRequest.OntVecs = append(queryRequest.OntVecs, ontVec)
+			queryRequest.NoOntVecs = append(queryRequest.NoOntVecs, noOntVec)
+			queryRequest.OntCards = append(queryRequest.OntCards, ontCard)
+			queryRequest.NoOntCards = append(queryRequest.NoOntCards, noOntCard)
+		}
+	}
+	if err := writeLines(path.Join(tableDir, "types"), types); err != nil {
+		return queryRequest, err
+	}
+	return queryRequest, nil
+}
+
+func writeLines(filename string, lines []string) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
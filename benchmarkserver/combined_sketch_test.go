@@ -0,0 +1,81 @@
+package benchmarkserver
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func Test_Sketch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "domains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	od := opendata.NewRepository(&opendata.Config{})
+	sk := NewTableSketcher(od)
+	headers := []string{"city", "population"}
+	rows := [][]string{
+		{"Toronto", "2731571"},
+		{"Montreal", "1704694"},
+		{"Vancouver", "631486"},
+	}
+	queryRequest, err := sk.Sketch(headers, rows, dir, "uploads/cities.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queryRequest.SetVecs) != 1 || len(queryRequest.SetVecs[0]) != 256 {
+		t.Fail()
+	}
+	if queryRequest.SetCards[0] != 3 {
+		t.Fail()
+	}
+	textDomains := getTextDomains("uploads/cities.csv", dir)
+	if len(textDomains) != 1 || textDomains[0] != 0 {
+		t.Fail()
+	}
+	sig, err := od.ReadMinhashSignature(path.Join(dir, "uploads/cities.csv", "0.minhash"), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sig {
+		if sig[i] != queryRequest.SetVecs[0][i] {
+			t.Fail()
+		}
+	}
+	// the cardinality is read from the sketch without the text file
+	if err := os.Remove(path.Join(dir, "uploads/cities.csv", "0.card")); err != nil {
+		t.Fatal(err)
+	}
+	if card := getDomainCardinality(od, "uploads/cities.csv", dir, 0); card != 3 {
+		t.Errorf("cardinality %d of the text column", card)
+	}
+	// the weighted set sketch of the value frequencies
+	wsig, err := od.ReadMinhashSignature(path.Join(dir, "uploads/cities.csv", "0.wminhash"), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queryRequest.WSetVecs) != 1 || estimateJaccard(wsig, queryRequest.WSetVecs[0]) != 1.0 {
+		t.Fatal("bad weighted minhash of the text column")
+	}
+	// the population column is sketched for the numeric measure
+	if len(queryRequest.NumVecs) != 1 || len(queryRequest.NumSketches) != 1 {
+		t.Fatal("numeric column is not sketched")
+	}
+	if len(queryRequest.SetColumns) != 1 || queryRequest.SetColumns[0] != 0 || len(queryRequest.NumColumns) != 1 || queryRequest.NumColumns[0] != 1 {
+		t.Errorf("query columns of the vectors are %v and %v", queryRequest.SetColumns, queryRequest.NumColumns)
+	}
+	numSketch, err := od.ReadNumericSketch(path.Join(dir, "uploads/cities.csv", "1.num-sketch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numSketch.Count != 3 || numSketch.Min() != 631486 || numSketch.Max() != 2731571 {
+		t.Errorf("bad numeric sketch %v", numSketch)
+	}
+	if domains := getDomains("uploads/cities.csv", dir, "text", "numeric"); len(domains) != 2 {
+		t.Errorf("got domains %v", domains)
+	}
+}
@@ -0,0 +1,164 @@
+package benchmarkserver
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tableIndex is implemented by measures with their own index, which is
+// updated when tables are inserted or deleted.
+type tableIndex interface {
+	InsertTable(tableID string) (int, error)
+	DeleteTable(tableID string) (int, error)
+}
+
+// validTableID rejects table IDs that escape the domain dir, cannot be
+// used in column IDs or collide with the uploaded query tables.
+func validTableID(tableID string) bool {
+	if tableID == "" || path.Clean(tableID) != tableID || path.IsAbs(tableID) {
+		return false
+	}
+	if strings.HasPrefix(tableID, "..") || strings.HasPrefix(tableID, "uploads/") {
+		return false
+	}
+	return !strings.Contains(tableID, ":")
+}
+
+// insertTableHandler sketches an uploaded CSV table, given as the "file" field
+// of a multipart form or as the request body, and inserts it into the indexes.
+// The sketches and the CSV file are kept in the domain dir so the table can
+// be searched, unioned and deleted later.
+func (s *CombinedServer) insertTableHandler(c *gin.Context) {
+	if s.sketcher == nil {
+		c.AbortWithStatus(http.StatusNotImplemented)
+		return
+	}
+	name, records, status := readCSVTable(c, c.Query("id"))
+	if status != http.StatusOK {
+		c.AbortWithStatus(status)
+		return
+	}
+	tableID := c.DefaultQuery("id", name)
+	if !validTableID(tableID) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	s.tablesLock.Lock()
+	defer s.tablesLock.Unlock()
+	tableDir := path.Join(s.seti.domainDir, tableID)
+	if _, err := os.Stat(tableDir); err == nil {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+	if _, err := s.sketcher.Sketch(records[0], records[1:], s.seti.domainDir, tableID); err != nil {
+		log.Printf("Error in sketching %s: %s", tableID, err.Error())
+		os.RemoveAll(tableDir)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	// kept to materialize unions with the table
+	if err := writeTableCSV(records, path.Join(tableDir, tableCSVFilename)); err != nil {
+		log.Printf("Error in saving %s: %s", tableID, err.Error())
+		os.RemoveAll(tableDir)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	numSet, err := s.seti.InsertTable(tableID, "minhash")
+	if err == nil {
+		_, err = s.semi.InsertTable(tableID, "ont-minhash-l1")
+	}
+	if err == nil {
+		_, err = s.semseti.InsertTable(tableID, "noann-minhash")
+	}
+	if err == nil {
+		_, err = s.nli.InsertTable(tableID)
+	}
+	for _, m := range s.measures {
+		if index, ok := m.(tableIndex); ok && err == nil {
+			_, err = index.InsertTable(tableID)
+		}
+	}
+	if s.joini != nil && err == nil {
+		_, err = s.joini.InsertTable(tableID)
+	}
+	if err != nil {
+		log.Printf("Error in inserting %s: %s", tableID, err.Error())
+		s.deleteTable(tableID)
+		os.RemoveAll(tableDir)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Inserted table %s with %d text columns.", tableID, numSet)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      tableID,
+		"columns": numSet,
+	})
+}
+
+// deleteTableHandler removes a table from the indexes and deletes its
+// sketches from the domain dir.
+func (s *CombinedServer) deleteTableHandler(c *gin.Context) {
+	tableID := strings.TrimPrefix(c.Param("id"), "/")
+	if !validTableID(tableID) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	s.tablesLock.Lock()
+	defer s.tablesLock.Unlock()
+	tableDir := path.Join(s.seti.domainDir, tableID)
+	if _, err := os.Stat(path.Join(tableDir, "types")); os.IsNotExist(err) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	numSet, err := s.deleteTable(tableID)
+	if err != nil {
+		log.Printf("Error in deleting %s: %s", tableID, err.Error())
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if err := os.RemoveAll(tableDir); err != nil {
+		log.Printf("Error in removing %s: %s", tableDir, err.Error())
+	}
+	s.od.InvalidateSketches(tableID)
+	log.Printf("Deleted table %s with %d text columns.", tableID, numSet)
+	c.JSON(http.StatusOK, gin.H{
+		"id":      tableID,
+		"columns": numSet,
+	})
+}
+
+// deleteTable removes a table from all indexes. The sketch files are read
+// to find the buckets of the columns, so they are removed afterwards.
+func (s *CombinedServer) deleteTable(tableID string) (int, error) {
+	numSet, err := s.seti.DeleteTable(tableID, "minhash")
+	if err != nil {
+		return numSet, err
+	}
+	if _, err := s.semi.DeleteTable(tableID, "ont-minhash-l1"); err != nil {
+		return numSet, err
+	}
+	if _, err := s.semseti.DeleteTable(tableID, "noann-minhash"); err != nil {
+		return numSet, err
+	}
+	if _, err := s.nli.DeleteTable(tableID); err != nil {
+		return numSet, err
+	}
+	for _, m := range s.measures {
+		if index, ok := m.(tableIndex); ok {
+			if _, err := index.DeleteTable(tableID); err != nil {
+				return numSet, err
+			}
+		}
+	}
+	if s.joini != nil {
+		if _, err := s.joini.DeleteTable(tableID); err != nil {
+			return numSet, err
+		}
+	}
+	return numSet, nil
+}
@@ -0,0 +1,200 @@
+package benchmarkserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fnargesian/pqueuespan"
+	"github.com/gin-gonic/gin"
+)
+
+var defaultShardTimeout = 30 * time.Second
+
+// Coordinator fans the queries out to shard servers, each a CombinedServer
+// over a partition of the tables, and merges the results of the shards
+// into a global top-n. The query table has to be sketched in the domain dir
+// of every shard, or uploaded with /query-csv.
+type Coordinator struct {
+	shards []string
+	cli    *http.Client
+	// the time a shard has to answer, after which its results so far are
+	// used
+	timeout time.Duration
+	router  *gin.Engine
+}
+
+// ShardStatus reports the number of results of a shard, and the error if
+// the shard failed or timed out.
+type ShardStatus struct {
+	Shard string `json:"shard"`
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// CoordinatorResponse is a QueryResponse with the status of the shards.
+// Partial is true if a shard did not return all of its results.
+type CoordinatorResponse struct {
+	Result  []QueryResult `json:"result"`
+	Partial bool          `json:"partial"`
+	Shards  []ShardStatus `json:"shards"`
+}
+
+// NewCoordinator creates a coordinator over the shard servers, given by
+// their base URLs, e.g. http://localhost:4064.
+func NewCoordinator(shards []string, timeout time.Duration) *Coordinator {
+	if timeout == 0 {
+		timeout = defaultShardTimeout
+	}
+	s := &Coordinator{
+		shards:  shards,
+		cli:     &http.Client{},
+		timeout: timeout,
+		router:  gin.Default(),
+	}
+	s.router.POST("/query", s.queryHandler)
+	s.router.POST("/query-csv", s.queryCSVHandler)
+	log.Printf("New coordinator of %d shards.", len(shards))
+	return s
+}
+
+func (s *Coordinator) Run(port string) error {
+	return s.router.Run(":" + port)
+}
+
+func (s *Coordinator) Close() error {
+	return nil
+}
+
+// Query sends a request to the path of every shard and merges the results
+// by the percentile interval of their best c-alignment.
+func (s *Coordinator) Query(ctx context.Context, path string, params url.Values, contentType string, body []byte, n int) CoordinatorResponse {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	statuses := make([]ShardStatus, len(s.shards))
+	results := make(chan QueryResult)
+	wg := &sync.WaitGroup{}
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard string) {
+			defer wg.Done()
+			statuses[i].Shard = shard
+			err := s.queryShard(ctx, shard+path, params, contentType, body, func(result QueryResult) {
+				statuses[i].Count += 1
+				results <- result
+			})
+			if err != nil {
+				log.Printf("Error in querying shard %s: %s", shard, err.Error())
+				statuses[i].Error = err.Error()
+			}
+		}(i, shard)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	queue := pqueuespan.NewTopKQueue(n)
+	for result := range results {
+		union := result.TableUnion
+		if union.BestC < 1 || union.BestC > len(union.CUnionabilityPercentiles) {
+			continue
+		}
+		p := union.CUnionabilityPercentiles[union.BestC-1]
+		queue.Push(result, p.ValueMinus, p.ValuePlus)
+	}
+	response := CoordinatorResponse{
+		Result: make([]QueryResult, 0),
+		Shards: statuses,
+	}
+	merged, _, _ := queue.Descending()
+	for i := range merged {
+		result := merged[i].(QueryResult)
+		result.TableUnion.N = i
+		response.Result = append(response.Result, result)
+	}
+	for _, status := range statuses {
+		if status.Error != "" {
+			response.Partial = true
+		}
+	}
+	return response
+}
+
+// queryShard streams the results of a shard as ndjson. The results read
+// before an error are kept.
+func (s *Coordinator) queryShard(ctx context.Context, target string, params url.Values, contentType string, body []byte, emit func(QueryResult)) error {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("stream", streamNDJSON)
+	req, err := http.NewRequest("POST", target+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var result QueryResult
+		if err := decoder.Decode(&result); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		emit(result)
+	}
+}
+
+func (s *Coordinator) queryHandler(c *gin.Context) {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, 1048576))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	var queryRequest CombinedQueryRequest
+	if err := json.Unmarshal(body, &queryRequest); err != nil {
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+		return
+	}
+	if queryRequest.N < 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	response := s.Query(c.Request.Context(), "/query", nil, c.GetHeader("Content-Type"), body, queryRequest.N)
+	c.JSON(http.StatusOK, response)
+}
+
+// queryCSVHandler forwards an uploaded CSV table to the shards, which
+// sketch it and query with it.
+func (s *Coordinator) queryCSVHandler(c *gin.Context) {
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil || n < 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxQueryCSVSize))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	response := s.Query(c.Request.Context(), "/query-csv", c.Request.URL.Query(), c.GetHeader("Content-Type"), body, n)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,77 @@
+package benchmarkserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/gin-gonic/gin"
+)
+
+func testUnion(tableID string, p float64) Union {
+	return Union{
+		CandTableID:              tableID,
+		BestC:                    1,
+		CUnionabilityPercentiles: []opendata.Percentile{{Value: p, ValueMinus: p, ValuePlus: p}},
+	}
+}
+
+// testShard serves the unions and then waits for delay before ending
+// the response.
+func testShard(unions []Union, delay time.Duration) *httptest.Server {
+	router := gin.New()
+	router.POST("/query", func(c *gin.Context) {
+		w := newResultWriter(c)
+		for _, union := range unions {
+			w.Write(QueryResult{TableUnion: union})
+		}
+		select {
+		case <-time.After(delay):
+		case <-c.Request.Context().Done():
+		}
+		w.Close()
+	})
+	return httptest.NewServer(router)
+}
+
+func Test_Coordinator(t *testing.T) {
+	a := testShard([]Union{testUnion("a1.csv", 0.9), testUnion("a2.csv", 0.5)}, 0)
+	defer a.Close()
+	b := testShard([]Union{testUnion("b1.csv", 0.7)}, 0)
+	defer b.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	// the slow shard sends its first result before the timeout
+	slow := testShard([]Union{testUnion("s1.csv", 0.8)}, time.Minute)
+	defer slow.Close()
+
+	coordinator := NewCoordinator([]string{a.URL, b.URL}, time.Second)
+	response := coordinator.Query(context.Background(), "/query", nil, "application/json", []byte("{}"), 2)
+	if response.Partial || len(response.Result) != 2 {
+		t.Fatalf("response is %v", response)
+	}
+	if response.Result[0].TableUnion.CandTableID != "a1.csv" || response.Result[1].TableUnion.CandTableID != "b1.csv" || response.Result[1].TableUnion.N != 1 {
+		t.Errorf("merged results are %v", response.Result)
+	}
+
+	coordinator = NewCoordinator([]string{a.URL, failing.URL, slow.URL}, 500*time.Millisecond)
+	response = coordinator.Query(context.Background(), "/query", nil, "application/json", []byte("{}"), 10)
+	if !response.Partial {
+		t.Error("response with a failing shard is not partial")
+	}
+	ids := make([]string, 0)
+	for _, r := range response.Result {
+		ids = append(ids, r.TableUnion.CandTableID)
+	}
+	if len(ids) != 3 || ids[0] != "a1.csv" || ids[1] != "s1.csv" || ids[2] != "a2.csv" {
+		t.Errorf("partial results are %v", ids)
+	}
+	if response.Shards[0].Error != "" || response.Shards[1].Error == "" || response.Shards[2].Error == "" || response.Shards[2].Count != 1 {
+		t.Errorf("shard statuses are %v", response.Shards)
+	}
+}
@@ -0,0 +1,180 @@
+package benchmarkserver
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Modes to diversify the results of a query.
+const (
+	// DatasetDiversity returns one representative per dataset, with the
+	// IDs of the other resources of the dataset as siblings.
+	DatasetDiversity = "dataset"
+	// MMRDiversity reranks the results by maximal marginal relevance,
+	// penalizing candidates similar to the ones ranked before.
+	MMRDiversity = "mmr"
+)
+
+var (
+	// the search finds more candidates when the results are diversified
+	diversifyFactor  = 5
+	defaultMMRLambda = 0.7
+)
+
+func validDiversify(mode string, lambda *float64) bool {
+	if lambda != nil && (*lambda < 0.0 || *lambda > 1.0) {
+		return false
+	}
+	switch mode {
+	case "", DatasetDiversity, MMRDiversity:
+		return true
+	}
+	return false
+}
+
+// datasetID returns the dataset of an open data resource, the prefix of
+// the table ID before "____".
+func datasetID(tableID string) string {
+	if i := strings.LastIndex(tableID, "____"); i != -1 {
+		return tableID[:i]
+	}
+	return tableID
+}
+
+// relevance is the score that ranks the results of the combined search.
+func relevance(result SearchResult) float64 {
+	if result.BestC < 1 || result.BestC > len(result.CUnionabilityPercentiles) {
+		return 0.0
+	}
+	return result.CUnionabilityPercentiles[result.BestC-1].Value
+}
+
+func sortByRelevance(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return relevance(results[i]) > relevance(results[j])
+	})
+}
+
+// groupByDataset keeps the most relevant result of every dataset, in the
+// order of relevance.
+func groupByDataset(results []SearchResult) []SearchResult {
+	sortByRelevance(results)
+	groups := make(map[string]int)
+	grouped := make([]SearchResult, 0)
+	for _, r := range results {
+		dataset := datasetID(r.CandidateTableID)
+		if i, ok := groups[dataset]; ok {
+			grouped[i].Siblings = append(grouped[i].Siblings, r.CandidateTableID)
+			continue
+		}
+		r.Dataset = dataset
+		groups[dataset] = len(grouped)
+		grouped = append(grouped, r)
+	}
+	return grouped
+}
+
+// candidateSimilarity is 1 for resources of the same dataset, and the
+// Jaccard similarity of the headers otherwise.
+func candidateSimilarity(a, b string, headers map[string][]string) float64 {
+	if datasetID(a) == datasetID(b) {
+		return 1.0
+	}
+	ha := make(map[string]bool)
+	for _, h := range headers[a] {
+		ha[strings.ToLower(h)] = true
+	}
+	hb := make(map[string]bool)
+	for _, h := range headers[b] {
+		hb[strings.ToLower(h)] = true
+	}
+	if len(ha) == 0 || len(hb) == 0 {
+		return 0.0
+	}
+	intersection := 0
+	for h := range ha {
+		if hb[h] {
+			intersection += 1
+		}
+	}
+	return float64(intersection) / float64(len(ha)+len(hb)-intersection)
+}
+
+// mmrRerank orders the results greedily by
+// lambda * relevance - (1 - lambda) * max similarity to the results before.
+func mmrRerank(results []SearchResult, lambda float64, headers map[string][]string) []SearchResult {
+	sortByRelevance(results)
+	remaining := make([]SearchResult, len(results))
+	copy(remaining, results)
+	// the max similarity of every remaining result to the reranked ones
+	maxSim := make([]float64, len(remaining))
+	reranked := make([]SearchResult, 0, len(results))
+	for len(remaining) > 0 {
+		best := 0
+		bestScore := 0.0
+		for i, r := range remaining {
+			score := lambda*relevance(r) - (1.0-lambda)*maxSim[i]
+			if i == 0 || score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+		picked := remaining[best]
+		picked.Dataset = datasetID(picked.CandidateTableID)
+		reranked = append(reranked, picked)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		maxSim = append(maxSim[:best], maxSim[best+1:]...)
+		for i, r := range remaining {
+			if s := candidateSimilarity(picked.CandidateTableID, r.CandidateTableID, headers); s > maxSim[i] {
+				maxSim[i] = s
+			}
+		}
+	}
+	return reranked
+}
+
+// search runs the combined search. If the query asks for diversity,
+// diversifyFactor times more candidates are searched and the top-N
+// diversified results are returned once the search is done.
+func (s *CombinedServer) search(ctx context.Context, query CombinedQueryRequest) <-chan SearchResult {
+	if query.Diversify == "" {
+		return s.CombinedOrderAll(ctx, query)
+	}
+	n := query.N
+	query.N = n * diversifyFactor
+	lambda := defaultMMRLambda
+	if query.Lambda != nil {
+		lambda = *query.Lambda
+	}
+	out := make(chan SearchResult)
+	go func() {
+		defer close(out)
+		results := make([]SearchResult, 0)
+		for result := range s.CombinedOrderAll(ctx, query) {
+			results = append(results, result)
+		}
+		switch query.Diversify {
+		case DatasetDiversity:
+			results = groupByDataset(results)
+		case MMRDiversity:
+			headers := make(map[string][]string)
+			for _, r := range results {
+				headers[r.CandidateTableID] = getHeaders(s.od, r.CandidateTableID, s.seti.domainDir)
+			}
+			results = mmrRerank(results, lambda, headers)
+		}
+		for i, result := range results {
+			if i == n {
+				break
+			}
+			result.N = i
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,78 @@
+package benchmarkserver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func testResult(tableID string, p float64) SearchResult {
+	return SearchResult{
+		CandidateTableID:         tableID,
+		CUnionabilityPercentiles: []opendata.Percentile{{Value: p}},
+		BestC:                    1,
+	}
+}
+
+func Test_groupByDataset(t *testing.T) {
+	results := []SearchResult{
+		testResult("d1____0/2015.csv", 0.8),
+		testResult("d1____1/2016.csv", 0.9),
+		testResult("d2____0/a.csv", 0.85),
+		testResult("d1____2/2017.csv", 0.7),
+	}
+	grouped := groupByDataset(results)
+	if len(grouped) != 2 {
+		t.Fatalf("grouped results %v", grouped)
+	}
+	if grouped[0].CandidateTableID != "d1____1/2016.csv" || grouped[0].Dataset != "d1" {
+		t.Errorf("representative of d1 is %s", grouped[0].CandidateTableID)
+	}
+	if want := []string{"d1____0/2015.csv", "d1____2/2017.csv"}; !reflect.DeepEqual(grouped[0].Siblings, want) {
+		t.Errorf("siblings of d1 are %v, want %v", grouped[0].Siblings, want)
+	}
+	if grouped[1].CandidateTableID != "d2____0/a.csv" || len(grouped[1].Siblings) != 0 {
+		t.Errorf("second result is %v", grouped[1])
+	}
+}
+
+func Test_mmrRerank(t *testing.T) {
+	results := []SearchResult{
+		testResult("d1____0/2015.csv", 0.9),
+		testResult("d1____1/2016.csv", 0.89),
+		testResult("d2____0/a.csv", 0.8),
+		testResult("d3____0/b.csv", 0.7),
+	}
+	headers := map[string][]string{
+		"d2____0/a.csv": {"city", "province"},
+		"d3____0/b.csv": {"City", "Province"},
+	}
+	var ids []string
+	for _, r := range mmrRerank(results, 0.7, headers) {
+		ids = append(ids, r.CandidateTableID)
+	}
+	// the sibling and the table with the same headers as d2 are pushed down
+	want := []string{"d1____0/2015.csv", "d2____0/a.csv", "d1____1/2016.csv", "d3____0/b.csv"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("reranked results %v, want %v", ids, want)
+	}
+	ids = nil
+	for _, r := range mmrRerank(results, 1.0, headers) {
+		ids = append(ids, r.CandidateTableID)
+	}
+	if ids[1] != "d1____1/2016.csv" {
+		t.Errorf("results with lambda 1 are %v", ids)
+	}
+}
+
+func Test_validDiversify(t *testing.T) {
+	zero, two := 0.0, 2.0
+	// pure diversity is a valid weight, unlike the default
+	if !validDiversify(MMRDiversity, nil) || !validDiversify(MMRDiversity, &zero) {
+		t.Error("lambda 0 or not set is invalid")
+	}
+	if validDiversify(MMRDiversity, &two) || validDiversify("other", nil) {
+		t.Error("invalid diversity accepted")
+	}
+}
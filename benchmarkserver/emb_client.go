@@ -4,24 +4,23 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"math"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/ekzhu/datatable"
 	fasttext "github.com/ekzhu/go-fasttext"
 )
 
 type Client struct {
+	od       *opendata.Repository
 	ft       *fasttext.FastText
 	host     string
 	cli      *http.Client
@@ -29,9 +28,10 @@ type Client struct {
 	tokenFun func(string) []string
 }
 
-func NewClient(ft *fasttext.FastText, host string) (*Client, error) {
+func NewClient(od *opendata.Repository, ft *fasttext.FastText, host string) (*Client, error) {
 	log.Printf("New emb client for experiments.")
 	return &Client{
+		od:       od,
 		ft:       ft,
 		host:     host,
 		cli:      &http.Client{},
@@ -162,7 +162,7 @@ func (c *Client) QueryWithFixedN(queryCSVFilename string, minK, n int) []QueryRe
 		if classifyValues(col) == "text" {
 			//mean, covar, err := embedding.GetDomainEmbMeanCovar(c.ft, c.tokenFun, c.transFun, col)
 			//mean, err := embedding.GetDomainEmbSum(c.ft, c.tokenFun, c.transFun, col)
-			mean, covar, err := getDomainEmbMeanCovar(queryCSVFilename, i)
+			mean, covar, err := getDomainEmbMeanCovar(c.od, queryCSVFilename, i)
 			if err != nil {
 				//log.Printf("Embedding not found for column %d", i)
 				continue
@@ -223,13 +223,9 @@ func containsNan(matrix []float64) bool {
 	return false
 }
 
-func getDomainEmbMeanCovar(tableID string, colIndex int) ([]float64, []float64, error) {
-	if strings.HasPrefix(tableID, "us.") {
-		tableID = strings.Replace(tableID, opendataDirUS, "", -1)
-	} else {
-		tableID = strings.Replace(tableID, opendataDir, "", -1)
-	}
-	meanFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ft-mean", tableID, colIndex))
+func getDomainEmbMeanCovar(od *opendata.Repository, tableID string, colIndex int) ([]float64, []float64, error) {
+	tableID = tableIDOf(od, tableID)
+	meanFilename := od.DomainFilename(tableID, colIndex, "ft-mean")
 	if _, err := os.Stat(meanFilename); os.IsNotExist(err) {
 		//log.Printf("Mean embedding file %s does not exist.", meanFilename)
 		return nil, nil, err
@@ -240,7 +236,7 @@ func getDomainEmbMeanCovar(tableID string, colIndex int) ([]float64, []float64,
 		return nil, nil, err
 	}
 	// reading covariance matrix
-	covarFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ft-covar", tableID, colIndex))
+	covarFilename := od.DomainFilename(tableID, colIndex, "ft-covar")
 	if _, err := os.Stat(covarFilename); os.IsNotExist(err) {
 		//log.Printf("Embedding file %s does not exist.", covarFilename)
 		return nil, nil, err
@@ -1,6 +1,7 @@
 package benchmarkserver
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"log"
@@ -39,16 +40,34 @@ type SearchResult struct {
 	SketchedQueryColsNum     int
 	SketchedCandidateColsNum int
 	C                        int
+	// the dataset of the candidate and the other resources of the dataset
+	// in the results, if the results are diversified
+	Dataset  string
+	Siblings []string
+}
+
+// VectorIndex is an index of the mean embeddings of columns, either the
+// cosine LSH or the HNSW graph.
+type VectorIndex interface {
+	Add(point []float64, key string)
+	Index()
+	Insert(point []float64, key string)
+	Delete(point []float64, key string) bool
+	Vector(key string) ([]float64, bool)
+	QueryPlus(points [][]float64, done <-chan struct{}) <-chan simhashlsh.UnionPair
+	SaveFile(filename string) error
 }
 
 type UnionIndex struct {
-	lsh       *simhashlsh.CosineLSH
+	od        *opendata.Repository
+	lsh       VectorIndex
 	domainDir string
 	byteOrder binary.ByteOrder
 }
 
-func NewUnionIndex(domainDir string, lsh *simhashlsh.CosineLSH) *UnionIndex {
+func NewUnionIndex(od *opendata.Repository, domainDir string, lsh VectorIndex) *UnionIndex {
 	index := &UnionIndex{
+		od:        od,
 		lsh:       lsh,
 		domainDir: domainDir,
 		byteOrder: ByteOrder,
@@ -56,10 +75,31 @@ func NewUnionIndex(domainDir string, lsh *simhashlsh.CosineLSH) *UnionIndex {
 	return index
 }
 
+// storedVector returns the mean embedding of a column kept in the LSH
+// index, or nil if the index does not store them.
+func (index *UnionIndex) storedVector(columnID string) []float64 {
+	vec, ok := index.lsh.Vector(columnID)
+	if !ok {
+		return nil
+	}
+	return vec
+}
+
+// SetThresholdModel tunes the LSH queries of the nl measure to the
+// thresholds predicted by the model. Only the cosine LSH is tuned.
+func (index *UnionIndex) SetThresholdModel(m *simhashlsh.ThresholdModel) {
+	lsh, ok := index.lsh.(*simhashlsh.CosineLSH)
+	if !ok {
+		log.Printf("The threshold model is ignored by the %T index.", index.lsh)
+		return
+	}
+	lsh.SetThresholdModel(m)
+}
+
 func (index *UnionIndex) BuildScalability(size int) error {
 	start := getNow()
-	domainfilenames := opendata.StreamFilenames()
-	embfilenames := opendata.StreamAllODEmbVectors(10, domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	embfilenames := index.od.StreamAllODEmbVectors(10, domainfilenames)
 	count := 0
 	for file := range embfilenames {
 		if count < size {
@@ -85,8 +125,8 @@ func (index *UnionIndex) BuildScalability(size int) error {
 }
 
 func (index *UnionIndex) Build() error {
-	domainfilenames := opendata.StreamFilenames()
-	embfilenames := opendata.StreamEmbVectors(10, domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	embfilenames := index.od.StreamEmbVectors(10, domainfilenames)
 	//embfilenames := StreamAllODEmbVectors(10, domainfilenames)
 	start := getNow()
 	count := 0
@@ -159,7 +199,7 @@ func (a alignment) get(candidateTableID string) []Pair {
 	return pairs
 }
 
-func (a alignment) processPairsEmbedding(pairQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
+func (a alignment) processPairsEmbedding(ctx context.Context, pairQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
 	pairs, _ := pairQueue.Descending()
 	for i := range pairs {
 		pair := pairs[i].(Pair)
@@ -185,7 +225,11 @@ func (a alignment) processPairsEmbedding(pairQueue *pqueue.TopKQueue, out chan<-
 				N:                a.completedTables.Unique(),
 				Duration:         float64(time.Now().Sub(a.startTime)) / float64(1000000),
 			}
-			out <- result
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return true
+			}
 		}
 		// Check if we are done
 		if a.completedTables.Unique() == a.n {
@@ -195,7 +239,7 @@ func (a alignment) processPairsEmbedding(pairQueue *pqueue.TopKQueue, out chan<-
 	return a.completedTables.Unique() == a.n
 }
 
-func (index *UnionIndex) QueryOrderAll(query, queryCovar [][]float64, N, K int, queryCardinality []int) <-chan SearchResult {
+func (index *UnionIndex) QueryOrderAll(ctx context.Context, query, queryCovar [][]float64, N, K int, queryCardinality []int) <-chan SearchResult {
 	//log.Printf("Started querying the index with %d columns.", len(query))
 	//start := getNow()
 	results := make(chan SearchResult)
@@ -203,15 +247,15 @@ func (index *UnionIndex) QueryOrderAll(query, queryCovar [][]float64, N, K int,
 		defer close(results)
 		alignment := initAlignment(K, N)
 		batch := pqueue.NewTopKQueue(batchSize)
-		done := make(chan struct{})
-		defer close(done)
-		for pair := range index.lsh.QueryPlus(query, done) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for pair := range index.lsh.QueryPlus(query, ctx.Done()) {
 			tableID, columnIndex := fromColumnID(pair.CandidateKey)
 			// discard columns of already aligned tables
 			if alignment.hasCompleted(tableID) {
 				continue
 			}
-			e := getColumnPairPlus(tableID, index.domainDir, columnIndex, pair.QueryIndex, query[pair.QueryIndex], queryCovar[pair.QueryIndex], queryCardinality[pair.QueryIndex])
+			e := getColumnPairPlus(tableID, index.domainDir, columnIndex, pair.QueryIndex, index.storedVector(pair.CandidateKey), query[pair.QueryIndex], queryCovar[pair.QueryIndex], queryCardinality[pair.QueryIndex])
 			if !math.IsNaN(e.T2) && !math.IsNaN(e.F) && !math.IsInf(e.T2, 0) && !math.IsInf(e.F, 0) {
 				if e.Cosine != 0.0 {
 					batch.Push(e, e.Cosine)
@@ -225,30 +269,35 @@ func (index *UnionIndex) QueryOrderAll(query, queryCovar [][]float64, N, K int,
 				continue
 			}
 			// Process the batch
-			if finished := alignment.processPairsEmbedding(batch, results); finished {
+			if finished := alignment.processPairsEmbedding(ctx, batch, results); finished {
 				//log.Printf("elapse time: %f", getNow()-start)
 				return
 			}
 		}
 		// Don't forget remaining pairs in the queue
-		if !batch.Empty() {
-			alignment.processPairsEmbedding(batch, results)
+		if !batch.Empty() && ctx.Err() == nil {
+			alignment.processPairsEmbedding(ctx, batch, results)
 		}
 	}()
 	return results
 }
 
-func getColumnPairPlus(candTableID, domainDir string, candColIndex, queryColIndex int, queryMean, queryCovar []float64, queryCardinality int) Pair {
+// getColumnPairPlus scores a candidate column by the cosine of its mean
+// embedding, or by the mean read from disk if it is nil.
+func getColumnPairPlus(candTableID, domainDir string, candColIndex, queryColIndex int, mean, queryMean, queryCovar []float64, queryCardinality int) Pair {
 	// getting the embedding of the candidate column
-	meanFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ft-mean", candTableID, candColIndex))
-	if _, err := os.Stat(meanFilename); os.IsNotExist(err) {
-		log.Printf("Mean embedding file %s does not exist.", meanFilename)
-		panic(err)
-	}
-	mean, err := embedding.ReadVecFromDisk(meanFilename, ByteOrder)
-	if err != nil {
-		log.Printf("Error in reading %s from disk.", meanFilename)
-		panic(err)
+	if mean == nil {
+		meanFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ft-mean", candTableID, candColIndex))
+		if _, err := os.Stat(meanFilename); os.IsNotExist(err) {
+			log.Printf("Mean embedding file %s does not exist.", meanFilename)
+			panic(err)
+		}
+		var err error
+		mean, err = embedding.ReadVecFromDisk(meanFilename, ByteOrder)
+		if err != nil {
+			log.Printf("Error in reading %s from disk.", meanFilename)
+			panic(err)
+		}
 	}
 	// reading covariance matrix
 	//covarFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ft-covar", candTableID, candColIndex))
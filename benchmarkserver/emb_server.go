@@ -51,6 +51,8 @@ type Union struct {
 	SketchedQueryColsNum     int
 	SketchedCandidateColsNum int
 	C                        int
+	Dataset                  string
+	Siblings                 []string
 }
 
 func NewServer(ui *UnionIndex) *Server {
@@ -86,15 +88,15 @@ func (s *Server) queryHandler(c *gin.Context) {
 		return
 	}
 	// Query index
-	searchResults := make([]QueryResult, 0)
 	//start := time.Now()
-	queryResults := s.ui.QueryOrderAll(queryRequest.Vecs, queryRequest.Covars, queryRequest.N, queryRequest.K, queryRequest.Cards)
+	queryResults := s.ui.QueryOrderAll(c.Request.Context(), queryRequest.Vecs, queryRequest.Covars, queryRequest.N, queryRequest.K, queryRequest.Cards)
 	//dur := time.Since(start)
+	w := newResultWriter(c)
 	for result := range queryResults {
 		union := Union{
 			CandTableID:    result.CandidateTableID,
-			CandHeader:     getHeaders(result.CandidateTableID, s.ui.domainDir),
-			CandTextHeader: getTextHeaders(result.CandidateTableID, s.ui.domainDir),
+			CandHeader:     getHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
+			CandTextHeader: getTextHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
 			Alignment:      result.Alignment,
 			Kunioability:   result.Alignment[len(result.Alignment)-1].Sim,
 			K:              result.K,
@@ -102,12 +104,9 @@ func (s *Server) queryHandler(c *gin.Context) {
 			Duration:       result.Duration,
 		}
 
-		searchResults = append(searchResults, QueryResult{
-			TableUnion: union,
-		})
-	}
-	response := QueryResponse{
-		Result: searchResults,
+		if err := w.Write(QueryResult{TableUnion: union}); err != nil {
+			log.Printf("Error in writing results: %s", err.Error())
+		}
 	}
-	c.JSON(http.StatusOK, response)
+	w.Close()
 }
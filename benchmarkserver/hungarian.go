@@ -0,0 +1,208 @@
+package benchmarkserver
+
+import (
+	"math"
+	"sort"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+// Strategies to align the columns of a query and a candidate table.
+const (
+	// GreedyAlignment takes the pairs in descending order of percentile
+	// if both columns are still unaligned.
+	GreedyAlignment = "greedy"
+	// OptimalAlignment finds the one-to-one alignment with the maximum sum
+	// of percentiles.
+	OptimalAlignment = "optimal"
+	// OptimalLogAlignment finds the one-to-one alignment with the maximum
+	// product of percentiles.
+	OptimalLogAlignment = "optimal-log"
+)
+
+// smallest percentile in the log-percentile weights, so that every aligned
+// pair has a positive weight
+var minLogPercentile = 1e-6
+
+func validAlignment(strategy string) bool {
+	switch strategy {
+	case "", GreedyAlignment, OptimalAlignment, OptimalLogAlignment:
+		return true
+	}
+	return false
+}
+
+// AlignPairs picks a one-to-one alignment from the scored column pairs of
+// a query and a candidate table. The aligned pairs are returned in
+// descending order of percentile. The empty strategy is greedy.
+func AlignPairs(pairs []Pair, strategy string) []Pair {
+	switch strategy {
+	case OptimalAlignment:
+		return optimalAlign(pairs, func(p opendata.Percentile) float64 {
+			return p.Value
+		})
+	case OptimalLogAlignment:
+		return optimalAlign(pairs, func(p opendata.Percentile) float64 {
+			return math.Log(math.Max(p.Value, minLogPercentile) / minLogPercentile)
+		})
+	}
+	return greedyAlign(pairs)
+}
+
+func greedyAlign(pairs []Pair) []Pair {
+	alignment := make([]Pair, 0)
+	sorted := make([]Pair, len(pairs))
+	copy(sorted, pairs)
+	sortPairs(sorted)
+	queryAligned := make(map[int]bool)
+	candAligned := make(map[int]bool)
+	for _, pair := range sorted {
+		if queryAligned[pair.QueryColIndex] || candAligned[pair.CandColIndex] {
+			continue
+		}
+		queryAligned[pair.QueryColIndex] = true
+		candAligned[pair.CandColIndex] = true
+		alignment = append(alignment, pair)
+	}
+	return alignment
+}
+
+func optimalAlign(pairs []Pair, weight func(opendata.Percentile) float64) []Pair {
+	queryCols := make(map[int]int)
+	candCols := make(map[int]int)
+	for _, p := range pairs {
+		if _, ok := queryCols[p.QueryColIndex]; !ok {
+			queryCols[p.QueryColIndex] = len(queryCols)
+		}
+		if _, ok := candCols[p.CandColIndex]; !ok {
+			candCols[p.CandColIndex] = len(candCols)
+		}
+	}
+	// pairs without a score have weight zero and are never aligned
+	weights := make([][]float64, len(queryCols))
+	best := make([][]int, len(queryCols))
+	for i := range weights {
+		weights[i] = make([]float64, len(candCols))
+		best[i] = make([]int, len(candCols))
+		for j := range best[i] {
+			best[i][j] = -1
+		}
+	}
+	for k, p := range pairs {
+		i, j := queryCols[p.QueryColIndex], candCols[p.CandColIndex]
+		if w := weight(p.Percentile); w > weights[i][j] {
+			weights[i][j] = w
+			best[i][j] = k
+		}
+	}
+	alignment := make([]Pair, 0)
+	for i, j := range maxWeightMatching(weights) {
+		if j != -1 && best[i][j] != -1 {
+			alignment = append(alignment, pairs[best[i][j]])
+		}
+	}
+	sortPairs(alignment)
+	return alignment
+}
+
+// sortPairs sorts pairs in descending order of the lower and then the upper
+// bound of the perturbed percentiles. Ties keep the order of the measures.
+func sortPairs(pairs []Pair) {
+	sort.SliceStable(pairs, func(a, b int) bool {
+		pa, pb := pairs[a].Percentile, pairs[b].Percentile
+		if pa.ValueMinus != pb.ValueMinus {
+			return pa.ValueMinus > pb.ValueMinus
+		}
+		return pa.ValuePlus > pb.ValuePlus
+	})
+}
+
+// maxWeightMatching returns the column matched to each row of the weight
+// matrix, or -1, in the matching with the maximum total weight.
+// It runs the Hungarian algorithm in O(n^2 m) for n <= m.
+func maxWeightMatching(weights [][]float64) []int {
+	n := len(weights)
+	if n == 0 {
+		return []int{}
+	}
+	m := len(weights[0])
+	if n > m {
+		transposed := make([][]float64, m)
+		for j := range transposed {
+			transposed[j] = make([]float64, n)
+			for i := range weights {
+				transposed[j][i] = weights[i][j]
+			}
+		}
+		match := make([]int, n)
+		for i := range match {
+			match[i] = -1
+		}
+		for j, i := range maxWeightMatching(transposed) {
+			if i != -1 {
+				match[i] = j
+			}
+		}
+		return match
+	}
+	// potentials and the row matched to each column, 1-indexed with
+	// column 0 as the unmatched root
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				// minimizing the negative weights
+				cur := -weights[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+	match := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			match[p[j]-1] = j - 1
+		}
+	}
+	return match
+}
@@ -0,0 +1,94 @@
+package benchmarkserver
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+// bruteForceMatching returns the maximum total weight of a matching.
+func bruteForceMatching(weights [][]float64, row int, used map[int]bool) float64 {
+	if row == len(weights) {
+		return 0.0
+	}
+	// leave the row unmatched
+	best := bruteForceMatching(weights, row+1, used)
+	for j := range weights[row] {
+		if used[j] {
+			continue
+		}
+		used[j] = true
+		best = math.Max(best, weights[row][j]+bruteForceMatching(weights, row+1, used))
+		used[j] = false
+	}
+	return best
+}
+
+func Test_maxWeightMatching(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 100; trial++ {
+		n, m := r.Intn(5)+1, r.Intn(5)+1
+		weights := make([][]float64, n)
+		for i := range weights {
+			weights[i] = make([]float64, m)
+			for j := range weights[i] {
+				weights[i][j] = r.Float64()
+			}
+		}
+		match := maxWeightMatching(weights)
+		total := 0.0
+		cols := make(map[int]bool)
+		for i, j := range match {
+			if j == -1 {
+				continue
+			}
+			if cols[j] {
+				t.Fatalf("column %d is matched twice in %v", j, match)
+			}
+			cols[j] = true
+			total += weights[i][j]
+		}
+		if want := bruteForceMatching(weights, 0, make(map[int]bool)); math.Abs(total-want) > 1e-9 {
+			t.Errorf("matching of %dx%d weights has weight %f, want %f", n, m, total, want)
+		}
+	}
+}
+
+func testPair(qindex, cindex int, p float64) Pair {
+	return Pair{
+		QueryColIndex: qindex,
+		CandColIndex:  cindex,
+		Percentile:    opendata.Percentile{Value: p, ValueMinus: p, ValuePlus: p},
+	}
+}
+
+func Test_AlignPairs(t *testing.T) {
+	// greedy takes (0, 0) and is left with (1, 1)
+	pairs := []Pair{
+		testPair(0, 0, 0.9),
+		testPair(0, 1, 0.8),
+		testPair(1, 0, 0.8),
+		testPair(1, 1, 0.1),
+	}
+	greedy := AlignPairs(pairs, GreedyAlignment)
+	if len(greedy) != 2 || greedy[0].CandColIndex != 0 || greedy[1].CandColIndex != 1 {
+		t.Errorf("greedy alignment is %v", greedy)
+	}
+	for _, strategy := range []string{OptimalAlignment, OptimalLogAlignment} {
+		optimal := AlignPairs(pairs, strategy)
+		if len(optimal) != 2 {
+			t.Fatalf("%s alignment is %v", strategy, optimal)
+		}
+		for _, p := range optimal {
+			if p.QueryColIndex == p.CandColIndex {
+				t.Errorf("%s alignment is %v", strategy, optimal)
+			}
+		}
+	}
+	// pairs without a score are not aligned
+	if optimal := AlignPairs(pairs[:1], OptimalAlignment); len(optimal) != 1 {
+		t.Errorf("optimal alignment of one pair is %v", optimal)
+	}
+}
@@ -5,13 +5,10 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -24,6 +21,7 @@ var (
 )
 
 type JaccardClient struct {
+	od       *opendata.Repository
 	host     string
 	cli      *http.Client
 	transFun func(string) string
@@ -31,9 +29,10 @@ type JaccardClient struct {
 	numHash  int
 }
 
-func NewJaccardClient(host string, numHash int) (*JaccardClient, error) {
+func NewJaccardClient(od *opendata.Repository, host string, numHash int) (*JaccardClient, error) {
 	log.Printf("New jaccard client for experiments.")
 	return &JaccardClient{
+		od:       od,
 		host:     host,
 		cli:      &http.Client{},
 		transFun: DefaultTransFun,
@@ -90,7 +89,7 @@ func (c *JaccardClient) QueryWithFixedK(queryCSVFilename string, k, maxN int) []
 	for i := 0; i < queryTable.NumCol(); i++ {
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
-			vec := opendata.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
+			vec := c.od.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
 			if len(vec) != 0 {
 				vecs = append(vecs, vec)
 				queryTextHeaders = append(queryTextHeaders, queryHeaders[i])
@@ -143,7 +142,7 @@ func (c *JaccardClient) QueryWithFixedN(queryCSVFilename string, minK, n int) []
 	for i := 0; i < queryTable.NumCol(); i++ {
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
-			vec := opendata.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
+			vec := c.od.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
 			if len(vec) != 0 {
 				vecs = append(vecs, vec)
 				queryTextHeaders = append(queryTextHeaders, queryHeaders[i])
@@ -179,33 +178,33 @@ func (c *JaccardClient) QueryWithFixedN(queryCSVFilename string, minK, n int) []
 	return results
 }
 
-func getAttributeOntologyData(tableID string, colIndex, numHash int) ([]uint64, []uint64, []uint64, int, int, int, error) {
-	tableID = strings.Replace(tableID, opendataDir, "", -1)
-	ontVecFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.ont-minhash-l1", tableID, colIndex))
-	ontVec, err := opendata.ReadMinhashSignature(ontVecFilename, numHash)
+func getAttributeOntologyData(od *opendata.Repository, tableID string, colIndex, numHash int) ([]uint64, []uint64, []uint64, int, int, int, error) {
+	tableID = tableIDOf(od, tableID)
+	ontVecFilename := od.DomainFilename(tableID, colIndex, "ont-minhash-l1")
+	ontVec, err := od.ReadMinhashSignature(ontVecFilename, numHash)
 	if err != nil {
 		log.Printf("Error in reading %s from disk.", ontVecFilename)
 		//ontVec = make([]uint64, 0)
 		return nil, nil, nil, 0, 0, 0, err
 	}
 	//
-	noOntVecFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.noann-minhash", tableID, colIndex))
-	noOntVec, err := opendata.ReadMinhashSignature(noOntVecFilename, numHash)
+	noOntVecFilename := od.DomainFilename(tableID, colIndex, "noann-minhash")
+	noOntVec, err := od.ReadMinhashSignature(noOntVecFilename, numHash)
 	if err != nil {
 		log.Printf("Error in reading %s from disk.", noOntVecFilename)
 		//noOntVec = make([]uint64, 0)
 		return nil, nil, nil, 0, 0, 0, err
 	}
 	//
-	vecFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.minhash", tableID, colIndex))
-	vec, err := opendata.ReadMinhashSignature(vecFilename, numHash)
+	vecFilename := od.DomainFilename(tableID, colIndex, "minhash")
+	vec, err := od.ReadMinhashSignature(vecFilename, numHash)
 	if err != nil {
 		log.Printf("Error in reading %s from disk.", vecFilename)
 		//vec = make([]uint64, 0)
 		return nil, nil, nil, 0, 0, 0, err
 	}
 	//
-	cardpath := path.Join(domainDir, tableID, fmt.Sprintf("%d.%s", colIndex, "ont-noann-card"))
+	cardpath := od.DomainFilename(tableID, colIndex, "ont-noann-card")
 	f, err := os.Open(cardpath)
 	defer f.Close()
 	if err != nil {
@@ -231,7 +230,7 @@ func getAttributeOntologyData(tableID string, colIndex, numHash int) ([]uint64,
 		lineIndex += 1
 	}
 	//
-	cardpath = path.Join(domainDir, tableID, fmt.Sprintf("%d.%s", colIndex, "ont-card"))
+	cardpath = od.DomainFilename(tableID, colIndex, "ont-card")
 	f, err = os.Open(cardpath)
 	defer f.Close()
 	if err != nil {
@@ -253,13 +252,17 @@ func getAttributeOntologyData(tableID string, colIndex, numHash int) ([]uint64,
 	return ontVec, noOntVec, vec, ontCard, noOntCard, card, nil
 }
 
-func getAttributeMinhash(tableID string, colIndex, numHash int) ([]uint64, error) {
-	tableID = strings.Replace(tableID, opendataDir, "", -1)
-	vecFilename := filepath.Join(domainDir, fmt.Sprintf("%s/%d.minhash", tableID, colIndex))
-	vec, err := opendata.ReadMinhashSignature(vecFilename, numHash)
+func getAttributeMinhash(od *opendata.Repository, tableID string, colIndex, numHash int) ([]uint64, error) {
+	vecFilename := od.DomainFilename(tableIDOf(od, tableID), colIndex, "minhash")
+	vec, err := od.ReadMinhashSignature(vecFilename, numHash)
 	if err != nil {
 		return nil, err
 	}
 	//
 	return vec, err
 }
+
+func getAttributeWeightedMinhash(od *opendata.Repository, tableID string, colIndex, numHash int) ([]uint64, error) {
+	vecFilename := od.DomainFilename(tableIDOf(od, tableID), colIndex, "wminhash")
+	return od.ReadMinhashSignature(vecFilename, numHash)
+}
@@ -1,6 +1,7 @@
 package benchmarkserver
 
 import (
+	"context"
 	"encoding/binary"
 	"log"
 	"math"
@@ -17,14 +18,16 @@ import (
 )
 
 type JaccardUnionIndex struct {
+	od        *opendata.Repository
 	lsh       *minhashlsh.MinhashLSH
 	domainDir string
 	byteOrder binary.ByteOrder
 	numHash   int
 }
 
-func NewJaccardUnionIndex(domainDir string, lsh *minhashlsh.MinhashLSH, numHash int) *JaccardUnionIndex {
+func NewJaccardUnionIndex(od *opendata.Repository, domainDir string, lsh *minhashlsh.MinhashLSH, numHash int) *JaccardUnionIndex {
 	index := &JaccardUnionIndex{
+		od:        od,
 		lsh:       lsh,
 		domainDir: domainDir,
 		byteOrder: ByteOrder,
@@ -33,9 +36,19 @@ func NewJaccardUnionIndex(domainDir string, lsh *minhashlsh.MinhashLSH, numHash
 	return index
 }
 
+// storedSignature returns the minhash signature of a column kept in the
+// LSH index, or nil if the index does not store them.
+func (index *JaccardUnionIndex) storedSignature(columnID string) []uint64 {
+	sig, ok := index.lsh.Signature(columnID)
+	if !ok {
+		return nil
+	}
+	return sig
+}
+
 func (index *JaccardUnionIndex) Build() error {
-	domainfilenames := opendata.StreamFilenames()
-	minhashFilenames := opendata.StreamMinhashVectors(10, "minhash", domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamMinhashVectors(10, "minhash", domainfilenames)
 	count := 0
 	start := getNow()
 	for file := range minhashFilenames {
@@ -47,7 +60,7 @@ func (index *JaccardUnionIndex) Build() error {
 		if count%1000 == 0 {
 			log.Printf("indexed %d domains", count)
 		}
-		vec, err := opendata.ReadMinhashSignature(file, index.numHash)
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
 		if err != nil {
 			log.Printf("Error in reading minhash %s from disk.", file)
 			return err
@@ -63,7 +76,7 @@ func (index *JaccardUnionIndex) Build() error {
 	return nil
 }
 
-func (index *JaccardUnionIndex) QueryOrderAll(query [][]uint64, N, K int, queryCardinality []int) <-chan SearchResult {
+func (index *JaccardUnionIndex) QueryOrderAll(ctx context.Context, query [][]uint64, N, K int, queryCardinality []int) <-chan SearchResult {
 	log.Printf("Started querying the minhash index with %d columns.", len(query))
 	results := make(chan SearchResult)
 	querySigs := make([]minhashlsh.Signature, len(query))
@@ -75,16 +88,16 @@ func (index *JaccardUnionIndex) QueryOrderAll(query [][]uint64, N, K int, queryC
 		}
 		alignment := initAlignment(K, N)
 		batch := pqueue.NewTopKQueue(batchSize)
-		done := make(chan struct{})
-		defer close(done)
-		for pair := range index.lsh.QueryPlus(querySigs, done) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for pair := range index.lsh.QueryPlus(querySigs, ctx.Done()) {
 			tableID, columnIndex := fromColumnID(pair.CandidateKey)
 			// discard columns of already aligned tables
 			if alignment.hasCompleted(tableID) {
 				continue
 			}
 			//e := getColumnPairJaccard(tableID, index.domainDir, columnIndex, pair.QueryIndex, index.numHash, query)
-			e := getColumnPairJaccardPlus(tableID, index.domainDir, columnIndex, pair.QueryIndex, index.numHash, query, queryCardinality[pair.QueryIndex])
+			e := getColumnPairJaccardPlus(index.od, tableID, index.domainDir, columnIndex, pair.QueryIndex, index.numHash, index.storedSignature(pair.CandidateKey), query, queryCardinality[pair.QueryIndex])
 			if e.Sim != 0.0 {
 				batch.Push(e, e.Sim)
 			}
@@ -92,19 +105,19 @@ func (index *JaccardUnionIndex) QueryOrderAll(query [][]uint64, N, K int, queryC
 				continue
 			}
 			// Process the batch
-			if finished := alignment.processPairsSyntactic(batch, results); finished {
+			if finished := alignment.processPairsSyntactic(ctx, batch, results); finished {
 				return
 			}
 		}
 		// Don't forget remaining pairs in the queue
-		if !batch.Empty() {
-			alignment.processPairsSyntactic(batch, results)
+		if !batch.Empty() && ctx.Err() == nil {
+			alignment.processPairsSyntactic(ctx, batch, results)
 		}
 	}()
 	return results
 }
 
-func (a alignment) processPairsSyntactic(pairQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
+func (a alignment) processPairsSyntactic(ctx context.Context, pairQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
 	pairs, _ := pairQueue.Descending()
 	for i := range pairs {
 		pair := pairs[i].(Pair)
@@ -130,7 +143,11 @@ func (a alignment) processPairsSyntactic(pairQueue *pqueue.TopKQueue, out chan<-
 				N:                a.completedTables.Unique(),
 				Duration:         float64(time.Now().Sub(a.startTime)) / float64(1000000),
 			}
-			out <- result
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return true
+			}
 		}
 		// Check if we are done
 		if a.completedTables.Unique() == a.n {
@@ -140,21 +157,36 @@ func (a alignment) processPairsSyntactic(pairQueue *pqueue.TopKQueue, out chan<-
 	return a.completedTables.Unique() == a.n
 }
 
-func getColumnPairJaccardPlus(candTableID, domainDir string, candColIndex, queryColIndex, numHash int, query [][]uint64, queryCardinality int) Pair {
+// getColumnPairJaccardPlus scores a candidate column by its minhash
+// signature vec, or by the signature read from disk if it is nil.
+func getColumnPairJaccardPlus(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, vec []uint64, query [][]uint64, queryCardinality int) Pair {
 	// getting the embedding of the candidate column
-	minhashFilename := getMinhashFilename(candTableID, domainDir, candColIndex)
-	//if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
-	//	log.Printf("Minhash file %s does not exist.", minhashFilename)
-	//	panic(err)
-	//}
-	vec, err := opendata.ReadMinhashSignature(minhashFilename, numHash)
-	if err != nil {
-		log.Printf("Error in reading %s from disk.", minhashFilename)
-		panic(err)
+	if vec == nil {
+		minhashFilename := getMinhashFilename(candTableID, domainDir, candColIndex)
+		//if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
+		//	log.Printf("Minhash file %s does not exist.", minhashFilename)
+		//	panic(err)
+		//}
+		var err error
+		vec, err = od.ReadMinhashSignature(minhashFilename, numHash)
+		if err != nil {
+			log.Printf("Error in reading %s from disk.", minhashFilename)
+			panic(err)
+		}
 	}
 	// inserting the pair into its corresponding priority queue
 	jaccard := estimateJaccard(vec, query[queryColIndex])
-	nB := getDomainCardinality(candTableID, domainDir, candColIndex)
+	nB := getDomainCardinality(od, candTableID, domainDir, candColIndex)
+	if nB == -1 {
+		log.Printf("No cardinality of %s.%d.", candTableID, candColIndex)
+		return Pair{
+			QueryColIndex: queryColIndex,
+			CandTableID:   candTableID,
+			CandColIndex:  candColIndex,
+			Jaccard:       jaccard,
+			Measure:       []string{"set"},
+		}
+	}
 	nA := queryCardinality
 	//containment := (jaccard * (float64(nA + nB))) / ((1.0 + jaccard) * float64(nA))
 	sig := sameDomainProb(jaccard, nA, nB)
@@ -172,14 +204,14 @@ func getColumnPairJaccardPlus(candTableID, domainDir string, candColIndex, query
 	return p
 }
 
-func getColumnPairJaccard(candTableID, domainDir string, candColIndex, queryColIndex, numHash int, query [][]uint64) Pair {
+func getColumnPairJaccard(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, query [][]uint64) Pair {
 	// getting the embedding of the candidate column
 	minhashFilename := getMinhashFilename(candTableID, domainDir, candColIndex)
 	if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 		log.Printf("Minhash file %s does not exist.", minhashFilename)
 		panic(err)
 	}
-	vec, err := opendata.ReadMinhashSignature(minhashFilename, numHash)
+	vec, err := od.ReadMinhashSignature(minhashFilename, numHash)
 	if err != nil {
 		log.Printf("Error in reading %s from disk.", minhashFilename)
 		panic(err)
@@ -0,0 +1,72 @@
+package benchmarkserver
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func buildTestJaccardIndex(t *testing.T, dir string, numTables int) (*JaccardUnionIndex, CombinedQueryRequest) {
+	numHash := 64
+	// the repository of the domain dir, read by the unionability measures
+	od := opendata.NewRepository(&opendata.Config{OutputDir: path.Dir(dir), NumHash: strconv.Itoa(numHash)})
+	sk := NewTableSketcher(od)
+	headers := []string{"city"}
+	rows := [][]string{{"Toronto"}, {"Montreal"}, {"Vancouver"}, {"Calgary"}}
+	lsh := minhashlsh.NewMinhashLSH32(numHash, 0.5)
+	var queryRequest CombinedQueryRequest
+	for i := 0; i < numTables; i++ {
+		tableID := fmt.Sprintf("t%d.csv", i)
+		r, err := sk.Sketch(headers, rows, dir, tableID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsh.Add(toColumnID(tableID, 0), r.SetVecs[0])
+		queryRequest = r
+	}
+	lsh.Index()
+	return NewJaccardUnionIndex(od, dir, lsh, numHash), queryRequest
+}
+
+func waitGoroutines(t *testing.T, before int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are still running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_QueryOrderAllCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "domains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index, queryRequest := buildTestJaccardIndex(t, dir, 2*batchSize)
+	before := runtime.NumGoroutine()
+	// the client leaves after the first result
+	ctx, cancel := context.WithCancel(context.Background())
+	results := index.QueryOrderAll(ctx, queryRequest.SetVecs, 2*batchSize, 1, queryRequest.SetCards)
+	if _, ok := <-results; !ok {
+		t.Fatal("no result found")
+	}
+	cancel()
+	waitGoroutines(t, before)
+	// the request is cancelled before the search starts
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	for range index.QueryOrderAll(ctx, queryRequest.SetVecs, 2*batchSize, 1, queryRequest.SetCards) {
+	}
+	waitGoroutines(t, before)
+}
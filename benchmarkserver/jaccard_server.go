@@ -53,13 +53,13 @@ func (s *JaccardServer) queryHandler(c *gin.Context) {
 		return
 	}
 	// Query index
-	searchResults := make([]QueryResult, 0)
-	queryResults := s.ui.QueryOrderAll(queryRequest.Vecs, queryRequest.N, queryRequest.K, queryRequest.Cardinality)
+	queryResults := s.ui.QueryOrderAll(c.Request.Context(), queryRequest.Vecs, queryRequest.N, queryRequest.K, queryRequest.Cardinality)
+	w := newResultWriter(c)
 	for result := range queryResults {
 		union := Union{
 			CandTableID:    result.CandidateTableID,
-			CandHeader:     getHeaders(result.CandidateTableID, s.ui.domainDir),
-			CandTextHeader: getTextHeaders(result.CandidateTableID, s.ui.domainDir),
+			CandHeader:     getHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
+			CandTextHeader: getTextHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
 			Alignment:      result.Alignment,
 			Kunioability:   result.Alignment[len(result.Alignment)-1].Sim,
 			K:              result.K,
@@ -67,12 +67,9 @@ func (s *JaccardServer) queryHandler(c *gin.Context) {
 			Duration:       result.Duration,
 		}
 
-		searchResults = append(searchResults, QueryResult{
-			TableUnion: union,
-		})
-	}
-	response := QueryResponse{
-		Result: searchResults,
+		if err := w.Write(QueryResult{TableUnion: union}); err != nil {
+			log.Printf("Error in writing results: %s", err.Error())
+		}
 	}
-	c.JSON(http.StatusOK, response)
+	w.Close()
 }
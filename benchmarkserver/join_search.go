@@ -0,0 +1,349 @@
+package benchmarkserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/RJMillerLab/table-union/hll"
+	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	defaultJoinThreshold = 0.5
+	defaultJoinN         = 10
+)
+
+// JoinIndex finds the columns that contain the values of a query column,
+// using the minhash and the cardinality of the text domains.
+type JoinIndex struct {
+	od        *opendata.Repository
+	lsh       *minhashlsh.LshEnsemble
+	domainDir string
+	numHash   int
+	numPart   int
+	maxK      int
+}
+
+// JoinPair is a candidate join column of a query column.
+type JoinPair struct {
+	QueryColIndex   int
+	CandColIndex    int
+	Containment     float64
+	CandCardinality int
+}
+
+// JoinQueryRequest asks for the tables joinable with the text columns of
+// a query table. The sketches of a table in the domain dir are read if
+// SetVecs is empty.
+type JoinQueryRequest struct {
+	QueryTableID string     `json:"querytableid"`
+	SetVecs      [][]uint64 `json:"settable"`
+	SetCards     []int      `json:"setcard"`
+	// the column index of each sketch, 0, 1, ... if empty
+	Columns   []int   `json:"columns"`
+	Threshold float64 `json:"threshold"`
+	N         int     `json:"n"`
+}
+
+// JoinResult is a candidate table with its join columns, in descending
+// order of containment.
+type JoinResult struct {
+	CandTableID string
+	CandHeader  []string
+	Pairs       []JoinPair
+}
+
+// NewJoinIndex creates an empty index, which is filled by Build or
+// InsertTable.
+func NewJoinIndex(od *opendata.Repository, domainDir string, numHash, numPart int) *JoinIndex {
+	index := &JoinIndex{
+		od:        od,
+		domainDir: domainDir,
+		numHash:   numHash,
+		numPart:   numPart,
+		maxK:      4,
+	}
+	index.lsh = minhashlsh.BootstrapLshEnsemble(numPart, numHash, index.maxK, nil)
+	return index
+}
+
+// Build indexes the minhash sketches of the text domains, partitioned by
+// their cardinality.
+func (index *JoinIndex) Build() error {
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamMinhashVectors(10, "minhash", domainfilenames)
+	records := make([]*minhashlsh.DomainRecord, 0)
+	start := getNow()
+	for file := range minhashFilenames {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
+		if err != nil {
+			log.Printf("Error in reading minhash %s from disk.", file)
+			return err
+		}
+		tableID, columnIndex := parseFilename(index.domainDir, file)
+		card := getDomainCardinality(index.od, tableID, index.domainDir, columnIndex)
+		if card <= 0 {
+			continue
+		}
+		records = append(records, &minhashlsh.DomainRecord{
+			Key:       toColumnID(tableID, columnIndex),
+			Size:      card,
+			Signature: vec,
+		})
+		if len(records)%1000 == 0 {
+			log.Printf("read %d domains", len(records))
+		}
+	}
+	index.lsh = minhashlsh.BootstrapLshEnsemble(index.numPart, index.numHash, index.maxK, records)
+	log.Printf("index time for join: %f", getNow()-start)
+	return nil
+}
+
+// InsertTable adds the text columns of a sketched table to the index.
+func (index *JoinIndex) InsertTable(tableID string) (int, error) {
+	records, err := index.readTableRecords(tableID)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		index.lsh.Insert(r.Key, r.Size, r.Signature)
+	}
+	return len(records), nil
+}
+
+// DeleteTable removes the columns of a table from the index.
+func (index *JoinIndex) DeleteTable(tableID string) (int, error) {
+	records, err := index.readTableRecords(tableID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, r := range records {
+		if index.lsh.Delete(r.Key, r.Size, r.Signature) {
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+func (index *JoinIndex) readTableRecords(tableID string) ([]*minhashlsh.DomainRecord, error) {
+	records := make([]*minhashlsh.DomainRecord, 0)
+	for _, columnIndex := range getTextDomains(tableID, index.domainDir) {
+		file := getMinhashFilename(tableID, index.domainDir, columnIndex)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		card := getDomainCardinality(index.od, tableID, index.domainDir, columnIndex)
+		if card <= 0 {
+			continue
+		}
+		sig, err := index.od.ReadMinhashSignature(file, index.numHash)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &minhashlsh.DomainRecord{
+			Key:       toColumnID(tableID, columnIndex),
+			Size:      card,
+			Signature: sig,
+		})
+	}
+	return records, nil
+}
+
+// Query returns the top-N tables with a column containing at least the
+// threshold fraction of the values of a query column. The query columns
+// are given by their indexes, minhash signatures and cardinalities.
+func (index *JoinIndex) Query(ctx context.Context, queryTableID string, columns []int, sigs [][]uint64, cards []int, threshold float64, N int) []JoinResult {
+	done := ctx.Done()
+	tables := make(map[string]*JoinResult)
+	for i, sig := range sigs {
+		if cards[i] <= 0 {
+			continue
+		}
+		// the cardinality sketch of a query table in the domain dir
+		queryHLL, _ := index.od.ReadCardinalitySketch(getMinhashFilename(queryTableID, index.domainDir, columns[i]))
+		for columnID := range index.lsh.Query(minhashlsh.Signature(sig), cards[i], threshold, done) {
+			candTableID, candColIndex := fromColumnID(columnID)
+			if candTableID == queryTableID {
+				continue
+			}
+			candFilename := getMinhashFilename(candTableID, index.domainDir, candColIndex)
+			candSig, err := index.od.ReadMinhashSignature(candFilename, index.numHash)
+			if err != nil {
+				log.Printf("Error in reading minhash of %s: %s", columnID, err.Error())
+				continue
+			}
+			candCard := getDomainCardinality(index.od, candTableID, index.domainDir, candColIndex)
+			if candCard <= 0 {
+				continue
+			}
+			containment := estimateContainment(sig, candSig, cards[i], candCard)
+			if candHLL, err := index.od.ReadCardinalitySketch(candFilename); err == nil && queryHLL != nil {
+				if c, err := estimateSketchContainment(sig, candSig, queryHLL, candHLL); err == nil {
+					containment = c
+				}
+			}
+			if containment < threshold {
+				continue
+			}
+			if _, ok := tables[candTableID]; !ok {
+				tables[candTableID] = &JoinResult{CandTableID: candTableID}
+			}
+			tables[candTableID].Pairs = append(tables[candTableID].Pairs, JoinPair{
+				QueryColIndex:   columns[i],
+				CandColIndex:    candColIndex,
+				Containment:     containment,
+				CandCardinality: candCard,
+			})
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	results := make([]JoinResult, 0, len(tables))
+	for _, r := range tables {
+		sort.Slice(r.Pairs, func(a, b int) bool {
+			return r.Pairs[a].Containment > r.Pairs[b].Containment
+		})
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(a, b int) bool {
+		ca, cb := results[a].Pairs[0].Containment, results[b].Pairs[0].Containment
+		if ca != cb {
+			return ca > cb
+		}
+		return results[a].CandTableID < results[b].CandTableID
+	})
+	if len(results) > N {
+		results = results[:N]
+	}
+	return results
+}
+
+// estimateContainment estimates the fraction of the values of the query
+// domain in the candidate domain from the Jaccard similarity of the minhash
+// signatures and the cardinalities.
+func estimateContainment(query, candidate []uint64, queryCard, candCard int) float64 {
+	j := estimateJaccard(query, candidate)
+	c := j * float64(queryCard+candCard) / ((1.0 + j) * float64(queryCard))
+	return math.Min(1.0, c)
+}
+
+// estimateSketchContainment estimates the fraction of the values of the
+// query domain in the candidate domain as the Jaccard similarity of the
+// minhash signatures times the number of distinct values of the union of
+// the cardinality sketches, divided by the number of query values.
+func estimateSketchContainment(query, candidate []uint64, queryHLL, candHLL *hll.HLL) (float64, error) {
+	union, err := hll.Union(queryHLL, candHLL)
+	if err != nil {
+		return 0.0, err
+	}
+	queryCard := queryHLL.Count()
+	if queryCard == 0.0 {
+		return 0.0, nil
+	}
+	j := estimateJaccard(query, candidate)
+	return math.Min(1.0, j*union/queryCard), nil
+}
+
+// readQuerySketches reads the minhash signatures and the cardinalities of
+// the text columns of a sketched table.
+func readQuerySketches(od *opendata.Repository, tableID, domainDir string, numHash int) ([]int, [][]uint64, []int, error) {
+	columns := make([]int, 0)
+	sigs := make([][]uint64, 0)
+	cards := make([]int, 0)
+	for _, columnIndex := range getTextDomains(tableID, domainDir) {
+		file := getMinhashFilename(tableID, domainDir, columnIndex)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		sig, err := od.ReadMinhashSignature(file, numHash)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("reading %s: %s", file, err.Error())
+		}
+		columns = append(columns, columnIndex)
+		sigs = append(sigs, sig)
+		cards = append(cards, getDomainCardinality(od, tableID, domainDir, columnIndex))
+	}
+	return columns, sigs, cards, nil
+}
+
+// joinHandler returns the tables with columns that contain the values of
+// the query columns, with the estimated containment of each join column.
+func (s *CombinedServer) joinHandler(c *gin.Context) {
+	if s.joini == nil {
+		c.AbortWithStatus(http.StatusNotImplemented)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, 1048576))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	var request JoinQueryRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+		return
+	}
+	if request.Threshold == 0.0 {
+		request.Threshold = defaultJoinThreshold
+	}
+	if request.N == 0 {
+		request.N = defaultJoinN
+	}
+	if request.Threshold < 0.0 || request.Threshold > 1.0 || request.N < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if len(request.SetVecs) == 0 {
+		if !validTableID(request.QueryTableID) {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		if _, err := os.Stat(path.Join(s.joini.domainDir, request.QueryTableID, "types")); os.IsNotExist(err) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		columns, sigs, cards, err := readQuerySketches(s.joini.od, request.QueryTableID, s.joini.domainDir, s.joini.numHash)
+		if err != nil {
+			log.Printf("Error in reading the sketches of %s: %s", request.QueryTableID, err.Error())
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		request.Columns, request.SetVecs, request.SetCards = columns, sigs, cards
+	}
+	if request.Columns == nil {
+		for i := range request.SetVecs {
+			request.Columns = append(request.Columns, i)
+		}
+	}
+	if len(request.Columns) != len(request.SetVecs) || len(request.SetCards) != len(request.SetVecs) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	for _, sig := range request.SetVecs {
+		if len(sig) != s.joini.numHash {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	results := s.joini.Query(c.Request.Context(), request.QueryTableID, request.Columns, request.SetVecs, request.SetCards, request.Threshold, request.N)
+	for i := range results {
+		results[i].CandHeader = getHeaders(s.od, results[i].CandTableID, s.joini.domainDir)
+	}
+	c.JSON(http.StatusOK, gin.H{"result": results})
+}
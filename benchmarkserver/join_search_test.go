@@ -0,0 +1,77 @@
+package benchmarkserver
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func Test_JoinIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "domains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	numHash := 64
+	od := opendata.NewRepository(&opendata.Config{NumHash: strconv.Itoa(numHash)})
+	sk := NewTableSketcher(od)
+	keys := [][]string{{"Toronto"}, {"Montreal"}, {"Vancouver"}, {"Calgary"}}
+	tables := map[string][][]string{
+		"keys.csv":  keys,
+		"big.csv":   append(keys, []string{"Ottawa"}, []string{"Edmonton"}, []string{"Winnipeg"}, []string{"Halifax"}),
+		"other.csv": {{"Paris"}, {"Berlin"}, {"Madrid"}, {"Rome"}},
+	}
+	index := NewJoinIndex(od, dir, numHash, 2)
+	for tableID, rows := range tables {
+		if _, err := sk.Sketch([]string{"city"}, rows, dir, tableID); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := index.InsertTable(tableID); err != nil || n != 1 {
+			t.Fatalf("inserted %d columns of %s: %v", n, tableID, err)
+		}
+	}
+	columns, sigs, cards, err := readQuerySketches(od, "keys.csv", dir, numHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := index.Query(context.Background(), "keys.csv", columns, sigs, cards, 0.8, 10)
+	if len(results) != 1 || results[0].CandTableID != "big.csv" {
+		t.Fatalf("join results %v", results)
+	}
+	if p := results[0].Pairs[0]; p.QueryColIndex != 0 || p.CandColIndex != 0 || p.Containment < 0.8 {
+		t.Errorf("join pair %v", p)
+	}
+	if n, err := index.DeleteTable("big.csv"); err != nil || n != 1 {
+		t.Fatalf("deleted %d columns: %v", n, err)
+	}
+	if results := index.Query(context.Background(), "keys.csv", columns, sigs, cards, 0.8, 10); len(results) != 0 {
+		t.Errorf("join results %v after deleting", results)
+	}
+}
+
+func Test_estimateSketchContainment(t *testing.T) {
+	small := make([]string, 0)
+	large := make([]string, 0)
+	for i := 0; i < 1000; i++ {
+		if i < 100 {
+			small = append(small, fmt.Sprintf("value%d", i))
+		}
+		large = append(large, fmt.Sprintf("value%d", i))
+	}
+	od := opendata.NewRepository(&opendata.Config{})
+	smallSig, smallHLL := od.GetDomainSketch(small, 256)
+	largeSig, largeHLL := od.GetDomainSketch(large, 256)
+	c, err := estimateSketchContainment(smallSig, largeSig, smallHLL, largeHLL)
+	if err != nil || c < 0.8 {
+		t.Errorf("containment %f of a subset: %v", c, err)
+	}
+	c, err = estimateSketchContainment(largeSig, smallSig, largeHLL, smallHLL)
+	if err != nil || c < 0.05 || c > 0.15 {
+		t.Errorf("containment %f of a superset: %v", c, err)
+	}
+}
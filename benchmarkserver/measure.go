@@ -0,0 +1,237 @@
+package benchmarkserver
+
+import (
+	"context"
+
+	"github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/RJMillerLab/table-union/simhashlsh"
+)
+
+// Measure is an attribute unionability measure of the combined search.
+// The pairwise score and the CDF come from opendata.UnionabilityMeasure.
+type Measure interface {
+	opendata.UnionabilityMeasure
+	// Candidates streams the candidate columns found in the LSH index of the
+	// measure for the query column sketches, with their scores in Sim.
+	// The stream stops when ctx is done. Measures without an index return nil
+	// and only score the candidates found by other measures.
+	Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair
+}
+
+type alignMeasure struct {
+	opendata.UnionabilityMeasure
+}
+
+// AlignMeasure uses a measure only to align candidate tables.
+func AlignMeasure(m opendata.UnionabilityMeasure) Measure {
+	return &alignMeasure{m}
+}
+
+func (m *alignMeasure) Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair {
+	return nil
+}
+
+type setMeasure struct {
+	opendata.UnionabilityMeasure
+	index *JaccardUnionIndex
+}
+
+// NewSetMeasure creates the set measure over the minhash index of the columns.
+func NewSetMeasure(od *opendata.Repository, index *JaccardUnionIndex) Measure {
+	return &setMeasure{
+		UnionabilityMeasure: od.GetMeasure("set"),
+		index:               index,
+	}
+}
+
+func (m *setMeasure) Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair {
+	out := make(chan Pair)
+	go func() {
+		defer close(out)
+		if len(query.SetVecs) == 0 {
+			return
+		}
+		// cast the type of query columns to Signature
+		sigs := make([]minhashlsh.Signature, len(query.SetVecs))
+		for i := range query.SetVecs {
+			sigs[i] = minhashlsh.Signature(query.SetVecs[i])
+		}
+		for pair := range m.index.lsh.QueryPlus(sigs, ctx.Done()) {
+			tableID, columnIndex := fromColumnID(pair.CandidateKey)
+			e := getColumnPairJaccardPlus(m.index.od, tableID, m.index.domainDir, columnIndex, pair.QueryIndex, m.index.numHash, m.index.storedSignature(pair.CandidateKey), query.SetVecs, query.SetCards[pair.QueryIndex])
+			e.QueryColIndex = queryColumn(query.SetColumns, pair.QueryIndex)
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+type wsetMeasure struct {
+	opendata.UnionabilityMeasure
+	index *JaccardUnionIndex
+}
+
+// NewWSetMeasure creates the weighted set measure over the weighted minhash
+// index of the value frequencies of the columns.
+func NewWSetMeasure(od *opendata.Repository, index *JaccardUnionIndex) Measure {
+	return &wsetMeasure{
+		UnionabilityMeasure: od.GetMeasure("wset"),
+		index:               index,
+	}
+}
+
+func (m *wsetMeasure) Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair {
+	out := make(chan Pair)
+	go func() {
+		defer close(out)
+		if len(query.WSetVecs) == 0 {
+			return
+		}
+		sigs := make([]minhashlsh.Signature, len(query.WSetVecs))
+		for i := range query.WSetVecs {
+			sigs[i] = minhashlsh.Signature(query.WSetVecs[i])
+		}
+		for pair := range m.index.lsh.QueryPlus(sigs, ctx.Done()) {
+			tableID, columnIndex := fromColumnID(pair.CandidateKey)
+			e := getColumnPairWeightedJaccard(m.index.od, tableID, m.index.domainDir, columnIndex, pair.QueryIndex, m.index.numHash, m.index.storedSignature(pair.CandidateKey), query.WSetVecs)
+			e.QueryColIndex = queryColumn(query.WSetColumns, pair.QueryIndex)
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (m *wsetMeasure) InsertTable(tableID string) (int, error) {
+	return m.index.InsertTable(tableID, "wminhash")
+}
+
+func (m *wsetMeasure) DeleteTable(tableID string) (int, error) {
+	return m.index.DeleteTable(tableID, "wminhash")
+}
+
+type nlMeasure struct {
+	opendata.UnionabilityMeasure
+	index *UnionIndex
+}
+
+// NewNlMeasure creates the natural language measure over the simhash index
+// of the mean fastText embeddings of the columns.
+func NewNlMeasure(od *opendata.Repository, index *UnionIndex) Measure {
+	return &nlMeasure{
+		UnionabilityMeasure: od.GetMeasure("nl"),
+		index:               index,
+	}
+}
+
+func (m *nlMeasure) Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair {
+	out := make(chan Pair)
+	go func() {
+		defer close(out)
+		if len(query.NlMeans) == 0 {
+			return
+		}
+		var pairs <-chan simhashlsh.UnionPair
+		if lsh, ok := m.index.lsh.(*simhashlsh.CosineLSH); !ok {
+			pairs = m.index.lsh.QueryPlus(query.NlMeans, ctx.Done())
+		} else if query.Probes > 0 {
+			pairs = lsh.QueryPlusMultiProbe(query.NlMeans, query.Probes, ctx.Done())
+		} else {
+			pairs = lsh.QueryPlusTuned(query.NlMeans, ctx.Done())
+		}
+		for pair := range pairs {
+			tableID, columnIndex := fromColumnID(pair.CandidateKey)
+			e := getColumnPairPlus(tableID, m.index.domainDir, columnIndex, pair.QueryIndex, m.index.storedVector(pair.CandidateKey), query.NlMeans[pair.QueryIndex], query.NlCovars[pair.QueryIndex], query.NlCards[pair.QueryIndex])
+			e.QueryColIndex = queryColumn(query.NlColumns, pair.QueryIndex)
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+type numMeasure struct {
+	opendata.UnionabilityMeasure
+	index *JaccardUnionIndex
+}
+
+// NewNumMeasure creates the numeric measure over the minhash index of the
+// log-scale bins of numeric columns. Candidates are scored with the
+// Kolmogorov-Smirnov statistic of the distribution sketches.
+func NewNumMeasure(od *opendata.Repository, index *JaccardUnionIndex) Measure {
+	return &numMeasure{
+		UnionabilityMeasure: od.GetMeasure("num"),
+		index:               index,
+	}
+}
+
+func (m *numMeasure) Candidates(ctx context.Context, query CombinedQueryRequest) <-chan Pair {
+	out := make(chan Pair)
+	go func() {
+		defer close(out)
+		if len(query.NumVecs) == 0 || len(query.NumSketches) != len(query.NumVecs) {
+			return
+		}
+		sigs := make([]minhashlsh.Signature, len(query.NumVecs))
+		sketches := make([]*opendata.NumericSketch, len(query.NumVecs))
+		for i := range query.NumVecs {
+			sigs[i] = minhashlsh.Signature(query.NumVecs[i])
+			sketch, err := opendata.NumericSketchFromVec(query.NumSketches[i])
+			if err != nil {
+				return
+			}
+			sketches[i] = sketch
+		}
+		for pair := range m.index.lsh.QueryPlus(sigs, ctx.Done()) {
+			tableID, columnIndex := fromColumnID(pair.CandidateKey)
+			e := getColumnPairNumeric(m.index.od, tableID, m.index.domainDir, columnIndex, pair.QueryIndex, sketches[pair.QueryIndex])
+			e.QueryColIndex = queryColumn(query.NumColumns, pair.QueryIndex)
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (m *numMeasure) InsertTable(tableID string) (int, error) {
+	return m.index.InsertTable(tableID, "num-minhash")
+}
+
+func (m *numMeasure) DeleteTable(tableID string) (int, error) {
+	return m.index.DeleteTable(tableID, "num-minhash")
+}
+
+// measureColumnTypes returns the types of columns scored by the measures.
+func measureColumnTypes(measures []opendata.UnionabilityMeasure) []string {
+	colTypes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range measures {
+		if !seen[m.ColumnType()] {
+			seen[m.ColumnType()] = true
+			colTypes = append(colTypes, m.ColumnType())
+		}
+	}
+	return colTypes
+}
+
+func toOpendataMeasures(measures []Measure) []opendata.UnionabilityMeasure {
+	ms := make([]opendata.UnionabilityMeasure, len(measures))
+	for i, m := range measures {
+		ms[i] = m
+	}
+	return ms
+}
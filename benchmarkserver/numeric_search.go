@@ -0,0 +1,58 @@
+package benchmarkserver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+// NumBuild indexes the minhash sketches of the bins of numeric domains.
+func (index *JaccardUnionIndex) NumBuild() error {
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamNumericMinhashVectors(10, domainfilenames)
+	count := 0
+	start := getNow()
+	for file := range minhashFilenames {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
+		if err != nil {
+			log.Printf("Error in reading minhash %s from disk.", file)
+			return err
+		}
+		tableID, columnIndex := parseFilename(index.domainDir, file)
+		index.lsh.Add(toColumnID(tableID, columnIndex), vec)
+		count += 1
+		if count%1000 == 0 {
+			log.Printf("indexed %d domains", count)
+		}
+	}
+	index.lsh.Index()
+	log.Printf("num build count %d", count)
+	log.Printf("index time for num: %f", getNow()-start)
+	return nil
+}
+
+func getColumnPairNumeric(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex int, query *opendata.NumericSketch) Pair {
+	filename := path.Join(domainDir, candTableID, fmt.Sprintf("%d.num-sketch", candColIndex))
+	sketch, err := od.ReadNumericSketch(filename)
+	if err != nil {
+		log.Printf("Error in reading %s from disk.", filename)
+		panic(err)
+	}
+	ks := opendata.KSStatistic(query, sketch)
+	p := Pair{
+		QueryColIndex:    queryColIndex,
+		CandTableID:      candTableID,
+		CandColIndex:     candColIndex,
+		Sim:              1.0 - ks,
+		QueryCardinality: query.Count,
+		CandCardinality:  sketch.Count,
+		Measure:          []string{"num"},
+	}
+	return p
+}
@@ -0,0 +1,101 @@
+package benchmarkserver
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/minhashlsh"
+)
+
+// InsertTable adds the columns of a sketched table to the running index,
+// reading the minhash signatures with the given extension from the domain dir.
+// It returns the number of columns inserted.
+func (index *JaccardUnionIndex) InsertTable(tableID, ext string) (int, error) {
+	sigs, err := index.readTableSigs(tableID, ext)
+	if err != nil {
+		return 0, err
+	}
+	for columnIndex, sig := range sigs {
+		index.lsh.Insert(toColumnID(tableID, columnIndex), sig)
+	}
+	return len(sigs), nil
+}
+
+// DeleteTable removes the columns of a table from the running index.
+// The sketch files must still be in the domain dir.
+func (index *JaccardUnionIndex) DeleteTable(tableID, ext string) (int, error) {
+	sigs, err := index.readTableSigs(tableID, ext)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for columnIndex, sig := range sigs {
+		if index.lsh.Delete(toColumnID(tableID, columnIndex), sig) {
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+func (index *JaccardUnionIndex) readTableSigs(tableID, ext string) (map[int]minhashlsh.Signature, error) {
+	sigs := make(map[int]minhashlsh.Signature)
+	for _, columnIndex := range getDomains(tableID, index.domainDir, "text", "numeric") {
+		file := path.Join(index.domainDir, tableID, fmt.Sprintf("%d.%s", columnIndex, ext))
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		sig, err := index.od.ReadMinhashSignature(file, index.numHash)
+		if err != nil {
+			return nil, err
+		}
+		sigs[columnIndex] = minhashlsh.Signature(sig)
+	}
+	return sigs, nil
+}
+
+// InsertTable adds the embedding vectors of the text columns of a sketched
+// table to the running index. It returns the number of columns inserted.
+func (index *UnionIndex) InsertTable(tableID string) (int, error) {
+	vecs, err := index.readTableVecs(tableID)
+	if err != nil {
+		return 0, err
+	}
+	for columnIndex, vec := range vecs {
+		index.lsh.Insert(vec, toColumnID(tableID, columnIndex))
+	}
+	return len(vecs), nil
+}
+
+// DeleteTable removes the columns of a table from the running index.
+// The sketch files must still be in the domain dir.
+func (index *UnionIndex) DeleteTable(tableID string) (int, error) {
+	vecs, err := index.readTableVecs(tableID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for columnIndex, vec := range vecs {
+		if index.lsh.Delete(vec, toColumnID(tableID, columnIndex)) {
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+func (index *UnionIndex) readTableVecs(tableID string) (map[int][]float64, error) {
+	vecs := make(map[int][]float64)
+	for _, columnIndex := range getTextDomains(tableID, index.domainDir) {
+		file := path.Join(index.domainDir, tableID, fmt.Sprintf("%d.ft-mean", columnIndex))
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		vec, err := embedding.ReadVecFromDisk(file, ByteOrder)
+		if err != nil {
+			return nil, err
+		}
+		vecs[columnIndex] = vec
+	}
+	return vecs, nil
+}
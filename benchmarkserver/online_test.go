@@ -0,0 +1,49 @@
+package benchmarkserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/minhashlsh"
+)
+
+func Test_InsertDeleteTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "domains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index, _ := buildTestJaccardIndex(t, dir, 1)
+	sk := NewTableSketcher(index.od)
+	headers := []string{"id", "country"}
+	rows := [][]string{{"1001", "Canada"}, {"1002", "Mexico"}, {"1003", "Brazil"}, {"1004", "Argentina"}}
+	queryRequest, err := sk.Sketch(headers, rows, dir, "new.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := minhashlsh.Signature(queryRequest.SetVecs[0])
+	if n, err := index.InsertTable("new.csv", "minhash"); err != nil || n != 1 {
+		t.Fatalf("inserted %d columns: %v", n, err)
+	}
+	found := false
+	for _, key := range index.lsh.Query(sig) {
+		if key == toColumnID("new.csv", 1) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("inserted column not found")
+	}
+	if n, err := index.DeleteTable("new.csv", "minhash"); err != nil || n != 1 {
+		t.Fatalf("deleted %d columns: %v", n, err)
+	}
+	for _, key := range index.lsh.Query(sig) {
+		if key == toColumnID("new.csv", 1) {
+			t.Fatal("deleted column found")
+		}
+	}
+	if headers := getHeaders(index.od, "new.csv", dir); len(headers) != 2 || headers[1] != "country" {
+		t.Fatalf("headers %v", headers)
+	}
+}
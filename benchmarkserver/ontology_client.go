@@ -14,19 +14,13 @@ import (
 	"path"
 	"strings"
 
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/RJMillerLab/table-union/yago"
 	"github.com/ekzhu/datatable"
 )
 
-var (
-	yagoDB             = "/home/kenpu/TABLE_UNION_OUTPUT/yago.sqlite3.0"
-	wordEntityFilename = "/home/kenpu/TABLE_UNION_OUTPUT/word-entity.txt"
-	classFilename      = "/home/fnargesian/TABLE_UNION_OUTPUT/entity-category.txt"
-	yagoFilename       = "/home/fnargesian/TABLE_UNION_OUTPUT/yago.sqlite3.0"
-	//classFilename = "/home/fnargesian/TABLE_UNION_OUTPUT/entity-class.txt"
-)
-
 type OntologyJaccardClient struct {
+	od           *opendata.Repository
 	host         string
 	cli          *http.Client
 	transFun     func(string) string
@@ -38,13 +32,14 @@ type OntologyJaccardClient struct {
 	yago         *yago.Yago
 }
 
-func NewOntologyJaccardClient(host string, numHash int) (*OntologyJaccardClient, error) {
+func NewOntologyJaccardClient(od *opendata.Repository, host string, numHash int) (*OntologyJaccardClient, error) {
 	log.Printf("New jaccard client for experiments.")
 	//lookup := loadEntityWords(wordEntityFilename)
 	//counts := loadEntityWordCount(yagoDB)
-	//classes := loadEntityClasses(classFilename)
+	//classes := LoadEntityClasses(classFilename)
 	//yg := yago.InitYago(yagoFilename)
 	return &OntologyJaccardClient{
+		od:       od,
 		host:     host,
 		cli:      &http.Client{},
 		transFun: DefaultTransFun,
@@ -109,7 +104,7 @@ func (c *OntologyJaccardClient) QueryWithFixedK(queryCSVFilename string, k, maxN
 	for i := 0; i < queryTable.NumCol(); i++ {
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
-			ontVec, noOntVec, vec, ontCard, noOntCard, card, err := getAttributeOntologyData(queryCSVFilename, i, c.numHash)
+			ontVec, noOntVec, vec, ontCard, noOntCard, card, err := getAttributeOntologyData(c.od, queryCSVFilename, i, c.numHash)
 			//ontVec, noOntVec, vec, ontCard, noOntCard, card := opendata.GetOntDomain(c.yago.Copy(), col, c.numHash, c.entityClass, c.transFun, c.tokenFun)
 			if err == nil {
 				vecs = append(vecs, vec)
@@ -176,7 +171,7 @@ func (c *OntologyJaccardClient) QueryWithFixedN(queryCSVFilename string, minK, n
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
 			//ontVec, noOntVec, vec, ontCard, noOntCard, card := opendata.GetOntDomain(c.yago, col, c.numHash, c.entityClass, c.transFun, c.tokenFun)
-			ontVec, noOntVec, vec, ontCard, noOntCard, card, err := getAttributeOntologyData(queryCSVFilename, i, c.numHash)
+			ontVec, noOntVec, vec, ontCard, noOntCard, card, err := getAttributeOntologyData(c.od, queryCSVFilename, i, c.numHash)
 			if err == nil {
 				noOntVecs = append(noOntVecs, noOntVec)
 				vecs = append(vecs, vec)
@@ -272,7 +267,8 @@ func loadEntityWordCount(yagoDB string) map[string]int {
 	return counts
 }
 
-func loadEntityClasses(classFilename string) map[string][]string {
+// LoadEntityClasses reads the entity to class mapping of YAGO.
+func LoadEntityClasses(classFilename string) map[string][]string {
 	lookup := make(map[string][]string)
 	f, err := os.Open(path.Join(classFilename))
 	//f, err := os.Open(path.Join(OutputDir, "entity-class.txt"))
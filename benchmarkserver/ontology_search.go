@@ -1,6 +1,7 @@
 package benchmarkserver
 
 import (
+	"context"
 	"log"
 	"os"
 	"strconv"
@@ -17,8 +18,8 @@ import (
 
 func (index *JaccardUnionIndex) OntBuild() error {
 	log.Printf("ont build")
-	domainfilenames := opendata.StreamFilenames()
-	minhashFilenames := opendata.StreamMinhashVectors(10, "ont-minhash-l1", domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamMinhashVectors(10, "ont-minhash-l1", domainfilenames)
 	count := 0
 	start := getNow()
 	for file := range minhashFilenames {
@@ -26,7 +27,7 @@ func (index *JaccardUnionIndex) OntBuild() error {
 			//		log.Printf("Minhash file does not exist: %s", file)
 			continue
 		}
-		vec, err := opendata.ReadMinhashSignature(file, index.numHash)
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
 		if err != nil {
 			return err
 		}
@@ -47,9 +48,9 @@ func (index *JaccardUnionIndex) OntBuild() error {
 
 func (index *JaccardUnionIndex) NoOntBuild() error {
 	log.Printf("no ont build")
-	domainfilenames := opendata.StreamFilenames()
-	//minhashFilenames := opendata.StreamMinhashVectors(10, "minhash", domainfilenames)
-	minhashFilenames := opendata.StreamMinhashVectors(10, "noann-minhash", domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	//minhashFilenames := index.od.StreamMinhashVectors(10, "minhash", domainfilenames)
+	minhashFilenames := index.od.StreamMinhashVectors(10, "noann-minhash", domainfilenames)
 	count := 0
 	start := getNow()
 	for file := range minhashFilenames {
@@ -57,7 +58,7 @@ func (index *JaccardUnionIndex) NoOntBuild() error {
 			//log.Printf("Minhash file does not exist: %s", file)
 			continue
 		}
-		vec, err := opendata.ReadMinhashSignature(file, index.numHash)
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
 		if err != nil {
 			continue
 			//return err
@@ -77,7 +78,7 @@ func (index *JaccardUnionIndex) NoOntBuild() error {
 	return nil
 }
 
-func (server *OntologyJaccardServer) OntQueryOrderAll(noOntQuery, ontQuery [][]uint64, N, K int, noOntQueryCard, ontQueryCard []int) <-chan SearchResult {
+func (server *OntologyJaccardServer) OntQueryOrderAll(ctx context.Context, noOntQuery, ontQuery [][]uint64, N, K int, noOntQueryCard, ontQueryCard []int) <-chan SearchResult {
 	results := make(chan SearchResult)
 	//querySigs := make([]minhashlsh.Signature, len(query))
 	ontQuerySigs := make([]minhashlsh.Signature, len(ontQuery))
@@ -95,26 +96,35 @@ func (server *OntologyJaccardServer) OntQueryOrderAll(noOntQuery, ontQuery [][]u
 	alignment := initAlignment(K, N)
 	reduceQueue := pqueue.NewTopKQueue(batchSize)
 	reduceBatch := make(chan Pair)
-	done1 := make(chan struct{})
-	done2 := make(chan struct{})
+	ctx, cancel := context.WithCancel(ctx)
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 	go func() {
-		for pair := range server.ui.lsh.QueryPlus(noOntQuerySigs, done1) {
+		for pair := range server.ui.lsh.QueryPlus(noOntQuerySigs, ctx.Done()) {
 			tableID, columnIndex := fromColumnID(pair.CandidateKey)
-			e := getColumnPairOntJaccardPlus(tableID, server.ui.domainDir, columnIndex, pair.QueryIndex, server.ui.numHash, ontQuery[pair.QueryIndex], noOntQuery[pair.QueryIndex], ontQueryCard[pair.QueryIndex], noOntQueryCard[pair.QueryIndex])
+			e := getColumnPairOntJaccardPlus(server.ui.od, tableID, server.ui.domainDir, columnIndex, pair.QueryIndex, server.ui.numHash, ontQuery[pair.QueryIndex], noOntQuery[pair.QueryIndex], ontQueryCard[pair.QueryIndex], noOntQueryCard[pair.QueryIndex])
 			if e.Sim != 0.0 {
-				reduceBatch <- e
+				select {
+				case reduceBatch <- e:
+				case <-ctx.Done():
+					wg.Done()
+					return
+				}
 			}
 		}
 		wg.Done()
 	}()
 	go func() {
-		for pair := range server.oi.lsh.QueryPlus(ontQuerySigs, done2) {
+		for pair := range server.oi.lsh.QueryPlus(ontQuerySigs, ctx.Done()) {
 			tableID, columnIndex := fromColumnID(pair.CandidateKey)
-			e := getColumnPairOntJaccardPlus(tableID, server.ui.domainDir, columnIndex, pair.QueryIndex, server.ui.numHash, ontQuery[pair.QueryIndex], noOntQuery[pair.QueryIndex], ontQueryCard[pair.QueryIndex], noOntQueryCard[pair.QueryIndex])
+			e := getColumnPairOntJaccardPlus(server.ui.od, tableID, server.ui.domainDir, columnIndex, pair.QueryIndex, server.ui.numHash, ontQuery[pair.QueryIndex], noOntQuery[pair.QueryIndex], ontQueryCard[pair.QueryIndex], noOntQueryCard[pair.QueryIndex])
 			if e.Sim != 0.0 {
-				reduceBatch <- e
+				select {
+				case reduceBatch <- e:
+				case <-ctx.Done():
+					wg.Done()
+					return
+				}
 			}
 		}
 		wg.Done()
@@ -128,18 +138,18 @@ func (server *OntologyJaccardServer) OntQueryOrderAll(noOntQuery, ontQuery [][]u
 			}
 			reduceQueue.Push(pair, pair.Sim)
 			if reduceQueue.Size() == batchSize {
-				if finished := alignment.processPairsOntology(reduceQueue, results); finished {
-					close(done1)
-					close(done2)
+				if finished := alignment.processPairsOntology(ctx, reduceQueue, results); finished {
+					cancel()
 					wwg.Done()
 					return
 				}
 				reduceQueue = pqueue.NewTopKQueue(batchSize)
 			}
 		}
-		if reduceQueue.Size() != 0 {
-			alignment.processPairsOntology(reduceQueue, results)
+		if reduceQueue.Size() != 0 && ctx.Err() == nil {
+			alignment.processPairsOntology(ctx, reduceQueue, results)
 		}
+		cancel()
 		wwg.Done()
 	}()
 	go func() {
@@ -155,7 +165,7 @@ func (server *OntologyJaccardServer) OntQueryOrderAll(noOntQuery, ontQuery [][]u
 	return results
 }
 
-func (a alignment) processPairsOntology(reduceQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
+func (a alignment) processPairsOntology(ctx context.Context, reduceQueue *pqueue.TopKQueue, out chan<- SearchResult) bool {
 	pairs, _ := reduceQueue.Descending()
 	for i := range pairs {
 		pair := pairs[i].(Pair)
@@ -181,7 +191,11 @@ func (a alignment) processPairsOntology(reduceQueue *pqueue.TopKQueue, out chan<
 				N:                a.completedTables.Unique(),
 				Duration:         float64(time.Now().Sub(a.startTime)) / float64(1000000),
 			}
-			out <- result
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return true
+			}
 		}
 		// Check if we are done
 		if a.completedTables.Unique() == a.n {
@@ -191,7 +205,7 @@ func (a alignment) processPairsOntology(reduceQueue *pqueue.TopKQueue, out chan<
 	return a.completedTables.Unique() == a.n
 }
 
-func getColumnPairOntJaccardPlus(candTableID, domainDir string, candColIndex, queryColIndex, numHash int, ontQuery, noOntQuery []uint64, ontQueryCard, noOntQueryCard int) Pair {
+func getColumnPairOntJaccardPlus(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, ontQuery, noOntQuery []uint64, ontQueryCard, noOntQueryCard int) Pair {
 	// getting the embedding of the candidate column
 	/*
 		minhashFilename := getUnannotatedMinhashFilename(candTableID, domainDir, candColIndex)
@@ -203,7 +217,7 @@ func getColumnPairOntJaccardPlus(candTableID, domainDir string, candColIndex, qu
 				Sim:           0.0,
 			}
 		}
-		vec, err := opendata.ReadMinhashSignature(minhashFilename, numHash)
+		vec, err := od.ReadMinhashSignature(minhashFilename, numHash)
 		if err != nil {
 			return Pair{
 				QueryColIndex: queryColIndex,
@@ -224,7 +238,7 @@ func getColumnPairOntJaccardPlus(candTableID, domainDir string, candColIndex, qu
 			Sim:           0.0,
 		}
 	}
-	vec, err := opendata.ReadMinhashSignature(ontMinhashFilename, numHash)
+	vec, err := od.ReadMinhashSignature(ontMinhashFilename, numHash)
 	if err != nil {
 		return Pair{
 			QueryColIndex: queryColIndex,
@@ -234,7 +248,15 @@ func getColumnPairOntJaccardPlus(candTableID, domainDir string, candColIndex, qu
 		}
 	}
 	ontJaccard := estimateJaccard(vec, ontQuery)
-	_, nA := getOntDomainCardinality(candTableID, domainDir, candColIndex)
+	_, nA := getOntDomainCardinality(od, candTableID, domainDir, candColIndex)
+	if nA == -1 {
+		return Pair{
+			QueryColIndex: queryColIndex,
+			CandTableID:   candTableID,
+			CandColIndex:  candColIndex,
+			Sim:           0.0,
+		}
+	}
 	nB := ontQueryCard
 	//noB := noOntQueryCard
 	//	coverage := float64(queryCard-noOntQueryCard) / float64(queryCard)
@@ -260,7 +282,7 @@ func getColumnPairOntJaccardPlus(candTableID, domainDir string, candColIndex, qu
 	return p
 }
 
-func getColumnPairSem(candTableID, domainDir string, candColIndex, queryColIndex, numHash int, ontQuery, noOntQuery []uint64, ontQueryCard, noOntQueryCard int) Pair {
+func getColumnPairSem(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, ontQuery, noOntQuery []uint64, ontQueryCard, noOntQueryCard int) Pair {
 	// computing ontology jaccard
 	ontMinhashFilename := getOntMinhashFilename(candTableID, domainDir, candColIndex)
 	if _, err := os.Stat(ontMinhashFilename); os.IsNotExist(err) {
@@ -271,7 +293,7 @@ func getColumnPairSem(candTableID, domainDir string, candColIndex, queryColIndex
 			Sim:           0.0,
 		}
 	}
-	vec, err := opendata.ReadMinhashSignature(ontMinhashFilename, numHash)
+	vec, err := od.ReadMinhashSignature(ontMinhashFilename, numHash)
 	if err != nil {
 		return Pair{
 			QueryColIndex: queryColIndex,
@@ -281,7 +303,15 @@ func getColumnPairSem(candTableID, domainDir string, candColIndex, queryColIndex
 		}
 	}
 	ontJaccard := estimateJaccard(vec, ontQuery)
-	_, nA := getOntDomainCardinality(candTableID, domainDir, candColIndex)
+	_, nA := getOntDomainCardinality(od, candTableID, domainDir, candColIndex)
+	if nA == -1 {
+		return Pair{
+			QueryColIndex: queryColIndex,
+			CandTableID:   candTableID,
+			CandColIndex:  candColIndex,
+			Sim:           0.0,
+		}
+	}
 	nB := ontQueryCard
 	ontProb := sameDomainProb(ontJaccard, nA, nB)
 	p := Pair{
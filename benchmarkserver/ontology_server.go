@@ -58,14 +58,14 @@ func (s *OntologyJaccardServer) queryHandler(c *gin.Context) {
 		return
 	}
 	// Query index
-	searchResults := make([]QueryResult, 0)
 	//(noOntQuery, ontQuery [][]uint64, N, K int,              noOntQueryCard, ontQueryCard []int)
-	queryResults := s.OntQueryOrderAll(queryRequest.NoOntVecs, queryRequest.OntVecs, queryRequest.N, queryRequest.K, queryRequest.NoOntCardinality, queryRequest.OntCardinality)
+	queryResults := s.OntQueryOrderAll(c.Request.Context(), queryRequest.NoOntVecs, queryRequest.OntVecs, queryRequest.N, queryRequest.K, queryRequest.NoOntCardinality, queryRequest.OntCardinality)
+	w := newResultWriter(c)
 	for result := range queryResults {
 		union := Union{
 			CandTableID:    result.CandidateTableID,
-			CandHeader:     getHeaders(result.CandidateTableID, s.ui.domainDir),
-			CandTextHeader: getTextHeaders(result.CandidateTableID, s.ui.domainDir),
+			CandHeader:     getHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
+			CandTextHeader: getTextHeaders(s.ui.od, result.CandidateTableID, s.ui.domainDir),
 			Alignment:      result.Alignment,
 			Kunioability:   result.Alignment[len(result.Alignment)-1].Sim,
 			K:              result.K,
@@ -73,12 +73,9 @@ func (s *OntologyJaccardServer) queryHandler(c *gin.Context) {
 			Duration:       result.Duration,
 		}
 
-		searchResults = append(searchResults, QueryResult{
-			TableUnion: union,
-		})
-	}
-	response := QueryResponse{
-		Result: searchResults,
+		if err := w.Write(QueryResult{TableUnion: union}); err != nil {
+			log.Printf("Error in writing results: %s", err.Error())
+		}
 	}
-	c.JSON(http.StatusOK, response)
+	w.Close()
 }
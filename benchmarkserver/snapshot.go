@@ -0,0 +1,165 @@
+package benchmarkserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/RJMillerLab/table-union/hnsw"
+	"github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/simhashlsh"
+)
+
+type snapshotIndex interface {
+	LoadSnapshot(r io.Reader) error
+	SaveSnapshot(w io.Writer) error
+}
+
+// SnapshotParams are the parameters an index is built with, saved in the
+// header of its snapshot. A snapshot of other parameters is not loaded.
+type SnapshotParams struct {
+	DomainDir string  `json:"domain_dir"`
+	Threshold float64 `json:"threshold"`
+	NumHash   int     `json:"num_hash"`
+	// the kind of index and its other parameters, e.g. "hnsw m=16 ef=200"
+	Index string `json:"index,omitempty"`
+	// whether the index stores the signatures or vectors of its keys
+	StoreVectors bool `json:"store_vectors,omitempty"`
+}
+
+var snapshotMagic = "table-union-snapshot "
+
+// LoadSnapshot replaces the LSH index with the snapshot in r.
+func (index *JaccardUnionIndex) LoadSnapshot(r io.Reader) error {
+	lsh, err := minhashlsh.Load(r)
+	if err != nil {
+		return err
+	}
+	index.lsh = lsh
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the LSH index to w.
+func (index *JaccardUnionIndex) SaveSnapshot(w io.Writer) error {
+	return index.lsh.Save(w)
+}
+
+// LoadSnapshot replaces the index with the snapshot in r, which is of the
+// same kind of index.
+func (index *UnionIndex) LoadSnapshot(r io.Reader) error {
+	var lsh VectorIndex
+	var err error
+	switch index.lsh.(type) {
+	case *hnsw.HNSW:
+		lsh, err = hnsw.Load(r)
+	default:
+		lsh, err = simhashlsh.Load(r)
+	}
+	if err != nil {
+		return err
+	}
+	index.lsh = lsh
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the LSH index to w.
+func (index *UnionIndex) SaveSnapshot(w io.Writer) error {
+	switch lsh := index.lsh.(type) {
+	case *hnsw.HNSW:
+		return lsh.Save(w)
+	case *simhashlsh.CosineLSH:
+		return lsh.Save(w)
+	}
+	return fmt.Errorf("no snapshot of a %T index", index.lsh)
+}
+
+// BuildWithSnapshot loads the index from snapshotDir/name.lsh if it is
+// present and was built with the same parameters. Otherwise the index is
+// built and the snapshot is saved for the next start. An empty snapshotDir
+// always builds the index.
+func BuildWithSnapshot(index snapshotIndex, build func() error, snapshotDir, name string, params SnapshotParams) error {
+	if snapshotDir == "" {
+		return build()
+	}
+	filename := path.Join(snapshotDir, name+".lsh")
+	start := getNow()
+	loaded, err := loadSnapshot(index, filename, params)
+	if err != nil {
+		return err
+	}
+	if loaded {
+		log.Printf("loaded index snapshot %s in %f seconds", filename, getNow()-start)
+		return nil
+	}
+	if err := build(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return err
+	}
+	if err := saveSnapshot(index, filename, params); err != nil {
+		return err
+	}
+	log.Printf("saved index snapshot %s", filename)
+	return nil
+}
+
+// loadSnapshot returns false if there is no snapshot of the parameters.
+func loadSnapshot(index snapshotIndex, filename string, params SnapshotParams) (bool, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	header, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, snapshotMagic) {
+		log.Printf("rebuilding the index of snapshot %s without parameters", filename)
+		return false, nil
+	}
+	var saved SnapshotParams
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(header, snapshotMagic)), &saved); err != nil {
+		return false, err
+	}
+	if saved != params {
+		log.Printf("rebuilding the index of snapshot %s built with %+v instead of %+v", filename, saved, params)
+		return false, nil
+	}
+	return true, index.LoadSnapshot(r)
+}
+
+func saveSnapshot(index snapshotIndex, filename string, params SnapshotParams) error {
+	header, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := fmt.Fprintf(w, "%s%s\n", snapshotMagic, header); err != nil {
+		f.Close()
+		return err
+	}
+	if err := index.SaveSnapshot(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,87 @@
+package benchmarkserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/RJMillerLab/table-union/hnsw"
+	"github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+func Test_BuildWithSnapshotHNSW(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	vecs := [][]float64{{1, 0, 0}, {0, 1, 0}, {1, 1, 0}}
+	index := NewUnionIndex(opendata.NewRepository(&opendata.Config{}), dir, hnsw.NewHNSW(3, 4, 10, 10))
+	build := func() error {
+		for i, vec := range vecs {
+			index.lsh.Add(vec, toColumnID("t.csv", i))
+		}
+		index.lsh.Index()
+		return nil
+	}
+	params := SnapshotParams{DomainDir: dir, NumHash: 256, Index: "hnsw m=4 ef=10"}
+	if err := BuildWithSnapshot(index, build, dir, "nl", params); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewUnionIndex(opendata.NewRepository(&opendata.Config{}), dir, hnsw.NewHNSW(3, 4, 10, 10))
+	if err := BuildWithSnapshot(loaded, func() error { return nil }, dir, "nl", params); err != nil {
+		t.Fatal(err)
+	}
+	h, ok := loaded.lsh.(*hnsw.HNSW)
+	if !ok || h.Len() != len(vecs) {
+		t.Fatalf("loaded %T", loaded.lsh)
+	}
+	if key := h.Query([]float64{1, 0.1, 0}, 1)[0]; key != toColumnID("t.csv", 0) {
+		t.Fatalf("nearest column is %s", key)
+	}
+}
+
+func Test_BuildWithSnapshotParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	builds := 0
+	build := func() error {
+		builds += 1
+		return nil
+	}
+	params := SnapshotParams{DomainDir: dir, Threshold: 0.5, NumHash: 256}
+	index := NewJaccardUnionIndex(opendata.NewRepository(&opendata.Config{}), dir, minhashlsh.NewMinhashLSH32(256, 0.5), 256)
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil {
+		t.Fatal(err)
+	}
+	if builds != 1 {
+		t.Fatalf("built %d times with the same parameters", builds)
+	}
+	params.Threshold = 0.7
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil {
+		t.Fatal(err)
+	}
+	params.NumHash = 128
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil {
+		t.Fatal(err)
+	}
+	// a snapshot without stored vectors is rebuilt to store them
+	params.StoreVectors = true
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil {
+		t.Fatal(err)
+	}
+	if builds != 4 {
+		t.Fatalf("built %d times with other parameters", builds)
+	}
+	// the snapshot of the last parameters is saved
+	if err := BuildWithSnapshot(index, build, dir, "set", params); err != nil || builds != 4 {
+		t.Fatalf("built %d times: %v", builds, err)
+	}
+}
@@ -0,0 +1,96 @@
+package benchmarkserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	streamNone   = ""
+	streamNDJSON = "ndjson"
+	streamSSE    = "sse"
+)
+
+// resultWriter sends query results to the client. In streaming mode each
+// result is written and flushed as soon as it is found, otherwise
+// the results are buffered into a single QueryResponse.
+type resultWriter struct {
+	c       *gin.Context
+	format  string
+	results []QueryResult
+	count   int
+}
+
+// newResultWriter picks the format from the "stream" query parameter
+// ("ndjson" or "sse") or from the Accept header.
+func newResultWriter(c *gin.Context) *resultWriter {
+	w := &resultWriter{
+		c:       c,
+		format:  streamFormat(c),
+		results: make([]QueryResult, 0),
+	}
+	switch w.format {
+	case streamNDJSON:
+		c.Header("Content-Type", "application/x-ndjson")
+	case streamSSE:
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+	}
+	if w.format != streamNone {
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+	}
+	return w
+}
+
+func streamFormat(c *gin.Context) string {
+	switch c.Query("stream") {
+	case streamNDJSON:
+		return streamNDJSON
+	case streamSSE:
+		return streamSSE
+	}
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "application/x-ndjson"):
+		return streamNDJSON
+	case strings.Contains(accept, "text/event-stream"):
+		return streamSSE
+	}
+	return streamNone
+}
+
+func (w *resultWriter) Write(result QueryResult) error {
+	w.count += 1
+	switch w.format {
+	case streamNDJSON:
+		if err := json.NewEncoder(w.c.Writer).Encode(&result); err != nil {
+			return err
+		}
+	case streamSSE:
+		w.c.SSEvent("result", result)
+	default:
+		w.results = append(w.results, result)
+		return nil
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// Close ends the response. SSE clients receive a final "done" event.
+func (w *resultWriter) Close() {
+	switch w.format {
+	case streamNDJSON:
+	case streamSSE:
+		w.c.SSEvent("done", gin.H{"count": w.count})
+		w.c.Writer.Flush()
+	default:
+		w.c.JSON(http.StatusOK, QueryResponse{
+			Result: w.results,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package benchmarkserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveResults(target, accept string, unions []Union) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/query", func(c *gin.Context) {
+		w := newResultWriter(c)
+		for _, union := range unions {
+			w.Write(QueryResult{TableUnion: union})
+		}
+		w.Close()
+	})
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_resultWriter(t *testing.T) {
+	unions := []Union{
+		Union{CandTableID: "a.csv", Duration: 1.5},
+		Union{CandTableID: "b.csv", Duration: 2.5},
+	}
+	// buffered
+	rec := serveResults("/query", "", unions)
+	var response QueryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(response.Result) != 2 {
+		t.Fail()
+	}
+	// ndjson
+	rec = serveResults("/query?stream=ndjson", "", unions)
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fail()
+	}
+	scanner := bufio.NewScanner(rec.Body)
+	i := 0
+	for scanner.Scan() {
+		var result QueryResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.TableUnion.CandTableID != unions[i].CandTableID || result.TableUnion.Duration != unions[i].Duration {
+			t.Fail()
+		}
+		i += 1
+	}
+	if i != 2 {
+		t.Fail()
+	}
+	// sse
+	rec = serveResults("/query", "text/event-stream", unions)
+	body := rec.Body.String()
+	if strings.Count(body, "event:result") != 2 || !strings.Contains(body, "event:done") {
+		t.Log(body)
+		t.Fail()
+	}
+}
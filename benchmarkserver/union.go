@@ -0,0 +1,168 @@
+package benchmarkserver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the raw CSV file of a table inserted online, in its domain dir
+var tableCSVFilename = "table.csv"
+
+// UnionRequest asks for the union of a query table and a candidate table.
+type UnionRequest struct {
+	QueryTableID string `json:"querytableid"`
+	// the query table with its header row, if it is not indexed
+	QueryTable  [][]string `json:"querytable"`
+	CandTableID string     `json:"candtableid"`
+	Alignment   []Pair     `json:"alignment"`
+	// keep only the aligned query columns instead of all of them
+	AlignedOnly bool `json:"alignedonly"`
+}
+
+// UnionTables appends the rows of the candidate table to the rows of the
+// query table. The columns of the output are the query columns, all or only
+// the aligned ones, followed by the source table and the source row of each
+// row. The source row is the line in the CSV file, the header being line 0.
+// The cells of candidate rows in unaligned columns are empty.
+func UnionTables(queryTableID string, queryTable [][]string, candTableID string, candTable [][]string, alignment []Pair, alignedOnly bool) ([][]string, error) {
+	if len(queryTable) == 0 || len(candTable) == 0 {
+		return nil, fmt.Errorf("table without a header")
+	}
+	queryHeaders, candHeaders := queryTable[0], candTable[0]
+	// the candidate column of each query column
+	candColumn := make(map[int]int)
+	for _, p := range alignment {
+		if p.QueryColIndex < 0 || p.QueryColIndex >= len(queryHeaders) {
+			return nil, fmt.Errorf("query column %d out of range", p.QueryColIndex)
+		}
+		if p.CandColIndex < 0 || p.CandColIndex >= len(candHeaders) {
+			return nil, fmt.Errorf("candidate column %d out of range", p.CandColIndex)
+		}
+		if _, ok := candColumn[p.QueryColIndex]; ok {
+			return nil, fmt.Errorf("query column %d is aligned twice", p.QueryColIndex)
+		}
+		candColumn[p.QueryColIndex] = p.CandColIndex
+	}
+	columns := make([]int, 0)
+	for i := range queryHeaders {
+		if _, ok := candColumn[i]; ok || !alignedOnly {
+			columns = append(columns, i)
+		}
+	}
+	header := make([]string, 0, len(columns)+2)
+	for _, i := range columns {
+		header = append(header, queryHeaders[i])
+	}
+	header = append(header, "source_table", "source_row")
+	union := [][]string{header}
+	for r := 1; r < len(queryTable); r++ {
+		row := make([]string, 0, len(header))
+		for _, i := range columns {
+			row = append(row, cell(queryTable[r], i))
+		}
+		union = append(union, append(row, queryTableID, fmt.Sprint(r)))
+	}
+	for r := 1; r < len(candTable); r++ {
+		row := make([]string, 0, len(header))
+		for _, i := range columns {
+			if j, ok := candColumn[i]; ok {
+				row = append(row, cell(candTable[r], j))
+			} else {
+				row = append(row, "")
+			}
+		}
+		union = append(union, append(row, candTableID, fmt.Sprint(r)))
+	}
+	return union, nil
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// readTableCSV reads the raw CSV file of a table from the table dir, or from
+// the domain dir for tables inserted online. The table dir may be empty.
+func readTableCSV(tableID, tableDir, domainDir string) ([][]string, error) {
+	f, err := os.Open(path.Join(domainDir, tableID, tableCSVFilename))
+	if os.IsNotExist(err) && tableDir != "" {
+		f, err = os.Open(path.Join(tableDir, tableID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+func writeTableCSV(records [][]string, filename string) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// unionHandler returns the union of a query table and a candidate table
+// as CSV, given the alignment of a search result.
+func (s *CombinedServer) unionHandler(c *gin.Context) {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxQueryCSVSize))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	var request UnionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+		return
+	}
+	if !validTableID(request.CandTableID) || (request.QueryTable == nil && !validTableID(request.QueryTableID)) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	queryTable := request.QueryTable
+	if queryTable == nil {
+		queryTable, err = readTableCSV(request.QueryTableID, s.tableDir, s.seti.domainDir)
+		if err != nil {
+			log.Printf("Error in reading %s: %s", request.QueryTableID, err.Error())
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+	}
+	candTable, err := readTableCSV(request.CandTableID, s.tableDir, s.seti.domainDir)
+	if err != nil {
+		log.Printf("Error in reading %s: %s", request.CandTableID, err.Error())
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	union, err := UnionTables(request.QueryTableID, queryTable, request.CandTableID, candTable, request.Alignment, request.AlignedOnly)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	if err := w.WriteAll(union); err != nil {
+		log.Printf("Error in writing the union: %s", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package benchmarkserver
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func Test_UnionTables(t *testing.T) {
+	query := [][]string{
+		{"city", "province", "population"},
+		{"Toronto", "ON", "2731571"},
+		{"Montreal", "QC"},
+	}
+	cand := [][]string{
+		{"PROV_NAME", "CITY_NAME"},
+		{"BC", "Vancouver"},
+	}
+	alignment := []Pair{
+		{QueryColIndex: 0, CandColIndex: 1},
+		{QueryColIndex: 1, CandColIndex: 0},
+	}
+	union, err := UnionTables("q.csv", query, "c.csv", cand, alignment, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"city", "province", "population", "source_table", "source_row"},
+		{"Toronto", "ON", "2731571", "q.csv", "1"},
+		{"Montreal", "QC", "", "q.csv", "2"},
+		{"Vancouver", "BC", "", "c.csv", "1"},
+	}
+	if !reflect.DeepEqual(union, want) {
+		t.Errorf("union is %v, want %v", union, want)
+	}
+	union, err = UnionTables("q.csv", query, "c.csv", cand, alignment[:1], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]string{
+		{"city", "source_table", "source_row"},
+		{"Toronto", "q.csv", "1"},
+		{"Montreal", "q.csv", "2"},
+		{"Vancouver", "c.csv", "1"},
+	}
+	if !reflect.DeepEqual(union, want) {
+		t.Errorf("union of aligned columns is %v, want %v", union, want)
+	}
+	if _, err := UnionTables("q.csv", query, "c.csv", cand, []Pair{{QueryColIndex: 0, CandColIndex: 2}}, false); err == nil {
+		t.Error("alignment out of range is accepted")
+	}
+}
+
+func Test_readTableCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tableDir, domainDir := path.Join(dir, "csvfiles"), path.Join(dir, "domains")
+	os.MkdirAll(tableDir, 0755)
+	os.MkdirAll(path.Join(domainDir, "new.csv"), 0755)
+	ioutil.WriteFile(path.Join(tableDir, "old.csv"), []byte("a,b\n1,2\n"), 0644)
+	ioutil.WriteFile(path.Join(domainDir, "new.csv", tableCSVFilename), []byte("c\n3\n"), 0644)
+	if table, err := readTableCSV("old.csv", tableDir, domainDir); err != nil || len(table) != 2 || table[0][1] != "b" {
+		t.Fatalf("old.csv is %v: %v", table, err)
+	}
+	if table, err := readTableCSV("new.csv", "", domainDir); err != nil || len(table) != 2 || table[0][0] != "c" {
+		t.Fatalf("new.csv is %v: %v", table, err)
+	}
+	if _, err := readTableCSV("old.csv", "", domainDir); err == nil {
+		t.Fatal("read old.csv without a table dir")
+	}
+}
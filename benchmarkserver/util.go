@@ -107,54 +107,11 @@ func getUnannotatedMinhashFilename(tableID, domainDir string, index int) string
 	return fullpath
 }
 
-func getOntDomainCardinality(tableID, domainDir string, index int) (int, int) {
-	cardpath := path.Join(domainDir, tableID)
-	cardpath = path.Join(cardpath, fmt.Sprintf("%d.%s", index, "ont-noann-card"))
-	f, err := os.Open(cardpath)
-	defer f.Close()
-	if err != nil {
-		return 0.0, 0.0
-	}
-	card := 0
-	ocard := 0
-	scanner := bufio.NewScanner(f)
-	lineIndex := 0
-	for scanner.Scan() {
-		if lineIndex == 0 {
-			c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-			if err == nil {
-				card = c
-			}
-			lineIndex += 1
-		}
-		//if lineIndex == 1 {
-		//	c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-		//	if err == nil {
-		//		ocard = c
-		//	}
-		//	lineIndex += 1
-		//}
-	}
-	ontCardpath := path.Join(domainDir, tableID)
-	ontCardpath = path.Join(ontCardpath, fmt.Sprintf("%d.%s", index, "ont-card"))
-	fo, err := os.Open(ontCardpath)
-	defer fo.Close()
-	if err != nil {
-		return 0.0, 0.0
-	}
-	scanner = bufio.NewScanner(fo)
-	lineIndex = 0
-	for scanner.Scan() {
-		if lineIndex == 0 {
-			c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-			if err == nil {
-				ocard = c
-			} else {
-				panic(err)
-			}
-		}
-		lineIndex += 1
-	}
+// getOntDomainCardinality returns the number of values without annotation
+// and the number of ontology classes of a domain, -1 if not found.
+func getOntDomainCardinality(od *opendata.Repository, tableID, domainDir string, index int) (int, int) {
+	card := od.ReadCardinality(getUnannotatedMinhashFilename(tableID, domainDir, index), path.Join(domainDir, tableID, fmt.Sprintf("%d.%s", index, "ont-noann-card")))
+	ocard := od.ReadCardinality(getOntMinhashFilename(tableID, domainDir, index), path.Join(domainDir, tableID, fmt.Sprintf("%d.%s", index, "ont-card")))
 	return card, ocard
 }
 
@@ -181,27 +138,12 @@ func getDomainSize(tableID, domainDir string, index int) int {
 	return card
 }
 
-func getDomainCardinality(tableID, domainDir string, index int) int {
+// getDomainCardinality returns the number of distinct values of a domain, or
+// -1 if it has neither a cardinality sketch nor a card file.
+func getDomainCardinality(od *opendata.Repository, tableID, domainDir string, index int) int {
 	cardpath := path.Join(domainDir, tableID)
 	cardpath = path.Join(cardpath, fmt.Sprintf("%d.%s", index, "card"))
-	f, err := os.Open(cardpath)
-	defer f.Close()
-	if err != nil {
-		return 0.0
-	}
-	card := 0
-	scanner := bufio.NewScanner(f)
-	lineIndex := 0
-	for scanner.Scan() {
-		if lineIndex == 0 {
-			c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-			if err == nil {
-				card = c
-			}
-			lineIndex += 1
-		}
-	}
-	return card
+	return od.ReadCardinality(getMinhashFilename(tableID, domainDir, index), cardpath)
 }
 
 func getOntMinhashFilename(tableID, domainDir string, index int) string {
@@ -212,6 +154,12 @@ func getOntMinhashFilename(tableID, domainDir string, index int) string {
 }
 
 func getTextDomains(file, domainDir string) (indices []int) {
+	return getDomains(file, domainDir, "text")
+}
+
+// getDomains returns the columns of the given types in the order of the
+// types file.
+func getDomains(file, domainDir string, colTypes ...string) (indices []int) {
 	typesFile := path.Join(domainDir, file, "types")
 	f, err := os.Open(typesFile)
 	defer f.Close()
@@ -227,8 +175,10 @@ func getTextDomains(file, domainDir string) (indices []int) {
 			if err != nil {
 				panic(err)
 			}
-			if parts[1] == "text" {
-				indices = append(indices, index)
+			for _, colType := range colTypes {
+				if parts[1] == colType {
+					indices = append(indices, index)
+				}
 			}
 		}
 	}
@@ -236,7 +186,9 @@ func getTextDomains(file, domainDir string) (indices []int) {
 	return
 }
 
-func getHeaders(file, domainDir string) (headers []string) {
+// Returns the headers of a table of the opendata dir of a repository, or
+// of the domain dir if the table was inserted online.
+func getHeaders(od *opendata.Repository, file, domainDir string) (headers []string) {
 	/*
 		typesFile := path.Join(domainDir, file, "index")
 		f, err := os.Open(typesFile)
@@ -251,7 +203,11 @@ func getHeaders(file, domainDir string) (headers []string) {
 		}
 		return
 	*/
-	f, err := os.Open(path.Join(queryDir, file))
+	f, err := os.Open(od.Filepath(file))
+	if os.IsNotExist(err) {
+		// tables inserted online only have their headers in the domain dir
+		return readHeaders(file, domainDir)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -262,9 +218,22 @@ func getHeaders(file, domainDir string) (headers []string) {
 	return queryHeaders
 }
 
-func getTextHeaders(file, domainDir string) []string {
+func readHeaders(file, domainDir string) (headers []string) {
+	f, err := os.Open(path.Join(domainDir, file, "index"))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		headers = append(headers, scanner.Text())
+	}
+	return
+}
+
+func getTextHeaders(od *opendata.Repository, file, domainDir string) []string {
 	textHeaderIndices := getTextDomains(file, domainDir)
-	headers := getHeaders(file, domainDir)
+	headers := getHeaders(od, file, domainDir)
 	textHeaders := make([]string, 0)
 	for _, i := range textHeaderIndices {
 		textHeaders = append(textHeaders, headers[i])
@@ -0,0 +1,64 @@
+package benchmarkserver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/RJMillerLab/table-union/opendata"
+)
+
+// WSetBuild indexes the weighted minhash sketches of the value frequencies
+// of text domains.
+func (index *JaccardUnionIndex) WSetBuild() error {
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamMinhashVectors(10, "wminhash", domainfilenames)
+	count := 0
+	start := getNow()
+	for file := range minhashFilenames {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
+		if err != nil {
+			log.Printf("Error in reading weighted minhash %s from disk.", file)
+			return err
+		}
+		tableID, columnIndex := parseFilename(index.domainDir, file)
+		index.lsh.Add(toColumnID(tableID, columnIndex), vec)
+		count += 1
+		if count%1000 == 0 {
+			log.Printf("indexed %d domains", count)
+		}
+	}
+	index.lsh.Index()
+	log.Printf("wset build count %d", count)
+	log.Printf("index time for wset: %f", getNow()-start)
+	return nil
+}
+
+// getColumnPairWeightedJaccard scores a candidate column with the weighted
+// Jaccard of the value frequencies. vec is the signature of the candidate,
+// read from disk if nil.
+func getColumnPairWeightedJaccard(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, vec []uint64, query [][]uint64) Pair {
+	if vec == nil {
+		filename := path.Join(domainDir, candTableID, fmt.Sprintf("%d.wminhash", candColIndex))
+		var err error
+		vec, err = od.ReadMinhashSignature(filename, numHash)
+		if err != nil {
+			log.Printf("Error in reading %s from disk.", filename)
+			panic(err)
+		}
+	}
+	jaccard := estimateJaccard(vec, query[queryColIndex])
+	p := Pair{
+		QueryColIndex: queryColIndex,
+		CandTableID:   candTableID,
+		CandColIndex:  candColIndex,
+		Jaccard:       jaccard,
+		Sim:           jaccard,
+		Measure:       []string{"wset"},
+	}
+	return p
+}
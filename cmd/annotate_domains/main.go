@@ -1,18 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
-	InitAnnotator()
-	filenames := StreamFilenames()
-	annotations := AnnotateDomainsFromEntityFiles(filenames, 30, "entities-l0")
-	progress := DoSaveAnnotations(annotations)
+	od.InitAnnotator()
+	filenames := od.StreamFilenames()
+	annotations := od.AnnotateDomainsFromEntityFiles(filenames, 30, "entities-l0")
+	progress := od.DoSaveAnnotations(annotations)
 	total := ProgressCounter{}
 	for n := range progress {
 		total.Values += n.Values
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"sync"
 
@@ -18,21 +19,23 @@ type pair struct {
 
 func main() {
 	log.Printf("start of octopus column text expeirments")
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
 	//results := make(chan OctopusScore)
 	results := make(chan OctopusAlignment)
-	allFilenames := StreamFilenames()
-	idf := ComputeIDF(allFilenames)
-	queryFilenames := StreamQueryFilenames()
+	allFilenames := od.StreamFilenames()
+	idf := od.ComputeIDF(allFilenames)
+	queryFilenames := od.StreamQueryFilenames()
 	pairs := make(chan pair)
 	go func() {
 		//seen := make(map[string]bool)
 		for query := range queryFilenames {
-			queryTfidfs, queryL2s := GetTableColumnsTFIDF(query, idf)
-			candFilenames := StreamFilenames()
+			queryTfidfs, queryL2s := od.GetTableColumnsTFIDF(query, idf)
+			candFilenames := od.StreamFilenames()
 			for cand := range candFilenames {
-				candTfidfs, candL2s := GetTableColumnsTFIDF(cand, idf)
+				candTfidfs, candL2s := od.GetTableColumnsTFIDF(cand, idf)
 				//if _, ok := seen[query+" "+cand]; !ok {
 				//	if _, ok := seen[cand+" "+query]; !ok {
 				//		seen[cand+" "+query] = true
@@ -68,7 +71,7 @@ func main() {
 		close(results)
 	}()
 	//progress := DoSaveOctopusScores(results)
-	progress := DoSaveOctopusAlignments(results)
+	progress := od.DoSaveOctopusAlignments(results)
 	total := ProgressCounter{}
 	for n := range progress {
 		total.Values += n.Values
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"sync"
 
@@ -16,19 +17,21 @@ type pair struct {
 
 func main() {
 	log.Printf("start of octopus size experiments")
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
 	//results := make(chan OctopusScore)
 	results := make(chan OctopusAlignment)
-	queryFilenames := StreamQueryFilenames()
+	queryFilenames := od.StreamQueryFilenames()
 	pairs := make(chan pair)
 	go func() {
 		//seen := make(map[string]bool)
 		for query := range queryFilenames {
-			queryLens := GetTableColumnMeanLength(query)
-			candFilenames := StreamFilenames()
+			queryLens := od.GetTableColumnMeanLength(query)
+			candFilenames := od.StreamFilenames()
 			for cand := range candFilenames {
-				candLens := GetTableColumnMeanLength(cand)
+				candLens := od.GetTableColumnMeanLength(cand)
 				//if _, ok := seen[query+" "+cand]; !ok {
 				//	if _, ok := seen[cand+" "+query]; !ok {
 				//		seen[cand+" "+query] = true
@@ -61,7 +64,7 @@ func main() {
 		wg.Wait()
 		close(results)
 	}()
-	progress := DoSaveOctopusAlignments(results)
+	progress := od.DoSaveOctopusAlignments(results)
 	//progress := DoSaveOctopusScores(results)
 	total := ProgressCounter{}
 	for n := range progress {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"sync"
 
@@ -9,21 +10,23 @@ import (
 
 func main() {
 	log.Printf("start of octopus text cluster experiments")
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
 	results := make(chan OctopusScore)
-	allFilenames := StreamFilenames()
-	idf := ComputeIDF(allFilenames)
-	queryFilenames := StreamQueryFilenames()
+	allFilenames := od.StreamFilenames()
+	idf := od.ComputeIDF(allFilenames)
+	queryFilenames := od.StreamQueryFilenames()
 	wg := sync.WaitGroup{}
 	go func() {
 		for query := range queryFilenames {
-			candFilenames := StreamFilenames()
+			candFilenames := od.StreamFilenames()
 			for i := 0; i < 60; i++ {
 				wg.Add(1)
 				go func() {
 					for candidate := range candFilenames {
-						sp := ComputeTextClusterScore(query, candidate, idf)
+						sp := od.ComputeTextClusterScore(query, candidate, idf)
 						results <- sp
 					}
 					wg.Done()
@@ -33,7 +36,7 @@ func main() {
 		}
 		close(results)
 	}()
-	progress := DoSaveOctopusScores(results)
+	progress := od.DoSaveOctopusScores(results)
 	total := ProgressCounter{}
 	for n := range progress {
 		total.Values += n.Values
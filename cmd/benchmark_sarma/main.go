@@ -1,19 +1,22 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
-	InitSarma()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	od.InitSarma()
 	start := GetNow()
-	//yg := yago.InitYago(Yago_db)
-	queryFilenames := StreamQueryFilenames()
-	results := DoFindSarmaUnionableTables(queryFilenames, 10)
-	progress := DoSaveSarmaScores(results)
+	//yg := yago.InitYago(od.YagoDB)
+	queryFilenames := od.StreamQueryFilenames()
+	results := od.DoFindSarmaUnionableTables(queryFilenames, 10)
+	progress := od.DoSaveSarmaScores(results)
 	total := ProgressCounter{}
 	for n := range progress {
 		total.Values += n.Values
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 
@@ -8,15 +9,17 @@ import (
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
 	total := ProgressCounter{}
-	queries := GetQueryFilenames()
+	queries := od.GetQueryFilenames()
 	log.Printf("number of queries: %d", len(queries))
-	filenames := GetCODFilenames()
+	filenames := od.GetCODFilenames()
 	log.Printf("number of CODs: %d", len(filenames))
-	scores := DoAlign(queries, filenames, 40)
-	progress := DoSaveKUnionabilityScores(scores, 1)
+	scores := od.DoAlign(queries, filenames, 40)
+	progress := od.DoSaveKUnionabilityScores(scores, 1)
 	for n := range progress {
 		total.Values += n.Values
 		now := GetNow()
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -15,10 +16,12 @@ import (
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile, "fasttext_db")
 
 	start := GetNow()
-	ft, err := embedding.InitInMemoryFastText("/home/ekzhu/FB_WORD_VEC/fasttext.db", func(v string) []string {
+	ft, err := embedding.InitInMemoryFastText(od.FastTextDB, func(v string) []string {
 		return strings.Split(v, " ")
 	}, func(v string) string {
 		return strings.ToLower(strings.TrimFunc(strings.TrimSpace(v), unicode.IsPunct))
@@ -29,8 +32,8 @@ func main() {
 
 	fmt.Printf("fasttext.db loaded in %.2f seconds.\n", GetNow()-start)
 
-	filenames := StreamFilenames()
-	valuefreqs := StreamValueFreqFromCache(10, filenames)
+	filenames := od.StreamFilenames()
+	valuefreqs := od.StreamValueFreqFromCache(10, filenames)
 
 	progress := make(chan ProgressCounter)
 	fanout := 40
@@ -53,15 +56,15 @@ func main() {
 					continue
 				}
 				//vecFilename := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-sum", vf.Filename, vf.Index))
-				vecFilename := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", vf.Filename, vf.Index))
+				vecFilename := filepath.Join(od.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", vf.Filename, vf.Index))
 				if err := embedding.WriteVecToDisk(mean, binary.BigEndian, vecFilename); err != nil {
 					panic(err)
 				}
-				vecFilename = filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-covar", vf.Filename, vf.Index))
+				vecFilename = filepath.Join(od.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-covar", vf.Filename, vf.Index))
 				if err := embedding.WriteVecToDisk(covar, binary.BigEndian, vecFilename); err != nil {
 					panic(err)
 				}
-				sizeFilename := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.size", vf.Filename, vf.Index))
+				sizeFilename := filepath.Join(od.OutputDir, "domains", fmt.Sprintf("%s/%d.size", vf.Filename, vf.Index))
 				f, err := os.OpenFile(sizeFilename, os.O_CREATE|os.O_WRONLY, 0644)
 				if err != nil {
 					panic(err)
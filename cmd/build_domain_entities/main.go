@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"sync"
@@ -47,12 +48,14 @@ func DoAnnotateDomainSegment(domain *Domain, yg *yago.Yago) *Annotation {
 }
 
 func main() {
-	CheckEnv()
-	yg := yago.InitYago(Yago_db)
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	yg := yago.InitYago(od.YagoDB)
 
 	start := GetNow()
-	filenames := StreamFilenames()
-	domains := StreamDomainValuesFromFiles(10, filenames)
+	filenames := od.StreamFilenames()
+	domains := od.StreamDomainValuesFromFiles(10, filenames)
 	fanout := 30
 	progress := make(chan *Annotation)
 
@@ -82,7 +85,7 @@ func main() {
 			}
 
 			if nEntities > 0 {
-				output_filename := annotation.Domain.PhysicalFilename("entities")
+				output_filename := od.DomainFilename(annotation.Domain.Filename, annotation.Domain.Index, "entities")
 				f, err := os.OpenFile(output_filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 				if err != nil {
 					panic(err)
@@ -1,18 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
-	filenames := StreamFilenames()
-	sketches := DoMinhashDomainsFromFiles(10, filenames, "values")
+	filenames := od.StreamFilenames()
+	sketches := od.DoMinhashDomainsFromFiles(10, filenames, "values")
 	//sketches := DoMinhashDomainsFromFiles(10, filenames, "entities")
-	progress := DoSaveDomainSketches(10, sketches, "minhash")
+	progress := od.DoSaveDomainSketches(10, sketches, "minhash")
 	//progress := DoSaveDomainSketches(10, sketches, "entities-minhash")
 	total := ProgressCounter{}
 	for n := range progress {
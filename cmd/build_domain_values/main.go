@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
@@ -11,19 +12,21 @@ const nReaders = 30
 const nWriters = 30
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 
 	// Get the channel of filenames (as string)
-	filenames := StreamFilenames()
+	filenames := od.StreamFilenames()
 
 	// Map filenames to domain fragments using
 	// nReader goroutines
-	domains := StreamDomainsFromFilenames(nReaders, filenames)
+	domains := od.StreamDomainsFromFilenames(nReaders, filenames)
 
 	// Save the domain fragments to disk
 	// and report the progress with the progress
 	// counter channel
-	progress := DoSaveDomainValues(nWriters, domains)
+	progress := od.DoSaveDomainValues(nWriters, domains)
 
 	i := 0
 	total := ProgressCounter{}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	. "github.com/RJMillerLab/table-union/opendata"
+)
+
+func main() {
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	start := GetNow()
+	filenames := od.StreamFilenames()
+	freqs := od.StreamValueFreqFromCache(10, filenames)
+	sketches := od.DoWeightedMinhashDomains(10, freqs)
+	progress := od.DoSaveWeightedDomainSketches(10, sketches, "wminhash")
+	total := ProgressCounter{}
+	for n := range progress {
+		total.Values += n.Values
+		now := GetNow()
+		if total.Values%100 == 0 {
+			fmt.Printf("Processed %d domains in %.2f seconds\n", total.Values, now-start)
+		}
+	}
+	fmt.Printf("Done generating weighted minhash sketches for COD.")
+}
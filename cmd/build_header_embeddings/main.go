@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/embedding"
+	. "github.com/RJMillerLab/table-union/opendata"
+)
+
+func main() {
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile, "fasttext_db")
+	start := GetNow()
+	ft, err := embedding.InitInMemoryFastText(od.FastTextDB, benchmarkserver.DefaultTokenFun, benchmarkserver.DefaultTransFun)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("fasttext.db loaded in %.2f seconds.\n", GetNow()-start)
+	filenames := od.StreamFilenames()
+	progress := od.DoEmbedHeadersFromFiles(ft, 10, filenames)
+	total := ProgressCounter{}
+	for n := range progress {
+		total.Values += n.Values
+		now := GetNow()
+		if total.Values%100 == 0 {
+			fmt.Printf("Processed %d headers in %.2f seconds\n", total.Values, now-start)
+		}
+	}
+	fmt.Printf("Done embedding headers.")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	. "github.com/RJMillerLab/table-union/opendata"
+)
+
+func main() {
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	start := GetNow()
+	filenames := od.StreamFilenames()
+	progress := od.DoSketchNumericDomainsFromFiles(10, filenames)
+	total := ProgressCounter{}
+	for n := range progress {
+		total.Values += n.Values
+		now := GetNow()
+		if total.Values%100 == 0 {
+			fmt.Printf("Processed %d domains in %.2f seconds\n", total.Values, now-start)
+		}
+	}
+	fmt.Printf("Done generating numeric sketches.")
+}
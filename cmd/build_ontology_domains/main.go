@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -117,14 +118,16 @@ func DoFindPartiallyAnnotatedDomainSegmentPlus(domain *Domain, lookup map[string
 }
 
 func main() {
-	CheckEnv()
-	yg := yago.InitYago(Yago_db)
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	yg := yago.InitYago(od.YagoDB)
 	//lookup := LoadEntityWords()
 	//counts := LoadEntityWordCount()
 
 	start := GetNow()
-	filenames := StreamFilenames()
-	domains := StreamDomainValuesFromFiles(20, filenames)
+	filenames := od.StreamFilenames()
+	domains := od.StreamDomainValuesFromFiles(20, filenames)
 	fanout := 10
 	progress := make(chan *PartialAnnotation)
 
@@ -158,7 +161,7 @@ func main() {
 			fmt.Printf("%d segments with %d entities in %.2f seconds\n", segCount, totalValueCount, GetNow()-start)
 		}
 		if nValues > 0 {
-			outputFilename := partialAnnotation.Domain.PhysicalFilename("no-annotation")
+			outputFilename := od.DomainFilename(partialAnnotation.Domain.Filename, partialAnnotation.Domain.Index, "no-annotation")
 			f, err := os.OpenFile(outputFilename, os.O_CREATE|os.O_WRONLY, 0644)
 			if err != nil {
 				panic(err)
@@ -172,7 +175,7 @@ func main() {
 			f.Close()
 		}
 		if len(partialAnnotation.Entities) > 0 {
-			outputFilename := partialAnnotation.Domain.PhysicalFilename("entities-l0")
+			outputFilename := od.DomainFilename(partialAnnotation.Domain.Filename, partialAnnotation.Domain.Index, "entities-l0")
 			f, err := os.OpenFile(outputFilename, os.O_CREATE|os.O_WRONLY, 0644)
 			if err != nil {
 				panic(err)
@@ -185,7 +188,7 @@ func main() {
 			}
 			f.Close()
 		}
-		cardFilename := partialAnnotation.Domain.PhysicalFilename("ont-noann-card")
+		cardFilename := od.DomainFilename(partialAnnotation.Domain.Filename, partialAnnotation.Domain.Index, "ont-noann-card")
 		f, err := os.OpenFile(cardFilename, os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
 			panic(err)
@@ -194,14 +197,14 @@ func main() {
 		fmt.Fprintln(f, len(partialAnnotation.Entities))
 		fmt.Fprintln(f, partialAnnotation.Domain.Cardinality)
 		f.Close()
-		cardFilename = partialAnnotation.Domain.PhysicalFilename("card")
+		cardFilename = od.DomainFilename(partialAnnotation.Domain.Filename, partialAnnotation.Domain.Index, "card")
 		f, err = os.OpenFile(cardFilename, os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
 			panic(err)
 		}
 		fmt.Fprintln(f, partialAnnotation.Domain.Cardinality)
 		f.Close()
-		//sizeFilename := partialAnnotation.Domain.PhysicalFilename("size")
+		//sizeFilename := od.DomainFilename(partialAnnotation.Domain.Filename, partialAnnotation.Domain.Index, "size")
 		//f, err = os.OpenFile(sizeFilename, os.O_CREATE|os.O_WRONLY, 0644)
 		//if err != nil {
 		//	panic(err)
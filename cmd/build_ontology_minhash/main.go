@@ -1,17 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
-	filenames := StreamFilenames()
-	sketches := DoOntologyMinhashFromDB(10, filenames)
-	progress := DoSaveDomainSketches(10, sketches, "ont-minhash-l1")
+	filenames := od.StreamFilenames()
+	sketches := od.DoOntologyMinhashFromDB(10, filenames)
+	progress := od.DoSaveDomainSketches(10, sketches, "ont-minhash-l1")
 	//progress := DoSaveDomainSketches(10, sketches, "ont-minhash-l2")
 	total := ProgressCounter{}
 	for n := range progress {
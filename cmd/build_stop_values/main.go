@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+
+	. "github.com/RJMillerLab/table-union/opendata"
+)
+
+// Computes the document frequencies of the values of all domains and saves
+// the values found in too many domains. The minhash sketches of domains
+// and query columns leave out the values of the STOP_VALUES file.
+func main() {
+	var fraction float64
+	var output string
+	var sample int
+	var threshold float64
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Float64Var(&fraction, "fraction", 0.01, "The fraction of domains a value must be found in to be a stop value")
+	flag.StringVar(&output, "output", "", "The file of the stop values, stop-values.txt in the output directory by default")
+	flag.IntVar(&sample, "sample", 10000, "The number of domains indexed to compare the LSH buckets with and without the stop values")
+	flag.Float64Var(&threshold, "t", 0.3, "The Jaccard threshold of the LSH index of the sample")
+	flag.Parse()
+	od := OpenRepository(configFile, "output_dir")
+	if output == "" {
+		output = path.Join(od.OutputDir, "stop-values.txt")
+	}
+	start := GetNow()
+	filenames := od.StreamFilenames()
+	freqs := od.StreamValueFreqFromCache(10, filenames)
+	df := ComputeValueDocFreq(freqs, sample)
+	stopValues := df.StopValues(fraction)
+	if err := WriteStopValues(output, stopValues); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Found %d stop values in %d domains in %.2f seconds\n", len(stopValues), df.NumDomains, GetNow()-start)
+	for i := 0; i < len(stopValues) && i < 20; i++ {
+		fmt.Printf("%s: %d domains\n", stopValues[i], df.Freq[stopValues[i]])
+	}
+	before, after := df.CompareBuckets(stopValues, od.MinhashSize(), threshold)
+	fmt.Printf("Buckets of %d domains:\n", len(df.Sample))
+	fmt.Printf("with stop values: %d buckets, max size %d, mean size %.2f, mean candidates %.2f\n", before.NumBuckets, before.MaxSize, before.MeanSize, before.MeanCandidates)
+	fmt.Printf("without stop values: %d buckets, max size %d, mean size %.2f, mean candidates %.2f\n", after.NumBuckets, after.MaxSize, after.MeanSize, after.MeanCandidates)
+}
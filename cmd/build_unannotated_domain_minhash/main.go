@@ -1,17 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
-	filenames := StreamFilenames()
-	sketches := DoMinhashDomainsFromFiles(10, filenames, "no-annotation")
-	progress := DoSaveDomainSketches(10, sketches, "noann-minhash")
+	filenames := od.StreamFilenames()
+	sketches := od.DoMinhashDomainsFromFiles(10, filenames, "no-annotation")
+	progress := od.DoSaveDomainSketches(10, sketches, "noann-minhash")
 	total := ProgressCounter{}
 	for n := range progress {
 		total.Values += n.Values
@@ -1,19 +1,22 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 
 	// Get the stream of filenames as a channel of strings
-	filenames := StreamFilenames()
+	filenames := od.StreamFilenames()
 
 	// Classify the domains
-	progress := DoClassifyDomainsFromFiles(10, filenames)
+	progress := od.DoClassifyDomainsFromFiles(10, filenames)
 
 	start := GetNow()
 	tick := start
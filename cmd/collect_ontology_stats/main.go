@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -52,10 +53,12 @@ func DoAnnotateDomainSegment(domain *Domain, yg *yago.Yago) *Annotation {
 }
 
 func main() {
-	CheckEnv()
-	filenames := StreamFilenames()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	filenames := od.StreamFilenames()
 	statsTable := "card_stats"
-	statsDB := "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark/stats.sqlite"
+	statsDB := path.Join(od.OutputDir, "benchmark", "stats.sqlite")
 	// create db
 	db, err := sql.Open("sqlite3", statsDB)
 	if err != nil {
@@ -71,7 +74,7 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	domainDir := "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark"
+	domainDir := path.Join(od.OutputDir, "benchmark")
 	for tableID := range filenames {
 		for _, index := range getTextDomains(domainDir, tableID) {
 			ontcardFilename := path.Join(domainDir, "domains", tableID, fmt.Sprintf("%d.%s", index, "ont-noann-card"))
@@ -4,7 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
-	"sync"
+	"path"
 	"time"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
@@ -30,58 +30,68 @@ func main() {
 	var fanout int
 	var opendataDir string
 	var experimentType string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/domains", "The top-level director for all domain and embedding files")
-	//flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains", "The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "", "The top-level director for all domain and embedding files, benchmark-v7/domains in the output dir of the repository if empty")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
-	//flag.StringVar(&queryDir, "query-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only", "The directory of query files")
-	flag.StringVar(&queryDir, "query-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/csvfiles", "The directory of query files")
+	flag.StringVar(&queryDir, "query-dir", "", "The directory of query files, benchmark-v7/csvfiles in the output dir of the repository if empty")
 	flag.Float64Var(&threshold, "t", 0.7, "Search Parameter: k-unionability threshold")
 	flag.IntVar(&n, "n", 60, "Search Parameter: top (n,k) unionable tables")
 	flag.StringVar(&host, "host", "http://localhost:4064", "Server host")
 	flag.StringVar(&port, "port", "4064", "Server port")
-	flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/investigate.sqlite", "experiments DB")
-	//flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/scalability.sqlite", "experiments DB")
-	flag.StringVar(&opendataDir, "opendata-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7", "The directory of open data tables.")
-	//flag.StringVar(&opendataDir, "opendata-dir", "/home/fnargesian/TABLE_UNION_OUTPUT", "The directory of open data tables.")
-	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "/home/ekzhu/FB_WORD_VEC/fasttext.db",
-		"Sqlite database file for fastText vecs")
-	flag.IntVar(&fanout, "fanout", 15, "Number threads querying the server in parallel.")
+	flag.StringVar(&experimentsDB, "experiments-db", "", "experiments DB, benchmark-v7/investigate.sqlite in the output dir of the repository if empty")
+	flag.StringVar(&opendataDir, "opendata-dir", "", "The directory of open data tables, benchmark-v7 in the output dir of the repository if empty")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "",
+		"Sqlite database file for fastText vecs, the fastText database of the repository if empty")
+	flag.IntVar(&fanout, "fanout", 15, "Number of queries run by the server in parallel.")
 	flag.StringVar(&experimentType, "type", "fixedn", "The type of experiments: fixed k or fixed n.")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile, "query_list")
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "benchmark-v7/domains")
+	}
+	if queryDir == "" {
+		queryDir = path.Join(od.OutputDir, "benchmark-v7/csvfiles")
+	}
+	if experimentsDB == "" {
+		experimentsDB = path.Join(od.OutputDir, "benchmark-v7/investigate.sqlite")
+	}
+	if opendataDir == "" {
+		opendataDir = path.Join(od.OutputDir, "benchmark-v7")
+	}
+	if fastTextSqliteDB == "" {
+		fastTextSqliteDB = od.FastTextDB
+	}
 	// Create client
 	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
 		panic("FastText Sqlite DB does not exist")
 	}
 	ft := fasttext.NewFastText(fastTextSqliteDB)
 
-	client, err := benchmarkserver.NewCombinedClient(ft, host, numHash)
+	client, err := benchmarkserver.NewCombinedClient(od, ft, host, numHash)
 	if err != nil {
 		panic(err)
 	}
-	queries := opendata.StreamQueryFilenames()
+	queries := make([]string, 0)
+	queryPaths := make([]string, 0)
+	for query := range od.StreamQueryFilenames() {
+		queries = append(queries, query)
+		queryPaths = append(queryPaths, experiment.GetQueryPath(queryDir, query))
+	}
 	//
 	log.Printf("start time: %v", time.Now())
-	alignments := make(chan benchmarkserver.Union, 10)
-	wg := &sync.WaitGroup{}
-	wg.Add(fanout)
-	for i := 0; i < fanout; i++ {
-		go func() {
-			for query := range queries {
-				queryPath := experiment.GetQueryPath(queryDir, query)
-				log.Printf("Query: %s", queryPath)
-				results := make([]benchmarkserver.QueryResult, 0)
-				results = client.Query(queryPath, n)
-				for _, res := range results {
-					res.TableUnion.QueryTableID = query
-					alignments <- res.TableUnion
-				}
-			}
-			//close(alignments)
-			wg.Done()
-		}()
+	results, err := client.QueryTables(queryPaths, n, fanout)
+	if err != nil {
+		panic(err)
 	}
+	alignments := make(chan benchmarkserver.Union, 10)
 	go func() {
-		wg.Wait()
+		for i, query := range queries {
+			for _, res := range results[i] {
+				res.TableUnion.QueryTableID = query
+				alignments <- res.TableUnion
+			}
+		}
 		close(alignments)
 	}()
 
@@ -2,10 +2,18 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/hnsw"
 	"github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/RJMillerLab/table-union/simhashlsh"
+	"github.com/RJMillerLab/table-union/yago"
 )
 
 var (
@@ -17,31 +25,168 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v7/domains", "The top-level director for all domain and embedding files")
-	//flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains", "The top-level director for all domain and embedding files")
+	var snapshotDir string
+	var fastTextDB string
+	var yagoDB string
+	var classFilename string
+	var numeric bool
+	var header bool
+	var join bool
+	var joinPartitions int
+	var shards string
+	var shardTimeout time.Duration
+	var sketchCache int
+	var storeVectors bool
+	var lshModel string
+	var nlIndex string
+	var hnswM, hnswEf int
+	var setMeasure string
+	var tableDir string
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "", "The top-level director for all domain and embedding files, the domains of the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4064", "Server port")
-	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
+	flag.IntVar(&numHash, "h", 0, "LSH Parameter: number of hash functions, the minhash size of the repository if 0")
 	flag.Float64Var(&threshold, "t", 0.01, "Search Parameter: k-unionability threshold")
+	flag.StringVar(&fastTextDB, "fasttext-db", "", "The fastText database for sketching uploaded query tables")
+	flag.StringVar(&yagoDB, "yago-db", "", "The YAGO database for sketching uploaded query tables")
+	flag.StringVar(&classFilename, "entity-class", "", "The entity to class mapping of YAGO")
+	flag.BoolVar(&numeric, "numeric", false, "Search and align numeric columns with the num measure")
+	flag.BoolVar(&header, "header", false, "Align columns with the header measure as well")
+	flag.BoolVar(&join, "join", false, "Build the containment index for join search")
+	flag.IntVar(&joinPartitions, "join-partitions", 16, "Join Parameter: number of partitions by domain size")
+	flag.StringVar(&snapshotDir, "index-snapshot", "", "The directory of the LSH index snapshots, loaded if present and saved otherwise")
+	flag.StringVar(&shards, "shards", "", "Run as the coordinator of the comma-separated shard servers, e.g. http://localhost:4065,http://localhost:4066")
+	flag.DurationVar(&shardTimeout, "shard-timeout", 30*time.Second, "The time a shard has to answer a query")
+	flag.IntVar(&sketchCache, "sketch-cache", 0, "The number of sketch files kept in memory across queries, 0 to read them from disk every time")
+	flag.BoolVar(&storeVectors, "store-vectors", false, "Keep the embeddings and minhash signatures of the columns in the LSH indexes, to score candidates without reading them from disk. Saved in the index snapshots")
+	flag.StringVar(&lshModel, "lsh-model", "", "The threshold model trained by wwtlshtrain, to tune the nl LSH queries per query column")
+	flag.StringVar(&nlIndex, "nl-index", "lsh", "The index of the column embeddings: lsh or hnsw")
+	flag.IntVar(&hnswM, "hnsw-m", 16, "HNSW Parameter: number of neighbours of each node")
+	flag.IntVar(&hnswEf, "hnsw-ef", 200, "HNSW Parameter: number of candidates kept while building and searching")
+	flag.StringVar(&setMeasure, "set-measure", "set", "The set measure of the search and alignment: set, wset (weighted Jaccard of value frequencies) or both")
+	flag.StringVar(&tableDir, "table-dir", "", "The directory of the raw CSV files of the indexed tables, read by /union")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if numHash == 0 {
+		numHash = od.MinhashSize()
+	} else if numHash != od.MinhashSize() {
+		panic(fmt.Sprintf("%d hash functions but the minhash size of the repository is %d", numHash, od.MinhashSize()))
+	}
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
+	if shards != "" {
+		c := benchmarkserver.NewCoordinator(strings.Split(shards, ","), shardTimeout)
+		defer c.Close()
+		c.Run(port)
+		return
+	}
 	// Build Search Index
-	seti := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash)    //0.7
-	semi := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash)    // 0.7
-	semseti := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash) // 0.7
-	nli := benchmarkserver.NewUnionIndex(domainDir, simhashlsh.NewCosineLSH(FastTextDim, numHash, 0.9))
-	if err := seti.Build(); err != nil {
+	setlsh := minhashlsh.NewMinhashLSH32(numHash, 0.3) //0.7
+	nllsh := simhashlsh.NewCosineLSH(FastTextDim, numHash, 0.9)
+	if storeVectors {
+		setlsh.StoreSignatures()
+		nllsh.StoreVectors()
+	}
+	seti := benchmarkserver.NewJaccardUnionIndex(od, domainDir, setlsh, numHash)
+	semi := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash)    // 0.7
+	semseti := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash) // 0.7
+	// the parameters of the snapshots of the indexes
+	params := func(threshold float64) benchmarkserver.SnapshotParams {
+		return benchmarkserver.SnapshotParams{DomainDir: domainDir, Threshold: threshold, NumHash: numHash}
+	}
+	// the snapshots of the indexes with stored vectors include them
+	storedParams := func(threshold float64) benchmarkserver.SnapshotParams {
+		p := params(threshold)
+		p.StoreVectors = storeVectors
+		return p
+	}
+	nlParams := storedParams(0.9)
+	nlParams.Index = nlIndex
+	var nli *benchmarkserver.UnionIndex
+	switch nlIndex {
+	case "lsh":
+		nli = benchmarkserver.NewUnionIndex(od, domainDir, nllsh)
+	case "hnsw":
+		nli = benchmarkserver.NewUnionIndex(od, domainDir, hnsw.NewHNSW(FastTextDim, hnswM, hnswEf, hnswEf))
+		nlParams = params(0.9)
+		nlParams.Index = fmt.Sprintf("hnsw m=%d ef=%d", hnswM, hnswEf)
+	default:
+		panic("Unknown nl index " + nlIndex)
+	}
+	if err := benchmarkserver.BuildWithSnapshot(seti, seti.Build, snapshotDir, "set", storedParams(0.3)); err != nil {
 		panic(err)
 	}
-	if err := semi.OntBuild(); err != nil {
+	if err := benchmarkserver.BuildWithSnapshot(semi, semi.OntBuild, snapshotDir, "sem", params(0.3)); err != nil {
 		panic(err)
 	}
-	if err := semseti.NoOntBuild(); err != nil {
+	if err := benchmarkserver.BuildWithSnapshot(semseti, semseti.NoOntBuild, snapshotDir, "semset", params(0.3)); err != nil {
 		panic(err)
 	}
-	if err := nli.Build(); err != nil {
+	if err := benchmarkserver.BuildWithSnapshot(nli, nli.Build, snapshotDir, "nl", nlParams); err != nil {
 		panic(err)
 	}
+	if lshModel != "" {
+		m, err := simhashlsh.LoadThresholdModel(lshModel)
+		if err != nil {
+			panic(err)
+		}
+		nli.SetThresholdModel(m)
+	}
+	// the cache is enabled once the indexes are built from the sketches
+	od.SetSketchCacheSize(sketchCache)
 	// Start server
-	s := benchmarkserver.NewCombinedServer(seti, semi, semseti, nli)
+	s := benchmarkserver.NewCombinedServer(od, seti, semi, semseti, nli)
+	if numeric {
+		numi := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, 0.3), numHash)
+		if err := benchmarkserver.BuildWithSnapshot(numi, numi.NumBuild, snapshotDir, "num", params(0.3)); err != nil {
+			panic(err)
+		}
+		s.AddMeasure(benchmarkserver.NewNumMeasure(od, numi))
+	}
+	switch setMeasure {
+	case "set":
+	case "wset", "both":
+		wsetlsh := minhashlsh.NewWeightedMinhashLSH(numHash, 0.3)
+		if storeVectors {
+			wsetlsh.StoreSignatures()
+		}
+		wseti := benchmarkserver.NewJaccardUnionIndex(od, domainDir, wsetlsh, numHash)
+		if err := benchmarkserver.BuildWithSnapshot(wseti, wseti.WSetBuild, snapshotDir, "wset", storedParams(0.3)); err != nil {
+			panic(err)
+		}
+		s.AddMeasure(benchmarkserver.NewWSetMeasure(od, wseti))
+		if setMeasure == "wset" {
+			s.RemoveMeasure("set")
+		}
+	default:
+		panic("Unknown set measure " + setMeasure)
+	}
+	if header {
+		s.AddMeasure(benchmarkserver.AlignMeasure(od.GetMeasure("header")))
+	}
+	if join {
+		joini := benchmarkserver.NewJoinIndex(od, domainDir, numHash, joinPartitions)
+		if err := joini.Build(); err != nil {
+			panic(err)
+		}
+		s.SetJoinIndex(joini)
+	}
+	if fastTextDB != "" || yagoDB != "" {
+		if fastTextDB != "" {
+			ft, err := embedding.InitInMemoryFastText(fastTextDB, benchmarkserver.DefaultTokenFun, benchmarkserver.DefaultTransFun)
+			if err != nil {
+				panic(err)
+			}
+			od.SetFastText(ft)
+		}
+		if yagoDB != "" {
+			od.SetOntology(yago.InitYago(yagoDB), benchmarkserver.LoadEntityClasses(classFilename))
+		}
+		s.SetSketcher(benchmarkserver.NewTableSketcher(od))
+	}
+	s.SetTableDir(tableDir)
 	defer s.Close()
 	s.Run(port)
 }
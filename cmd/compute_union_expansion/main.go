@@ -1,11 +1,16 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/RJMillerLab/table-union/experiment"
+	"github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	experiment.CheckEnv()
-	//experiment.DoComputeAndSaveExpansion()
-	experiment.DoComputeAndSaveRowExpansion()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := opendata.OpenRepository(*configFile, "opendata_dir", "experiment_db", "experiment_table", "expansion_db", "expansion_table")
+	//experiment.DoComputeAndSaveExpansion(od.Config)
+	experiment.DoComputeAndSaveRowExpansion(od.Config)
 }
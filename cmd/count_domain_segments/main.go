@@ -1,15 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	count := 0
 	start := GetNow()
-	for _ = range StreamDomainValuesFromFiles(10, StreamFilenames()) {
+	for _ = range od.StreamDomainValuesFromFiles(10, od.StreamFilenames()) {
 		count += 1
 		if count%1000 == 0 {
 			fmt.Printf("Domain segments: %d in %.2f seconds\n", count, GetNow()-start)
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"path"
 	"sync"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
@@ -30,35 +31,53 @@ func main() {
 	var fanout int
 	var opendataDir string
 	var experimentType string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
-	flag.StringVar(&queryDir, "query-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only",
-		"The directory of query files")
+	flag.StringVar(&queryDir, "query-dir", "",
+		"The directory of query files, the opendata dir of the repository if empty")
 	flag.Float64Var(&threshold, "t", 0.5, "Search Parameter: k-unionability threshold")
 	// k=5 and n:[1,75]
 	flag.IntVar(&k, "k", 1, "Search Parameter: top (n,k) unionable tables")
 	flag.IntVar(&n, "n", 10, "Search Parameter: top (n,k) unionable tables")
 	flag.StringVar(&host, "host", "http://localhost:4004", "Server host")
 	flag.StringVar(&port, "port", "4004", "Server port")
-	flag.StringVar(&experimentsDB, "emb-experiments", "/home/fnargesian/TABLE_UNION_OUTPUT/emb-experiments.sqlite", "experiments DB")
-	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "/home/ekzhu/FB_WORD_VEC/fasttext.db",
-		"Sqlite database file for fastText vecs")
-	flag.StringVar(&opendataDir, "opendate-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only", "The directory of open data tables.")
+	flag.StringVar(&experimentsDB, "emb-experiments", "", "experiments DB, emb-experiments.sqlite in the output dir of the repository if empty")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "",
+		"Sqlite database file for fastText vecs, the fastText database of the repository if empty")
+	flag.StringVar(&opendataDir, "opendate-dir", "", "The directory of open data tables, the opendata dir of the repository if empty")
 	flag.IntVar(&fanout, "fanout", 5, "Number threads querying the server in parallel.")
 	flag.StringVar(&experimentType, "type", "fixedn", "The type of experiments: fixed k or fixed n.")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile, "query_list")
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
+	if queryDir == "" {
+		queryDir = od.OpendataDir
+	}
+	if experimentsDB == "" {
+		experimentsDB = path.Join(od.OutputDir, "emb-experiments.sqlite")
+	}
+	if fastTextSqliteDB == "" {
+		fastTextSqliteDB = od.FastTextDB
+	}
+	if opendataDir == "" {
+		opendataDir = od.OpendataDir
+	}
 	// Create client
 	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
 		panic("FastText Sqlite DB does not exist")
 	}
 	ft := fasttext.NewFastText(fastTextSqliteDB)
 
-	client, err := benchmarkserver.NewClient(ft, host)
+	client, err := benchmarkserver.NewClient(od, ft, host)
 	if err != nil {
 		panic(err)
 	}
-	queries := opendata.StreamQueryFilenames()
+	queries := od.StreamQueryFilenames()
 	//
 	log.Printf("start")
 	alignments := make(chan benchmarkserver.Union, 30)
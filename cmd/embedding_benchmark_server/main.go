@@ -2,8 +2,12 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"path"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/hnsw"
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/RJMillerLab/table-union/simhashlsh"
 )
 
@@ -16,15 +20,38 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var snapshotDir string
+	var nlIndex string
+	var hnswM, hnswEf int
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4004", "Server port")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
 	flag.Float64Var(&threshold, "t", 0.5, "Search Parameter: k-unionability threshold")
+	flag.StringVar(&snapshotDir, "index-snapshot", "", "The directory of the LSH index snapshots, loaded if present and saved otherwise")
+	flag.StringVar(&nlIndex, "nl-index", "lsh", "The index of the column embeddings: lsh or hnsw")
+	flag.IntVar(&hnswM, "hnsw-m", 16, "HNSW Parameter: number of neighbours of each node")
+	flag.IntVar(&hnswEf, "hnsw-ef", 200, "HNSW Parameter: number of candidates kept while building and searching")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
 	// Build Search Index
-	ui := benchmarkserver.NewUnionIndex(domainDir, simhashlsh.NewCosineLSH(FastTextDim, numHash, threshold))
-	if err := ui.Build(); err != nil {
+	params := benchmarkserver.SnapshotParams{DomainDir: domainDir, Threshold: threshold, NumHash: numHash, Index: nlIndex}
+	var ui *benchmarkserver.UnionIndex
+	switch nlIndex {
+	case "lsh":
+		ui = benchmarkserver.NewUnionIndex(od, domainDir, simhashlsh.NewCosineLSH(FastTextDim, numHash, threshold))
+	case "hnsw":
+		ui = benchmarkserver.NewUnionIndex(od, domainDir, hnsw.NewHNSW(FastTextDim, hnswM, hnswEf, hnswEf))
+		params.Index = fmt.Sprintf("hnsw m=%d ef=%d", hnswM, hnswEf)
+	default:
+		panic("Unknown nl index " + nlIndex)
+	}
+	if err := benchmarkserver.BuildWithSnapshot(ui, ui.Build, snapshotDir, "nl", params); err != nil {
 		panic(err)
 	}
 	// Start server
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"path"
 	"sync"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
@@ -30,35 +31,53 @@ func main() {
 	var fanout int
 	var opendataDir string
 	var experimentType string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, benchmark-v3/domains in the output dir of the repository if empty")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
-	flag.StringVar(&queryDir, "query-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3/csvfiles",
-		"The directory of query files")
+	flag.StringVar(&queryDir, "query-dir", "",
+		"The directory of query files, benchmark-v3/csvfiles in the output dir of the repository if empty")
 	flag.Float64Var(&threshold, "t", 0.7, "Search Parameter: k-unionability threshold")
 	// k=5 and n:[1,75]
 	flag.IntVar(&k, "k", 3, "Search Parameter: top (n,k) unionable tables")
 	flag.IntVar(&n, "n", 25, "Search Parameter: top (n,k) unionable tables")
 	flag.StringVar(&host, "host", "http://localhost:4074", "Server host")
 	flag.StringVar(&port, "port", "4074", "Server port")
-	flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3/emb-experiments.sqlite", "experiments DB")
-	flag.StringVar(&opendataDir, "opendate-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3", "The directory of open data tables.")
-	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "/home/ekzhu/FB_WORD_VEC/fasttext.db",
-		"Sqlite database file for fastText vecs")
+	flag.StringVar(&experimentsDB, "experiments-db", "", "experiments DB, benchmark-v3/emb-experiments.sqlite in the output dir of the repository if empty")
+	flag.StringVar(&opendataDir, "opendate-dir", "", "The directory of open data tables, benchmark-v3 in the output dir of the repository if empty")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "",
+		"Sqlite database file for fastText vecs, the fastText database of the repository if empty")
 	flag.IntVar(&fanout, "fanout", 8, "Number threads querying the server in parallel.")
 	flag.StringVar(&experimentType, "type", "fixedn", "The type of experiments: fixed k or fixed n.")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile, "query_list")
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "benchmark-v3/domains")
+	}
+	if queryDir == "" {
+		queryDir = path.Join(od.OutputDir, "benchmark-v3/csvfiles")
+	}
+	if experimentsDB == "" {
+		experimentsDB = path.Join(od.OutputDir, "benchmark-v3/emb-experiments.sqlite")
+	}
+	if opendataDir == "" {
+		opendataDir = path.Join(od.OutputDir, "benchmark-v3")
+	}
+	if fastTextSqliteDB == "" {
+		fastTextSqliteDB = od.FastTextDB
+	}
 	// Create client
 	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
 		panic("FastText Sqlite DB does not exist")
 	}
 	ft := fasttext.NewFastText(fastTextSqliteDB)
 
-	client, err := benchmarkserver.NewClient(ft, host)
+	client, err := benchmarkserver.NewClient(od, ft, host)
 	if err != nil {
 		panic(err)
 	}
-	queries := opendata.StreamQueryFilenames()
+	queries := od.StreamQueryFilenames()
 	//
 	log.Printf("start")
 	alignments := make(chan benchmarkserver.Union, 100)
@@ -2,8 +2,10 @@ package main
 
 import (
 	"flag"
+	"path"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/RJMillerLab/table-union/simhashlsh"
 )
 
@@ -16,14 +18,20 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, benchmark-v3/domains in the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4074", "Server port")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
 	flag.Float64Var(&threshold, "t", 0.7, "Search Parameter: k-unionability threshold")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "benchmark-v3/domains")
+	}
 	// Build Search Index
-	ui := benchmarkserver.NewUnionIndex(domainDir, simhashlsh.NewCosineLSH(FastTextDim, numHash, threshold))
+	ui := benchmarkserver.NewUnionIndex(od, domainDir, simhashlsh.NewCosineLSH(FastTextDim, numHash, threshold))
 	if err := ui.Build(); err != nil {
 		panic(err)
 	}
@@ -14,15 +14,21 @@ func main() {
 	var queryCSVFilename string
 	var k int
 	var fastTextSqliteDB string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
-	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "/home/ekzhu/FB_WORD_VEC/fasttext.db",
-		"Sqlite database file for fastText vecs")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, required")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "",
+		"Sqlite database file for fastText vecs, required")
 	flag.StringVar(&queryCSVFilename, "query", "",
 		"Query CSV file")
 	flag.StringVar(&host, "host", "http://localhost:4003", "Server host")
 	flag.IntVar(&k, "k", 5, "Top-K")
 	flag.Parse()
+	if domainDir == "" {
+		panic("Domain dir is required")
+	}
+	if fastTextSqliteDB == "" {
+		panic("FastText Sqlite DB is required")
+	}
 
 	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
 		panic("FastText Sqlite DB does not exist")
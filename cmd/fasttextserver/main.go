@@ -11,14 +11,23 @@ func main() {
 	var domainDir string
 	var port string
 	var l, m int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var storeVectors bool
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, required")
 	flag.StringVar(&port, "port", "4003", "Server port")
 	flag.IntVar(&l, "l", 5, "LSH Parameter: number of bands or hash tables")
 	flag.IntVar(&m, "m", 20, "LSH Parameter: size of each band or hash key")
+	flag.BoolVar(&storeVectors, "store-vectors", false, "Keep the embeddings in the LSH index to rank candidates without reading them from disk")
 	flag.Parse()
+	if domainDir == "" {
+		panic("Domain dir is required")
+	}
 	// Build Search Index
-	si := embserver.NewSearchIndex(domainDir, embserver.NewCosineLsh(fasttext.Dim, l, m))
+	lsh := embserver.NewCosineLsh(fasttext.Dim, l, m)
+	if storeVectors {
+		lsh.StoreVectors()
+	}
+	si := embserver.NewSearchIndex(domainDir, lsh)
 	if err := si.Build(); err != nil {
 		panic(err)
 	}
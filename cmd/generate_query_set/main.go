@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
@@ -15,21 +16,24 @@ import (
 )
 
 func main() {
-	getStrantifiedSamples(1000)
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	getStrantifiedSamples(od, 1000)
 }
-func getRandomSamples() {
-	embTables := getEmbTables()
+func getRandomSamples(od *Repository) {
+	embTables := getEmbTables(od)
 	log.Printf("embTableS: %d", len(embTables))
 	queryTables := make([]string, 0)
 	eNum := 0
 	oNum := 0
-	for filename := range StreamFilenames() {
+	for filename := range od.StreamFilenames() {
 		if len(queryTables) == 3000 {
 			break
 		}
-		textDomains := getTextDomains(filename)
+		textDomains := getTextDomains(od, filename)
 		for _, index := range textDomains {
-			filepath := path.Join(OutputDir, "domains", filename, fmt.Sprintf("%d.entities", index))
+			filepath := path.Join(od.OutputDir, "domains", filename, fmt.Sprintf("%d.entities", index))
 			_, err := os.Open(filepath)
 			if err == nil {
 				queryTables = append(queryTables, filename)
@@ -40,7 +44,7 @@ func getRandomSamples() {
 		//}
 	}
 
-	fout, err := os.OpenFile("/home/fnargesian/TABLE_UNION_OUTPUT/stratified.queries.shuffle", os.O_CREATE|os.O_WRONLY, 0644)
+	fout, err := os.OpenFile(path.Join(od.OutputDir, "stratified.queries.shuffle"), os.O_CREATE|os.O_WRONLY, 0644)
 	defer fout.Close()
 
 	if err != nil {
@@ -54,13 +58,13 @@ func getRandomSamples() {
 	log.Printf("Total number of queries: %d", len(queryTables))
 }
 
-func getStrantifiedSamples(sampleSize int) {
+func getStrantifiedSamples(od *Repository, sampleSize int) {
 	ontTables := make(map[string]bool)
 	embTables := make(map[string]bool)
 	valTables := make(map[string]bool)
 	ontEmbTables := make(map[string]bool)
-	for filename := range StreamFilenames() {
-		textDomains := getTextDomains(filename)
+	for filename := range od.StreamFilenames() {
+		textDomains := getTextDomains(od, filename)
 		if len(textDomains) < 3 { // len(textDomains) > 30 {
 			if _, ok := valTables[filename]; !ok {
 				valTables[filename] = true
@@ -68,11 +72,11 @@ func getStrantifiedSamples(sampleSize int) {
 			continue
 		}
 		for _, index := range textDomains {
-			filepath := path.Join(OutputDir, "domains", filename, fmt.Sprintf("%d.ont-minhash-l1", index))
+			filepath := path.Join(od.OutputDir, "domains", filename, fmt.Sprintf("%d.ont-minhash-l1", index))
 			f, err1 := os.Open(filepath)
 			f.Close()
 			if err1 == nil {
-				filepath := path.Join(OutputDir, "domains", filename, fmt.Sprintf("%d.ft-mean", index))
+				filepath := path.Join(od.OutputDir, "domains", filename, fmt.Sprintf("%d.ft-mean", index))
 				f, err := os.Open(filepath)
 				f.Close()
 				if err == nil {
@@ -87,7 +91,7 @@ func getStrantifiedSamples(sampleSize int) {
 					continue
 				}
 			} else {
-				filepath := path.Join(OutputDir, "domains", filename, fmt.Sprintf("%d.ft-mean", index))
+				filepath := path.Join(od.OutputDir, "domains", filename, fmt.Sprintf("%d.ft-mean", index))
 				f, err := os.Open(filepath)
 				f.Close()
 				if err == nil {
@@ -104,7 +108,7 @@ func getStrantifiedSamples(sampleSize int) {
 	ontSamples := make([]string, 0)
 	embSamples := make([]string, 0)
 	ontEmbSamples := make([]string, 0)
-	fout, err := os.OpenFile("/home/fnargesian/TABLE_UNION_OUTPUT/stratified.queries.shuffle", os.O_CREATE|os.O_WRONLY, 0644)
+	fout, err := os.OpenFile(path.Join(od.OutputDir, "stratified.queries.shuffle"), os.O_CREATE|os.O_WRONLY, 0644)
 	defer fout.Close()
 	if err != nil {
 		panic(err)
@@ -146,7 +150,7 @@ func getStrantifiedSamples(sampleSize int) {
 			continue
 		}
 		if lineNum != sampleSize {
-			filepath := path.Join(OpendataDir, k)
+			filepath := path.Join(od.OpendataDir, k)
 			f, err := os.Open(filepath)
 			f.Close()
 			if err == nil {
@@ -163,7 +167,7 @@ func getStrantifiedSamples(sampleSize int) {
 			continue
 		}
 		if lineNum != sampleSize {
-			filepath := path.Join(OpendataDir, k)
+			filepath := path.Join(od.OpendataDir, k)
 			f, err := os.Open(filepath)
 			f.Close()
 			if err == nil {
@@ -180,7 +184,7 @@ func getStrantifiedSamples(sampleSize int) {
 			continue
 		}
 		if lineNum != sampleSize {
-			filepath := path.Join(OpendataDir, k)
+			filepath := path.Join(od.OpendataDir, k)
 			f, err := os.Open(filepath)
 			f.Close()
 			if err == nil {
@@ -197,8 +201,8 @@ func getStrantifiedSamples(sampleSize int) {
 	log.Printf("Num of ont samples: %d", len(ontSamples))
 	log.Printf("Num of ont and emb samples: %d", len(ontEmbSamples))
 }
-func getEmbTables() map[string]bool {
-	db, err := sql.Open("sqlite3", "/home/fnargesian/TABLE_UNION_OUTPUT/cod_fasttext_matches.db")
+func getEmbTables(od *Repository) map[string]bool {
+	db, err := sql.Open("sqlite3", path.Join(od.OutputDir, "cod_fasttext_matches.db"))
 	if err != nil {
 		panic(err)
 	}
@@ -222,8 +226,8 @@ func getEmbTables() map[string]bool {
 	return embTables
 }
 
-func getTextDomains(file string) (indices []int) {
-	typesFile := path.Join(OutputDir, "domains", file, "types")
+func getTextDomains(od *Repository, file string) (indices []int) {
+	typesFile := path.Join(od.OutputDir, "domains", file, "types")
 	f, err := os.Open(typesFile)
 	defer f.Close()
 	if err != nil {
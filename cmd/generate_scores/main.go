@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 
@@ -8,18 +9,20 @@ import (
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	start := GetNow()
 	total := ProgressCounter{}
 	// load ontology
 	//Init()
-	queries := GetQueryFilenames()
+	queries := od.GetQueryFilenames()
 	log.Printf("number of queries: %d", len(queries))
-	filenames := GetCODFilenames()
+	filenames := od.GetCODFilenames()
 	log.Printf("number of CODs: %d", len(filenames))
 	//scores := DoComputeUnionability(queries, filenames, 40)
 	//progress := DoSaveScores(scores, 1)
-	progress := ComputeAndSaveUnionability(queries, filenames, 45)
+	progress := od.ComputeAndSaveUnionability(queries, filenames, 45)
 	for n := range progress {
 		total.Values += n.Values
 		now := GetNow()
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	. "github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
-	CheckEnv()
-	ComputeTableStitchingUnionability()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
+	od.ComputeTableStitchingUnionability()
 	log.Printf("Done generating alignemnts for stitching correspondences.")
 }
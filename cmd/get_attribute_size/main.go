@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -10,15 +11,17 @@ import (
 )
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 
 	start := GetNow()
-	filenames := StreamFilenames()
-	domains := StreamDomainValuesFromFiles(20, filenames)
+	filenames := od.StreamFilenames()
+	domains := od.StreamDomainValuesFromFiles(20, filenames)
 	segCount := 0
 	totalValueCount := 0
 	for domain := range domains {
-		sizeFilename := domain.PhysicalFilename("size")
+		sizeFilename := od.DomainFilename(domain.Filename, domain.Index, "size")
 		f, err := os.OpenFile(sizeFilename, os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
 			panic(err)
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/RJMillerLab/table-union/embedding"
+	. "github.com/RJMillerLab/table-union/opendata"
+	"github.com/RJMillerLab/table-union/yago"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func unique(values []string) []string {
+	set := make(map[string]bool)
+	var array []string
+	for _, v := range values {
+		if !set[v] {
+			array = append(array, v)
+		}
+		set[v] = true
+	}
+	return array
+}
+
+func writeLines(filename string, lines ...interface{}) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Saves the YAGO entities matching the values of the text domains of a
+// table, the values without entity and the cardinalities of the domains,
+// like build_ontology_domains.
+func matchTableEntities(od *Repository, yg *yago.Yago, table string) error {
+	for _, domain := range od.TextDomainWords(table) {
+		notAnnotated := make([]interface{}, 0)
+		entities := make(map[string]bool)
+		uniqueValues := unique(domain.Values)
+		for _, value := range uniqueValues {
+			found := yg.MatchEntity(value, 3)
+			if len(found) == 0 {
+				notAnnotated = append(notAnnotated, value)
+			}
+			for _, entity := range found {
+				entities[entity] = true
+			}
+		}
+		if len(notAnnotated) > 0 {
+			if err := writeLines(od.DomainFilename(domain.Filename, domain.Index, "no-annotation"), notAnnotated...); err != nil {
+				return err
+			}
+		}
+		if len(entities) > 0 {
+			lines := make([]interface{}, 0, len(entities))
+			for entity := range entities {
+				lines = append(lines, entity)
+			}
+			if err := writeLines(od.DomainFilename(domain.Filename, domain.Index, "entities-l0"), lines...); err != nil {
+				return err
+			}
+		}
+		err := writeLines(od.DomainFilename(domain.Filename, domain.Index, "ont-noann-card"), len(notAnnotated), len(entities), len(uniqueValues))
+		if err != nil {
+			return err
+		}
+		if err := writeLines(od.DomainFilename(domain.Filename, domain.Index, "card"), len(uniqueValues)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs the pipeline of build_domain_values, classify_domain_values, the
+// sketch step of each measure that can sketch columns, named by the
+// extension of its sketch files, build_ontology_domains, annotate_domains
+// and build_ontology_minhash for each table of the opendata_list of the
+// configuration. The sketches of the ontology measures are saved by the
+// last three stages, which also save the annotations of the tables. The completed stages of a table are saved in its manifest
+// and skipped when the ingestion is run again.
+// mk_opendata_stats is not a stage: it profiles the datasets of the portal
+// catalog databases, not the tables of the list, and is run once per
+// repository.
+func main() {
+	var fanout int
+	var fasttextDB string
+	var yagoDB string
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.IntVar(&fanout, "fanout", 10, "The number of tables ingested in parallel")
+	flag.StringVar(&fasttextDB, "fasttext-db", "", "The fastText database of the nl and header sketch stages, fasttext_db of the configuration by default. The stages are left out if neither is set")
+	flag.StringVar(&yagoDB, "yago-db", "", "The YAGO database of the entities stage, yago_db of the configuration by default. The stage is left out with the ontology stages if neither is set")
+	flag.Parse()
+	od := OpenRepository(configFile, "opendata_dir", "opendata_list", "output_dir")
+	if fasttextDB == "" {
+		fasttextDB = od.FastTextDB
+	}
+	if yagoDB == "" {
+		yagoDB = od.YagoDB
+	}
+	start := GetNow()
+
+	stages := []Stage{
+		{Name: "values", Run: od.SaveTableDomains},
+		{Name: "types", After: []string{"values"}, Run: od.ClassifyTableDomains},
+	}
+	if fasttextDB != "" {
+		ft, err := embedding.InitInMemoryFastText(fasttextDB, func(v string) []string {
+			return strings.Split(v, " ")
+		}, func(v string) string {
+			return strings.ToLower(strings.TrimFunc(strings.TrimSpace(v), unicode.IsPunct))
+		})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("fasttext.db loaded in %.2f seconds.\n", GetNow()-start)
+		od.SetFastText(ft)
+	}
+	sketched := make(map[string]bool)
+	for _, m := range od.Measures() {
+		if sketched[m.Extension()] || !od.CanSketch(m) {
+			continue
+		}
+		sketched[m.Extension()] = true
+		m := m
+		stages = append(stages, Stage{
+			Name:  m.Extension(),
+			After: []string{"types"},
+			Run: func(table string) error {
+				return od.SketchTableDomains(table, m)
+			},
+		})
+	}
+	if yagoDB != "" {
+		if od.AnnotationDB == "" || od.AllAnnotationTable == "" {
+			panic("annotation_db and all_annotation_table are required by the ontology stages")
+		}
+		yg := yago.InitYago(yagoDB)
+		// each goroutine must use its own copy
+		copies := make(chan *yago.Yago, fanout)
+		for i := 0; i < fanout; i++ {
+			copies <- yg.Copy()
+		}
+		od.ResumeAnnotator()
+		stages = append(stages, Stage{
+			Name:  "entities",
+			After: []string{"types"},
+			Run: func(table string) error {
+				yg := <-copies
+				defer func() { copies <- yg }()
+				return matchTableEntities(od, yg, table)
+			},
+		}, Stage{
+			Name:  "annotations",
+			After: []string{"entities"},
+			Run: func(table string) error {
+				return od.AnnotateTableDomains(table, "entities-l0")
+			},
+		}, Stage{
+			Name:  "ont-minhash",
+			After: []string{"annotations"},
+			Run:   od.OntologyMinhashTableDomains,
+		})
+	}
+	ing, err := NewIngester(od, stages)
+	if err != nil {
+		panic(err)
+	}
+
+	results := ing.Run(fanout, od.StreamFilenames())
+	var numTables, numRan, numSkipped int
+	failed := make([]string, 0)
+	for result := range results {
+		numTables += 1
+		numRan += len(result.Ran)
+		numSkipped += len(result.Skipped)
+		if len(result.Failed) > 0 {
+			failed = append(failed, result.Table)
+			for stage, err := range result.Failed {
+				log.Printf("Stage %s of %s failed: %s", stage, result.Table, err.Error())
+			}
+			if len(result.Blocked) > 0 {
+				log.Printf("Stages %s of %s are left out", strings.Join(result.Blocked, ", "), result.Table)
+			}
+		}
+		if numTables%100 == 0 {
+			fmt.Printf("Ingested %d tables in %.2f seconds\n", numTables, GetNow()-start)
+		}
+	}
+	fmt.Printf("Ingested %d tables in %.2f seconds: %d stages run, %d stages completed earlier, %d tables failed\n", numTables, GetNow()-start, numRan, numSkipped, len(failed))
+	sort.Strings(failed)
+	for _, table := range failed {
+		fmt.Println(table)
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"path"
 	"sync"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
@@ -23,28 +24,42 @@ func main() {
 	var fanout int
 	var opendataDir string
 	var experimentType string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
-	flag.StringVar(&queryDir, "query-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only",
-		"The directory of query files")
+	flag.StringVar(&queryDir, "query-dir", "",
+		"The directory of query files, the opendata dir of the repository if empty")
 	flag.Float64Var(&threshold, "t", 0.9, "Search Parameter: k-unionability threshold")
 	flag.IntVar(&k, "k", 3, "Search Parameter: top (n,k) unionable tables")
 	flag.IntVar(&n, "n", 10, "Search Parameter: top (n,k) unionable tables")
 	flag.StringVar(&host, "host", "http://localhost:4008", "Server host")
 	flag.StringVar(&port, "port", "4005", "Server port")
-	//flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/jaccard-experiments-fixedn.sqlite", "experiments DB")
-	flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/jaccard-experiments.sqlite", "experiments DB")
-	flag.StringVar(&opendataDir, "opendate-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only", "The    directory of open data tables.")
+	flag.StringVar(&experimentsDB, "experiments-db", "", "experiments DB, jaccard-experiments.sqlite in the output dir of the repository if empty")
+	flag.StringVar(&opendataDir, "opendate-dir", "", "The directory of open data tables, the opendata dir of the repository if empty")
 	flag.IntVar(&fanout, "fanout", 1, "Number threads querying the server in parallel.")
 	flag.StringVar(&experimentType, "type", "fixedn", "The type of experiments: fixed k or fixed n.")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile, "query_list")
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
+	if queryDir == "" {
+		queryDir = od.OpendataDir
+	}
+	if experimentsDB == "" {
+		experimentsDB = path.Join(od.OutputDir, "jaccard-experiments.sqlite")
+	}
+	if opendataDir == "" {
+		opendataDir = od.OpendataDir
+	}
 	// Create client
-	client, err := benchmarkserver.NewJaccardClient(host, numHash)
+	client, err := benchmarkserver.NewJaccardClient(od, host, numHash)
 	if err != nil {
 		panic(err)
 	}
-	queries := opendata.StreamQueryFilenames()
+	queries := od.StreamQueryFilenames()
 	//
 	alignments := make(chan benchmarkserver.Union, 100)
 	wg := &sync.WaitGroup{}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"path"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
 	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
@@ -12,14 +14,20 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark-v3/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, benchmark-v3/domains in the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4026", "Server port")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
 	flag.Float64Var(&threshold, "t", 0.7, "Search Parameter: k-unionability threshold")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "benchmark-v3/domains")
+	}
 	// Build Search Index
-	ui := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
+	ui := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
 	if err := ui.Build(); err != nil {
 		panic(err)
 	}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"flag"
+	"path"
 
 	minhashlsh "github.com/RJMillerLab/table-union/minhash-lsh"
+	"github.com/RJMillerLab/table-union/opendata"
 	"github.com/RJMillerLab/table-union/unionserver"
 )
 
@@ -12,14 +14,20 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4003", "Server port")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
 	flag.Float64Var(&threshold, "t", 0.8, "Search Parameter: k-unionability threshold")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
 	// Build Search Index
-	ui := unionserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
+	ui := unionserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
 	if err := ui.Build(); err != nil {
 		panic(err)
 	}
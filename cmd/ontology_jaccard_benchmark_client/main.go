@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"path"
 	"sync"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
@@ -23,27 +24,42 @@ func main() {
 	var fanout int
 	var opendataDir string
 	var experimentType string
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
-	flag.StringVar(&queryDir, "query-dir", "/home/ekzhu/OPENDATA/resource-2016-12-15-csv-only",
-		"The directory of query files")
+	flag.StringVar(&queryDir, "query-dir", "",
+		"The directory of query files, the opendata dir of the repository if empty")
 	flag.Float64Var(&threshold, "t", 0.5, "Search Parameter: k-unionability threshold")
 	flag.IntVar(&k, "k", 3, "Search Parameter: top (n,k) unionable tables")
 	flag.IntVar(&n, "n", 10, "Search Parameter: top (n,k) unionable tables")
 	flag.StringVar(&host, "host", "http://localhost:4007", "Server host")
 	flag.StringVar(&port, "port", "4007", "Server port")
-	flag.StringVar(&experimentsDB, "experiments-db", "/home/fnargesian/TABLE_UNION_OUTPUT/ont-jaccard-experiments.sqlite", "experiments DB")
-	flag.StringVar(&opendataDir, "opendate-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/benchmark", "The        directory of open data tables.")
+	flag.StringVar(&experimentsDB, "experiments-db", "", "experiments DB, ont-jaccard-experiments.sqlite in the output dir of the repository if empty")
+	flag.StringVar(&opendataDir, "opendate-dir", "", "The     directory of open data tables, benchmark in the output dir of the repository if empty")
 	flag.IntVar(&fanout, "fanout", 6, "Number threads querying the server in parallel.")
 	flag.StringVar(&experimentType, "type", "fixedn", "The type of experiments: fixed k or fixed n.")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile, "query_list")
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
+	if queryDir == "" {
+		queryDir = od.OpendataDir
+	}
+	if experimentsDB == "" {
+		experimentsDB = path.Join(od.OutputDir, "ont-jaccard-experiments.sqlite")
+	}
+	if opendataDir == "" {
+		opendataDir = path.Join(od.OutputDir, "benchmark")
+	}
 	// Create client
-	client, err := benchmarkserver.NewOntologyJaccardClient(host, numHash)
+	client, err := benchmarkserver.NewOntologyJaccardClient(od, host, numHash)
 	if err != nil {
 		panic(err)
 	}
-	queries := opendata.StreamQueryFilenames()
+	queries := od.StreamQueryFilenames()
 	//
 	alignments := make(chan benchmarkserver.Union, 100)
 	wg := &sync.WaitGroup{}
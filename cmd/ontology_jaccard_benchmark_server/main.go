@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"path"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
 	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
+	"github.com/RJMillerLab/table-union/opendata"
 )
 
 func main() {
@@ -12,19 +14,28 @@ func main() {
 	var port string
 	var threshold float64
 	var numHash int
-	flag.StringVar(&domainDir, "domain-dir", "/home/fnargesian/TABLE_UNION_OUTPUT/domains",
-		"The top-level director for all domain and embedding files")
+	var snapshotDir string
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, domains in the output dir of the repository if empty")
 	flag.StringVar(&port, "port", "4007", "Server port")
 	flag.IntVar(&numHash, "h", 256, "LSH Parameter: number of hash functions")
 	flag.Float64Var(&threshold, "t", 0.6, "Search Parameter: k-unionability threshold")
+	flag.StringVar(&snapshotDir, "index-snapshot", "", "The directory of the LSH index snapshots, loaded if present and saved otherwise")
 	flag.Parse()
+	od := opendata.OpenRepository(configFile)
+	if domainDir == "" {
+		domainDir = path.Join(od.OutputDir, "domains")
+	}
 	// Build Search Index
-	ui := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
-	if err := ui.NoOntBuild(); err != nil {
+	params := benchmarkserver.SnapshotParams{DomainDir: domainDir, Threshold: threshold, NumHash: numHash}
+	ui := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
+	if err := benchmarkserver.BuildWithSnapshot(ui, ui.NoOntBuild, snapshotDir, "semset", params); err != nil {
 		panic(err)
 	}
-	oi := benchmarkserver.NewJaccardUnionIndex(domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
-	if err := oi.OntBuild(); err != nil {
+	oi := benchmarkserver.NewJaccardUnionIndex(od, domainDir, minhashlsh.NewMinhashLSH32(numHash, threshold), numHash)
+	if err := benchmarkserver.BuildWithSnapshot(oi, oi.OntBuild, snapshotDir, "sem", params); err != nil {
 		panic(err)
 	}
 	// Start server
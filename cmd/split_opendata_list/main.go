@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	. "github.com/RJMillerLab/table-union/opendata"
+)
+
+// Splits the list of tables in OPENDATA_LIST into one list per shard. The
+// resources of a dataset go to the same shard, so that the shards can group
+// results by dataset. A shard server is started with OPENDATA_LIST set to
+// its list.
+func main() {
+	var numShards int
+	var output string
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.IntVar(&numShards, "shards", 2, "The number of shards")
+	flag.StringVar(&output, "output", "", "The prefix of the lists of the shards, followed by .0, .1, ..., the list of the repository by default")
+	flag.Parse()
+	od := OpenRepository(configFile, "opendata_list")
+	if output == "" {
+		output = od.OpendataList
+	}
+	f, err := os.Open(od.OpendataList)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	writers := make([]*bufio.Writer, numShards)
+	for i := range writers {
+		out, err := os.Create(fmt.Sprintf("%s.%d", output, i))
+		if err != nil {
+			panic(err)
+		}
+		defer out.Close()
+		writers[i] = bufio.NewWriter(out)
+		defer writers[i].Flush()
+	}
+	counts := make([]int, numShards)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		dataset := line
+		if i := strings.LastIndex(line, "____"); i != -1 {
+			dataset = line[:i]
+		}
+		h := fnv.New32a()
+		h.Write([]byte(dataset))
+		shard := int(h.Sum32() % uint32(numShards))
+		fmt.Fprintln(writers[shard], line)
+		counts[shard] += 1
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	for i, count := range counts {
+		fmt.Printf("shard %d: %d tables\n", i, count)
+	}
+}
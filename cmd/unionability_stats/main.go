@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	. "github.com/RJMillerLab/table-union/opendata"
@@ -12,11 +13,13 @@ type pair struct {
 }
 
 func main() {
-	CheckEnv()
+	configFile := flag.String("config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.Parse()
+	od := OpenRepository(*configFile)
 	//ComputeTableUnionabilityVariousC()
 	//ComputeAttUnionabilityCDF(100)
 	//ComputeAllAttUnionabilityCDF(5000)
-	ComputeTableUnionabilityCDF(500)
+	od.ComputeTableUnionabilityCDF(500)
 	//SavePercentileAttUnionability()
 	/*
 		tstart := GetNow()
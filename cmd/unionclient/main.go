@@ -9,14 +9,16 @@ import (
 )
 
 func main() {
-	//var domainDir string
+	var domainDir string
 	var host string
 	var queryCSVFilename string
 	var n, k int
 	var fastTextSqliteDB string
 	var resultDir string
-	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "/home/ekzhu/FB_WORD_VEC/fasttext.db",
-		"Sqlite database file for fastText vecs")
+	flag.StringVar(&domainDir, "domain-dir", "",
+		"The top-level director for all domain and embedding files, required")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "",
+		"Sqlite database file for fastText vecs, required")
 	flag.StringVar(&queryCSVFilename, "query", "",
 		"Query CSV file")
 	flag.StringVar(&resultDir, "result-dir", "",
@@ -25,13 +27,19 @@ func main() {
 	flag.IntVar(&n, "n", 10, "Search Parameter: top (n,k) unionable tables")
 	flag.IntVar(&k, "k", 5, "Search Parameter: top (n,k) unionable tables")
 	flag.Parse()
+	if domainDir == "" {
+		panic("Domain dir is required")
+	}
+	if fastTextSqliteDB == "" {
+		panic("FastText Sqlite DB is required")
+	}
 
 	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
 		panic("FastText Sqlite DB does not exist")
 	}
 	ft := fasttext.NewFastText(fastTextSqliteDB)
 
-	client, err := unionserver.NewClient(ft, host)
+	client, err := unionserver.NewClient(ft, host, domainDir)
 	if err != nil {
 		panic(err)
 	}
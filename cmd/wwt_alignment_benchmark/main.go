@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/opendata"
+	"github.com/RJMillerLab/table-union/wwt"
+	fasttext "github.com/ekzhu/go-fasttext"
+)
+
+// Compares the greedy, optimal and optimal-log column alignments on the WWT
+// ground truth. The columns are scored by the nl measure, the cosine of
+// their embeddings, and aligned with the percentiles of the nl CDF as in the
+// combined search. Two columns are unionable if they share an annotation,
+// and two tables are compared if they have a pair of unionable columns.
+func main() {
+	var wwtDir string
+	var fastTextSqliteDB string
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "The JSON configuration file of the repository, the environment variables are read if empty")
+	flag.StringVar(&wwtDir, "wwtdir", "", "The top directory of the WWT benchmark xml files")
+	flag.StringVar(&fastTextSqliteDB, "fasttext-db", "", "Sqlite database file for fastText vecs")
+	flag.Parse()
+	if wwtDir == "" || fastTextSqliteDB == "" {
+		panic("-wwtdir and -fasttext-db are required")
+	}
+	od := opendata.OpenRepository(configFile, "att_stats_db", "table_stats_db")
+	if _, err := os.Stat(fastTextSqliteDB); os.IsNotExist(err) {
+		panic("FastText Sqlite DB does not exist")
+	}
+	ft := fasttext.NewFastText(fastTextSqliteDB)
+	w := wwt.NewWWT(wwtDir, ft)
+
+	tables := make(map[string][]*wwt.WWTColumn)
+	vecs := make(map[string][]float64)
+	for column := range w.ReadColumns() {
+		tables[column.TableID] = append(tables[column.TableID], column)
+		vecs[columnKey(column.TableID, column.ColumnIndex)] = column.Vec
+	}
+	tableIDs := make([]string, 0, len(tables))
+	for tableID := range tables {
+		tableIDs = append(tableIDs, tableID)
+	}
+	sort.Strings(tableIDs)
+	log.Printf("Read %d tables", len(tableIDs))
+
+	// the alignment reads the text columns of the tables from a domain dir
+	domainDir, err := ioutil.TempDir("", "wwt-domains")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(domainDir)
+	for tableID, columns := range tables {
+		if err := writeTypes(domainDir, tableID, columns); err != nil {
+			panic(err)
+		}
+	}
+	nl := opendata.NewMeasure("nl", "text", "", "", nil, func(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+		q, ok1 := vecs[columnKey(queryTable, queryIndex)]
+		c, ok2 := vecs[columnKey(candidateTable, candIndex)]
+		if !ok1 || !ok2 {
+			return -1.0
+		}
+		return math.Abs(embedding.Cosine(q, c))
+	})
+	measures := []opendata.UnionabilityMeasure{nl}
+	attCDFs, tableCDF := od.LoadCDF()
+
+	strategies := []string{benchmarkserver.GreedyAlignment, benchmarkserver.OptimalAlignment, benchmarkserver.OptimalLogAlignment}
+	correct := make(map[string]int)
+	aligned := make(map[string]int)
+	sumPercentile := make(map[string]float64)
+	sumLogPercentile := make(map[string]float64)
+	sumBestC := make(map[string]int)
+	sumCPercentile := make(map[string]float64)
+	var expected, tablePairs int
+	for i, queryTable := range tableIDs {
+		for _, candTable := range tableIDs[i+1:] {
+			truth := make([]benchmarkserver.Pair, 0)
+			unionable := make(map[[2]int]bool)
+			for _, q := range tables[queryTable] {
+				for _, c := range tables[candTable] {
+					if shareAnnotation(q, c) {
+						unionable[[2]int{q.ColumnIndex, c.ColumnIndex}] = true
+						truth = append(truth, benchmarkserver.Pair{
+							CandTableID:   candTable,
+							QueryColIndex: q.ColumnIndex,
+							CandColIndex:  c.ColumnIndex,
+							Percentile:    opendata.Percentile{Value: 1.0, ValuePlus: 1.0, ValueMinus: 1.0},
+						})
+					}
+				}
+			}
+			if len(truth) == 0 {
+				continue
+			}
+			tablePairs += 1
+			// the most unionable columns that can be aligned one-to-one
+			expected += len(benchmarkserver.AlignPairs(truth, benchmarkserver.OptimalAlignment))
+			for _, strategy := range strategies {
+				result := benchmarkserver.AlignTables(context.Background(), queryTable, candTable, domainDir, measures, attCDFs, tableCDF, strategy)
+				for _, p := range result.Alignment {
+					aligned[strategy] += 1
+					sumPercentile[strategy] += p.Percentile.Value
+					sumLogPercentile[strategy] += math.Log(p.Percentile.Value)
+					if unionable[[2]int{p.QueryColIndex, p.CandColIndex}] {
+						correct[strategy] += 1
+					}
+				}
+				if result.BestC > 0 {
+					sumBestC[strategy] += result.BestC
+					sumCPercentile[strategy] += result.CUnionabilityPercentiles[result.BestC-1].Value
+				}
+			}
+		}
+	}
+	fmt.Printf("%d unionable table pairs, %d unionable column alignments\n", tablePairs, expected)
+	fmt.Println("strategy, aligned, correct, precision, recall, sum of percentiles, sum of log percentiles, mean best c, mean c-unionability percentile")
+	for _, strategy := range strategies {
+		precision := float64(correct[strategy]) / float64(aligned[strategy])
+		recall := float64(correct[strategy]) / float64(expected)
+		meanBestC := float64(sumBestC[strategy]) / float64(tablePairs)
+		meanCPercentile := sumCPercentile[strategy] / float64(tablePairs)
+		fmt.Printf("%s, %d, %d, %.4f, %.4f, %.4f, %.4f, %.4f, %.4f\n", strategy, aligned[strategy], correct[strategy], precision, recall, sumPercentile[strategy], sumLogPercentile[strategy], meanBestC, meanCPercentile)
+	}
+}
+
+func columnKey(tableID string, index int) string {
+	return fmt.Sprintf("%s:%d", tableID, index)
+}
+
+// Writes the types file of a table listing its annotated columns as text.
+func writeTypes(domainDir, tableID string, columns []*wwt.WWTColumn) error {
+	if err := os.MkdirAll(path.Join(domainDir, tableID), 0755); err != nil {
+		return err
+	}
+	lines := make([]string, len(columns))
+	for i, column := range columns {
+		lines[i] = fmt.Sprintf("%d text\n", column.ColumnIndex)
+	}
+	return ioutil.WriteFile(path.Join(domainDir, tableID, "types"), []byte(strings.Join(lines, "")), 0644)
+}
+
+func shareAnnotation(q, c *wwt.WWTColumn) bool {
+	for _, a := range q.Annotations {
+		for _, b := range c.Annotations {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/RJMillerLab/table-union/benchmark"
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/simhashlsh"
+)
+
+type scoredLabel struct {
+	cosine float64
+	label  int
+}
+
+type column struct {
+	vec    []float64
+	scores []scoredLabel
+}
+
+// Trains the model that predicts the cosine LSH threshold of a query column
+// from its embedding, on the column pairs of the WWT benchmark written by
+// wwtbenchmarkgen. The target of a column is the cosine threshold that best
+// separates its unionable pairs from the others.
+func main() {
+	var benchmarkSqliteDB string
+	var output string
+	var lambda float64
+	var holdout float64
+	flag.StringVar(&benchmarkSqliteDB, "bench-db", "", "The SqliteDB file of the sample pairs")
+	flag.StringVar(&output, "output", "", "The output model file")
+	flag.Float64Var(&lambda, "lambda", 1.0, "The L2 penalty of the ridge regression")
+	flag.Float64Var(&holdout, "holdout", 0.2, "The fraction of columns held out to report the error of the model")
+	flag.Parse()
+	if benchmarkSqliteDB == "" || output == "" {
+		panic("Missing benchmark DB or output destination")
+	}
+	columns := make(map[string]*column)
+	add := func(id string, vec []float64, s scoredLabel) {
+		if _, exists := columns[id]; !exists {
+			columns[id] = &column{vec: vec}
+		}
+		columns[id].scores = append(columns[id].scores, s)
+	}
+	pairs := benchmark.ReadFromDB(benchmarkSqliteDB)
+	for _, p := range pairs {
+		if p.Vec1 == nil || p.Vec2 == nil {
+			continue
+		}
+		id1, id2 := p.ColumnIDs()
+		s := scoredLabel{cosine: embedding.Cosine(p.Vec1, p.Vec2), label: p.Label}
+		add(id1, p.Vec1, s)
+		add(id2, p.Vec2, s)
+	}
+	log.Printf("Read %d pairs of %d columns", len(pairs), len(columns))
+	ids := make([]string, 0, len(columns))
+	for id := range columns {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	points := make([][]float64, 0)
+	thresholds := make([]float64, 0)
+	for _, id := range ids {
+		if t, ok := bestThreshold(columns[id].scores); ok {
+			points = append(points, columns[id].vec)
+			thresholds = append(thresholds, t)
+		}
+	}
+	log.Printf("Found the thresholds of %d columns with unionable pairs", len(points))
+	// report the error on held out columns before fitting all of them
+	perm := rand.New(rand.NewSource(1)).Perm(len(points))
+	numTest := int(holdout * float64(len(points)))
+	if numTest > 0 && numTest < len(points) {
+		trainPoints, trainThresholds := make([][]float64, 0), make([]float64, 0)
+		for _, i := range perm[numTest:] {
+			trainPoints = append(trainPoints, points[i])
+			trainThresholds = append(trainThresholds, thresholds[i])
+		}
+		m, err := simhashlsh.FitThresholdModel(trainPoints, trainThresholds, lambda)
+		if err != nil {
+			panic(err)
+		}
+		var mae float64
+		for _, i := range perm[:numTest] {
+			mae += math.Abs(m.Predict(points[i])-thresholds[i]) / float64(numTest)
+		}
+		log.Printf("Mean absolute error on %d held out columns: %f", numTest, mae)
+	}
+	m, err := simhashlsh.FitThresholdModel(points, thresholds, lambda)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.SaveFile(output); err != nil {
+		panic(err)
+	}
+	log.Printf("Saved the model to %s", output)
+}
+
+// bestThreshold returns the cosine threshold with the highest F1 score in
+// telling the unionable pairs of a column from the others, and false if the
+// column has no unionable pair.
+func bestThreshold(scores []scoredLabel) (float64, bool) {
+	sort.Slice(scores, func(i, j int) bool { return scores[i].cosine > scores[j].cosine })
+	positives := 0
+	for _, s := range scores {
+		positives += s.label
+	}
+	if positives == 0 {
+		return 0, false
+	}
+	best, threshold := -1.0, 0.0
+	tp := 0
+	for i, s := range scores {
+		tp += s.label
+		// the pairs above the next lower cosine are predicted unionable
+		if i+1 < len(scores) && scores[i+1].cosine == s.cosine {
+			continue
+		}
+		f1 := 2 * float64(tp) / float64(i+1+positives)
+		if f1 > best {
+			best, threshold = f1, s.cosine
+		}
+	}
+	return threshold, true
+}
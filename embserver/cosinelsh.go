@@ -4,6 +4,8 @@ import (
 	"math/rand"
 	"strconv"
 	"sync"
+
+	"github.com/RJMillerLab/table-union/embedding"
 )
 
 type signature []uint8
@@ -108,6 +110,8 @@ type CosineLsh struct {
 	*cosineLshParam
 	// Tables
 	tables []hashTable
+	// the points of the ids, if they are stored
+	vectors map[string][]float64
 }
 
 // NewCosineLsh created an instance of Cosine LSH.
@@ -147,6 +151,37 @@ func (index *CosineLsh) Insert(point []float64, id string) {
 		}(table, hv)
 	}
 	wg.Wait()
+	if index.vectors != nil {
+		index.vectors[id] = point
+	}
+}
+
+// StoreVectors keeps the points of the ids inserted from now on, for
+// QueryTopK.
+func (index *CosineLsh) StoreVectors() {
+	if index.vectors == nil {
+		index.vectors = make(map[string][]float64)
+	}
+}
+
+// QueryTopK returns the k candidates of Query with the highest cosine
+// similarity to the query point, with their similarities, in decreasing
+// order. Candidates without a stored point are skipped.
+func (index *CosineLsh) QueryTopK(q []float64, k int) ([]string, []float64) {
+	queue := NewTopKQueue(k)
+	for _, id := range index.Query(q) {
+		if vec, ok := index.vectors[id]; ok {
+			queue.Push(id, embedding.Cosine(q, vec))
+		}
+	}
+	ids := make([]string, queue.Size())
+	scores := make([]float64, queue.Size())
+	for i := len(ids) - 1; i >= 0; i-- {
+		v, score := queue.Pop()
+		ids[i] = v.(string)
+		scores[i] = score
+	}
+	return ids, scores
 }
 
 // Query finds the ids of approximate nearest neighbour candidates,
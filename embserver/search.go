@@ -39,6 +39,7 @@ type LSHEntry struct {
 }
 
 type SearchIndex struct {
+	od        *opendata.Repository
 	lsh       *CosineLsh
 	transFun  func(string) string
 	tokenFun  func(string) []string
@@ -46,8 +47,9 @@ type SearchIndex struct {
 	byteOrder binary.ByteOrder
 }
 
-func NewSearchIndex(domainDir string, lsh *CosineLsh) *SearchIndex {
+func NewSearchIndex(od *opendata.Repository, domainDir string, lsh *CosineLsh) *SearchIndex {
 	index := &SearchIndex{
+		od:        od,
 		lsh:       lsh,
 		transFun:  DefaultTransFun,
 		tokenFun:  DefaultTokenFun,
@@ -58,8 +60,8 @@ func NewSearchIndex(domainDir string, lsh *CosineLsh) *SearchIndex {
 }
 
 func (index *SearchIndex) Build() error {
-	domainfilenames := opendata.StreamFilenames()
-	embfilenames := opendata.StreamEmbVectors(10, domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	embfilenames := index.od.StreamEmbVectors(10, domainfilenames)
 	count := 0
 	for file := range embfilenames {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -93,6 +95,9 @@ func (index *SearchIndex) Get(tableID string, columnIndex int) (*EmbEntry, error
 }
 
 func (index *SearchIndex) TopK(query []float64, k int) []*EmbEntry {
+	if index.lsh.vectors != nil {
+		return index.storedTopK(query, k)
+	}
 	start := time.Now()
 	lshResults := index.lsh.Query(query)
 	log.Printf("LSH Returns %d candidates in %.4f", len(lshResults), time.Now().Sub(start).Seconds())
@@ -114,3 +119,22 @@ func (index *SearchIndex) TopK(query []float64, k int) []*EmbEntry {
 	log.Printf("Post-proc took %.4f secs", time.Now().Sub(start).Seconds())
 	return result
 }
+
+// storedTopK ranks the candidates by the vectors stored in the LSH, which
+// have the direction of the sum embeddings, and only reads the sum
+// embeddings of the top k.
+func (index *SearchIndex) storedTopK(query []float64, k int) []*EmbEntry {
+	start := time.Now()
+	ids, _ := index.lsh.QueryTopK(query, k)
+	log.Printf("LSH Returns the top %d candidates in %.4f", len(ids), time.Now().Sub(start).Seconds())
+	result := make([]*EmbEntry, len(ids))
+	for i, id := range ids {
+		tableID, columnIndex := fromColumnID(id)
+		entry, err := index.Get(tableID, columnIndex)
+		if err != nil {
+			panic(err)
+		}
+		result[i] = entry
+	}
+	return result
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/RJMillerLab/table-union/benchmarkserver"
+	"github.com/RJMillerLab/table-union/opendata"
 )
 
 type columnPair struct {
@@ -125,15 +126,15 @@ func DoSaveAlignments(alignments <-chan benchmarkserver.Union, measure, experime
 	return progress
 }
 
-func computeUnionPairRowExpansion(tablePairs <-chan tableAlignment, fanout int) <-chan rowExpansion {
+func computeUnionPairRowExpansion(cfg *opendata.Config, tablePairs <-chan tableAlignment, fanout int) <-chan rowExpansion {
 	out := make(chan rowExpansion)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
 	for i := 0; i < fanout; i++ {
 		go func() {
 			for pair := range tablePairs {
-				queryTableFilename := path.Join(OpendataDir, pair.queryTable)
-				candidateTableFilename := path.Join(OpendataDir, pair.candidateTable)
+				queryTableFilename := path.Join(cfg.OpendataDir, pair.queryTable)
+				candidateTableFilename := path.Join(cfg.OpendataDir, pair.candidateTable)
 				expansion := ComputeRowExpansion(queryTableFilename, candidateTableFilename, pair.matches)
 				out <- rowExpansion{
 					alignment:        pair,
@@ -153,15 +154,15 @@ func computeUnionPairRowExpansion(tablePairs <-chan tableAlignment, fanout int)
 	return out
 }
 
-func computeUnionPairExpansion(columnPairs <-chan columnPair, fanout int) <-chan columnExpansion {
+func computeUnionPairExpansion(cfg *opendata.Config, columnPairs <-chan columnPair, fanout int) <-chan columnExpansion {
 	out := make(chan columnExpansion)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
 	for i := 0; i < fanout; i++ {
 		go func() {
 			for pair := range columnPairs {
-				queryTableFilename := path.Join(OpendataDir, pair.queryTable)
-				candidateTableFilename := path.Join(OpendataDir, pair.candidateTable)
+				queryTableFilename := path.Join(cfg.OpendataDir, pair.queryTable)
+				candidateTableFilename := path.Join(cfg.OpendataDir, pair.candidateTable)
 				if selfUnion(pair) {
 					out <- columnExpansion{
 						columns: pair,
@@ -191,18 +192,18 @@ func selfUnion(pair columnPair) bool {
 	return pair.queryTable == pair.candidateTable && pair.queryColIndex == pair.candidateColIndex
 }
 
-func readAlignments() <-chan tableAlignment {
+func readAlignments(cfg *opendata.Config) <-chan tableAlignment {
 	log.Printf("started reading alignments.")
-	log.Printf("experimentdb: %s", ExperimentDB)
-	log.Printf("experimenttable: %s", ExperimentTable)
+	log.Printf("experimentdb: %s", cfg.ExperimentDB)
+	log.Printf("experimenttable: %s", cfg.ExperimentTable)
 	out := make(chan tableAlignment, 100)
 	tablePairs := make([]tablePair, 0)
 	// reading table pairs
-	db2, err := sql.Open("sqlite3", ExperimentDB)
+	db2, err := sql.Open("sqlite3", cfg.ExperimentDB)
 	if err != nil {
 		panic(err)
 	}
-	rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, ExperimentTable))
+	rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, cfg.ExperimentTable))
 	if err != nil {
 		panic(err)
 	}
@@ -223,13 +224,13 @@ func readAlignments() <-chan tableAlignment {
 	rows.Close()
 	db2.Close()
 	go func() {
-		db2, err := sql.Open("sqlite3", ExperimentDB)
+		db2, err := sql.Open("sqlite3", cfg.ExperimentDB)
 		if err != nil {
 			panic(err)
 		}
 		defer db2.Close()
 		for _, p := range tablePairs {
-			rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_col_index, candidate_col_index FROM %s WHERE query_table = "%s" AND candidate_table = "%s";`, ExperimentTable, p.queryTable, p.candidateTable))
+			rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_col_index, candidate_col_index FROM %s WHERE query_table = "%s" AND candidate_table = "%s";`, cfg.ExperimentTable, p.queryTable, p.candidateTable))
 			if err != nil {
 				panic(err)
 			}
@@ -257,16 +258,16 @@ func readAlignments() <-chan tableAlignment {
 	return out
 }
 
-func readColumnPairs() <-chan columnPair {
+func readColumnPairs(cfg *opendata.Config) <-chan columnPair {
 	log.Printf("started reading column pairs.")
 	out := make(chan columnPair, 100)
 	go func() {
-		db2, err := sql.Open("sqlite3", SharedDB)
+		db2, err := sql.Open("sqlite3", cfg.SharedDB)
 		if err != nil {
 			panic(err)
 		}
 		defer db2.Close()
-		rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table, query_col_index, candidate_col_index FROM %s;`, SharedTable))
+		rows, err := db2.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table, query_col_index, candidate_col_index FROM %s;`, cfg.SharedTable))
 		if err != nil {
 			panic(err)
 		}
@@ -294,10 +295,13 @@ func readColumnPairs() <-chan columnPair {
 	return out
 }
 
-func DoComputeAndSaveRowExpansion() {
-	alignments := readAlignments()
-	expansions := computeUnionPairRowExpansion(alignments, 5)
-	progress := saveRowExpansion(expansions)
+// DoComputeAndSaveRowExpansion saves the number of rows added by the
+// unions of the aligned tables of the experiment table to the expansion
+// table.
+func DoComputeAndSaveRowExpansion(cfg *opendata.Config) {
+	alignments := readAlignments(cfg)
+	expansions := computeUnionPairRowExpansion(cfg, alignments, 5)
+	progress := saveRowExpansion(cfg, expansions)
 	i := 0
 	total := ProgressCounter{}
 	start := GetNow()
@@ -316,10 +320,12 @@ func DoComputeAndSaveRowExpansion() {
 	log.Printf("Done computing and saving expansion!")
 }
 
-func DoComputeAndSaveExpansion() {
-	columnPairs := readColumnPairs()
-	expansions := computeUnionPairExpansion(columnPairs, 5)
-	progress := saveExpansion(expansions)
+// DoComputeAndSaveExpansion saves the number of values added by the
+// unions of the column pairs of the shared table to the expansion table.
+func DoComputeAndSaveExpansion(cfg *opendata.Config) {
+	columnPairs := readColumnPairs(cfg)
+	expansions := computeUnionPairExpansion(cfg, columnPairs, 5)
+	progress := saveExpansion(cfg, expansions)
 	i := 0
 	total := ProgressCounter{}
 	start := GetNow()
@@ -338,19 +344,19 @@ func DoComputeAndSaveExpansion() {
 	log.Printf("Done computing and saving expansion!")
 }
 
-func saveRowExpansion(expansions <-chan rowExpansion) <-chan ProgressCounter {
+func saveRowExpansion(cfg *opendata.Config, expansions <-chan rowExpansion) <-chan ProgressCounter {
 	out := make(chan ProgressCounter)
-	db, err := sql.Open("sqlite3", ExpansionDB)
+	db, err := sql.Open("sqlite3", cfg.ExpansionDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s(query_table text,  candidate_table text, query_row_num int, candidate_row_num int, row_expansion_size int);`, ExpansionTable, ExpansionTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s(query_table text,  candidate_table text, query_row_num int, candidate_row_num int, row_expansion_size int);`, cfg.ExpansionTable, cfg.ExpansionTable))
 	if err != nil {
 		panic(err)
 	}
 	// Prepare insert stmt
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_row_num, candidate_row_num, row_expansion_size) values(?, ?, ?, ?, ?);`, ExpansionTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_row_num, candidate_row_num, row_expansion_size) values(?, ?, ?, ?, ?);`, cfg.ExpansionTable))
 	if err != nil {
 		panic(err)
 	}
@@ -368,19 +374,19 @@ func saveRowExpansion(expansions <-chan rowExpansion) <-chan ProgressCounter {
 	return out
 }
 
-func saveExpansion(expansions <-chan columnExpansion) <-chan ProgressCounter {
+func saveExpansion(cfg *opendata.Config, expansions <-chan columnExpansion) <-chan ProgressCounter {
 	out := make(chan ProgressCounter)
-	db, err := sql.Open("sqlite3", ExpansionDB)
+	db, err := sql.Open("sqlite3", cfg.ExpansionDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s(query_table text,  candidate_table text, query_col_index int,candidate_col_index int,query_domain_size int, candidate_domain_size int, expansion_size int);`, ExpansionTable, ExpansionTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s(query_table text,  candidate_table text, query_col_index int,candidate_col_index int,query_domain_size int, candidate_domain_size int, expansion_size int);`, cfg.ExpansionTable, cfg.ExpansionTable))
 	if err != nil {
 		panic(err)
 	}
 	// Prepare insert stmt
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_col_index, candidate_col_index, query_domain_size, candidate_domain_size, expansion_size) values(?, ?, ?, ?, ?, ?, ?);`, ExpansionTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_col_index, candidate_col_index, query_domain_size, candidate_domain_size, expansion_size) values(?, ?, ?, ?, ?, ?, ?);`, cfg.ExpansionTable))
 	if err != nil {
 		panic(err)
 	}
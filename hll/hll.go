@@ -0,0 +1,138 @@
+// Package hll implements HyperLogLog sketches to estimate the number of
+// distinct values of domains, and of their unions and intersections.
+package hll
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// DefaultPrecision gives 1024 registers, with a standard error of about 3%.
+const DefaultPrecision = 10
+
+var ErrPrecision = errors.New("hll: sketches of different precisions")
+
+// HLL is a HyperLogLog sketch (Flajolet et al., 2007) with 64-bit hash
+// values and the linear counting correction for small cardinalities.
+type HLL struct {
+	p         uint
+	registers []uint8
+}
+
+// New creates an empty sketch with 2^p registers, for p in [4, 16].
+func New(p int) *HLL {
+	if p < 4 || p > 16 {
+		panic("hll: precision out of range")
+	}
+	return &HLL{
+		p:         uint(p),
+		registers: make([]uint8, 1<<uint(p)),
+	}
+}
+
+// FromRegisters creates a sketch from the registers of another one.
+func FromRegisters(registers []uint8) (*HLL, error) {
+	p := bits.TrailingZeros(uint(len(registers)))
+	if len(registers) != 1<<uint(p) || p < 4 || p > 16 {
+		return nil, errors.New("hll: the number of registers is not a valid power of two")
+	}
+	h := New(p)
+	copy(h.registers, registers)
+	return h, nil
+}
+
+// Registers returns a copy of the registers of the sketch.
+func (h *HLL) Registers() []uint8 {
+	registers := make([]uint8, len(h.registers))
+	copy(registers, h.registers)
+	return registers
+}
+
+// Add a value, serialized to a byte slice, to the sketch.
+func (h *HLL) Add(b []byte) {
+	f := fnv.New64a()
+	f.Write(b)
+	h.addHash(mix(f.Sum64()))
+}
+
+func (h *HLL) addHash(x uint64) {
+	index := x >> (64 - h.p)
+	// the position of the first 1 bit of the remaining 64-p bits
+	rho := uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1)) + 1)
+	if rho > h.registers[index] {
+		h.registers[index] = rho
+	}
+}
+
+// Count returns the estimated number of distinct values added.
+func (h *HLL) Count() float64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1.0, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := alpha(len(h.registers)) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
+	}
+	return estimate
+}
+
+// Merge adds the values of another sketch of the same precision.
+func (h *HLL) Merge(other *HLL) error {
+	if h.p != other.p {
+		return ErrPrecision
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Union returns the estimated number of distinct values of two sketches.
+func Union(a, b *HLL) (float64, error) {
+	u := &HLL{p: a.p, registers: a.Registers()}
+	if err := u.Merge(b); err != nil {
+		return 0, err
+	}
+	return u.Count(), nil
+}
+
+// Intersection returns the estimated number of values in both sketches,
+// by inclusion-exclusion. The error grows with the size of the union.
+func Intersection(a, b *HLL) (float64, error) {
+	union, err := Union(a, b)
+	if err != nil {
+		return 0, err
+	}
+	countA, countB := a.Count(), b.Count()
+	intersection := countA + countB - union
+	return math.Max(0, math.Min(intersection, math.Min(countA, countB))), nil
+}
+
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1.0 + 1.079/float64(m))
+}
+
+// mix spreads the bits of FNV hash values.
+func mix(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
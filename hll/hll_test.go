@@ -0,0 +1,78 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func sketch(start, end int) *HLL {
+	h := New(DefaultPrecision)
+	for i := start; i < end; i++ {
+		h.Add([]byte(fmt.Sprintf("value%d", i)))
+	}
+	return h
+}
+
+func relativeError(estimate float64, exact int) float64 {
+	return math.Abs(estimate-float64(exact)) / float64(exact)
+}
+
+func Test_Count(t *testing.T) {
+	for _, n := range []int{10, 100, 1000, 100000} {
+		h := sketch(0, n)
+		// duplicates are not counted
+		for i := 0; i < n; i++ {
+			h.Add([]byte(fmt.Sprintf("value%d", i)))
+		}
+		if e := relativeError(h.Count(), n); e > 0.1 {
+			t.Errorf("count %.1f of %d values", h.Count(), n)
+		}
+	}
+	if New(DefaultPrecision).Count() != 0 {
+		t.Error("non-zero count of an empty sketch")
+	}
+}
+
+func Test_UnionIntersection(t *testing.T) {
+	a := sketch(0, 6000)
+	b := sketch(4000, 10000)
+	union, err := Union(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relativeError(union, 10000) > 0.1 {
+		t.Errorf("union %.1f", union)
+	}
+	intersection, err := Intersection(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relativeError(intersection, 2000) > 0.3 {
+		t.Errorf("intersection %.1f", intersection)
+	}
+	// merging keeps the union
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count() != union {
+		t.Errorf("merged count %.1f, union %.1f", a.Count(), union)
+	}
+	if _, err := Union(a, New(DefaultPrecision+1)); err != ErrPrecision {
+		t.Error("union of sketches of different precisions")
+	}
+}
+
+func Test_FromRegisters(t *testing.T) {
+	h := sketch(0, 500)
+	c, err := FromRegisters(h.Registers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Count() != h.Count() {
+		t.Fatalf("count %.1f of the copy, %.1f of the sketch", c.Count(), h.Count())
+	}
+	if _, err := FromRegisters(make([]uint8, 1000)); err == nil {
+		t.Fatal("registers of an invalid size")
+	}
+}
@@ -0,0 +1,384 @@
+package hnsw
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/RJMillerLab/table-union/simhashlsh"
+)
+
+// HNSW is a hierarchical navigable small world graph of points under the
+// cosine distance (Malkov and Yashunin, TPAMI 2018). Unlike the LSH, keys
+// are searchable as soon as they are added.
+type HNSW struct {
+	dim int
+	// the number of neighbours of a node above the bottom layer, and twice
+	// as many in the bottom layer
+	m              int
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	nodes          []*node
+	ids            map[string]int32
+	entry          int32
+	maxLevel       int
+	random         *rand.Rand
+	// guards the graph for online updates
+	lock sync.RWMutex
+}
+
+type node struct {
+	key   string
+	point []float64
+	norm  float64
+	// the neighbours in each layer up to the level of the node
+	friends [][]int32
+	// deleted nodes are still traversed but not returned
+	deleted bool
+}
+
+// NewHNSW creates an empty graph of points of dim dimensions. m is the
+// number of neighbours of each node, efConstruction and efSearch the number
+// of candidates kept while adding and searching.
+func NewHNSW(dim, m, efConstruction, efSearch int) *HNSW {
+	if m < 2 {
+		m = 2
+	}
+	if efConstruction < m {
+		efConstruction = m
+	}
+	if efSearch < 1 {
+		efSearch = 1
+	}
+	return &HNSW{
+		dim:            dim,
+		m:              m,
+		efConstruction: efConstruction,
+		efSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(m)),
+		nodes:          make([]*node, 0),
+		ids:            make(map[string]int32),
+		entry:          -1,
+		random:         rand.New(rand.NewSource(1)),
+	}
+}
+
+// Len returns the number of searchable keys.
+func (h *HNSW) Len() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return len(h.ids)
+}
+
+type candidate struct {
+	id   int32
+	dist float64
+}
+
+// nearer is a min-heap of candidates by distance.
+type nearer []candidate
+
+func (c nearer) Len() int            { return len(c) }
+func (c nearer) Less(i, j int) bool  { return c[i].dist < c[j].dist }
+func (c nearer) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *nearer) Push(x interface{}) { *c = append(*c, x.(candidate)) }
+func (c *nearer) Pop() interface{} {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
+
+// farther is a max-heap of candidates by distance.
+type farther struct{ nearer }
+
+func (c farther) Less(i, j int) bool { return c.nearer[i].dist > c.nearer[j].dist }
+
+func norm(point []float64) float64 {
+	var s float64
+	for _, v := range point {
+		s += v * v
+	}
+	return math.Sqrt(s)
+}
+
+func (h *HNSW) distance(point []float64, pointNorm float64, id int32) float64 {
+	n := h.nodes[id]
+	if pointNorm == 0 || n.norm == 0 {
+		return 1
+	}
+	var dp float64
+	for i, v := range point {
+		dp += v * n.point[i]
+	}
+	return 1 - dp/(pointNorm*n.norm)
+}
+
+func (h *HNSW) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * h.m
+	}
+	return h.m
+}
+
+// searchLayer returns the ef nearest nodes to the point found from the
+// entry points in a layer, in the order of increasing distance.
+func (h *HNSW) searchLayer(point []float64, pointNorm float64, eps []candidate, ef, level int) []candidate {
+	visited := make(map[int32]bool)
+	cands := make(nearer, 0, ef)
+	results := farther{make(nearer, 0, ef+1)}
+	for _, ep := range eps {
+		visited[ep.id] = true
+		heap.Push(&cands, ep)
+		heap.Push(&results, ep)
+	}
+	for cands.Len() > 0 {
+		c := heap.Pop(&cands).(candidate)
+		if c.dist > results.nearer[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, f := range h.nodes[c.id].friends[level] {
+			if visited[f] {
+				continue
+			}
+			visited[f] = true
+			d := h.distance(point, pointNorm, f)
+			if results.Len() < ef || d < results.nearer[0].dist {
+				heap.Push(&cands, candidate{f, d})
+				heap.Push(&results, candidate{f, d})
+				if results.Len() > ef {
+					heap.Pop(&results)
+				}
+			}
+		}
+	}
+	sorted := make([]candidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(&results).(candidate)
+	}
+	return sorted
+}
+
+// selectNeighbors keeps the candidates, in the order of increasing
+// distance, that are nearer to the point than to the neighbours kept so
+// far, and fills up to m with the nearest of the others.
+func (h *HNSW) selectNeighbors(cands []candidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	pruned := make([]int32, 0)
+	for _, c := range cands {
+		if len(selected) >= m {
+			break
+		}
+		n := h.nodes[c.id]
+		keep := true
+		for _, s := range selected {
+			if h.distance(n.point, n.norm, s) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.id)
+		} else {
+			pruned = append(pruned, c.id)
+		}
+	}
+	for _, id := range pruned {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, id)
+	}
+	return selected
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(-math.Log(1-h.random.Float64()) * h.levelMult)
+}
+
+// Add inserts a key with its point into the graph. The point is not copied
+// and must not be modified. A key added again replaces its point.
+func (h *HNSW) Add(point []float64, key string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.delete(key)
+	level := h.randomLevel()
+	id := int32(len(h.nodes))
+	n := &node{
+		key:     key,
+		point:   point,
+		norm:    norm(point),
+		friends: make([][]int32, level+1),
+	}
+	h.nodes = append(h.nodes, n)
+	h.ids[key] = id
+	if h.entry < 0 {
+		h.entry = id
+		h.maxLevel = level
+		return
+	}
+	ep := []candidate{{h.entry, h.distance(n.point, n.norm, h.entry)}}
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.searchLayer(n.point, n.norm, ep, 1, l)[:1]
+	}
+	for l := minInt(level, h.maxLevel); l >= 0; l-- {
+		cands := h.searchLayer(n.point, n.norm, ep, h.efConstruction, l)
+		n.friends[l] = h.selectNeighbors(cands, h.m)
+		for _, f := range n.friends[l] {
+			h.connect(f, id, l)
+		}
+		ep = cands
+	}
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = id
+	}
+}
+
+// connect adds a link from the node from to the node to in a layer, and
+// prunes the links of from if it has too many.
+func (h *HNSW) connect(from, to int32, level int) {
+	n := h.nodes[from]
+	n.friends[level] = append(n.friends[level], to)
+	if len(n.friends[level]) <= h.maxFriends(level) {
+		return
+	}
+	cands := make(nearer, len(n.friends[level]))
+	for i, f := range n.friends[level] {
+		cands[i] = candidate{f, h.distance(n.point, n.norm, f)}
+	}
+	heap.Init(&cands)
+	sorted := make([]candidate, 0, len(cands))
+	for cands.Len() > 0 {
+		sorted = append(sorted, heap.Pop(&cands).(candidate))
+	}
+	n.friends[level] = h.selectNeighbors(sorted, h.maxFriends(level))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Index does nothing, the keys are searchable as soon as they are added.
+// It makes the graph a drop-in replacement of the LSH.
+func (h *HNSW) Index() {}
+
+// Insert is Add.
+func (h *HNSW) Insert(point []float64, key string) {
+	h.Add(point, key)
+}
+
+// Delete removes a key. It returns false if the key is not found.
+// The node stays in the graph to keep it connected, but is not returned.
+func (h *HNSW) Delete(point []float64, key string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.delete(key)
+}
+
+func (h *HNSW) delete(key string) bool {
+	id, ok := h.ids[key]
+	if !ok {
+		return false
+	}
+	h.nodes[id].deleted = true
+	delete(h.ids, key)
+	return true
+}
+
+// Vector returns the point of a key.
+func (h *HNSW) Vector(key string) ([]float64, bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	id, ok := h.ids[key]
+	if !ok {
+		return nil, false
+	}
+	return h.nodes[id].point, true
+}
+
+// search returns up to ef nearest keys to the point, in the order of
+// increasing distance, with an ef-wide search of the bottom layer.
+func (h *HNSW) search(point []float64, ef int) []candidate {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if h.entry < 0 {
+		return nil
+	}
+	pointNorm := norm(point)
+	ep := []candidate{{h.entry, h.distance(point, pointNorm, h.entry)}}
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.searchLayer(point, pointNorm, ep, 1, l)[:1]
+	}
+	found := h.searchLayer(point, pointNorm, ep, ef, 0)
+	result := make([]candidate, 0, len(found))
+	for _, c := range found {
+		if !h.nodes[c.id].deleted {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Query returns the keys of the n nearest points found, nearest first.
+func (h *HNSW) Query(point []float64, n int) []string {
+	ef := h.efSearch
+	if ef < n {
+		ef = n
+	}
+	found := h.search(point, ef)
+	if len(found) > n {
+		found = found[:n]
+	}
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	keys := make([]string, len(found))
+	for i, c := range found {
+		keys[i] = h.nodes[c.id].key
+	}
+	return keys
+}
+
+// QueryPlus streams the keys near each of the points, nearest first. The
+// search is widened until every node is visited, so that the consumer can
+// stop it once it has enough keys.
+func (h *HNSW) QueryPlus(points [][]float64, done <-chan struct{}) <-chan simhashlsh.UnionPair {
+	out := make(chan simhashlsh.UnionPair)
+	go func() {
+		defer close(out)
+		seens := make(map[simhashlsh.UnionPair]bool)
+		for ef := h.efSearch; ; ef *= 2 {
+			for p, point := range points {
+				for _, c := range h.search(point, ef) {
+					h.lock.RLock()
+					rp := simhashlsh.UnionPair{
+						QueryIndex:   p,
+						CandidateKey: h.nodes[c.id].key,
+					}
+					h.lock.RUnlock()
+					if seens[rp] {
+						continue
+					}
+					seens[rp] = true
+					select {
+					case out <- rp:
+					case <-done:
+						return
+					}
+				}
+			}
+			h.lock.RLock()
+			size := len(h.nodes)
+			h.lock.RUnlock()
+			if ef >= size {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,222 @@
+package hnsw
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RJMillerLab/table-union/simhashlsh"
+)
+
+// clusteredVectors draws n points around a number of random centers, so
+// that each point has near neighbours.
+func clusteredVectors(random *rand.Rand, n, dim, clusters int, noise float64) [][]float64 {
+	centers := make([][]float64, clusters)
+	for i := range centers {
+		centers[i] = make([]float64, dim)
+		for d := range centers[i] {
+			centers[i][d] = random.NormFloat64()
+		}
+	}
+	vecs := make([][]float64, n)
+	for i := range vecs {
+		c := centers[random.Intn(clusters)]
+		vecs[i] = make([]float64, dim)
+		for d := range vecs[i] {
+			vecs[i][d] = c[d] + noise*random.NormFloat64()
+		}
+	}
+	return vecs
+}
+
+func cosine(x, y []float64) float64 {
+	var dp, nx, ny float64
+	for i := range x {
+		dp += x[i] * y[i]
+		nx += x[i] * x[i]
+		ny += y[i] * y[i]
+	}
+	return dp / math.Sqrt(nx*ny)
+}
+
+// exactTopK returns the keys of the k points nearest to the query.
+func exactTopK(vecs [][]float64, query []float64, k int) []string {
+	ids := make([]int, len(vecs))
+	scores := make([]float64, len(vecs))
+	for i := range vecs {
+		ids[i] = i
+		scores[i] = cosine(query, vecs[i])
+	}
+	sort.Slice(ids, func(a, b int) bool { return scores[ids[a]] > scores[ids[b]] })
+	keys := make([]string, k)
+	for i := range keys {
+		keys[i] = strconv.Itoa(ids[i])
+	}
+	return keys
+}
+
+func recall(expected, found []string) float64 {
+	set := make(map[string]bool)
+	for _, key := range found {
+		set[key] = true
+	}
+	hits := 0
+	for _, key := range expected {
+		if set[key] {
+			hits += 1
+		}
+	}
+	return float64(hits) / float64(len(expected))
+}
+
+func buildHNSW(vecs [][]float64) *HNSW {
+	h := NewHNSW(len(vecs[0]), 16, 100, 50)
+	for i, e := range vecs {
+		h.Add(e, strconv.Itoa(i))
+	}
+	h.Index()
+	return h
+}
+
+func Test_Query(t *testing.T) {
+	vecs := clusteredVectors(rand.New(rand.NewSource(1)), 1000, 50, 20, 0.5)
+	h := buildHNSW(vecs)
+	if h.Len() != len(vecs) {
+		t.Fatalf("%d keys", h.Len())
+	}
+	for i, e := range vecs[:20] {
+		result := h.Query(e, 10)
+		if len(result) != 10 || result[0] != strconv.Itoa(i) {
+			t.Fatalf("the query itself is not the first of %v", result)
+		}
+	}
+}
+
+func Test_Delete(t *testing.T) {
+	vecs := clusteredVectors(rand.New(rand.NewSource(1)), 200, 50, 5, 0.5)
+	h := buildHNSW(vecs)
+	if !h.Delete(vecs[0], "0") {
+		t.Fatal("unable to delete a key")
+	}
+	for _, key := range h.Query(vecs[0], 10) {
+		if key == "0" {
+			t.Fatal("deleted key is retrieved")
+		}
+	}
+	if _, ok := h.Vector("0"); ok {
+		t.Fatal("the vector of the deleted key is returned")
+	}
+	if h.Delete(vecs[0], "0") {
+		t.Fail()
+	}
+	h.Insert(vecs[0], "0")
+	if h.Query(vecs[0], 1)[0] != "0" {
+		t.Fatal("unable to retrieve the inserted key")
+	}
+}
+
+func Test_QueryPlus(t *testing.T) {
+	vecs := clusteredVectors(rand.New(rand.NewSource(1)), 300, 50, 5, 0.5)
+	h := buildHNSW(vecs)
+	done := make(chan struct{})
+	defer close(done)
+	seen := make(map[simhashlsh.UnionPair]bool)
+	for pair := range h.QueryPlus(vecs[:2], done) {
+		if seen[pair] {
+			t.Fatalf("%v is returned twice", pair)
+		}
+		seen[pair] = true
+	}
+	if len(seen) != 2*len(vecs) {
+		t.Errorf("%d pairs", len(seen))
+	}
+}
+
+func Test_Snapshot(t *testing.T) {
+	vecs := clusteredVectors(rand.New(rand.NewSource(1)), 300, 50, 5, 0.5)
+	h := buildHNSW(vecs)
+	h.Delete(vecs[1], "1")
+	buf := new(bytes.Buffer)
+	if err := h.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != h.Len() {
+		t.Fatalf("%d keys, expected %d", loaded.Len(), h.Len())
+	}
+	for _, e := range vecs[:10] {
+		if !reflect.DeepEqual(h.Query(e, 10), loaded.Query(e, 10)) {
+			t.Fatal("results do not match")
+		}
+	}
+	if _, err := Load(bytes.NewBufferString("not a snapshot")); err != ErrBadSnapshot {
+		t.Fail()
+	}
+}
+
+// Test_RecallComparison compares the recall of the 10 nearest neighbours
+// and the query latency of the graph with the cosine LSH, on noisy copies
+// of the indexed points.
+func Test_RecallComparison(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	vecs := clusteredVectors(random, 3000, 100, 30, 0.7)
+	queries := clusteredVectors(random, 50, 100, 30, 0.7)
+	h := buildHNSW(vecs)
+	clsh := simhashlsh.NewCosineLSH(100, 256, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	var hnswRecall, lshRecall float64
+	var hnswTime, lshTime time.Duration
+	for _, q := range queries {
+		expected := exactTopK(vecs, q, 10)
+		start := time.Now()
+		found := h.Query(q, 10)
+		hnswTime += time.Since(start)
+		hnswRecall += recall(expected, found) / float64(len(queries))
+		start = time.Now()
+		found = clsh.Query(q)
+		lshTime += time.Since(start)
+		lshRecall += recall(expected, found) / float64(len(queries))
+	}
+	t.Logf("hnsw: recall@10 %.3f, %v per query", hnswRecall, hnswTime/time.Duration(len(queries)))
+	t.Logf("cosine lsh: recall@10 %.3f, %v per query", lshRecall, lshTime/time.Duration(len(queries)))
+	if hnswRecall < 0.9 {
+		t.Errorf("recall of hnsw is %f", hnswRecall)
+	}
+}
+
+func Benchmark_QueryHNSW(b *testing.B) {
+	random := rand.New(rand.NewSource(2))
+	vecs := clusteredVectors(random, 3000, 100, 30, 0.7)
+	queries := clusteredVectors(random, 50, 100, 30, 0.7)
+	h := buildHNSW(vecs)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		h.Query(queries[n%len(queries)], 10)
+	}
+}
+
+func Benchmark_QueryCosineLSH(b *testing.B) {
+	random := rand.New(rand.NewSource(2))
+	vecs := clusteredVectors(random, 3000, 100, 30, 0.7)
+	queries := clusteredVectors(random, 50, 100, 30, 0.7)
+	clsh := simhashlsh.NewCosineLSH(100, 256, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clsh.Query(queries[n%len(queries)])
+	}
+}
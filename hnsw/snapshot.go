@@ -0,0 +1,188 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+)
+
+const (
+	snapshotMagic   = "HNSWSNAP"
+	snapshotVersion = uint32(1)
+)
+
+var (
+	byteOrder = binary.BigEndian
+	// ErrBadSnapshot is returned when loading a file that is not a snapshot
+	// of the graph or was written by an unknown version.
+	ErrBadSnapshot = errors.New("hnsw: invalid snapshot")
+)
+
+// Save writes a snapshot of the graph, including the points and the
+// deleted nodes.
+func (h *HNSW) Save(w io.Writer) error {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	header := []uint32{snapshotVersion, uint32(h.dim), uint32(h.m), uint32(h.efConstruction), uint32(h.efSearch), uint32(h.maxLevel)}
+	if err := binary.Write(bw, byteOrder, header); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, byteOrder, []int64{int64(h.entry), int64(len(h.nodes))}); err != nil {
+		return err
+	}
+	for _, n := range h.nodes {
+		if err := writeString(bw, n.key); err != nil {
+			return err
+		}
+		var deleted uint8
+		if n.deleted {
+			deleted = 1
+		}
+		if err := binary.Write(bw, byteOrder, deleted); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, byteOrder, uint32(len(n.point))); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, byteOrder, n.point); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, byteOrder, uint32(len(n.friends))); err != nil {
+			return err
+		}
+		for _, friends := range n.friends {
+			if err := binary.Write(bw, byteOrder, uint32(len(friends))); err != nil {
+				return err
+			}
+			if err := binary.Write(bw, byteOrder, friends); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Load reads a graph from a snapshot written by Save.
+func Load(r io.Reader) (*HNSW, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrBadSnapshot
+	}
+	header := make([]uint32, 6)
+	if err := binary.Read(br, byteOrder, header); err != nil {
+		return nil, err
+	}
+	if header[0] != snapshotVersion {
+		return nil, ErrBadSnapshot
+	}
+	h := NewHNSW(int(header[1]), int(header[2]), int(header[3]), int(header[4]))
+	h.maxLevel = int(header[5])
+	sizes := make([]int64, 2)
+	if err := binary.Read(br, byteOrder, sizes); err != nil {
+		return nil, err
+	}
+	h.entry = int32(sizes[0])
+	h.nodes = make([]*node, sizes[1])
+	for id := range h.nodes {
+		n := &node{}
+		var err error
+		if n.key, err = readString(br); err != nil {
+			return nil, err
+		}
+		var deleted uint8
+		if err := binary.Read(br, byteOrder, &deleted); err != nil {
+			return nil, err
+		}
+		n.deleted = deleted == 1
+		var dim uint32
+		if err := binary.Read(br, byteOrder, &dim); err != nil {
+			return nil, err
+		}
+		n.point = make([]float64, dim)
+		if err := binary.Read(br, byteOrder, n.point); err != nil {
+			return nil, err
+		}
+		n.norm = norm(n.point)
+		var levels uint32
+		if err := binary.Read(br, byteOrder, &levels); err != nil {
+			return nil, err
+		}
+		n.friends = make([][]int32, levels)
+		for l := range n.friends {
+			var numFriends uint32
+			if err := binary.Read(br, byteOrder, &numFriends); err != nil {
+				return nil, err
+			}
+			n.friends[l] = make([]int32, numFriends)
+			if err := binary.Read(br, byteOrder, n.friends[l]); err != nil {
+				return nil, err
+			}
+			for _, f := range n.friends[l] {
+				if f < 0 || int64(f) >= sizes[1] {
+					return nil, ErrBadSnapshot
+				}
+			}
+		}
+		h.nodes[id] = n
+		if !n.deleted {
+			h.ids[n.key] = int32(id)
+		}
+	}
+	if h.entry >= int32(len(h.nodes)) || (h.entry < 0 && len(h.nodes) > 0) {
+		return nil, ErrBadSnapshot
+	}
+	// new levels are drawn from another sequence than the saved graph's
+	h.random = rand.New(rand.NewSource(int64(len(h.nodes))))
+	return h, nil
+}
+
+// SaveFile writes a snapshot of the graph to filename.
+func (h *HNSW) SaveFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := h.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile reads a graph from the snapshot in filename.
+func LoadFile(filename string) (*HNSW, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := binary.Write(w, byteOrder, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint32
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,204 @@
+package minhashlsh
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// DomainRecord is a domain with its number of distinct values and its
+// MinHash signature.
+type DomainRecord struct {
+	Key       string
+	Size      int
+	Signature Signature
+}
+
+type partition struct {
+	lower int
+	upper int
+	lsh   *MinhashLSH
+}
+
+// LshEnsemble is an index for containment search, which finds the domains
+// that contain a given fraction of the values of a query domain
+// (http://www.vldb.org/pvldb/vol9/p1185-zhu.pdf).
+// The domains are partitioned by size and every partition is an LSH Forest,
+// whose K and L are picked at query time for the containment threshold.
+type LshEnsemble struct {
+	partitions []*partition
+	maxK       int
+	numHash    int
+	// guards the size bounds of the partitions
+	lock sync.RWMutex
+}
+
+// BootstrapLshEnsemble builds an index of the domain records with numPart
+// equi-depth partitions by domain size. Every partition is an LSH Forest
+// of numHash/maxK trees with prefixes of up to maxK hash values.
+func BootstrapLshEnsemble(numPart, numHash, maxK int, records []*DomainRecord) *LshEnsemble {
+	sorted := make([]*DomainRecord, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Size < sorted[j].Size
+	})
+	e := &LshEnsemble{
+		partitions: make([]*partition, 0, numPart),
+		maxK:       maxK,
+		numHash:    numHash,
+	}
+	if len(sorted) < numPart {
+		numPart = len(sorted)
+	}
+	if numPart == 0 {
+		e.partitions = append(e.partitions, &partition{lsh: e.newForest()})
+		return e
+	}
+	for i := 0; i < numPart; i++ {
+		start := i * len(sorted) / numPart
+		end := (i + 1) * len(sorted) / numPart
+		p := &partition{
+			lower: sorted[start].Size,
+			upper: sorted[end-1].Size,
+			lsh:   e.newForest(),
+		}
+		for _, r := range sorted[start:end] {
+			p.lsh.Add(r.Key, r.Signature)
+		}
+		p.lsh.Index()
+		e.partitions = append(e.partitions, p)
+	}
+	return e
+}
+
+func (e *LshEnsemble) newForest() *MinhashLSH {
+	return newMinhashLSHKL(e.maxK, e.numHash/e.maxK, 4)
+}
+
+// Query returns the keys of the candidate domains that contain at least
+// the threshold fraction of the values of the query domain.
+func (e *LshEnsemble) Query(sig Signature, size int, threshold float64, done <-chan struct{}) <-chan string {
+	out := make(chan string)
+	e.lock.RLock()
+	partitions := make([]partition, len(e.partitions))
+	for i, p := range e.partitions {
+		partitions[i] = *p
+	}
+	e.lock.RUnlock()
+	go func() {
+		defer close(out)
+		seen := make(map[string]bool)
+		for _, p := range partitions {
+			// the domains of the partition are too small
+			if float64(p.upper) < threshold*float64(size) {
+				continue
+			}
+			k, l := e.optimalKL(size, p.upper, threshold)
+			for _, key := range p.lsh.queryKL(sig, k, l) {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				select {
+				case out <- key:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Insert adds a domain to the partition of its size. The bounds of the
+// partition are extended to a size out of range, e.g. a size between the
+// ones of two partitions.
+func (e *LshEnsemble) Insert(key string, size int, sig Signature) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	last := e.partitions[len(e.partitions)-1]
+	if size > last.upper {
+		last.upper = size
+	}
+	p := e.partitionOf(size)
+	if size < p.lower {
+		p.lower = size
+	}
+	p.lsh.Insert(key, sig)
+}
+
+// Delete removes a domain given the size and the signature it was added with.
+// It returns false if the domain is not found.
+func (e *LshEnsemble) Delete(key string, size int, sig Signature) bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	p := e.partitionOf(size)
+	return p != nil && p.lsh.Delete(key, sig)
+}
+
+// partitionOf returns the first partition whose upper bound is at least
+// the size, or nil if the size is above all of them.
+func (e *LshEnsemble) partitionOf(size int) *partition {
+	for _, p := range e.partitions {
+		if size <= p.upper {
+			return p
+		}
+	}
+	return nil
+}
+
+// queryKL returns the keys in the first l trees sharing a prefix of
+// k hash values with the signature.
+func (f *MinhashLSH) queryKL(sig Signature, k, l int) []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := 0; i < l && i < f.l; i++ {
+		hk := f.hashKeyFunc(sig[i*f.k : i*f.k+k])
+		for _, ks := range f.lookup(i, hk, f.hashValueSize*k) {
+			for _, key := range ks {
+				if !seen[key] {
+					seen[key] = true
+					result = append(result, key)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// containmentToJaccard converts the containment of a query domain of size q
+// in a domain of size x to their Jaccard similarity.
+func containmentToJaccard(c float64, x, q int) float64 {
+	return c * float64(q) / (float64(x) + float64(q) - c*float64(q))
+}
+
+// optimalKL returns the K and L minimizing the mean false positive and
+// false negative probabilities of containment search in a partition with
+// the upper bound of domain sizes x, given the query size q and the
+// threshold t. The means, rather than the integrals, keep the false
+// negatives from being outweighed for high thresholds.
+func (e *LshEnsemble) optimalKL(q, x int, t float64) (optK, optL int) {
+	minError := math.MaxFloat64
+	numL := e.numHash / e.maxK
+	for l := 1; l <= numL; l++ {
+		for k := 1; k <= e.maxK; k++ {
+			var fp, fn float64
+			if t > 0.0 {
+				fp = integral(func(c float64) float64 {
+					return falsePositive(l, k)(containmentToJaccard(c, x, q))
+				}, 0, t, integrationPrecision) / t
+			}
+			if t < 1.0 {
+				fn = integral(func(c float64) float64 {
+					return falseNegative(l, k)(containmentToJaccard(c, x, q))
+				}, t, 1.0, integrationPrecision) / (1.0 - t)
+			}
+			if fp+fn < minError {
+				minError = fp + fn
+				optK = k
+				optL = l
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package minhashlsh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// domainSignature returns the signature of the values from start to end
+// in a random sequence.
+func domainSignature(start, end, numHash int) Signature {
+	r := rand.New(rand.NewSource(1))
+	mh := NewMinhash(1, numHash)
+	b := make([]byte, 8)
+	for i := 0; i < end; i++ {
+		v := r.Int63()
+		if i >= start {
+			binary.BigEndian.PutUint64(b, uint64(v))
+			mh.Push(b)
+		}
+	}
+	return mh.Signature()
+}
+
+func Test_LshEnsemble(t *testing.T) {
+	numHash := 256
+	records := make([]*DomainRecord, 0)
+	// domains [0, size) that contain the small ones, and disjoint domains
+	for i, size := range []int{100, 500, 800, 1000, 1500, 2000} {
+		records = append(records, &DomainRecord{
+			Key:       fmt.Sprintf("prefix%d", i),
+			Size:      size,
+			Signature: domainSignature(0, size, numHash),
+		})
+		records = append(records, &DomainRecord{
+			Key:       fmt.Sprintf("disjoint%d", i),
+			Size:      size,
+			Signature: domainSignature(100000*(i+1), 100000*(i+1)+size, numHash),
+		})
+	}
+	e := BootstrapLshEnsemble(3, numHash, 4, records)
+	query := domainSignature(0, 400, numHash)
+	found := make(map[string]bool)
+	for key := range e.Query(query, 400, 0.8, make(chan struct{})) {
+		found[key] = true
+	}
+	for i := 1; i < 6; i++ {
+		if !found[fmt.Sprintf("prefix%d", i)] {
+			t.Errorf("containing domain prefix%d is not found in %v", i, found)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		if found[fmt.Sprintf("disjoint%d", i)] {
+			t.Errorf("disjoint domain %d is found", i)
+		}
+	}
+	sig := domainSignature(0, 1800, numHash)
+	e.Insert("large", 1800, sig)
+	found = make(map[string]bool)
+	for key := range e.Query(query, 400, 0.8, make(chan struct{})) {
+		found[key] = true
+	}
+	if !found["large"] {
+		t.Error("inserted domain is not found")
+	}
+	if !e.Delete("large", 1800, sig) {
+		t.Error("inserted domain is not deleted")
+	}
+	for key := range e.Query(query, 400, 0.8, make(chan struct{})) {
+		if key == "large" {
+			t.Error("deleted domain is found")
+		}
+	}
+}
+
+func Test_LshEnsembleSizeGap(t *testing.T) {
+	numHash := 256
+	records := make([]*DomainRecord, 0)
+	for i, size := range []int{100, 500, 800, 1000} {
+		records = append(records, &DomainRecord{
+			Key:       fmt.Sprintf("prefix%d", i),
+			Size:      size,
+			Signature: domainSignature(0, size, numHash),
+		})
+	}
+	// partitions of sizes [100, 500] and [800, 1000]
+	e := BootstrapLshEnsemble(2, numHash, 4, records)
+	sig := domainSignature(0, 600, numHash)
+	e.Insert("gap", 600, sig)
+	query := domainSignature(0, 400, numHash)
+	found := false
+	for key := range e.Query(query, 400, 0.8, make(chan struct{})) {
+		found = found || key == "gap"
+	}
+	if !found {
+		t.Error("domain of a size between two partitions is not found")
+	}
+	if !e.Delete("gap", 600, sig) {
+		t.Fatal("domain of a size between two partitions is not deleted")
+	}
+	for key := range e.Query(query, 400, 0.8, make(chan struct{})) {
+		if key == "gap" {
+			t.Error("deleted domain is found")
+		}
+	}
+}
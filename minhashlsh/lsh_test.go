@@ -2,7 +2,11 @@ package minhashlsh
 
 import (
 	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func randomSignature(size int, seed int64) Signature {
@@ -58,3 +62,85 @@ func Test_MinhashLSH(t *testing.T) {
 		t.Fatal("unable to retrieve inserted keys")
 	}
 }
+
+func Test_QueryPlusCancel(t *testing.T) {
+	f := NewMinhashLSH16(64, 0.5)
+	sig := randomSignature(64, 1)
+	for i := 0; i < 1000; i++ {
+		f.Add(strconv.Itoa(i), sig)
+	}
+	f.Index()
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	results := f.QueryPlus([]Signature{sig, sig}, done)
+	<-results
+	// the client leaves after the first result
+	close(done)
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are still running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_InsertDelete(t *testing.T) {
+	f := NewMinhashLSH16(64, 0.5)
+	for i := 0; i < 100; i++ {
+		f.Add(strconv.Itoa(i), randomSignature(64, int64(i)))
+	}
+	f.Index()
+	sig := randomSignature(64, 1000)
+	f.Insert("new", sig)
+	found := false
+	for _, key := range f.Query(sig) {
+		if key == "new" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("unable to retrieve inserted key")
+	}
+	for i := range f.hashTables {
+		if !sort.IsSorted(f.hashTables[i]) {
+			t.Fatal("hash table is not sorted")
+		}
+	}
+	if !f.Delete("new", sig) {
+		t.Fatal("unable to delete inserted key")
+	}
+	for _, key := range f.Query(sig) {
+		if key == "new" {
+			t.Fatal("deleted key is retrieved")
+		}
+	}
+	if f.Delete("new", sig) {
+		t.Fail()
+	}
+}
+
+func Test_InsertWhileQuerying(t *testing.T) {
+	f := NewMinhashLSH16(64, 0.5)
+	sig := randomSignature(64, 1)
+	f.Add("0", sig)
+	f.Index()
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i < 100; i++ {
+			f.Insert(strconv.Itoa(i), sig)
+		}
+		close(done)
+	}()
+	for querying := true; querying; {
+		select {
+		case <-done:
+			querying = false
+		default:
+			f.Query(sig)
+		}
+	}
+	if len(f.Query(sig)) != 100 {
+		t.Fail()
+	}
+}
@@ -120,10 +120,18 @@ type MinhashLSH struct {
 	hashTables     []hashTable
 	hashKeyFunc    hashKeyFunc
 	hashValueSize  int
+	// the signatures of the keys, if they are stored
+	signatures map[string]Signature
+	// guards hashTables and signatures for online updates
+	lock sync.RWMutex
 }
 
 func newMinhashLSH(threshold float64, numHash, hashValueSize int) *MinhashLSH {
 	k, l, _, _ := optimalKL(numHash, threshold)
+	return newMinhashLSHKL(k, l, hashValueSize)
+}
+
+func newMinhashLSHKL(k, l, hashValueSize int) *MinhashLSH {
 	hashTables := make([]hashTable, l)
 	for i := range hashTables {
 		hashTables[i] = make(hashTable, 0)
@@ -184,10 +192,13 @@ func (f *MinhashLSH) Add(key string, sig Signature) {
 		}(f.initHashTables[i], Hs[i], key)
 	}
 	wg.Wait()
+	f.storeSignature(key, sig)
 }
 
 // Makes all the keys added searchable.
 func (f *MinhashLSH) Index() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	var wg sync.WaitGroup
 	wg.Add(len(f.hashTables))
 	for i := range f.hashTables {
@@ -212,6 +223,17 @@ func (f *MinhashLSH) Index() {
 	wg.Wait()
 }
 
+// Query returns the candidate keys given the query signature.
+func (f *MinhashLSH) Query(sig Signature) []string {
+	result := make([]string, 0)
+	done := make(chan struct{})
+	defer close(done)
+	for rp := range f.query([]Signature{sig}, done) {
+		result = append(result, rp.CandidateKey)
+	}
+	return result
+}
+
 func (f *MinhashLSH) QueryPlus(sigs []Signature, done <-chan struct{}) <-chan UnionPair {
 	return f.query(sigs, done)
 }
@@ -239,7 +261,11 @@ func (f *MinhashLSH) query(sigs []Signature, done <-chan struct{}) <-chan UnionP
 					continue
 				}
 				seens[rp] = true
-				out <- rp
+				select {
+				case out <- rp:
+				case <-done:
+					return
+				}
 			}
 		}
 	}()
@@ -259,24 +285,17 @@ func (f *MinhashLSH) probe(Hs [][]string, prefixSize int, done <-chan struct{})
 		for i := 0; i < f.l; i++ {
 			for p := 0; p < len(Hs); p++ {
 				go func(i, p int) {
-					hk := Hs[p][i]
-					ht := f.hashTables[i]
 					defer wg.Done()
-					k := sort.Search(len(ht), func(x int) bool {
-						return ht[x].hashKey[:prefixSize] >= hk
-					})
-					if k < len(ht) && ht[k].hashKey[:prefixSize] == hk[:prefixSize] {
-						for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk; j++ {
-							for _, key := range ht[j].keys {
-								rp := UnionPair{
-									QueryIndex:   p,
-									CandidateKey: key,
-								}
-								select {
-								case out <- rp:
-								case <-done:
-									return
-								}
+					for _, ks := range f.lookup(i, Hs[p][i], prefixSize) {
+						for _, key := range ks {
+							rp := UnionPair{
+								QueryIndex:   p,
+								CandidateKey: key,
+							}
+							select {
+							case out <- rp:
+							case <-done:
+								return
 							}
 						}
 					}
@@ -0,0 +1,98 @@
+package minhashlsh
+
+import (
+	"sort"
+)
+
+// lookup returns the keys of the buckets in the i-th hash table whose hash
+// keys start with hk. The key slices are never modified in place, so they
+// remain valid after the lock is released.
+func (f *MinhashLSH) lookup(i int, hk string, prefixSize int) []keys {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	ht := f.hashTables[i]
+	hk = hk[:prefixSize]
+	k := sort.Search(len(ht), func(x int) bool {
+		return ht[x].hashKey[:prefixSize] >= hk
+	})
+	found := make([]keys, 0)
+	for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk; j++ {
+		found = append(found, ht[j].keys)
+	}
+	return found
+}
+
+// Insert adds a key with MinHash signature into the index.
+// Unlike Add, the key is searchable right away.
+func (f *MinhashLSH) Insert(key string, sig Signature) {
+	Hs := make([]string, f.l)
+	for i := 0; i < f.l; i++ {
+		Hs[i] = f.hashKeyFunc(sig[i*f.k : (i+1)*f.k])
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i, hk := range Hs {
+		ht := f.hashTables[i]
+		j := sort.Search(len(ht), func(x int) bool {
+			return ht[x].hashKey >= hk
+		})
+		if j < len(ht) && ht[j].hashKey == hk {
+			ks := make(keys, len(ht[j].keys), len(ht[j].keys)+1)
+			copy(ks, ht[j].keys)
+			ht[j].keys = append(ks, key)
+			continue
+		}
+		ht = append(ht, bucket{})
+		copy(ht[j+1:], ht[j:])
+		ht[j] = bucket{
+			hashKey: hk,
+			keys:    keys{key},
+		}
+		f.hashTables[i] = ht
+	}
+	if f.signatures != nil {
+		f.signatures[key] = sig
+	}
+}
+
+// Delete removes a key given the MinHash signature it was added with.
+// It returns false if the key is not found.
+func (f *MinhashLSH) Delete(key string, sig Signature) bool {
+	Hs := make([]string, f.l)
+	for i := 0; i < f.l; i++ {
+		Hs[i] = f.hashKeyFunc(sig[i*f.k : (i+1)*f.k])
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	deleted := false
+	for i, hk := range Hs {
+		ht := f.hashTables[i]
+		j := sort.Search(len(ht), func(x int) bool {
+			return ht[x].hashKey >= hk
+		})
+		if j == len(ht) || ht[j].hashKey != hk {
+			continue
+		}
+		ks := make(keys, 0, len(ht[j].keys))
+		for _, k := range ht[j].keys {
+			if k != key {
+				ks = append(ks, k)
+			}
+		}
+		if len(ks) == len(ht[j].keys) {
+			continue
+		}
+		deleted = true
+		if len(ks) != 0 {
+			ht[j].keys = ks
+			continue
+		}
+		// drop the empty bucket
+		copy(ht[j:], ht[j+1:])
+		f.hashTables[i] = ht[:len(ht)-1]
+	}
+	if deleted && f.signatures != nil {
+		delete(f.signatures, key)
+	}
+	return deleted
+}
@@ -0,0 +1,224 @@
+package minhashlsh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	snapshotMagic   = "MHLSHSNP"
+	snapshotVersion = uint32(2)
+)
+
+var (
+	byteOrder = binary.BigEndian
+	// ErrBadSnapshot is returned when loading a file that is not a snapshot
+	// of the index or was written by an unknown version.
+	ErrBadSnapshot = errors.New("minhashlsh: invalid snapshot")
+)
+
+// Save writes a snapshot of the index, including the sorted hash tables
+// and the stored signatures. Keys added after the last call to Index() are
+// not included.
+func (f *MinhashLSH) Save(w io.Writer) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	header := []uint32{snapshotVersion, uint32(f.k), uint32(f.l), uint32(f.hashValueSize)}
+	if err := binary.Write(bw, byteOrder, header); err != nil {
+		return err
+	}
+	for _, ht := range f.hashTables {
+		if err := binary.Write(bw, byteOrder, uint64(len(ht))); err != nil {
+			return err
+		}
+		for _, b := range ht {
+			if err := writeString(bw, b.hashKey); err != nil {
+				return err
+			}
+			if err := binary.Write(bw, byteOrder, uint64(len(b.keys))); err != nil {
+				return err
+			}
+			for _, key := range b.keys {
+				if err := writeString(bw, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := writeSignatures(bw, f.signatures); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Load reads an index from a snapshot written by Save.
+// The index is searchable right away.
+func Load(r io.Reader) (*MinhashLSH, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrBadSnapshot
+	}
+	header := make([]uint32, 4)
+	if err := binary.Read(br, byteOrder, header); err != nil {
+		return nil, err
+	}
+	// version 1 snapshots have no stored signatures
+	version := header[0]
+	if version != 1 && version != snapshotVersion {
+		return nil, ErrBadSnapshot
+	}
+	k, l, hashValueSize := int(header[1]), int(header[2]), int(header[3])
+	f := &MinhashLSH{
+		k:              k,
+		l:              l,
+		hashValueSize:  hashValueSize,
+		initHashTables: make([]initHashTable, l),
+		hashTables:     make([]hashTable, l),
+		hashKeyFunc:    hashKeyFuncGen(hashValueSize),
+	}
+	for i := range f.hashTables {
+		f.initHashTables[i] = make(initHashTable)
+		var numBuckets uint64
+		if err := binary.Read(br, byteOrder, &numBuckets); err != nil {
+			return nil, err
+		}
+		ht := make(hashTable, numBuckets)
+		for j := range ht {
+			hashKey, err := readString(br)
+			if err != nil {
+				return nil, err
+			}
+			var numKeys uint64
+			if err := binary.Read(br, byteOrder, &numKeys); err != nil {
+				return nil, err
+			}
+			ks := make(keys, numKeys)
+			for x := range ks {
+				if ks[x], err = readString(br); err != nil {
+					return nil, err
+				}
+			}
+			ht[j] = bucket{
+				hashKey: hashKey,
+				keys:    ks,
+			}
+		}
+		f.hashTables[i] = ht
+	}
+	if version == snapshotVersion {
+		signatures, err := readSignatures(br)
+		if err != nil {
+			return nil, err
+		}
+		f.signatures = signatures
+	}
+	return f, nil
+}
+
+// writeSignatures writes the number of signatures, or -1 if they are not
+// stored, followed by the keys and the signatures in the order of the keys.
+func writeSignatures(w io.Writer, signatures map[string]Signature) error {
+	if signatures == nil {
+		return binary.Write(w, byteOrder, int64(-1))
+	}
+	keys := make([]string, 0, len(signatures))
+	for key := range signatures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if err := binary.Write(w, byteOrder, int64(len(keys))); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		sig := signatures[key]
+		if err := writeString(w, key); err != nil {
+			return err
+		}
+		if err := binary.Write(w, byteOrder, uint32(len(sig))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, byteOrder, []uint64(sig)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSignatures(r io.Reader) (map[string]Signature, error) {
+	var n int64
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, nil
+	}
+	signatures := make(map[string]Signature, n)
+	for i := int64(0); i < n; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		var size uint32
+		if err := binary.Read(r, byteOrder, &size); err != nil {
+			return nil, err
+		}
+		sig := make([]uint64, size)
+		if err := binary.Read(r, byteOrder, sig); err != nil {
+			return nil, err
+		}
+		signatures[key] = sig
+	}
+	return signatures, nil
+}
+
+// SaveFile writes a snapshot of the index to filename.
+func (f *MinhashLSH) SaveFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := f.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile reads an index from the snapshot in filename.
+func LoadFile(filename string) (*MinhashLSH, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := binary.Write(w, byteOrder, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint32
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,64 @@
+package minhashlsh
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func Test_Snapshot(t *testing.T) {
+	f := NewMinhashLSH32(64, 0.5)
+	sigs := make([]Signature, 100)
+	for i := range sigs {
+		sigs[i] = randomSignature(64, int64(i%10))
+		f.Add(strconv.Itoa(i), sigs[i])
+	}
+	f.Index()
+	buf := new(bytes.Buffer)
+	if err := f.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.k != f.k || g.l != f.l || g.hashValueSize != f.hashValueSize {
+		t.Fatal("parameters do not match")
+	}
+	if !reflect.DeepEqual(f.hashTables, g.hashTables) {
+		t.Fatal("hash tables do not match")
+	}
+	for _, sig := range sigs[:10] {
+		if len(f.Query(sig)) != len(g.Query(sig)) {
+			t.Fail()
+		}
+	}
+	if _, err := Load(bytes.NewBufferString("not a snapshot")); err != ErrBadSnapshot {
+		t.Fail()
+	}
+}
+
+func Test_SnapshotSignatures(t *testing.T) {
+	f := NewMinhashLSH32(64, 0.5)
+	f.StoreSignatures()
+	sig := randomSignature(64, 1)
+	f.Add("a", sig)
+	f.Add("b", randomSignature(64, 2))
+	f.Index()
+	buf := new(bytes.Buffer)
+	if err := f.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.signatures, g.signatures) {
+		t.Fatal("stored signatures do not match")
+	}
+	// the candidates of the loaded index are scored
+	if result := g.QueryTopK(sig, 1, 0.5); len(result) != 1 || result[0].Key != "a" {
+		t.Fatalf("top-k of the loaded index is %v", result)
+	}
+}
@@ -0,0 +1,37 @@
+package minhashlsh
+
+// BucketStats summarizes the sizes of the buckets of the hash tables
+// of an index.
+type BucketStats struct {
+	NumBuckets int
+	MaxSize    int
+	MeanSize   float64
+	// the average number of keys sharing a bucket with a key, i.e. the
+	// candidates of a key in a table at the full prefix
+	MeanCandidates float64
+}
+
+// BucketStats returns the statistics of the buckets of the indexed keys.
+func (f *MinhashLSH) BucketStats() BucketStats {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	var stats BucketStats
+	var total, squares int
+	for _, ht := range f.hashTables {
+		for _, b := range ht {
+			size := len(b.keys)
+			stats.NumBuckets += 1
+			if size > stats.MaxSize {
+				stats.MaxSize = size
+			}
+			total += size
+			squares += size * size
+		}
+	}
+	if stats.NumBuckets == 0 {
+		return stats
+	}
+	stats.MeanSize = float64(total) / float64(stats.NumBuckets)
+	stats.MeanCandidates = float64(squares) / float64(total)
+	return stats
+}
@@ -0,0 +1,27 @@
+package minhashlsh
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_BucketStats(t *testing.T) {
+	f := NewMinhashLSH16(64, 0.5)
+	if stats := f.BucketStats(); stats.NumBuckets != 0 {
+		t.Fatalf("%d buckets in an empty index", stats.NumBuckets)
+	}
+	sig := randomSignature(64, 1)
+	for i := 0; i < 4; i++ {
+		f.Add(strconv.Itoa(i), sig)
+	}
+	f.Add("other", randomSignature(64, 2))
+	f.Index()
+	stats := f.BucketStats()
+	if stats.NumBuckets != 2*f.l || stats.MaxSize != 4 {
+		t.Fatalf("stats %+v", stats)
+	}
+	// the candidates of 4 keys in a bucket of 4 and of 1 key alone
+	if stats.MeanCandidates != 17.0/5.0 {
+		t.Fatalf("mean candidates %f", stats.MeanCandidates)
+	}
+}
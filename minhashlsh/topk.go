@@ -0,0 +1,82 @@
+package minhashlsh
+
+import (
+	"sort"
+)
+
+// ScoredKey is a key with the Jaccard similarity of its set to the query
+// set, estimated from the full MinHash signatures.
+type ScoredKey struct {
+	Key   string
+	Score float64
+}
+
+// StoreSignatures keeps the signatures of the keys added or inserted from
+// now on, so that candidates can be scored without reading them back. The
+// signatures are not copied and must not be modified. They are saved in
+// snapshots.
+func (f *MinhashLSH) StoreSignatures() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.signatures == nil {
+		f.signatures = make(map[string]Signature)
+	}
+}
+
+func (f *MinhashLSH) storeSignature(key string, sig Signature) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.signatures != nil {
+		f.signatures[key] = sig
+	}
+}
+
+// Signature returns the stored signature of a key.
+func (f *MinhashLSH) Signature(key string) (Signature, bool) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	sig, ok := f.signatures[key]
+	return sig, ok
+}
+
+// QueryTopK returns the candidates of Query with an estimated Jaccard
+// similarity to the signature of at least threshold, in the order of
+// decreasing similarity. At most n keys are returned, or all of them if n
+// is 0. Candidates without a stored signature are skipped.
+func (f *MinhashLSH) QueryTopK(sig Signature, n int, threshold float64) []ScoredKey {
+	result := make([]ScoredKey, 0)
+	for _, key := range f.Query(sig) {
+		candidate, ok := f.Signature(key)
+		if !ok {
+			continue
+		}
+		if score := jaccard(sig, candidate); score >= threshold {
+			result = append(result, ScoredKey{Key: key, Score: score})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Key < result[j].Key
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// jaccard estimates the Jaccard similarity of two sets as the fraction of
+// equal hash values in their signatures.
+func jaccard(x, y Signature) float64 {
+	if len(x) == 0 || len(x) != len(y) {
+		return 0
+	}
+	equal := 0
+	for i := range x {
+		if x[i] == y[i] {
+			equal += 1
+		}
+	}
+	return float64(equal) / float64(len(x))
+}
@@ -0,0 +1,41 @@
+package minhashlsh
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_QueryTopK(t *testing.T) {
+	f := NewMinhashLSH16(64, 0.5)
+	f.StoreSignatures()
+	sig := randomSignature(64, 1000)
+	f.Add("query", sig)
+	// signatures sharing the first 64-8i hash values with the query
+	for i := 1; i < 8; i++ {
+		near := randomSignature(64, int64(i))
+		copy(near, sig[:64-8*i])
+		f.Add(strconv.Itoa(i), near)
+	}
+	f.Index()
+	result := f.QueryTopK(sig, 3, 0.5)
+	if len(result) != 3 {
+		t.Fatalf("%d results", len(result))
+	}
+	expected := []ScoredKey{{"query", 1.0}, {"1", 0.875}, {"2", 0.75}}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("result %d is %v, expected %v", i, result[i], expected[i])
+		}
+	}
+	for _, r := range f.QueryTopK(sig, 0, 0.5) {
+		if r.Score < 0.5 {
+			t.Fatal("result below the threshold")
+		}
+	}
+	if !f.Delete("query", sig) {
+		t.Fatal("unable to delete the query")
+	}
+	if _, ok := f.Signature("query"); ok {
+		t.Fatal("the signature of the deleted key is still stored")
+	}
+}
@@ -0,0 +1,99 @@
+package minhashlsh
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// WeightedMinhash is a MinHash of a weighted set computed with Improved
+// Consistent Weighted Sampling (Ioffe, ICDM 2010). The probability that
+// two signatures agree on a hash value is the weighted Jaccard similarity
+// sum(min(w_a, w_b)) / sum(max(w_a, w_b)) of the sets.
+type WeightedMinhash struct {
+	salt []byte
+	// the smallest log(a) of each hash function and the sample it picked
+	mins []float64
+	sig  []uint64
+}
+
+// NewWeightedMinhash initializes a weighted MinHash object with a seed and
+// the number of hash functions.
+func NewWeightedMinhash(seed, numHash int) *WeightedMinhash {
+	r := rand.New(rand.NewSource(int64(seed)))
+	salt := make([]byte, hashValueSize)
+	binary.BigEndian.PutUint64(salt, uint64(r.Int63()))
+	m := &WeightedMinhash{
+		salt: salt,
+		mins: make([]float64, numHash),
+		sig:  make([]uint64, numHash),
+	}
+	for i := range m.mins {
+		m.mins[i] = math.Inf(1)
+		m.sig[i] = math.MaxUint64
+	}
+	return m
+}
+
+// Push adds a value with its weight to the weighted MinHash object.
+// Each distinct value should be pushed once with its total weight.
+// Values with a non-positive weight are not in the set.
+func (m *WeightedMinhash) Push(b []byte, weight float64) {
+	if weight <= 0 {
+		return
+	}
+	h := fnv.New64a()
+	h.Write(m.salt)
+	h.Write(b)
+	key := h.Sum64()
+	logWeight := math.Log(weight)
+	for i := range m.mins {
+		// the random variables of the value for hash function i
+		state := key ^ (uint64(i+1) * 0x9e3779b97f4a7c15)
+		r := gamma2(&state)
+		c := gamma2(&state)
+		beta := uniform(&state)
+		t := math.Floor(logWeight/r + beta)
+		// log(a) with y = exp(r(t - beta)) and a = c / (y exp(r))
+		logA := math.Log(c) - r*(t-beta) - r
+		if logA < m.mins[i] {
+			m.mins[i] = logA
+			state = key ^ uint64(int64(t))*0xbf58476d1ce4e5b9
+			m.sig[i] = splitmix64(&state)
+		}
+	}
+}
+
+// Signature exports the weighted MinHash signature, where each hash value
+// identifies the sampled value and its quantized weight.
+func (m *WeightedMinhash) Signature() []uint64 {
+	sig := make([]uint64, len(m.sig))
+	copy(sig, m.sig)
+	return sig
+}
+
+// NewWeightedMinhashLSH creates an LSH index of weighted MinHash signatures.
+// The signatures are banded like the ones of MinHash, so the threshold is
+// a weighted Jaccard similarity.
+func NewWeightedMinhashLSH(numHash int, threshold float64) *MinhashLSH {
+	return NewMinhashLSH32(numHash, threshold)
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// uniform returns a value in (0, 1).
+func uniform(state *uint64) float64 {
+	return (float64(splitmix64(state)>>11) + 0.5) / (1 << 53)
+}
+
+// gamma2 returns a Gamma(2, 1) value.
+func gamma2(state *uint64) float64 {
+	return -math.Log(uniform(state) * uniform(state))
+}
@@ -0,0 +1,109 @@
+package minhashlsh
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func weightedSet(r *rand.Rand, size int) map[string]float64 {
+	s := make(map[string]float64)
+	for i := 0; i < size; i++ {
+		s[fmt.Sprintf("value%d", i)] = r.ExpFloat64()
+	}
+	return s
+}
+
+func weightedJaccard(a, b map[string]float64) float64 {
+	var num, den float64
+	for v, wa := range a {
+		wb := b[v]
+		num += math.Min(wa, wb)
+		den += math.Max(wa, wb)
+	}
+	for v, wb := range b {
+		if _, ok := a[v]; !ok {
+			den += wb
+		}
+	}
+	return num / den
+}
+
+func weightedSignature(s map[string]float64, numHash int) []uint64 {
+	m := NewWeightedMinhash(1, numHash)
+	for v, w := range s {
+		m.Push([]byte(v), w)
+	}
+	return m.Signature()
+}
+
+func Test_WeightedMinhash(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a := weightedSet(r, 200)
+	for _, scale := range []float64{0.0, 0.3, 1.0} {
+		// b has the values of a with perturbed weights and new values
+		b := make(map[string]float64)
+		for v, w := range a {
+			b[v] = w * math.Exp(scale*r.NormFloat64())
+		}
+		for v, w := range weightedSet(r, 50) {
+			b["new"+v] = w
+		}
+		sigA := weightedSignature(a, 512)
+		sigB := weightedSignature(b, 512)
+		agree := 0
+		for i := range sigA {
+			if sigA[i] == sigB[i] {
+				agree++
+			}
+		}
+		estimate := float64(agree) / float64(len(sigA))
+		exact := weightedJaccard(a, b)
+		if math.Abs(estimate-exact) > 0.07 {
+			t.Errorf("scale %.1f: estimated %.3f, exact weighted jaccard %.3f", scale, estimate, exact)
+		}
+	}
+	// the signature depends on the weights, not the order of pushes
+	c := NewWeightedMinhash(1, 64)
+	c.Push([]byte("x"), 2.0)
+	c.Push([]byte("y"), 1.0)
+	d := NewWeightedMinhash(1, 64)
+	d.Push([]byte("y"), 1.0)
+	d.Push([]byte("x"), 2.0)
+	d.Push([]byte("z"), 0.0)
+	sigC, sigD := c.Signature(), d.Signature()
+	for i := range sigC {
+		if sigC[i] != sigD[i] {
+			t.Fatal("signatures of the same weighted set differ")
+		}
+	}
+}
+
+func Test_WeightedMinhashLSH(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	query := weightedSet(r, 100)
+	f := NewWeightedMinhashLSH(256, 0.5)
+	// near has the weights of the query scaled up a little
+	near := make(map[string]float64)
+	for v, w := range query {
+		near[v] = w * 1.1
+	}
+	f.Add("near", weightedSignature(near, 256))
+	far := make(map[string]float64)
+	for v, w := range weightedSet(r, 100) {
+		far["other"+v] = w
+	}
+	f.Add("far", weightedSignature(far, 256))
+	f.Index()
+	found := make(map[string]bool)
+	for _, key := range f.Query(weightedSignature(query, 256)) {
+		found[key] = true
+	}
+	if !found["near"] {
+		t.Error("the near weighted set is not found")
+	}
+	if found["far"] {
+		t.Error("the far weighted set is found")
+	}
+}
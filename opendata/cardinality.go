@@ -0,0 +1,92 @@
+package opendata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/RJMillerLab/table-union/hll"
+)
+
+// The cardinality sketch follows the minhash signature in a sketch file as
+// the registers of the sketch, the precision and this magic.
+var cardinalityMagic = []byte("HLL1")
+
+var ErrNoCardinalitySketch = errors.New("no cardinality sketch in the sketch file")
+
+func writeCardinalitySketch(w io.Writer, card *hll.HLL) error {
+	registers := card.Registers()
+	if _, err := w.Write(registers); err != nil {
+		return err
+	}
+	p := 0
+	for 1<<uint(p) < len(registers) {
+		p++
+	}
+	if _, err := w.Write([]byte{byte(p)}); err != nil {
+		return err
+	}
+	_, err := w.Write(cardinalityMagic)
+	return err
+}
+
+// ReadCardinalitySketch reads the cardinality sketch saved after the
+// minhash signature in a sketch file by WriteMinhashSignature.
+func (r *Repository) ReadCardinalitySketch(filename string) (*hll.HLL, error) {
+	card, err := r.cachedSketch(sketchKey(filename, "card"), func() (interface{}, error) {
+		return readCardinalitySketch(filename)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card.(*hll.HLL), nil
+}
+
+func readCardinalitySketch(filename string) (*hll.HLL, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	trailer := make([]byte, 1+len(cardinalityMagic))
+	if size < int64(len(trailer)) {
+		return nil, ErrNoCardinalitySketch
+	}
+	if _, err := f.ReadAt(trailer, size-int64(len(trailer))); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(trailer[1:], cardinalityMagic) || trailer[0] > 16 {
+		return nil, ErrNoCardinalitySketch
+	}
+	// the signature before the registers is a whole number of hash values
+	m := int64(1) << trailer[0]
+	start := size - int64(len(trailer)) - m
+	if start < 0 || start%8 != 0 {
+		return nil, ErrNoCardinalitySketch
+	}
+	registers := make([]uint8, m)
+	if _, err := f.ReadAt(registers, start); err != nil {
+		return nil, err
+	}
+	return hll.FromRegisters(registers)
+}
+
+// ReadCardinality returns the estimated number of distinct values of a
+// domain from the cardinality sketch of a sketch file, or from the text
+// file of the cardinality for the sketch files without one. It returns -1
+// if neither is found.
+func (r *Repository) ReadCardinality(sketchFilename, cardFilename string) int {
+	if card, err := r.ReadCardinalitySketch(sketchFilename); err == nil {
+		return int(card.Count() + 0.5)
+	}
+	card, _ := r.cachedSketch(cardFilename, func() (interface{}, error) {
+		return readDomainCardinality(cardFilename), nil
+	})
+	return card.(int)
+}
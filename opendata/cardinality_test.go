@@ -0,0 +1,59 @@
+package opendata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_CardinalitySketch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cardinality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	column := make([]string, 0)
+	for i := 0; i < 300; i++ {
+		// 100 distinct values
+		column = append(column, fmt.Sprintf("Value%d", i%100))
+	}
+	od := NewRepository(&Config{})
+	sig, card := od.GetDomainSketch(column, 256)
+	filename := path.Join(dir, "0.minhash")
+	if err := WriteMinhashSignature(sig, card, filename); err != nil {
+		t.Fatal(err)
+	}
+	read, err := od.ReadMinhashSignature(filename, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimateJaccard(sig, read) != 1.0 {
+		t.Fatal("the signature changed")
+	}
+	if n := od.ReadCardinality(filename, path.Join(dir, "0.card")); n < 95 || n > 105 {
+		t.Fatalf("cardinality %d of 100 values", n)
+	}
+	// the files of the signature only fall back to the text files
+	filename = path.Join(dir, "1.minhash")
+	if err := WriteMinhashSignature(sig, nil, filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := od.ReadCardinalitySketch(filename); err != ErrNoCardinalitySketch {
+		t.Fatalf("got %v for a signature without cardinality sketch", err)
+	}
+	// the signature of a missing domain dir is not saved
+	if err := WriteMinhashSignature(sig, card, path.Join(dir, "missing", "0.minhash")); err == nil {
+		t.Fatal("saved a signature to a missing dir")
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "1.card"), []byte("42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n := od.ReadCardinality(filename, path.Join(dir, "1.card")); n != 42 {
+		t.Fatalf("cardinality %d from the text file", n)
+	}
+	if n := od.ReadCardinality(path.Join(dir, "2.minhash"), path.Join(dir, "2.card")); n != -1 {
+		t.Fatalf("cardinality %d of a missing domain", n)
+	}
+}
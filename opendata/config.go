@@ -0,0 +1,170 @@
+package opendata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Config locates the input and output files of a repository and the tables
+// of its databases. The keys of a configuration file are the names of the
+// environment variables read by ConfigFromEnv in lower case, e.g.
+// "output_dir" for OUTPUT_DIR. The check tag marks the input files and
+// directories that must be readable, and the numbers that must parse, if
+// set.
+type Config struct {
+	OpendataDir              string `json:"opendata_dir" check:"dir"`
+	OpendataList             string `json:"opendata_list" check:"file"`
+	OutputDir                string `json:"output_dir" check:"dir"`
+	YagoDB                   string `json:"yago_db" check:"file"`
+	FastTextDB               string `json:"fasttext_db" check:"file"`
+	SarmaFilename            string `json:"sarma_scores"`
+	JaccardFilename          string `json:"jaccard_scores"`
+	ContainmentFilename      string `json:"containment_scores"`
+	CosineFilename           string `json:"cosine_scores"`
+	ColumnUnionabilityTable  string `json:"column_unionability_table"`
+	DatasetUnionabilityTable string `json:"dataset_unionability_table"`
+	ColumnUnionabilityDB     string `json:"column_unionability_db"`
+	DatasetUnionabilityDB    string `json:"dataset_unionability_db"`
+	QueryList                string `json:"query_list" check:"file"`
+	QueryList2               string `json:"query_list2" check:"file"`
+	Measure                  string `json:"measure"`
+	Threshold                string `json:"threshold"`
+	AnnotationDB             string `json:"annotation_db"`
+	SubjectAnnotationTable   string `json:"subject_annotation_table"`
+	AllAnnotationTable       string `json:"all_annotation_table"`
+	SarmaTable               string `json:"sarma_table"`
+	SarmaDB                  string `json:"sarma_db"`
+	AllAnnotationTableL2     string `json:"all_annotation_table_l2"`
+	OctopusTable             string `json:"octopus_table"`
+	OctopusDB                string `json:"octopus_db"`
+	AttStatsTable            string `json:"att_stats_table"`
+	AttStatsDB               string `json:"att_stats_db"`
+	TableStatsTable          string `json:"table_stats_table"`
+	TableStatsDB             string `json:"table_stats_db"`
+	CTableStatsDB            string `json:"c_table_stats_db"`
+	CTableStatsTable         string `json:"c_table_stats_table"`
+	AttCDFTable              string `json:"att_cdf_table"`
+	TableCDFTable            string `json:"table_cdf_table"`
+	AllAttStatsTable         string `json:"all_att_stats_table"`
+	SetCDFTable              string `json:"set_cdf_table"`
+	WSetCDFTable             string `json:"wset_cdf_table"`
+	SemCDFTable              string `json:"sem_cdf_table"`
+	SemSetCDFTable           string `json:"semset_cdf_table"`
+	NlCDFTable               string `json:"nl_cdf_table"`
+	NumCDFTable              string `json:"num_cdf_table"`
+	HeaderCDFTable           string `json:"header_cdf_table"`
+	AllAttPercentileTable    string `json:"all_att_percentile_table"`
+	AttStitchingDB           string `json:"att_stitching_db"`
+	AttStitchingTable        string `json:"att_stitching_table"`
+	TableStitchingDB         string `json:"table_stitching_db"`
+	TableStitchingTable      string `json:"table_stitching_table"`
+	StopValuesFile           string `json:"stop_values" check:"file"`
+	NumHash                  string `json:"num_hash" check:"int"`
+	ExperimentDB             string `json:"experiment_db" check:"file"`
+	ExperimentTable          string `json:"experiment_table"`
+	SharedDB                 string `json:"shared_db" check:"file"`
+	SharedTable              string `json:"shared_table"`
+	ExpansionDB              string `json:"expansion_db"`
+	ExpansionTable           string `json:"expansion_table"`
+}
+
+// ConfigFromEnv reads a configuration from the environment variables.
+func ConfigFromEnv() *Config {
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("json")
+		v.Field(i).SetString(os.Getenv(strings.ToUpper(key)))
+	}
+	return cfg
+}
+
+// LoadConfig reads a configuration from a JSON file. Unknown keys are
+// reported as errors, so that a misspelled setting is not left unset.
+func LoadConfig(filename string) (*Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg := &Config{}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return cfg, nil
+}
+
+// ConfigError lists the problems found in a configuration.
+type ConfigError []string
+
+func (e ConfigError) Error() string {
+	return "invalid configuration:\n" + strings.Join(e, "\n")
+}
+
+// Validate checks that the settings of the given keys are set, and that
+// the input files and directories that are set can be read. It returns a
+// ConfigError listing every problem found, or nil.
+func (cfg *Config) Validate(required ...string) error {
+	var problems ConfigError
+	settings := make(map[string]string)
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, value := field.Tag.Get("json"), v.Field(i).String()
+		settings[key] = value
+		if value == "" {
+			continue
+		}
+		if err := checkPath(value, field.Tag.Get("check")); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, err.Error()))
+		}
+	}
+	for _, key := range required {
+		value, ok := settings[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown setting", key))
+		} else if value == "" {
+			problems = append(problems, fmt.Sprintf("%s: not set", key))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// Checks that a file or a directory, depending on kind, can be read, or
+// that a number parses.
+func checkPath(filename, kind string) error {
+	if kind == "" {
+		return nil
+	}
+	if kind == "int" {
+		if n, err := strconv.Atoi(filename); err != nil || n <= 0 {
+			return fmt.Errorf("%s is not a positive integer", filename)
+		}
+		return nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if kind == "dir" && !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", filename)
+	}
+	if kind == "file" && info.IsDir() {
+		return fmt.Errorf("%s is a directory", filename)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package opendata
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_LoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := path.Join(dir, "config.json")
+	content := `{"output_dir": "` + dir + `", "set_cdf_table": "set_cdf", "yago_db": "` + path.Join(dir, "yago.sqlite") + `"}`
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputDir != dir || cfg.SetCDFTable != "set_cdf" {
+		t.Fatalf("config %+v", cfg)
+	}
+	// the missing YAGO database and the required settings are all reported
+	err = cfg.Validate("output_dir", "opendata_list", "att_stats_db")
+	problems, ok := err.(ConfigError)
+	if !ok || len(problems) != 3 {
+		t.Fatalf("got %v", err)
+	}
+	if err := cfg.Validate("output_dir"); err == nil {
+		t.Fatal("unreadable YAGO database")
+	}
+	cfg.YagoDB = ""
+	if err := cfg.Validate("output_dir"); err != nil {
+		t.Fatal(err)
+	}
+	cfg.NumHash = "many"
+	if err := cfg.Validate("output_dir"); err == nil {
+		t.Fatal("invalid number of hash functions")
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(`{"ouptut_dir": "/tmp"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(filename); err == nil {
+		t.Fatal("misspelled setting")
+	}
+}
+
+func Test_NewRepository(t *testing.T) {
+	t.Setenv("SET_CDF_TABLE", "env_set_cdf")
+	if cfg := ConfigFromEnv(); cfg.SetCDFTable != "env_set_cdf" {
+		t.Fatalf("config %+v", cfg)
+	}
+	// the repositories of a process do not share their configuration
+	od1 := NewRepository(&Config{
+		OutputDir:   "/repository1",
+		SetCDFTable: "set_cdf1",
+	})
+	od2 := NewRepository(&Config{
+		OutputDir:   "/repository2",
+		SetCDFTable: "set_cdf2",
+		NumHash:     "128",
+	})
+	if filename := od1.DomainFilename("t", 0, "values"); filename != "/repository1/domains/t/0.values" {
+		t.Fatalf("values file %s", filename)
+	}
+	if filename := od2.DomainFilename("t", 0, "values"); filename != "/repository2/domains/t/0.values" {
+		t.Fatalf("values file %s", filename)
+	}
+	if table := od1.GetMeasure("set").CDFTable(); table != "set_cdf1" {
+		t.Fatalf("CDF table %s", table)
+	}
+	if table := od2.GetMeasure("set").CDFTable(); table != "set_cdf2" {
+		t.Fatalf("CDF table %s", table)
+	}
+	if od1.MinhashSize() != 256 || od2.MinhashSize() != 128 {
+		t.Fatalf("%d and %d hash functions", od1.MinhashSize(), od2.MinhashSize())
+	}
+}
@@ -6,72 +6,91 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/yago"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 const PARALLEL = 64
 const MIN_DOMSIZE = 5
 
-// Environment variables required to
-// locate the necessary input files
-var OpendataDir = os.Getenv("OPENDATA_DIR")
-var OpendataList = os.Getenv("OPENDATA_LIST")
+// Repository reads and writes the tables, the domains and the databases
+// located by a configuration. Several repositories can be used by a
+// process.
+type Repository struct {
+	*Config
+	// the registered unionability measures
+	measures []UnionabilityMeasure
+	// the stop values are read from StopValuesFile on first use
+	stopValues     map[string]bool
+	stopValuesOnce sync.Once
+	// set by InitAnnotator or ResumeAnnotator
+	entityToClass map[string][]string
+	// set by Init
+	entityWords      map[string]map[string]bool
+	entityWordCounts map[string]int
+	// the annotations of the tables are saved one table at a time
+	annotationLock sync.Mutex
+	// the number of hash functions of the minhash sketches, NumHash or
+	// defaultNumHash if not set
+	numHash int
+	// the cache of the sketch files, nil if disabled
+	sketches *sketchCache
+	// the models sketched by the measures, set by SetFastText and
+	// SetOntology
+	ft          *embedding.FastText
+	yago        *yago.Yago
+	yagoClasses map[string][]string
+}
 
-// Environment variable required to
-// write output
-var OutputDir = os.Getenv("OUTPUT_DIR")
+const defaultNumHash = 256
 
-// Environment variable for the Yago database
-var Yago_db = os.Getenv("YAGO_DB")
+// NewRepository creates a repository of a configuration, with the built-in
+// unionability measures registered.
+func NewRepository(cfg *Config) *Repository {
+	r := &Repository{
+		Config:   cfg,
+		measures: make([]UnionabilityMeasure, 0),
+		numHash:  defaultNumHash,
+	}
+	if n, err := strconv.Atoi(cfg.NumHash); err == nil && n > 0 {
+		r.numHash = n
+	}
+	r.registerMeasures()
+	return r
+}
 
-// Environment variable for domain pairs unionability scores
-var SarmaFilename = os.Getenv("SARMA_SCORES")
-var JaccardFilename = os.Getenv("JACCARD_SCORES")
-var ContainmentFilename = os.Getenv("CONTAINMENT_SCORES")
-var CosineFilename = os.Getenv("COSINE_SCORES")
-var ColumnUnionabilityTable = os.Getenv("COLUMN_UNIONABILITY_TABLE")
-var DatasetUnionabilityTable = os.Getenv("DATASET_UNIONABILITY_TABLE")
-var ColumnUnionabilityDB = os.Getenv("COLUMN_UNIONABILITY_DB")
-var DatasetUnionabilityDB = os.Getenv("DATASET_UNIONABILITY_DB")
-var QueryList = os.Getenv("QUERY_LIST")
-var QueryList2 = os.Getenv("QUERY_LIST2")
-var Measure = os.Getenv("MEASURE")
-var Threshold = os.Getenv("THRESHOLD")
-var AnnotationDB = os.Getenv("ANNOTATION_DB")
-var SubjectAnnotationTable = os.Getenv("SUBJECT_ANNOTATION_TABLE")
-var AllAnnotationTable = os.Getenv("ALL_ANNOTATION_TABLE")
-var SarmaTable = os.Getenv("SARMA_TABLE")
-var SarmaDB = os.Getenv("SARMA_DB")
-var AllAnnotationTableL2 = os.Getenv("ALL_ANNOTATION_TABLE_L2")
-var OctopusTable = os.Getenv("OCTOPUS_TABLE")
-var OctopusDB = os.Getenv("OCTOPUS_DB")
-var AttStatsTable = os.Getenv("ATT_STATS_TABLE")
-var AttStatsDB = os.Getenv("ATT_STATS_DB")
-var TableStatsTable = os.Getenv("TABLE_STATS_TABLE")
-var TableStatsDB = os.Getenv("TABLE_STATS_DB")
-var CTableStatsDB = os.Getenv("C_TABLE_STATS_DB")
-var CTableStatsTable = os.Getenv("C_TABLE_STATS_TABLE")
-var AttCDFTable = os.Getenv("ATT_CDF_TABLE")
-var TableCDFTable = os.Getenv("TABLE_CDF_TABLE")
-var AllAttStatsTable = os.Getenv("ALL_ATT_STATS_TABLE")
-var SetCDFTable = os.Getenv("SET_CDF_TABLE")
-var SemCDFTable = os.Getenv("SEM_CDF_TABLE")
-var SemSetCDFTable = os.Getenv("SEMSET_CDF_TABLE")
-var NlCDFTable = os.Getenv("NL_CDF_TABLE")
-var AllAttPercentileTable = os.Getenv("ALL_ATT_PERCENTILE_TABLE")
-var AttStitchingDB = os.Getenv("ATT_STITCHING_DB")
-var AttStitchingTable = os.Getenv("ATT_STITCHING_TABLE")
-var TableStitchingDB = os.Getenv("TABLE_STITCHING_DB")
-var TableStitchingTable = os.Getenv("TABLE_STITCHING_TABLE")
+// MinhashSize returns the number of hash functions of the minhash sketches
+// of the repository.
+func (r *Repository) MinhashSize() int {
+	return r.numHash
+}
 
-func CheckEnv() {
+// OpenRepository reads the configuration file, or the environment variables
+// if configFile is empty, and panics listing every required setting that is
+// not set and every input file or directory that cannot be read.
+func OpenRepository(configFile string, required ...string) *Repository {
+	cfg := ConfigFromEnv()
+	if configFile != "" {
+		var err error
+		cfg, err = LoadConfig(configFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if err := cfg.Validate(required...); err != nil {
+		panic(err)
+	}
+	return NewRepository(cfg)
 }
 
-func Filepath(filename string) string {
-	return path.Join(OpendataDir, filename)
+func (r *Repository) Filepath(filename string) string {
+	return path.Join(r.OpendataDir, filename)
 }
 
 func GetNow() float64 {
@@ -86,9 +105,9 @@ func GenericStrings(s []string) []interface{} {
 	return a
 }
 
-func LoadEntityWords() map[string]map[string]bool {
+func (r *Repository) LoadEntityWords() map[string]map[string]bool {
 	lookup := make(map[string]map[string]bool)
-	f, err := os.Open(path.Join(OutputDir, "word-entity.txt"))
+	f, err := os.Open(path.Join(r.OutputDir, "word-entity.txt"))
 	if err != nil {
 		panic(err)
 	}
@@ -111,9 +130,9 @@ func LoadEntityWords() map[string]map[string]bool {
 	}
 	return lookup
 }
-func LoadEntityWordCount() map[string]int {
+func (r *Repository) LoadEntityWordCount() map[string]int {
 	counts := make(map[string]int)
-	db, err := sql.Open("sqlite3", Yago_db)
+	db, err := sql.Open("sqlite3", r.YagoDB)
 	if err != nil {
 		panic(err)
 	}
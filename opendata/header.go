@@ -0,0 +1,284 @@
+package opendata
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/RJMillerLab/table-union/embedding"
+)
+
+// common abbreviations in the headers of open data tables
+var headerAbbreviations = map[string]string{
+	"abbr": "abbreviation",
+	"addr": "address",
+	"amt":  "amount",
+	"avg":  "average",
+	"cd":   "code",
+	"cnt":  "count",
+	"co":   "company",
+	"ctry": "country",
+	"cty":  "city",
+	"dept": "department",
+	"desc": "description",
+	"dist": "district",
+	"dt":   "date",
+	"emp":  "employee",
+	"gov":  "government",
+	"id":   "identifier",
+	"lat":  "latitude",
+	"lng":  "longitude",
+	"lon":  "longitude",
+	"max":  "maximum",
+	"min":  "minimum",
+	"mun":  "municipality",
+	"nbr":  "number",
+	"no":   "number",
+	"num":  "number",
+	"org":  "organization",
+	"pct":  "percent",
+	"pop":  "population",
+	"prov": "province",
+	"qty":  "quantity",
+	"st":   "street",
+	"tel":  "telephone",
+	"yr":   "year",
+	"zip":  "postal",
+}
+
+// tokens that say nothing about the domain of a column
+var headerStopTokens = map[string]bool{
+	"name":  true,
+	"nm":    true,
+	"value": true,
+	"val":   true,
+	"label": true,
+	"text":  true,
+	"txt":   true,
+	"the":   true,
+	"of":    true,
+	"and":   true,
+	"en":    true,
+	"fr":    true,
+}
+
+// HeaderTokens splits a header on non-alphanumeric characters and camel case,
+// expands abbreviations and removes generic tokens such as "name".
+// For example, "PROV_NAME" and "provName" both become [province].
+func HeaderTokens(header string) []string {
+	words := strings.FieldsFunc(header, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0)
+	for _, word := range words {
+		for _, t := range splitCamelCase(word) {
+			t = strings.ToLower(t)
+			if e, ok := headerAbbreviations[t]; ok {
+				t = e
+			}
+			if headerStopTokens[t] {
+				continue
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// splitCamelCase splits a word before an upper case letter that follows
+// a lower case letter, and between letters and digits.
+func splitCamelCase(word string) []string {
+	runes := []rune(word)
+	parts := make([]string, 0)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		if (unicode.IsLower(prev) && unicode.IsUpper(cur)) || unicode.IsDigit(prev) != unicode.IsDigit(cur) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// tokensMatch matches equal tokens and truncated tokens, e.g. "prov" and
+// "province", with a shared prefix of at least three letters.
+func tokensMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return len(a) >= 3 && strings.HasPrefix(b, a)
+}
+
+// headerTokenSimilarity is the fraction of the tokens of both headers that
+// match a token of the other header.
+func headerTokenSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0.0
+	}
+	return float64(countMatched(a, b)+countMatched(b, a)) / float64(len(a)+len(b))
+}
+
+func countMatched(tokens, other []string) int {
+	matched := 0
+	for _, s := range tokens {
+		for _, t := range other {
+			if tokensMatch(s, t) {
+				matched += 1
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// HeaderEmbedding returns the mean fastText vector of the tokens of a header.
+func HeaderEmbedding(ft *embedding.FastText, header string) ([]float64, error) {
+	var sum []float64
+	n := 0
+	for _, t := range HeaderTokens(header) {
+		emb, err := ft.GetEmb(t)
+		if err == embedding.ErrNoEmbFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sum == nil {
+			sum = make([]float64, len(emb))
+		}
+		for i := range emb {
+			sum[i] += emb[i]
+		}
+		n += 1
+	}
+	if n == 0 {
+		return nil, embedding.ErrNoEmbFound
+	}
+	for i := range sum {
+		sum[i] /= float64(n)
+	}
+	return sum, nil
+}
+
+func (r *Repository) getHeaderEmbFilename(tableID string, index int) string {
+	return path.Join(r.OutputDir, "domains", tableID, fmt.Sprintf("%d.header-ft", index))
+}
+
+func (r *Repository) readHeaderEmb(tableID string, index int) []float64 {
+	filename := r.getHeaderEmbFilename(tableID, index)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+	vec, err := r.readVec(filename)
+	if err != nil {
+		return nil
+	}
+	return vec
+}
+
+// readHeaderTokens returns the tokens of the header of a column. The
+// headers of a table are read and tokenized once through the sketch cache.
+func (r *Repository) readHeaderTokens(tableID string, index int) []string {
+	filename := path.Join(r.OutputDir, "domains", tableID, "index")
+	tokens, err := r.cachedSketch(sketchKey(filename, "tokens"), func() (interface{}, error) {
+		headers, err := readLines(filename, -1)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([][]string, len(headers))
+		for i, header := range headers {
+			tokens[i] = HeaderTokens(header)
+		}
+		return tokens, nil
+	})
+	if err != nil || index >= len(tokens.([][]string)) {
+		return nil
+	}
+	return tokens.([][]string)[index]
+}
+
+// Saves the embedding of the header of a column, read from the index file
+// of the table, for the header measure.
+func (r *Repository) sketchHeader(values []string, dir string, index int) error {
+	if r.ft == nil {
+		return ErrCannotSketch
+	}
+	headers, err := readLines(path.Join(dir, "index"), -1)
+	if err != nil {
+		return err
+	}
+	if index >= len(headers) {
+		return ErrNoSketch
+	}
+	vec, err := HeaderEmbedding(r.ft, headers[index])
+	if err == embedding.ErrNoEmbFound {
+		return ErrNoSketch
+	}
+	if err != nil {
+		return err
+	}
+	return embedding.WriteVecToDisk(vec, ByteOrder, sketchFilename(dir, index, "header-ft"))
+}
+
+// headerUnionability scores the headers of two columns by the matching of
+// their tokens and, if both have a header-ft vector, by the cosine
+// similarity of the vectors.
+func (r *Repository) headerUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	qTokens := r.readHeaderTokens(queryTable, queryIndex)
+	cTokens := r.readHeaderTokens(candidateTable, candIndex)
+	if len(qTokens) == 0 || len(cTokens) == 0 {
+		return -1.0
+	}
+	sim := headerTokenSimilarity(qTokens, cTokens)
+	qVec := r.readHeaderEmb(queryTable, queryIndex)
+	cVec := r.readHeaderEmb(candidateTable, candIndex)
+	if qVec != nil && cVec != nil && len(qVec) == len(cVec) {
+		cosine := embedding.Cosine(qVec, cVec)
+		if !math.IsNaN(cosine) {
+			sim = math.Max(sim, cosine)
+		}
+	}
+	return math.Max(0.0, sim)
+}
+
+// DoEmbedHeadersFromFiles saves the fastText vectors of the headers of
+// the text domains (header-ft).
+func (r *Repository) DoEmbedHeadersFromFiles(ft *embedding.FastText, fanout int, files <-chan string) <-chan ProgressCounter {
+	progress := make(chan ProgressCounter)
+	wg := &sync.WaitGroup{}
+	wg.Add(fanout)
+	for i := 0; i < fanout; i++ {
+		go func() {
+			for file := range files {
+				headers := r.getColumnNames(file)
+				for _, index := range r.getTextDomains(file) {
+					if index >= len(headers) {
+						continue
+					}
+					vec, err := HeaderEmbedding(ft, headers[index])
+					if err != nil {
+						continue
+					}
+					if err := embedding.WriteVecToDisk(vec, ByteOrder, r.getHeaderEmbFilename(file, index)); err != nil {
+						panic(err)
+					}
+					progress <- ProgressCounter{1}
+				}
+			}
+			wg.Done()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(progress)
+	}()
+	return progress
+}
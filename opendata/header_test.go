@@ -0,0 +1,63 @@
+package opendata
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func Test_HeaderTokens(t *testing.T) {
+	cases := map[string][]string{
+		"PROV_NAME":     {"province"},
+		"Province":      {"province"},
+		"provName":      {"province"},
+		"Street Addr 2": {"street", "address", "2"},
+		"Name":          {},
+	}
+	for header, want := range cases {
+		if got := HeaderTokens(header); !reflect.DeepEqual(got, want) {
+			t.Errorf("tokens of %q are %v, want %v", header, got, want)
+		}
+	}
+}
+
+func Test_HeaderTokenSimilarity(t *testing.T) {
+	if s := headerTokenSimilarity(HeaderTokens("Province"), HeaderTokens("PROV_NAME")); s != 1.0 {
+		t.Errorf("similarity of Province and PROV_NAME is %f", s)
+	}
+	if s := headerTokenSimilarity(HeaderTokens("Dept Desc"), HeaderTokens("department")); s != 2.0/3.0 {
+		t.Errorf("similarity of Dept Desc and department is %f", s)
+	}
+	if s := headerTokenSimilarity(HeaderTokens("Population"), HeaderTokens("City")); s != 0.0 {
+		t.Errorf("similarity of Population and City is %f", s)
+	}
+}
+
+func Test_headerUnionability(t *testing.T) {
+	dir, err := ioutil.TempDir("", "header")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	od := NewRepository(&Config{OutputDir: dir})
+	for table, headers := range map[string]string{"q.csv": "Province\nCity\n", "c.csv": "PROV_NAME\n"} {
+		if err := os.MkdirAll(path.Join(dir, "domains", table), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, "domains", table, "index"), []byte(headers), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u := od.headerUnionability("q.csv", "c.csv", 0, 0); u != 1.0 {
+		t.Errorf("unionability of Province and PROV_NAME is %f", u)
+	}
+	if u := od.headerUnionability("q.csv", "c.csv", 1, 0); u != 0.0 {
+		t.Errorf("unionability of City and PROV_NAME is %f", u)
+	}
+	// a column without header
+	if u := od.headerUnionability("q.csv", "c.csv", 0, 1); u != -1.0 {
+		t.Errorf("unionability of a missing header is %f", u)
+	}
+}
@@ -0,0 +1,397 @@
+package opendata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A Stage is a step of the ingestion of a table, e.g. saving the values of
+// its domains or sketching them.
+type Stage struct {
+	Name string
+	// the stages whose output the stage reads
+	After []string
+	// Run may panic, the panic is reported as the error of the stage.
+	Run func(table string) error
+}
+
+// StageStatus is the outcome of the last run of a stage for a table.
+type StageStatus struct {
+	Done     bool      `json:"done"`
+	Error    string    `json:"error,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// Manifest is the checkpoint of the ingestion of a table, saved in the
+// directory of its domains.
+type Manifest struct {
+	Table  string                  `json:"table"`
+	Stages map[string]*StageStatus `json:"stages"`
+	// the file the manifest is saved to
+	filename string
+}
+
+func (r *Repository) manifestFilename(table string) string {
+	return path.Join(r.OutputDir, "domains", table, "manifest.json")
+}
+
+// ReadManifest reads the manifest of a table, or returns an empty one if
+// the table has not been ingested.
+func (r *Repository) ReadManifest(table string) (*Manifest, error) {
+	m := &Manifest{
+		Table:    table,
+		Stages:   make(map[string]*StageStatus),
+		filename: r.manifestFilename(table),
+	}
+	content, err := ioutil.ReadFile(m.filename)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, err
+	}
+	if m.Stages == nil {
+		m.Stages = make(map[string]*StageStatus)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to a temporary file moved over the previous
+// manifest, so that a crash does not leave a partial manifest.
+func (m *Manifest) Save() error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := m.filename
+	if err := os.MkdirAll(path.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// IngestResult reports the stages of a table run, skipped because
+// completed by an earlier run, failed, or blocked by a failed stage.
+type IngestResult struct {
+	Table   string
+	Ran     []string
+	Skipped []string
+	Blocked []string
+	Failed  map[string]error
+}
+
+// Ingester runs the stages of the ingestion of tables.
+type Ingester struct {
+	od     *Repository
+	stages []Stage
+	// the stages that read the output of each stage, directly or not
+	dependents map[string][]string
+}
+
+// NewIngester creates an ingester of the tables of a repository by stages
+// listed after the stages they depend on.
+func NewIngester(od *Repository, stages []Stage) (*Ingester, error) {
+	ing := &Ingester{
+		od:         od,
+		stages:     stages,
+		dependents: make(map[string][]string),
+	}
+	seen := make(map[string]bool)
+	for _, stage := range stages {
+		if seen[stage.Name] {
+			return nil, fmt.Errorf("duplicate stage %s", stage.Name)
+		}
+		for _, dep := range stage.After {
+			if !seen[dep] {
+				return nil, fmt.Errorf("stage %s is not listed after the stage %s it depends on", stage.Name, dep)
+			}
+		}
+		seen[stage.Name] = true
+	}
+	for i, stage := range stages {
+		reached := map[string]bool{stage.Name: true}
+		for _, later := range stages[i+1:] {
+			for _, dep := range later.After {
+				if reached[dep] {
+					reached[later.Name] = true
+					ing.dependents[stage.Name] = append(ing.dependents[stage.Name], later.Name)
+					break
+				}
+			}
+		}
+	}
+	return ing, nil
+}
+
+// Ingest runs the stages of a table that are not completed in its
+// manifest. Running a stage again also runs again the stages after it.
+func (ing *Ingester) Ingest(table string) *IngestResult {
+	result := &IngestResult{
+		Table:  table,
+		Failed: make(map[string]error),
+	}
+	m, err := ing.od.ReadManifest(table)
+	if err != nil {
+		log.Printf("Starting over the ingestion of %s: %s", table, err.Error())
+		m = &Manifest{
+			Table:    table,
+			Stages:   make(map[string]*StageStatus),
+			filename: ing.od.manifestFilename(table),
+		}
+	}
+	done := make(map[string]bool)
+	for _, stage := range ing.stages {
+		ready := true
+		for _, dep := range stage.After {
+			if !done[dep] {
+				ready = false
+			}
+		}
+		if !ready {
+			result.Blocked = append(result.Blocked, stage.Name)
+			continue
+		}
+		if status, ok := m.Stages[stage.Name]; ok && status.Done {
+			done[stage.Name] = true
+			result.Skipped = append(result.Skipped, stage.Name)
+			continue
+		}
+		// the output of the stages after it is stale
+		for _, dependent := range ing.dependents[stage.Name] {
+			delete(m.Stages, dependent)
+		}
+		err := runStage(stage, table)
+		status := &StageStatus{
+			Done:     err == nil,
+			Finished: time.Now(),
+		}
+		if err != nil {
+			status.Error = err.Error()
+			result.Failed[stage.Name] = err
+		} else {
+			done[stage.Name] = true
+			result.Ran = append(result.Ran, stage.Name)
+		}
+		m.Stages[stage.Name] = status
+		if err := m.Save(); err != nil {
+			result.Failed[stage.Name] = fmt.Errorf("saving the manifest: %s", err.Error())
+			return result
+		}
+	}
+	return result
+}
+
+func runStage(stage Stage, table string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return stage.Run(table)
+}
+
+// Run ingests the tables of a channel using fanout goroutines.
+// Returns a channel of the results of the tables.
+func (ing *Ingester) Run(fanout int, tables <-chan string) <-chan *IngestResult {
+	out := make(chan *IngestResult)
+	wg := &sync.WaitGroup{}
+	wg.Add(fanout)
+	for i := 0; i < fanout; i++ {
+		go func(id int) {
+			for table := range tables {
+				out <- ing.Ingest(table)
+			}
+			wg.Done()
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Removes the files of the domains of a table matching any of the patterns.
+func (r *Repository) removeTableFiles(table string, patterns ...string) error {
+	files, err := ioutil.ReadDir(path.Join(r.OutputDir, "domains", table))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, file.Name()); ok {
+				if err := os.Remove(path.Join(r.OutputDir, "domains", table, file.Name())); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// SaveTableDomains saves the header and the values of the domains of a
+// table, replacing the ones of an earlier run.
+func (r *Repository) SaveTableDomains(table string) error {
+	f, err := os.Open(r.Filepath(table))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.MkdirAll(path.Join(r.OutputDir, "domains", table), 0755); err != nil {
+		return err
+	}
+	// the values files are appended to
+	if err := r.removeTableFiles(table, "index", "*.values"); err != nil {
+		return err
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	return readDomains(f, table, func(domain *Domain) {
+		r.SaveDomain(domain, logger)
+	})
+}
+
+// ClassifyTableDomains saves the types of the domains of a table.
+func (r *Repository) ClassifyTableDomains(table string) error {
+	if err := r.removeTableFiles(table, "types"); err != nil {
+		return err
+	}
+	r.classifyDomains(table)
+	return nil
+}
+
+// SketchTableDomains saves the sketches of a measure of the domains of its
+// column type of a table.
+func (r *Repository) SketchTableDomains(table string, m UnionabilityMeasure) error {
+	dir := path.Join(r.OutputDir, "domains", table)
+	for _, index := range r.getDomainsOfType(table, m.ColumnType()) {
+		values, err := readLines(r.DomainFilename(table, index, "values"), -1)
+		if err != nil {
+			return err
+		}
+		if err := m.Sketch(values, dir, index); err != nil && err != ErrNoSketch {
+			return err
+		}
+	}
+	return nil
+}
+
+// TableValueFreqs returns the value frequencies of the text domains of a
+// table.
+func (r *Repository) TableValueFreqs(table string) []*ValueFreq {
+	freqs := make([]*ValueFreq, 0)
+	for _, index := range r.getTextDomains(table) {
+		d := &Domain{
+			Filename: table,
+			Index:    index,
+		}
+		freqs = append(freqs, getValueFreq(r.DomainFilename(d.Filename, d.Index, "values"), table, index))
+	}
+	return freqs
+}
+
+// TextDomainWords returns the words of the values of the text domains of
+// a table.
+func (r *Repository) TextDomainWords(table string) []*Domain {
+	out := make(chan *Domain, 1)
+	domains := make([]*Domain, 0)
+	for _, index := range r.getTextDomains(table) {
+		r.streamDomainWords(table, index, out)
+		domains = append(domains, <-out)
+	}
+	return domains
+}
+
+// AnnotateTableDomains saves the ontology classes of the entities of the
+// text domains of a table, read from their files of the extension ext,
+// replacing the annotations of an earlier run. It must be called after
+// InitAnnotator or ResumeAnnotator.
+func (r *Repository) AnnotateTableDomains(table, ext string) error {
+	if r.entityToClass == nil {
+		return errors.New("the annotator is not initialized")
+	}
+	// annotateDomainEntities leaves out the domains with an ontology sketch
+	if err := r.removeTableFiles(table, "*.ont-minhash-l1", "*.ont-card"); err != nil {
+		return err
+	}
+	r.annotationLock.Lock()
+	defer r.annotationLock.Unlock()
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE table_name=?;`, r.AllAnnotationTable), table)
+	if err != nil {
+		return err
+	}
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, r.AllAnnotationTable))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	out := make(chan *domainAnnotation, 1)
+	for _, index := range r.getTextDomains(table) {
+		r.annotateDomainEntities(table, index, out, ext)
+		select {
+		case annotation := <-out:
+			r.saveAnnotation(annotation, stmt)
+		default:
+		}
+	}
+	return nil
+}
+
+// OntologyMinhashTableDomains saves the minhash sketches of the ontology
+// classes of the annotated domains of a table.
+func (r *Repository) OntologyMinhashTableDomains(table string) error {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT column_index FROM %s WHERE table_name=? AND class_frequncy != 0;`, r.AllAnnotationTable), table)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	indices := make([]int, 0)
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			db.Close()
+			return err
+		}
+		indices = append(indices, index)
+	}
+	rows.Close()
+	db.Close()
+	out := make(chan *DomainSketch, 1)
+	for _, index := range indices {
+		r.minhashDomainClasses(table, index, out)
+		domain := <-out
+		err := WriteMinhashSignature(domain.Sketch.Signature(), domain.Card, r.DomainFilename(domain.Filename, domain.Index, "ont-minhash-l1"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
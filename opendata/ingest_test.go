@@ -0,0 +1,116 @@
+package opendata
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_Ingester(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	od := NewRepository(&Config{
+		OpendataDir: dir,
+		OutputDir:   path.Join(dir, "output"),
+	})
+	content := "country,population\nCanada,37000000\nFrance,67000000\nJapan,126000000\n"
+	if err := ioutil.WriteFile(path.Join(dir, "countries.csv"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(map[string]int)
+	count := func(name string, run func(string) error) func(string) error {
+		return func(table string) error {
+			runs[name] += 1
+			return run(table)
+		}
+	}
+	sketchSet := func(table string) error {
+		return od.SketchTableDomains(table, od.GetMeasure("set"))
+	}
+	broken := true
+	stages := []Stage{
+		{Name: "values", Run: count("values", od.SaveTableDomains)},
+		{Name: "types", After: []string{"values"}, Run: count("types", od.ClassifyTableDomains)},
+		{Name: "minhash", After: []string{"types"}, Run: count("minhash", sketchSet)},
+		{Name: "broken", After: []string{"types"}, Run: count("broken", func(string) error {
+			if broken {
+				panic("broken stage")
+			}
+			return nil
+		})},
+		{Name: "after-broken", After: []string{"broken"}, Run: count("after-broken", func(string) error {
+			return nil
+		})},
+	}
+	ing, err := NewIngester(od, stages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ing.Ingest("countries.csv")
+	if len(result.Ran) != 3 || result.Failed["broken"] == nil || len(result.Blocked) != 1 {
+		t.Fatalf("first run: %+v", result)
+	}
+	if _, err := od.ReadMinhashSignature(path.Join(od.OutputDir, "domains", "countries.csv", "0.minhash"), defaultNumHash); err != nil {
+		t.Fatal(err)
+	}
+	m, err := od.ReadManifest("countries.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Stages["minhash"].Done || m.Stages["broken"].Done || m.Stages["broken"].Error == "" {
+		t.Fatalf("manifest %+v", m.Stages)
+	}
+
+	// the completed stages are skipped on restart
+	broken = false
+	result = ing.Ingest("countries.csv")
+	if len(result.Skipped) != 3 || len(result.Ran) != 2 || len(result.Failed) != 0 {
+		t.Fatalf("second run: %+v", result)
+	}
+	if runs["values"] != 1 || runs["broken"] != 2 || runs["after-broken"] != 1 {
+		t.Fatalf("runs %v", runs)
+	}
+
+	// running a stage again runs the stages after it
+	m, err = od.ReadManifest("countries.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(m.Stages, "types")
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	result = ing.Ingest("countries.csv")
+	if len(result.Skipped) != 1 || len(result.Ran) != 4 {
+		t.Fatalf("third run: %+v", result)
+	}
+	// the values are not appended twice
+	values, err := readLines(path.Join(od.OutputDir, "domains", "countries.csv", "0.values"), -1)
+	if err != nil || len(values) != 3 {
+		t.Fatalf("values %v: %v", values, err)
+	}
+	if err := os.Remove(path.Join(od.OutputDir, "domains", "countries.csv", "0.values")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sketchSet("countries.csv"); err == nil {
+		t.Fatal("minhash of a missing values file")
+	}
+
+	if _, err := NewIngester(od, []Stage{stages[1], stages[0]}); err == nil {
+		t.Fatal("stage listed before its dependency")
+	}
+	failing := Stage{Name: "values", Run: func(string) error {
+		return errors.New("no table")
+	}}
+	ing, _ = NewIngester(od, []Stage{failing, stages[1]})
+	result = ing.Ingest("missing.csv")
+	if result.Failed["values"] == nil || len(result.Blocked) != 1 {
+		t.Fatalf("missing table: %+v", result)
+	}
+}
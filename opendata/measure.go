@@ -0,0 +1,184 @@
+package opendata
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+
+	"github.com/RJMillerLab/table-union/embedding"
+	"github.com/RJMillerLab/table-union/yago"
+)
+
+// UnionabilityMeasure is an attribute unionability measure. The score of a
+// pair of columns is computed from the sketches of the columns in the domains
+// dir.
+type UnionabilityMeasure interface {
+	// Name identifies the measure in the stats tables and the CDF maps.
+	Name() string
+	// ColumnType is the type of the columns scored by the measure,
+	// "text" or "numeric", as classified in the types file of a table.
+	ColumnType() string
+	// CDFTable is the table in AttStatsDB that stores the CDF of the measure.
+	CDFTable() string
+	// Extension is the extension of the sketch file of a column saved by
+	// Sketch, e.g. "minhash" for 0.minhash. Measures with the same
+	// extension share their sketch files.
+	Extension() string
+	// Sketch saves the sketch files of the values of the column index of a
+	// table to the directory dir of the table. It returns ErrCannotSketch,
+	// before reading the values, if the measure cannot sketch columns,
+	// and ErrNoSketch if the values have no sketch, e.g. if there are none.
+	Sketch(values []string, dir string, index int) error
+	// Unionability returns the score of a pair of columns in [0, 1],
+	// or -1 if a column has no sketch for the measure.
+	Unionability(queryTable, candidateTable string, queryIndex, candIndex int) float64
+}
+
+var (
+	// ErrCannotSketch is returned by the measures without a sketch step or
+	// without the model they sketch with, e.g. the fastText embeddings.
+	ErrCannotSketch = errors.New("the measure cannot sketch columns")
+	// ErrNoSketch is returned for the columns without a sketch, e.g. the
+	// columns without any embedding.
+	ErrNoSketch = errors.New("no sketch of the values")
+)
+
+type funcMeasure struct {
+	name     string
+	colType  string
+	cdfTable string
+	ext      string
+	sketch   func(values []string, dir string, index int) error
+	score    func(queryTable, candidateTable string, queryIndex, candIndex int) float64
+}
+
+// NewMeasure creates a measure from a sketch function saving the files of
+// the extension ext, and a pairwise score function. The measure cannot
+// sketch columns if sketch is nil.
+func NewMeasure(name, colType, cdfTable, ext string, sketch func(values []string, dir string, index int) error, score func(queryTable, candidateTable string, queryIndex, candIndex int) float64) UnionabilityMeasure {
+	return &funcMeasure{
+		name:     name,
+		colType:  colType,
+		cdfTable: cdfTable,
+		ext:      ext,
+		sketch:   sketch,
+		score:    score,
+	}
+}
+
+func (m *funcMeasure) Name() string {
+	return m.name
+}
+
+func (m *funcMeasure) ColumnType() string {
+	return m.colType
+}
+
+func (m *funcMeasure) CDFTable() string {
+	return m.cdfTable
+}
+
+func (m *funcMeasure) Extension() string {
+	return m.ext
+}
+
+func (m *funcMeasure) Sketch(values []string, dir string, index int) error {
+	if m.sketch == nil {
+		return ErrCannotSketch
+	}
+	return m.sketch(values, dir, index)
+}
+
+func (m *funcMeasure) Unionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	u := m.score(queryTable, candidateTable, queryIndex, candIndex)
+	if u == -1.0 {
+		return u
+	}
+	return math.Min(1.0, u)
+}
+
+// RegisterMeasure adds a measure to the registry used by the stats and CDF
+// builders. It panics if a measure with the same name is registered.
+func (r *Repository) RegisterMeasure(m UnionabilityMeasure) {
+	if r.GetMeasure(m.Name()) != nil {
+		panic(fmt.Sprintf("measure %s is already registered", m.Name()))
+	}
+	r.measures = append(r.measures, m)
+}
+
+// Measures returns the registered measures in the order of registration.
+func (r *Repository) Measures() []UnionabilityMeasure {
+	return r.measures
+}
+
+// GetMeasure returns the registered measure with the given name, or nil.
+func (r *Repository) GetMeasure(name string) UnionabilityMeasure {
+	for _, m := range r.measures {
+		if m.Name() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// CanSketch returns true if a measure can sketch columns, e.g. its model is
+// set.
+func (r *Repository) CanSketch(m UnionabilityMeasure) bool {
+	return m.Sketch(nil, "", 0) != ErrCannotSketch
+}
+
+// SketchColumn saves the sketch files of the values of the column index of
+// a table to the directory dir of the table, for each measure of the
+// column type that can sketch columns. The files of an extension are saved
+// once.
+func (r *Repository) SketchColumn(values []string, dir string, index int, colType string) error {
+	done := make(map[string]bool)
+	for _, m := range r.measures {
+		if m.ColumnType() != colType || done[m.Extension()] || !r.CanSketch(m) {
+			continue
+		}
+		done[m.Extension()] = true
+		if err := m.Sketch(values, dir, index); err != nil && err != ErrNoSketch {
+			return fmt.Errorf("%s sketch of column %d: %s", m.Name(), index, err.Error())
+		}
+	}
+	return nil
+}
+
+// SetFastText sets the fastText embeddings sketched by the nl and header
+// measures.
+func (r *Repository) SetFastText(ft *embedding.FastText) {
+	r.ft = ft
+}
+
+// SetOntology sets the YAGO database and the classes of its entities
+// sketched by the sem and semset measures.
+func (r *Repository) SetOntology(yg *yago.Yago, entityClass map[string][]string) {
+	r.yago = yg
+	r.yagoClasses = entityClass
+}
+
+// Returns the name of the sketch file of the extension ext of the column
+// index of a table in the directory dir.
+func sketchFilename(dir string, index int, ext string) string {
+	return path.Join(dir, fmt.Sprintf("%d.%s", index, ext))
+}
+
+// Registers the built-in measures, with the CDF tables of the
+// configuration.
+func (r *Repository) registerMeasures() {
+	r.RegisterMeasure(NewMeasure("set", "text", r.SetCDFTable, "minhash", r.sketchSet, r.setUnionability))
+	r.RegisterMeasure(NewMeasure("wset", "text", r.WSetCDFTable, "wminhash", r.sketchWSet, r.wsetUnionability))
+	r.RegisterMeasure(NewMeasure("sem", "text", r.SemCDFTable, "ont-minhash-l1", r.sketchOntology, func(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+		uSem, _ := r.semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
+		return uSem
+	}))
+	r.RegisterMeasure(NewMeasure("semset", "text", r.SemSetCDFTable, "ont-minhash-l1", r.sketchOntology, func(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+		_, uSemSet := r.semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
+		return uSemSet
+	}))
+	r.RegisterMeasure(NewMeasure("nl", "text", r.NlCDFTable, "ft-mean", r.sketchEmbedding, r.nlUnionability))
+	r.RegisterMeasure(NewMeasure("num", "numeric", r.NumCDFTable, "num-sketch", r.sketchNumeric, r.numUnionability))
+	r.RegisterMeasure(NewMeasure("header", "text", r.HeaderCDFTable, "header-ft", r.sketchHeader, r.headerUnionability))
+}
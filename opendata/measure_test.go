@@ -0,0 +1,45 @@
+package opendata
+
+import (
+	"testing"
+)
+
+func constMeasure(name string, score float64) UnionabilityMeasure {
+	return NewMeasure(name, "text", name+"_cdf", "", nil, func(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+		return score
+	})
+}
+
+func Test_GetAttUnionabilityPercentile(t *testing.T) {
+	cdf := CDF{
+		Histogram: []Bin{
+			{LowerBound: 0.0, UpperBound: 0.5, Percentile: 0.2},
+			{LowerBound: 0.5, UpperBound: 1.0, Percentile: 0.9},
+		},
+	}
+	attCDFs := map[string]CDF{"a": cdf, "b": cdf, "c": cdf}
+	measures := []UnionabilityMeasure{constMeasure("a", 0.3), constMeasure("b", 0.8), constMeasure("c", 0.9)}
+	score, perc, names := GetAttUnionabilityPercentile("q", "c", 0, 0, measures, attCDFs, 0.05)
+	if score != 0.8 || perc.Value != 0.9 || len(names) != 2 || names[0] != "b" || names[1] != "c" {
+		t.Fatalf("got %f %v %v", score, perc, names)
+	}
+	measures = []UnionabilityMeasure{constMeasure("a", -1.0), constMeasure("b", -1.0)}
+	if score, _, names := GetAttUnionabilityPercentile("q", "c", 0, 0, measures, attCDFs, 0.05); score != -1.0 || len(names) != 0 {
+		t.Fatalf("got %f %v for unsketched columns", score, names)
+	}
+}
+
+func Test_RegisterMeasure(t *testing.T) {
+	od := NewRepository(&Config{})
+	for _, name := range []string{"set", "wset", "sem", "semset", "nl", "num", "header"} {
+		if od.GetMeasure(name) == nil {
+			t.Fatalf("measure %s is not registered", name)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a measure twice did not panic")
+		}
+	}()
+	od.RegisterMeasure(constMeasure("set", 0.0))
+}
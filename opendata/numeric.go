@@ -0,0 +1,262 @@
+package opendata
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/RJMillerLab/table-union/embedding"
+	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
+)
+
+var (
+	// number of quantile intervals kept in a numeric sketch
+	numQuantiles = 64
+	// resolution of the log-scale bins of numeric minhash sketches
+	binsPerDecade = 8.0
+)
+
+// NumericSketch summarizes the distribution of a numeric domain.
+type NumericSketch struct {
+	Count    int
+	Mean     float64
+	Variance float64
+	// evenly spaced quantiles, including the min and the max
+	Quantiles []float64
+}
+
+// ParseNumericValues returns the values that parse as finite numbers.
+func ParseNumericValues(values []string) []float64 {
+	nums := make([]float64, 0, len(values))
+	for _, v := range values {
+		x, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			continue
+		}
+		nums = append(nums, x)
+	}
+	return nums
+}
+
+// NewNumericSketch builds the sketch of a numeric domain.
+// It returns nil if there are no values.
+func NewNumericSketch(values []float64) *NumericSketch {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, x := range sorted {
+		sum += x
+	}
+	mean := sum / float64(len(sorted))
+	var ss float64
+	for _, x := range sorted {
+		ss += (x - mean) * (x - mean)
+	}
+	quantiles := make([]float64, numQuantiles+1)
+	for i := range quantiles {
+		pos := float64(i) * float64(len(sorted)-1) / float64(numQuantiles)
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		quantiles[i] = sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+	}
+	return &NumericSketch{
+		Count:     len(sorted),
+		Mean:      mean,
+		Variance:  ss / float64(len(sorted)),
+		Quantiles: quantiles,
+	}
+}
+
+func (s *NumericSketch) Min() float64 {
+	return s.Quantiles[0]
+}
+
+func (s *NumericSketch) Max() float64 {
+	return s.Quantiles[len(s.Quantiles)-1]
+}
+
+// cdf interpolates the empirical CDF of the domain between the quantiles.
+func (s *NumericSketch) cdf(x float64) float64 {
+	m := len(s.Quantiles) - 1
+	i := sort.Search(len(s.Quantiles), func(i int) bool { return s.Quantiles[i] > x })
+	if i == 0 {
+		return 0.0
+	}
+	if i > m {
+		return 1.0
+	}
+	j := i - 1
+	return (float64(j) + (x-s.Quantiles[j])/(s.Quantiles[i]-s.Quantiles[j])) / float64(m)
+}
+
+// KSStatistic estimates the two-sample Kolmogorov-Smirnov statistic of two
+// numeric domains from their sketches.
+func KSStatistic(a, b *NumericSketch) float64 {
+	d := 0.0
+	for _, qs := range [][]float64{a.Quantiles, b.Quantiles} {
+		for _, x := range qs {
+			d = math.Max(d, math.Abs(a.cdf(x)-b.cdf(x)))
+		}
+	}
+	return d
+}
+
+// Vec serializes the sketch as count, mean, variance and the quantiles.
+func (s *NumericSketch) Vec() []float64 {
+	vec := make([]float64, 0, len(s.Quantiles)+3)
+	vec = append(vec, float64(s.Count), s.Mean, s.Variance)
+	return append(vec, s.Quantiles...)
+}
+
+// NumericSketchFromVec reads a sketch serialized by Vec.
+func NumericSketchFromVec(vec []float64) (*NumericSketch, error) {
+	if len(vec) < 4 {
+		return nil, fmt.Errorf("numeric sketch of length %d", len(vec))
+	}
+	return &NumericSketch{
+		Count:     int(vec[0]),
+		Mean:      vec[1],
+		Variance:  vec[2],
+		Quantiles: vec[3:],
+	}, nil
+}
+
+func WriteNumericSketch(s *NumericSketch, filename string) error {
+	return embedding.WriteVecToDisk(s.Vec(), ByteOrder, filename)
+}
+
+func (r *Repository) ReadNumericSketch(filename string) (*NumericSketch, error) {
+	sketch, err := r.cachedSketch(filename, func() (interface{}, error) {
+		vec, err := embedding.ReadVecFromDisk(filename, ByteOrder)
+		if err != nil {
+			return nil, err
+		}
+		return NumericSketchFromVec(vec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sketch.(*NumericSketch), nil
+}
+
+// numericBin maps a value to a log-scale bin, so that domains with
+// overlapping ranges share bins.
+func numericBin(x float64) string {
+	if x == 0.0 {
+		return "0"
+	}
+	bin := int(math.Floor(math.Log10(1.0+math.Abs(x))*binsPerDecade)) + 1
+	if x < 0 {
+		bin = -bin
+	}
+	return strconv.Itoa(bin)
+}
+
+// GetNumericMinhash returns the minhash of the log-scale bins of a numeric
+// domain. The Jaccard similarity of the bins is used to find candidate
+// numeric columns with the minhash LSH index.
+func GetNumericMinhash(values []float64, numHash int) []uint64 {
+	return numericMinhash(values, numHash).Signature()
+}
+
+func numericMinhash(values []float64, numHash int) *minhashlsh.Minhash {
+	mh := minhashlsh.NewMinhash(seed, numHash)
+	seen := make(map[string]bool)
+	for _, x := range values {
+		bin := numericBin(x)
+		if !seen[bin] {
+			seen[bin] = true
+			mh.Push([]byte(bin))
+		}
+	}
+	return mh
+}
+
+func (r *Repository) getNumericSketchFilename(tableID string, index int) string {
+	return path.Join(r.OutputDir, "domains", tableID, fmt.Sprintf("%d.num-sketch", index))
+}
+
+func (r *Repository) numUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	filename := r.getNumericSketchFilename(candidateTable, candIndex)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return -1.0
+	}
+	cSketch, err := r.ReadNumericSketch(filename)
+	if err != nil {
+		return -1.0
+	}
+	filename = r.getNumericSketchFilename(queryTable, queryIndex)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return -1.0
+	}
+	qSketch, err := r.ReadNumericSketch(filename)
+	if err != nil {
+		return -1.0
+	}
+	return 1.0 - KSStatistic(qSketch, cSketch)
+}
+
+// Saves the distribution sketch and the minhash of the bins of the values
+// of a column for the num measure.
+func (r *Repository) sketchNumeric(values []string, dir string, index int) error {
+	nums := ParseNumericValues(values)
+	sketch := NewNumericSketch(nums)
+	if sketch == nil {
+		return ErrNoSketch
+	}
+	if err := WriteNumericSketch(sketch, sketchFilename(dir, index, "num-sketch")); err != nil {
+		return err
+	}
+	return WriteMinhashSignature(numericMinhash(nums, r.numHash).Signature(), nil, sketchFilename(dir, index, "num-minhash"))
+}
+
+// DoSketchNumericDomainsFromFiles saves the distribution sketch (num-sketch)
+// and the minhash of the bins (num-minhash) of the numeric domains.
+func (r *Repository) DoSketchNumericDomainsFromFiles(fanout int, files <-chan string) <-chan ProgressCounter {
+	progress := make(chan ProgressCounter)
+	wg := &sync.WaitGroup{}
+	wg.Add(fanout)
+	for i := 0; i < fanout; i++ {
+		go func() {
+			for file := range files {
+				for _, index := range r.getNumericDomains(file) {
+					d := &Domain{
+						Filename: file,
+						Index:    index,
+					}
+					values, err := readLines(r.DomainFilename(d.Filename, d.Index, "values"), -1)
+					if err != nil {
+						continue
+					}
+					nums := ParseNumericValues(values)
+					sketch := NewNumericSketch(nums)
+					if sketch == nil {
+						continue
+					}
+					if err := WriteNumericSketch(sketch, r.DomainFilename(d.Filename, d.Index, "num-sketch")); err != nil {
+						panic(err)
+					}
+					if err := WriteMinhashSignature(numericMinhash(nums, r.numHash).Signature(), nil, r.DomainFilename(d.Filename, d.Index, "num-minhash")); err != nil {
+						panic(err)
+					}
+					progress <- ProgressCounter{1}
+				}
+			}
+			wg.Done()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(progress)
+	}()
+	return progress
+}
@@ -0,0 +1,56 @@
+package opendata
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func Test_KSStatistic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a := make([]float64, 2000)
+	b := make([]float64, 3000)
+	c := make([]float64, 2000)
+	for i := range a {
+		a[i] = r.NormFloat64()*10 + 100
+		c[i] = r.NormFloat64()*10 + 150
+	}
+	for i := range b {
+		b[i] = r.NormFloat64()*10 + 100
+	}
+	sa, sb, sc := NewNumericSketch(a), NewNumericSketch(b), NewNumericSketch(c)
+	if d := KSStatistic(sa, sb); d > 0.1 {
+		t.Errorf("KS statistic of the same distribution is %f", d)
+	}
+	if d := KSStatistic(sa, sc); d < 0.9 {
+		t.Errorf("KS statistic of shifted distributions is %f", d)
+	}
+	if d := KSStatistic(sa, sa); d != 0.0 {
+		t.Errorf("KS statistic of a sketch with itself is %f", d)
+	}
+	if math.Abs(sa.Mean-100) > 1 || sa.Min() > sa.Max() {
+		t.Errorf("bad sketch %v", sa)
+	}
+	// constant domains
+	s1, s2 := NewNumericSketch([]float64{5, 5, 5}), NewNumericSketch([]float64{7, 7})
+	if d := KSStatistic(s1, s2); d != 1.0 {
+		t.Errorf("KS statistic of disjoint constant domains is %f", d)
+	}
+	s, err := NumericSketchFromVec(sa.Vec())
+	if err != nil || s.Count != sa.Count || KSStatistic(s, sa) != 0.0 {
+		t.Errorf("sketch does not round trip: %v", err)
+	}
+}
+
+func Test_GetNumericMinhash(t *testing.T) {
+	a := []float64{120, 250, 480, 900}
+	b := []float64{130, 260, 470, 910}
+	c := []float64{0.01, 0.02, 0.5}
+	sa, sb, sc := GetNumericMinhash(a, 128), GetNumericMinhash(b, 128), GetNumericMinhash(c, 128)
+	if j := estimateJaccard(sa, sb); j < 0.5 {
+		t.Errorf("jaccard of overlapping ranges is %f", j)
+	}
+	if j := estimateJaccard(sa, sc); j > 0.1 {
+		t.Errorf("jaccard of disjoint ranges is %f", j)
+	}
+}
@@ -30,12 +30,12 @@ type OctopusAlignment struct {
 	score     float64
 }
 
-func ComputeIDF(filenames <-chan string) map[string]float64 {
+func (r *Repository) ComputeIDF(filenames <-chan string) map[string]float64 {
 	numDocuments := 0
 	idf := make(map[string]float64)
 	for filename := range filenames {
 		numDocuments += 1
-		filename := path.Join(OpendataDir, filename)
+		filename := path.Join(r.OpendataDir, filename)
 		tFile, err := os.Open(filename)
 		if err != nil {
 			panic(err)
@@ -63,9 +63,9 @@ func ComputeIDF(filenames <-chan string) map[string]float64 {
 	return idf
 }
 
-func computeTableTFIDF(filename string, idf map[string]float64) (map[string]float64, float64) {
+func (r *Repository) computeTableTFIDF(filename string, idf map[string]float64) (map[string]float64, float64) {
 	tf := make(map[string]float64)
-	filename = path.Join(OpendataDir, filename)
+	filename = path.Join(r.OpendataDir, filename)
 	tFile, err := os.Open(filename)
 	if err != nil {
 		panic(err)
@@ -290,7 +290,7 @@ func ComputeSizeClusterScore(t1name, t2name string, lens1, lens2 []float64) Octo
 	return sp
 }
 
-func ComputeTextClusterScore(t1name, t2name string, idf map[string]float64) OctopusScore {
+func (r *Repository) ComputeTextClusterScore(t1name, t2name string, idf map[string]float64) OctopusScore {
 	t1Vec := make(map[string]float64)
 	t2Vec := make(map[string]float64)
 	var t1L2 float64
@@ -298,11 +298,11 @@ func ComputeTextClusterScore(t1name, t2name string, idf map[string]float64) Octo
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 	go func() {
-		t1Vec, t1L2 = computeTableTFIDF(t1name, idf)
+		t1Vec, t1L2 = r.computeTableTFIDF(t1name, idf)
 		wg.Done()
 	}()
 	go func() {
-		t2Vec, t2L2 = computeTableTFIDF(t2name, idf)
+		t2Vec, t2L2 = r.computeTableTFIDF(t2name, idf)
 		wg.Done()
 	}()
 	wg.Wait()
@@ -326,9 +326,9 @@ func computeCosine(v1, v2 map[string]float64, l21, l22 float64) float64 {
 	return cosine
 }
 
-func computeColumnTFIDF(tablename string, index int, idf map[string]float64) (map[string]float64, float64) {
+func (r *Repository) computeColumnTFIDF(tablename string, index int, idf map[string]float64) (map[string]float64, float64) {
 	tf := make(map[string]float64)
-	filepath := path.Join(OutputDir, "domains", tablename, fmt.Sprintf("%d.values", index))
+	filepath := path.Join(r.OutputDir, "domains", tablename, fmt.Sprintf("%d.values", index))
 	f, err := os.Open(filepath)
 	defer f.Close()
 	if err != nil {
@@ -359,18 +359,18 @@ func computeColumnTFIDF(tablename string, index int, idf map[string]float64) (ma
 	return tfidf, l2
 }
 
-func DoSaveOctopusScores(scores <-chan OctopusScore) <-chan ProgressCounter {
+func (r *Repository) DoSaveOctopusScores(scores <-chan OctopusScore) <-chan ProgressCounter {
 	log.Printf("saving octopus scores")
-	db, err := sql.Open("sqlite3", OctopusDB)
+	db, err := sql.Open("sqlite3", r.OctopusDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, score real);`, OctopusTable, OctopusTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, score real);`, r.OctopusTable, r.OctopusTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, score) values(?, ?, ?);`, OctopusTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, score) values(?, ?, ?);`, r.OctopusTable))
 	if err != nil {
 		panic(err)
 	}
@@ -400,18 +400,18 @@ func DoSaveOctopusScores(scores <-chan OctopusScore) <-chan ProgressCounter {
 	return progress
 }
 
-func DoSaveOctopusAlignments(alignments <-chan OctopusAlignment) <-chan ProgressCounter {
+func (r *Repository) DoSaveOctopusAlignments(alignments <-chan OctopusAlignment) <-chan ProgressCounter {
 	log.Printf("saving octopus scores")
-	db, err := sql.Open("sqlite3", OctopusDB)
+	db, err := sql.Open("sqlite3", r.OctopusDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column_id int, query_column_name text, candidate_column_id int, candidate_column_name text, score real);`, OctopusTable, OctopusTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column_id int, query_column_name text, candidate_column_id int, candidate_column_name text, score real);`, r.OctopusTable, r.OctopusTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column_id, query_column_name, candidate_column_id, candidate_column_name, score) values(?, ?, ?, ?, ?, ?, ?);`, OctopusTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column_id, query_column_name, candidate_column_id, candidate_column_name, score) values(?, ?, ?, ?, ?, ?, ?);`, r.OctopusTable))
 	if err != nil {
 		panic(err)
 	}
@@ -420,8 +420,8 @@ func DoSaveOctopusAlignments(alignments <-chan OctopusAlignment) <-chan Progress
 	progress := make(chan ProgressCounter)
 	go func() {
 		for a := range alignments {
-			qColNames := getColumnNames(a.query)
-			cColNames := getColumnNames(a.candidate)
+			qColNames := r.getColumnNames(a.query)
+			cColNames := r.getColumnNames(a.candidate)
 			// this condition is not required for size cluster
 			//if a.score <= 0.0 {
 			if math.IsNaN(a.score) || math.IsInf(a.score, 0) {
@@ -447,28 +447,28 @@ func DoSaveOctopusAlignments(alignments <-chan OctopusAlignment) <-chan Progress
 	return progress
 }
 
-func GetTableColumnsTFIDF(tablename string, idf map[string]float64) ([]map[string]float64, []float64) {
+func (r *Repository) GetTableColumnsTFIDF(tablename string, idf map[string]float64) ([]map[string]float64, []float64) {
 	coltfidfs := make([]map[string]float64, 0)
 	l2s := make([]float64, 0)
-	for _, index := range getNonNumericDomains(tablename) {
-		tfidf, l2 := computeColumnTFIDF(tablename, index, idf)
+	for _, index := range r.getNonNumericDomains(tablename) {
+		tfidf, l2 := r.computeColumnTFIDF(tablename, index, idf)
 		coltfidfs = append(coltfidfs, tfidf)
 		l2s = append(l2s, l2)
 	}
 	return coltfidfs, l2s
 }
 
-func GetTableColumnMeanLength(tablename string) []float64 {
+func (r *Repository) GetTableColumnMeanLength(tablename string) []float64 {
 	colens := make([]float64, 0)
-	for _, index := range getNonNumericDomains(tablename) {
-		colens = append(colens, getMeanLength(tablename, index))
+	for _, index := range r.getNonNumericDomains(tablename) {
+		colens = append(colens, r.getMeanLength(tablename, index))
 	}
 
 	return colens
 }
 
-func getMeanLength(tablename string, index int) float64 {
-	filepath := path.Join(OutputDir, "domains", tablename, fmt.Sprintf("%d.values", index))
+func (r *Repository) getMeanLength(tablename string, index int) float64 {
+	filepath := path.Join(r.OutputDir, "domains", tablename, fmt.Sprintf("%d.values", index))
 	f, err := os.Open(filepath)
 	defer f.Close()
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/RJMillerLab/table-union/minhashlsh"
 	"github.com/RJMillerLab/table-union/pqueue"
@@ -21,11 +22,10 @@ import (
 )
 
 var (
-	entityToClass map[string][]string
-	n1            = 2.0
-	m1            = 2.0
-	n2            = 2.0
-	m2            = 2.0
+	n1 = 2.0
+	m1 = 2.0
+	n2 = 2.0
+	m2 = 2.0
 )
 
 type domainAnnotation struct {
@@ -35,16 +35,23 @@ type domainAnnotation struct {
 	numEntities int
 }
 
-func InitSarma() {
-	entityToClass = loadEntityClasses()
+func (r *Repository) InitSarma() {
+	r.entityToClass = r.loadEntityClasses()
 }
 
-func InitAnnotator() {
-	entityToClass = loadEntityClasses()
-	prepareDB()
+func (r *Repository) InitAnnotator() {
+	r.entityToClass = r.loadEntityClasses()
+	r.prepareDB()
 }
 
-func AnnotateDomainsFromEntityFiles(files <-chan string, fanout int, ext string) <-chan *domainAnnotation {
+// ResumeAnnotator loads the entity classes like InitAnnotator, but keeps
+// the annotations already in the database.
+func (r *Repository) ResumeAnnotator() {
+	r.entityToClass = r.loadEntityClasses()
+	r.createAnnotationTable()
+}
+
+func (r *Repository) AnnotateDomainsFromEntityFiles(files <-chan string, fanout int, ext string) <-chan *domainAnnotation {
 	out := make(chan *domainAnnotation, 1000)
 	wg := &sync.WaitGroup{}
 
@@ -53,9 +60,9 @@ func AnnotateDomainsFromEntityFiles(files <-chan string, fanout int, ext string)
 		go func(id int) {
 			for file := range files {
 				//subjectColumn := getSubjectColumn(file)
-				textDomains := getTextDomains(file)
+				textDomains := r.getTextDomains(file)
 				for _, index := range textDomains {
-					annotateDomainEntities(file, index, out, ext)
+					r.annotateDomainEntities(file, index, out, ext)
 				}
 			}
 			wg.Done()
@@ -70,12 +77,12 @@ func AnnotateDomainsFromEntityFiles(files <-chan string, fanout int, ext string)
 	return out
 }
 
-func getSubjectColumn(tablename string) int {
-	db, err := sql.Open("sqlite3", AnnotationDB)
+func (r *Repository) getSubjectColumn(tablename string) int {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
-	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT subject_column FROM %s where table_name="%s";`, AllAnnotationTable, tablename))
+	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT subject_column FROM %s where table_name="%s";`, r.AllAnnotationTable, tablename))
 	if err != nil {
 		panic(err)
 	}
@@ -91,15 +98,15 @@ func getSubjectColumn(tablename string) int {
 	return subjectColumn
 }
 
-func getSubjectColumnPlus(tableName string) int {
+func (r *Repository) getSubjectColumnPlus(tableName string) int {
 	// assuming the first text column (non-numerical and non-date)
 	// with annotations as subject column
-	textDomains := getTextDomains(tableName)
+	textDomains := r.getTextDomains(tableName)
 	if len(textDomains) == 0 {
 		return -1
 	}
 	for _, i := range textDomains {
-		filepath := path.Join(OutputDir, "domains", tableName, fmt.Sprintf("%d.entities", i))
+		filepath := path.Join(r.OutputDir, "domains", tableName, fmt.Sprintf("%d.entities", i))
 		file, err := os.Open(filepath)
 		if err == nil {
 			//if _, err := os.Stat(filepath); os.IsExist(err) {
@@ -110,13 +117,13 @@ func getSubjectColumnPlus(tableName string) int {
 	return -1
 }
 
-func annotateDomainEntities(file string, index int, out chan *domainAnnotation, ext string) {
+func (r *Repository) annotateDomainEntities(file string, index int, out chan *domainAnnotation, ext string) {
 	// this is here just to restore annotation for unannotated domains
-	if _, err := os.Stat(path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, "ont-minhash-l1"))); !os.IsNotExist(err) {
+	if _, err := os.Stat(path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, "ont-minhash-l1"))); !os.IsNotExist(err) {
 		return
 	}
 
-	filepath := path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, ext))
+	filepath := path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, ext))
 	f, err := os.Open(filepath)
 	if err != nil {
 		return
@@ -127,11 +134,11 @@ func annotateDomainEntities(file string, index int, out chan *domainAnnotation,
 	numEntities := 0
 	for scanner.Scan() {
 		e := strings.ToLower(scanner.Text())
-		if len(entityToClass[e]) == 0 {
+		if len(r.entityToClass[e]) == 0 {
 			log.Printf("class not found for entity %s", e)
 		} else {
 			numEntities += 1
-			for _, c := range entityToClass[e] {
+			for _, c := range r.entityToClass[e] {
 				if _, ok := classes[c]; !ok {
 					classes[c] = 1
 				} else {
@@ -153,12 +160,12 @@ func annotateDomainEntities(file string, index int, out chan *domainAnnotation,
 	log.Printf("done annotating")
 }
 
-func DoSaveAnnotations(annotations <-chan *domainAnnotation) <-chan ProgressCounter {
-	db, err := sql.Open("sqlite3", AnnotationDB)
+func (r *Repository) DoSaveAnnotations(annotations <-chan *domainAnnotation) <-chan ProgressCounter {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, AllAnnotationTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, r.AllAnnotationTable))
 	//stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, SubjectAnnotationTable))
 	if err != nil {
 		panic(err)
@@ -169,32 +176,7 @@ func DoSaveAnnotations(annotations <-chan *domainAnnotation) <-chan ProgressCoun
 	go func() {
 		for annotation := range annotations {
 			log.Printf("saving annotations of %s", annotation.filename)
-			subjectName := GetDomainHeader(annotation.filename).Values[annotation.index]
-			if len(annotation.classes) == 0 {
-				log.Printf("No annotation for attribute %s.%d", annotation.filename, annotation.index)
-				_, err = stmt.Exec(annotation.filename, annotation.index, subjectName, "-1", 0, 0)
-				if err != nil {
-					panic(err)
-				}
-			}
-			if len(annotation.classes) != 0 {
-				// saving to a file
-				cardFilename := path.Join(OutputDir, "domains", annotation.filename, fmt.Sprintf("%d.%s", annotation.index, "ont-card"))
-				log.Printf("saving ontcard of %s", cardFilename)
-				f, err := os.OpenFile(cardFilename, os.O_CREATE|os.O_WRONLY, 0644)
-				if err != nil {
-					panic(err)
-				}
-				log.Printf("ont-card: %d", len(annotation.classes))
-				fmt.Fprintln(f, len(annotation.classes))
-				f.Close()
-			}
-			for class, freq := range annotation.classes {
-				_, err = stmt.Exec(annotation.filename, annotation.index, subjectName, class, freq, annotation.numEntities)
-				if err != nil {
-					panic(err)
-				}
-			}
+			r.saveAnnotation(annotation, stmt)
 			progress <- ProgressCounter{1}
 		}
 		wg.Done()
@@ -207,11 +189,42 @@ func DoSaveAnnotations(annotations <-chan *domainAnnotation) <-chan ProgressCoun
 	return progress
 }
 
-func (annotation *domainAnnotation) saveToFile() {
+// Inserts the classes of an annotated domain into the annotation
+// database and saves the number of classes to its ont-card file.
+func (r *Repository) saveAnnotation(annotation *domainAnnotation, stmt *sql.Stmt) {
+	subjectName := r.GetDomainHeader(annotation.filename).Values[annotation.index]
+	if len(annotation.classes) == 0 {
+		log.Printf("No annotation for attribute %s.%d", annotation.filename, annotation.index)
+		_, err := stmt.Exec(annotation.filename, annotation.index, subjectName, "-1", 0, 0)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(annotation.classes) != 0 {
+		// saving to a file
+		cardFilename := path.Join(r.OutputDir, "domains", annotation.filename, fmt.Sprintf("%d.%s", annotation.index, "ont-card"))
+		log.Printf("saving ontcard of %s", cardFilename)
+		f, err := os.OpenFile(cardFilename, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("ont-card: %d", len(annotation.classes))
+		fmt.Fprintln(f, len(annotation.classes))
+		f.Close()
+	}
+	for class, freq := range annotation.classes {
+		_, err := stmt.Exec(annotation.filename, annotation.index, subjectName, class, freq, annotation.numEntities)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *Repository) saveAnnotationToFile(annotation *domainAnnotation) {
 	if len(annotation.classes) == 0 {
 		return
 	}
-	dirPath := path.Join(OutputDir, "domains", annotation.filename)
+	dirPath := path.Join(r.OutputDir, "domains", annotation.filename)
 	filepath := path.Join(dirPath, fmt.Sprintf("%d.annos-l1", annotation.index))
 
 	f, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY, 0644)
@@ -228,18 +241,18 @@ func (annotation *domainAnnotation) saveToFile() {
 	return
 }
 
-func (annotation *domainAnnotation) saveToDB() {
+func (r *Repository) saveAnnotationToDB(annotation *domainAnnotation) {
 	// Prepare insert stmt
-	db, err := sql.Open("sqlite3", AnnotationDB)
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
 	stmt, err := db.Prepare(fmt.Sprintf(
-		`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, AllAnnotationTable))
+		`insert into %s(table_name, column_index, column_name, class, class_frequncy, num_entities) values(?, ?, ?, ?, ?, ?);`, r.AllAnnotationTable))
 	if err != nil {
 		panic(err)
 	}
-	subjectName := GetDomainHeader(annotation.filename).Values[annotation.index]
+	subjectName := r.GetDomainHeader(annotation.filename).Values[annotation.index]
 	if len(annotation.classes) == 0 {
 		_, err = stmt.Exec(annotation.filename, annotation.index, subjectName, "-1", 0, 0)
 		if err != nil {
@@ -256,9 +269,9 @@ func (annotation *domainAnnotation) saveToDB() {
 	return
 }
 
-func loadEntityClasses() map[string][]string {
+func (r *Repository) loadEntityClasses() map[string][]string {
 	lookup := make(map[string][]string)
-	f, err := os.Open(path.Join(OutputDir, "entity-category.txt"))
+	f, err := os.Open(path.Join(r.OutputDir, "entity-category.txt"))
 	//f, err := os.Open(path.Join(OutputDir, "entity-class.txt"))
 	if err != nil {
 		panic(err)
@@ -284,35 +297,47 @@ func loadEntityClasses() map[string][]string {
 	return lookup
 }
 
-func prepareDB() {
+func (r *Repository) prepareDB() {
 	// output db
-	db, err := sql.Open("sqlite3", AnnotationDB)
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (table_name text,column_index int, column_name text, class text, class_frequncy int, num_entities int);`, AllAnnotationTable, AllAnnotationTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (table_name text,column_index int, column_name text, class text, class_frequncy int, num_entities int);`, r.AllAnnotationTable, r.AllAnnotationTable))
 	//_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (table_name text, column_index int, column_name text, class text, class_frequncy int, num_entities int);`, SubjectAnnotationTable, SubjectAnnotationTable))
 	if err != nil {
 		panic(err)
 	}
 }
 
-func bucketize(queryFilename string) <-chan string {
+func (r *Repository) createAnnotationTable() {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(fmt.Sprintf(`create table if not exists %s (table_name text,column_index int, column_name text, class text, class_frequncy int, num_entities int);`, r.AllAnnotationTable))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (r *Repository) bucketize(queryFilename string) <-chan string {
 	candidateTables := make(chan string)
-	subjectColumn := getSubjectColumn(queryFilename)
+	subjectColumn := r.getSubjectColumn(queryFilename)
 	if subjectColumn == -1 {
 		log.Printf("No subject column was found for query %s", queryFilename)
 		return candidateTables
 	}
 	go func() {
 		// output db
-		db, err := sql.Open("sqlite3", AnnotationDB)
+		db, err := sql.Open("sqlite3", r.AnnotationDB)
 		if err != nil {
 			panic(err)
 		}
-		rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT a2.table_name as candidate_table FROM %s a2, (SELECT * FROM %s WHERE table_name="%s") a1 WHERE a2.subject_column=a2.column_index and (a1.column_name=a2.column_name OR a1.class=a2.class);`, AllAnnotationTable, AllAnnotationTable, queryFilename))
+		rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT a2.table_name as candidate_table FROM %s a2, (SELECT * FROM %s WHERE table_name="%s") a1 WHERE a2.subject_column=a2.column_index and (a1.column_name=a2.column_name OR a1.class=a2.class);`, r.AllAnnotationTable, r.AllAnnotationTable, queryFilename))
 		if err != nil {
 			panic(err)
 		}
@@ -341,9 +366,9 @@ type pair struct {
 	candidateColIndex int
 }
 
-func readEntities(tableName string, columnIndex int) []string {
+func (r *Repository) readEntities(tableName string, columnIndex int) []string {
 	entities := make([]string, 0)
-	filepath := path.Join(OutputDir, "domains", tableName, fmt.Sprintf("%d.ont-minhash-l1", columnIndex))
+	filepath := path.Join(r.OutputDir, "domains", tableName, fmt.Sprintf("%d.ont-minhash-l1", columnIndex))
 	f, err := os.Open(filepath)
 	if err != nil {
 		return []string{}
@@ -358,9 +383,9 @@ func readEntities(tableName string, columnIndex int) []string {
 	return entities
 }
 
-func shareEntities(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) bool {
-	queryEntities := readEntities(queryFilename, getSubjectColumn(queryFilename))
-	candidateEntities := readEntities(candidateFilename, getSubjectColumn(candidateFilename))
+func (r *Repository) shareEntities(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) bool {
+	queryEntities := r.readEntities(queryFilename, r.getSubjectColumn(queryFilename))
+	candidateEntities := r.readEntities(candidateFilename, r.getSubjectColumn(candidateFilename))
 	for _, e1 := range queryEntities {
 		for _, e2 := range candidateEntities {
 			if strings.ToLower(e1) == strings.ToLower(e2) {
@@ -371,17 +396,17 @@ func shareEntities(queryFilename, candidateFilename string, queryTextColumns, ca
 	return false
 }
 
-func computeSchemaConsistency(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) float64 {
+func (r *Repository) computeSchemaConsistency(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) float64 {
 	// topK queue is used to find max weight matching
 	schemaMapping := pqueue.NewTopKQueue(len(queryTextColumns) * len(candidateTextColumns))
 	// for each pair of columns read labels, tokenize and compute fuzzy jaccard
-	haveSharedEntities := shareEntities(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
+	haveSharedEntities := r.shareEntities(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
 	for iqtc, qtc := range queryTextColumns {
 		for ictc, ctc := range candidateTextColumns {
-			matchingScore := computeLabelsFuzzyJaccard(queryFilename, qtc, candidateFilename, ctc)
+			matchingScore := r.computeLabelsFuzzyJaccard(queryFilename, qtc, candidateFilename, ctc)
 			if matchingScore != 0.0 {
 				if haveSharedEntities {
-					valueConsistency := computeValueConsistency(queryFilename, candidateFilename, iqtc, ictc)
+					valueConsistency := r.computeValueConsistency(queryFilename, candidateFilename, iqtc, ictc)
 					log.Printf("valueConsistency: %f", valueConsistency)
 					if valueConsistency >= 0.8 {
 						p := pair{
@@ -424,14 +449,14 @@ func computeSchemaConsistency(queryFilename, candidateFilename string, queryText
 	return mappingScore
 }
 
-func makeDomainAnnotationCounterFromDB(tableName string, columnIndex int) *counter.Counter {
-	db, err := sql.Open("sqlite3", AnnotationDB)
+func (r *Repository) makeDomainAnnotationCounterFromDB(tableName string, columnIndex int) *counter.Counter {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 	// read and tokenize the labels of the query
-	rows, err := db.Query(fmt.Sprintf(`SELECT column_name, class FROM %s WHERE table_name = "%s" AND        column_index = %d;`, AllAnnotationTable, tableName, columnIndex))
+	rows, err := db.Query(fmt.Sprintf(`SELECT column_name, class FROM %s WHERE table_name = "%s" AND        column_index = %d;`, r.AllAnnotationTable, tableName, columnIndex))
 	if err != nil {
 		panic(err)
 	}
@@ -459,8 +484,8 @@ func makeDomainAnnotationCounterFromDB(tableName string, columnIndex int) *count
 	return domainCounter
 }
 
-func makeDomainEntityCounter(tableName string, columnIndex int) (*counter.Counter, error) {
-	filepath := path.Join(OutputDir, "domains", tableName, fmt.Sprintf("%d.entities-l0", columnIndex))
+func (r *Repository) makeDomainEntityCounter(tableName string, columnIndex int) (*counter.Counter, error) {
+	filepath := path.Join(r.OutputDir, "domains", tableName, fmt.Sprintf("%d.entities-l0", columnIndex))
 	f, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
@@ -476,9 +501,9 @@ func makeDomainEntityCounter(tableName string, columnIndex int) (*counter.Counte
 	return domainEntityCounter, nil
 }
 
-func computeLabelsFuzzyJaccard(queryFilename string, queryColumnIndex int, candidateFilename string, candidateColumnIndex int) float64 {
-	queryCounter := makeDomainAnnotationCounterFromDB(queryFilename, queryColumnIndex)
-	candidateCounter := makeDomainAnnotationCounterFromDB(candidateFilename, candidateColumnIndex)
+func (r *Repository) computeLabelsFuzzyJaccard(queryFilename string, queryColumnIndex int, candidateFilename string, candidateColumnIndex int) float64 {
+	queryCounter := r.makeDomainAnnotationCounterFromDB(queryFilename, queryColumnIndex)
+	candidateCounter := r.makeDomainAnnotationCounterFromDB(candidateFilename, candidateColumnIndex)
 	if queryCounter.Unique() == 0 && candidateCounter.Unique() == 0 {
 		return 0.0
 	}
@@ -488,11 +513,11 @@ func computeLabelsFuzzyJaccard(queryFilename string, queryColumnIndex int, candi
 	return float64(intersectCardinality) / float64(queryCounter.Unique()+candidateCounter.Unique()-intersectCardinality)
 }
 
-func annotateEntityCounter(counter *counter.Counter) map[string]int {
+func (r *Repository) annotateEntityCounter(counter *counter.Counter) map[string]int {
 	elems, freqs := counter.Freqs()
 	classes := make(map[string]int)
 	for i, entity := range elems {
-		for _, c := range entityToClass[strings.ToLower(entity.(string))] {
+		for _, c := range r.entityToClass[strings.ToLower(entity.(string))] {
 			if _, ok := classes[c]; !ok {
 				classes[c] = freqs[i]
 			} else {
@@ -502,15 +527,15 @@ func annotateEntityCounter(counter *counter.Counter) map[string]int {
 	}
 	return classes
 }
-func annotateEntityCounterPlus(counter *counter.Counter) map[string]int {
+func (r *Repository) annotateEntityCounterPlus(counter *counter.Counter) map[string]int {
 	classes := make(map[string]int)
 	elems, freqs := counter.Freqs()
 	for i, elem := range elems {
 		e := strings.ToLower(elem.(string))
-		if len(entityToClass[e]) == 0 {
+		if len(r.entityToClass[e]) == 0 {
 			log.Printf("No class found for entity %s", e)
 		}
-		for _, c := range entityToClass[e] {
+		for _, c := range r.entityToClass[e] {
 			if _, ok := classes[c]; !ok {
 				classes[c] = freqs[i]
 			} else {
@@ -521,19 +546,19 @@ func annotateEntityCounterPlus(counter *counter.Counter) map[string]int {
 	return classes
 }
 
-func computeEntityConsistencyAndExpansion(queryTable, candidateTable string, queryTextColumns, candidateTextColumns []int) float64 {
-	db, err := sql.Open("sqlite3", AnnotationDB)
+func (r *Repository) computeEntityConsistencyAndExpansion(queryTable, candidateTable string, queryTextColumns, candidateTextColumns []int) float64 {
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 	// computing w(X,c)
-	querySubjectCol := getSubjectColumn(queryTable)
-	candidateSubjectCol := getSubjectColumn(candidateTable)
+	querySubjectCol := r.getSubjectColumn(queryTable)
+	candidateSubjectCol := r.getSubjectColumn(candidateTable)
 	if querySubjectCol == -1 || candidateSubjectCol == -1 {
 		return 0.0
 	}
-	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT class, class_frequncy, num_entities FROM %s WHERE table_name="%s" AND column_index=%d;`, AllAnnotationTable, queryTable, querySubjectCol))
+	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT class, class_frequncy, num_entities FROM %s WHERE table_name="%s" AND column_index=%d;`, r.AllAnnotationTable, queryTable, querySubjectCol))
 	if err != nil {
 		panic(err)
 	}
@@ -551,17 +576,17 @@ func computeEntityConsistencyAndExpansion(queryTable, candidateTable string, que
 	}
 	rows.Close()
 	// finding Y\X classes
-	queryEntityCounter, err := makeDomainEntityCounter(queryTable, querySubjectCol)
+	queryEntityCounter, err := r.makeDomainEntityCounter(queryTable, querySubjectCol)
 	if err != nil {
 		return 0.0
 	}
-	candidateEntityCounter, err := makeDomainEntityCounter(candidateTable, candidateSubjectCol)
+	candidateEntityCounter, err := r.makeDomainEntityCounter(candidateTable, candidateSubjectCol)
 	if err != nil {
 		return 0.0
 	}
 	differenceEntityCounter := candidateEntityCounter.Difference(queryEntityCounter)
 	// annotate the difference
-	candidateAnnotations := annotateEntityCounter(differenceEntityCounter)
+	candidateAnnotations := r.annotateEntityCounter(differenceEntityCounter)
 	//if candidateEntityCounter.Unique() != differenceEntityCounter.Unique() {
 	//	log.Printf("c %d q %d f %d", candidateEntityCounter.Unique(), queryEntityCounter.Unique(), differenceEntityCounter.Unique())
 	//}
@@ -591,11 +616,11 @@ func computeEntityConsistencyAndExpansion(queryTable, candidateTable string, que
 	return SEP
 }
 
-func computeSarmaUnionabilityScores(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) (float64, float64) {
-	ece := computeEntityConsistencyAndExpansion(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
+func (r *Repository) computeSarmaUnionabilityScores(queryFilename, candidateFilename string, queryTextColumns, candidateTextColumns []int) (float64, float64) {
+	ece := r.computeEntityConsistencyAndExpansion(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
 	sc := 0.0
 	if ece > 0.0 {
-		sc = computeSchemaConsistency(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
+		sc = r.computeSchemaConsistency(queryFilename, candidateFilename, queryTextColumns, candidateTextColumns)
 	}
 	sarma := ece * sc
 	d1 := queryFilename[:strings.LastIndex(queryFilename, "____")]
@@ -607,7 +632,7 @@ func computeSarmaUnionabilityScores(queryFilename, candidateFilename string, que
 	return ece, sc
 }
 
-func DoFindSarmaUnionableTables(files <-chan string, fanout int) <-chan *sarmaResult {
+func (r *Repository) DoFindSarmaUnionableTables(files <-chan string, fanout int) <-chan *sarmaResult {
 	out := make(chan *sarmaResult, 10000)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
@@ -615,7 +640,7 @@ func DoFindSarmaUnionableTables(files <-chan string, fanout int) <-chan *sarmaRe
 		go func() {
 			for queryTable := range files {
 				start := GetNow()
-				findSarmaUnionableTables(queryTable, out)
+				r.findSarmaUnionableTables(queryTable, out)
 				fmt.Printf("Processed the query in %.2f seconds\n", GetNow()-start)
 			}
 			wg.Done()
@@ -628,18 +653,18 @@ func DoFindSarmaUnionableTables(files <-chan string, fanout int) <-chan *sarmaRe
 	return out
 }
 
-func DoSaveSarmaScores(scores <-chan *sarmaResult) <-chan ProgressCounter {
+func (r *Repository) DoSaveSarmaScores(scores <-chan *sarmaResult) <-chan ProgressCounter {
 	log.Printf("saving sarma scores")
-	db, err := sql.Open("sqlite3", SarmaDB)
+	db, err := sql.Open("sqlite3", r.SarmaDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, entity_consistency_expansion real, schema_consistency real, sarma real);`, SarmaTable, SarmaTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, entity_consistency_expansion real, schema_consistency real, sarma real);`, r.SarmaTable, r.SarmaTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, entity_consistency_expansion, schema_consistency, sarma) values(?, ?, ?, ?, ?);`, SarmaTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, entity_consistency_expansion, schema_consistency, sarma) values(?, ?, ?, ?, ?);`, r.SarmaTable))
 	if err != nil {
 		panic(err)
 	}
@@ -672,19 +697,19 @@ type sarmaResult struct {
 	schemaConsistency             float64
 }
 
-func findSarmaUnionableTables(queryTable string, out chan *sarmaResult) {
-	resultBucket := bucketize(queryTable)
+func (r *Repository) findSarmaUnionableTables(queryTable string, out chan *sarmaResult) {
+	resultBucket := r.bucketize(queryTable)
 	wg := &sync.WaitGroup{}
 	wg.Add(10)
 	for i := 0; i < 10; i++ {
 		go func() {
 			for candidateTable := range resultBucket {
-				queryTextColumns := getTextDomains(queryTable)
-				candidateTextColumns := getTextDomains(candidateTable)
+				queryTextColumns := r.getTextDomains(queryTable)
+				candidateTextColumns := r.getTextDomains(candidateTable)
 				if len(queryTextColumns) == 0 || len(candidateTextColumns) == 0 {
 					continue
 				}
-				ece, sc := computeSarmaUnionabilityScores(queryTable, candidateTable, queryTextColumns, candidateTextColumns) //, yg.Copy())
+				ece, sc := r.computeSarmaUnionabilityScores(queryTable, candidateTable, queryTextColumns, candidateTextColumns) //, yg.Copy())
 				if ece*sc != 0.0 {
 					out <- &sarmaResult{
 						queryTable:                    queryTable,
@@ -702,14 +727,14 @@ func findSarmaUnionableTables(queryTable string, out chan *sarmaResult) {
 	log.Printf("done waiting")
 }
 
-func computeValueConsistency(queryTable, candidateTable string, queryColumnIndex, candidateColumnIndex int) float64 {
+func (r *Repository) computeValueConsistency(queryTable, candidateTable string, queryColumnIndex, candidateColumnIndex int) float64 {
 	valueConsistency := 0.0
 	numPairs := 0
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 	for i := 0; i < 2; i++ {
 		go func() {
-			for vp := range makeDomainValuePairs(queryTable, candidateTable, queryColumnIndex, candidateColumnIndex) {
+			for vp := range r.makeDomainValuePairs(queryTable, candidateTable, queryColumnIndex, candidateColumnIndex) {
 				d := dice(vp.value1, vp.value2)
 				valueConsistency += d
 				if d > 0.0 {
@@ -731,12 +756,12 @@ type valuePair struct {
 	value2 string
 }
 
-func makeDomainValuePairs(queryTable, candidateTable string, queryColumnIndex, candidateColumnIndex int) <-chan valuePair {
-	queryValues, err := getDomainValues(queryTable, queryColumnIndex)
+func (r *Repository) makeDomainValuePairs(queryTable, candidateTable string, queryColumnIndex, candidateColumnIndex int) <-chan valuePair {
+	queryValues, err := r.getDomainValues(queryTable, queryColumnIndex)
 	if err != nil {
 		panic(err)
 	}
-	candidateValues, err := getDomainValues(candidateTable, candidateColumnIndex)
+	candidateValues, err := r.getDomainValues(candidateTable, candidateColumnIndex)
 	if err != nil {
 		panic(err)
 	}
@@ -769,8 +794,8 @@ func makeDomainValuePairs(queryTable, candidateTable string, queryColumnIndex, c
 	return out
 }
 
-func getDomainValues(tableID string, columnIndex int) ([]string, error) {
-	p := filepath.Join(OutputDir, "domains", tableID, fmt.Sprintf("%d.values", columnIndex))
+func (r *Repository) getDomainValues(tableID string, columnIndex int) ([]string, error) {
+	p := filepath.Join(r.OutputDir, "domains", tableID, fmt.Sprintf("%d.values", columnIndex))
 	file, err := os.Open(p)
 	if err != nil {
 		return nil, err
@@ -814,6 +839,37 @@ func dice(a, b string) (coefficient float64) {
 	return 2 * coefficient / denom
 }
 
+// Saves the minhash of the classes of the YAGO entities of the values of a
+// column and the minhash of the values without entity, with their
+// cardinalities, for the sem and semset measures.
+func (r *Repository) sketchOntology(values []string, dir string, index int) error {
+	if r.yago == nil {
+		return ErrCannotSketch
+	}
+	if len(values) == 0 {
+		return ErrNoSketch
+	}
+	yg := r.yago.Copy()
+	defer yg.Close()
+	transFun := func(s string) string {
+		return strings.ToLower(strings.TrimFunc(strings.TrimSpace(s), unicode.IsPunct))
+	}
+	tokenFun := func(s string) []string {
+		return strings.Split(s, " ")
+	}
+	ontVec, noOntVec, _, ontCard, noOntCard, _ := GetOntDomain(yg, values, r.numHash, r.yagoClasses, transFun, tokenFun)
+	if err := WriteMinhashSignature(ontVec, nil, sketchFilename(dir, index, "ont-minhash-l1")); err != nil {
+		return err
+	}
+	if err := WriteMinhashSignature(noOntVec, nil, sketchFilename(dir, index, "noann-minhash")); err != nil {
+		return err
+	}
+	if err := writeNumbers(sketchFilename(dir, index, "ont-card"), ontCard); err != nil {
+		return err
+	}
+	return writeNumbers(sketchFilename(dir, index, "ont-noann-card"), noOntCard)
+}
+
 func GetOntDomain(yg *yago.Yago, values []string, numHash int, entityClass map[string][]string, transFun func(string) string, tokenFun func(string) []string) ([]uint64, []uint64, []uint64, int, int, int) {
 	// The set of entities found
 	noAnnotation := make(map[string]bool)
@@ -5,25 +5,27 @@ import (
 	"database/sql"
 	"encoding/binary"
 	"fmt"
+	"log"
 	"os"
 	"path"
 	"sync"
 
+	"github.com/RJMillerLab/table-union/hll"
 	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
 )
 
-var (
-	seed    = 1
-	numHash = 256
-)
+// the seed of the minhash sketches of all repositories, so that the
+// sketches of a table can be compared across repositories
+const seed = 1
 
 type DomainSketch struct {
 	Filename string              // the logical filename of the CSV file
 	Index    int                 // the position of the domain in the csv file
 	Sketch   *minhashlsh.Minhash // the minhash sketch
+	Card     *hll.HLL            // the cardinality sketch, if any
 }
 
-func DoMinhashDomainsFromFiles(fanout int, files <-chan string, ext string) <-chan *DomainSketch {
+func (r *Repository) DoMinhashDomainsFromFiles(fanout int, files <-chan string, ext string) <-chan *DomainSketch {
 	out := make(chan *DomainSketch)
 	wg := &sync.WaitGroup{}
 
@@ -31,8 +33,8 @@ func DoMinhashDomainsFromFiles(fanout int, files <-chan string, ext string) <-ch
 		wg.Add(1)
 		go func(id int) {
 			for file := range files {
-				for _, index := range getTextDomains(file) {
-					minhashDomainWords(file, index, out, ext)
+				for _, index := range r.getTextDomains(file) {
+					r.minhashDomainWords(file, index, out, ext)
 				}
 			}
 			wg.Done()
@@ -47,48 +49,51 @@ func DoMinhashDomainsFromFiles(fanout int, files <-chan string, ext string) <-ch
 	return out
 }
 
-func minhashDomainWords(file string, index int, out chan *DomainSketch, ext string) {
-	filepath := path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, ext))
+func (r *Repository) minhashDomainWords(file string, index int, out chan *DomainSketch, ext string) {
+	filepath := path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.%s", index, ext))
 	f, err := os.Open(filepath)
 	if err != nil {
 		return
 		//panic(err)
 	}
 	defer f.Close()
-	mh := minhashlsh.NewMinhash(seed, numHash)
+	mh := minhashlsh.NewMinhash(seed, r.numHash)
+	card := hll.New(hll.DefaultPrecision)
 	scanner := bufio.NewScanner(f)
-
+	values := make([]string, 0)
 	for scanner.Scan() {
-		mh.Push([]byte(normalize(scanner.Text())))
+		values = append(values, normalize(scanner.Text()))
 		//words := wordsFromLine(scanner.Text())
 		//for _, word := range words {
 		//	mh.Push([]byte(word))
 		//}
 	}
+	pushValues(mh, card, values, r.getStopValues())
 	out <- &DomainSketch{
 		Filename: file,
 		Index:    index,
 		Sketch:   mh,
+		Card:     card,
 	}
 }
 
-func DoOntologyMinhashFromDB(fanout int, files <-chan string) <-chan *DomainSketch {
+func (r *Repository) DoOntologyMinhashFromDB(fanout int, files <-chan string) <-chan *DomainSketch {
 	out := make(chan *DomainSketch)
 	wg := &sync.WaitGroup{}
 	//defer db.Close()
-	dbDomains := readDomainsWithOntologyFromDB()
+	dbDomains := r.readDomainsWithOntologyFromDB()
 	for i := 0; i < fanout; i++ {
 		wg.Add(1)
 		go func(id int) {
 			for domain := range dbDomains {
 				// checkpoint - do not process the domains that have been already minhashed.
-				fullpath := path.Join(OutputDir, "domains", domain.tableName)
+				fullpath := path.Join(r.OutputDir, "domains", domain.tableName)
 				fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", domain.columnIndex, "ont-minhash-l1"))
 				if _, err := os.Stat(fullpath); !os.IsNotExist(err) {
 					continue
 				}
 				//
-				minhashDomainClasses(domain.tableName, domain.columnIndex, out)
+				r.minhashDomainClasses(domain.tableName, domain.columnIndex, out)
 			}
 			wg.Done()
 		}(i)
@@ -107,14 +112,14 @@ type dbDomain struct {
 	columnIndex int
 }
 
-func readDomainsWithOntologyFromDB() <-chan dbDomain {
+func (r *Repository) readDomainsWithOntologyFromDB() <-chan dbDomain {
 	out := make(chan dbDomain)
-	db, err := sql.Open("sqlite3", AnnotationDB)
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
 	//defer db.Close()
-	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT table_name, column_index FROM %s WHERE class_frequncy != 0;`, AllAnnotationTable))
+	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT table_name, column_index FROM %s WHERE class_frequncy != 0;`, r.AllAnnotationTable))
 	//rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT table_name, column_index FROM %s;`, AllAnnotationTableL2))
 	if err != nil {
 		panic(err)
@@ -139,9 +144,10 @@ func readDomainsWithOntologyFromDB() <-chan dbDomain {
 	return out
 }
 
-func minhashDomainClasses(file string, index int, out chan *DomainSketch) {
-	mh := minhashlsh.NewMinhash(seed, numHash)
-	db, err := sql.Open("sqlite3", AnnotationDB)
+func (r *Repository) minhashDomainClasses(file string, index int, out chan *DomainSketch) {
+	mh := minhashlsh.NewMinhash(seed, r.numHash)
+	card := hll.New(hll.DefaultPrecision)
+	db, err := sql.Open("sqlite3", r.AnnotationDB)
 	if err != nil {
 		panic(err)
 	}
@@ -160,7 +166,7 @@ func minhashDomainClasses(file string, index int, out chan *DomainSketch) {
 	//}
 	//rows.Close()
 	//rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT class FROM %s WHERE table_name="%s" AND column_index=%d AND class_frequncy != 0;`, AllAnnotationTable, file, index))
-	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT class FROM %s WHERE table_name="%s" AND column_index=%d;`, AllAnnotationTable, file, index))
+	rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT class FROM %s WHERE table_name="%s" AND column_index=%d;`, r.AllAnnotationTable, file, index))
 	if err != nil {
 		panic(err)
 	}
@@ -171,29 +177,33 @@ func minhashDomainClasses(file string, index int, out chan *DomainSketch) {
 			panic(err)
 		}
 		mh.Push([]byte(class))
+		card.Add([]byte(class))
 	}
 	rows.Close()
 	out <- &DomainSketch{
 		Filename: file,
 		Index:    index,
 		Sketch:   mh,
+		Card:     card,
 	}
 }
 
 // Saves the domain skecthes from an input channel to disk
 // Returns a channel of progress counter
-func DoSaveDomainSketches(fanout int, sketches <-chan *DomainSketch, ext string) <-chan ProgressCounter {
+func (r *Repository) DoSaveDomainSketches(fanout int, sketches <-chan *DomainSketch, ext string) <-chan ProgressCounter {
 	progress := make(chan ProgressCounter)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
 	for i := 0; i < fanout; i++ {
 		go func(id int, sketches <-chan *DomainSketch) {
 			for domain := range sketches {
-				minhashFilename := domain.PhysicalFilename(ext)
-				err := writeMinhashSignature(domain.Sketch, minhashFilename)
-				if err == nil {
-					progress <- ProgressCounter{1}
+				minhashFilename := r.DomainFilename(domain.Filename, domain.Index, ext)
+				err := WriteMinhashSignature(domain.Sketch.Signature(), domain.Card, minhashFilename)
+				if err != nil {
+					log.Printf("Saving the sketch of %s:%d failed: %s", domain.Filename, domain.Index, err.Error())
+					continue
 				}
+				progress <- ProgressCounter{1}
 			}
 			wg.Done()
 		}(i, sketches)
@@ -205,31 +215,51 @@ func DoSaveDomainSketches(fanout int, sketches <-chan *DomainSketch, ext string)
 	return progress
 }
 
-func (domain *DomainSketch) PhysicalFilename(ext string) string {
-	fullpath := path.Join(OutputDir, "domains", domain.Filename)
-
-	if ext != "" {
-		fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", domain.Index, ext))
+// WriteMinhashSignature saves a minhash signature, followed by the
+// cardinality sketch of the domain if card is not nil.
+func WriteMinhashSignature(sig []uint64, card *hll.HLL, filename string) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
 	}
-
-	return fullpath
+	defer f.Close()
+	for i := range sig {
+		if err := binary.Write(f, binary.BigEndian, sig[i]); err != nil {
+			return err
+		}
+	}
+	if card != nil {
+		return writeCardinalitySketch(f, card)
+	}
+	return nil
 }
 
-func writeMinhashSignature(mh *minhashlsh.Minhash, filename string) error {
+// Saves numbers, one per line, e.g. the cardinalities of a domain.
+func writeNumbers(filename string, numbers ...int) error {
 	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer f.Close()
-	for i := range mh.Signature() {
-		if err := binary.Write(f, binary.BigEndian, mh.Signature()[i]); err != nil {
+	for _, n := range numbers {
+		if _, err := fmt.Fprintln(f, n); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func ReadMinhashSignature(filename string, numHash int) ([]uint64, error) {
+func (r *Repository) ReadMinhashSignature(filename string, numHash int) ([]uint64, error) {
+	sig, err := r.cachedSketch(sketchKey(filename, numHash), func() (interface{}, error) {
+		return readMinhashSignature(filename, numHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sig.([]uint64), nil
+}
+
+func readMinhashSignature(filename string, numHash int) ([]uint64, error) {
 	f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
 	if err != nil {
 		return nil, err
@@ -245,18 +275,147 @@ func ReadMinhashSignature(filename string, numHash int) ([]uint64, error) {
 	return signature, nil
 }
 
-func GetDomainMinhash(tokenFun func(string) []string, transFun func(string) string, column []string, numHash int) []uint64 {
+func (r *Repository) GetDomainMinhash(tokenFun func(string) []string, transFun func(string) string, column []string, numHash int) []uint64 {
 	//values := TokenizedValues(column, tokenFun, transFun)
-	mh := minhashlsh.NewMinhash(seed, numHash)
-	for _, value := range column {
-		mh.Push([]byte(normalize(value)))
-	}
+	sig, _ := r.GetDomainSketch(column, numHash)
 	//for tokens := range values {
 	//	for _, word := range tokens {
 	//		mh.Push([]byte(word))
 	//	}
 	//}
-	return mh.Signature()
+	return sig
+}
+
+// GetDomainSketch returns the minhash signature of the normalized values of
+// a column and the cardinality sketch of the same values.
+func (r *Repository) GetDomainSketch(column []string, numHash int) ([]uint64, *hll.HLL) {
+	mh := minhashlsh.NewMinhash(seed, numHash)
+	card := hll.New(hll.DefaultPrecision)
+	values := make([]string, len(column))
+	for i, value := range column {
+		values[i] = normalize(value)
+	}
+	pushValues(mh, card, values, r.getStopValues())
+	return mh.Signature(), card
+}
+
+// Saves the minhash and cardinality sketch of the values of a column for
+// the set measure.
+func (r *Repository) sketchSet(values []string, dir string, index int) error {
+	if len(values) == 0 {
+		return ErrNoSketch
+	}
+	sig, card := r.GetDomainSketch(values, r.numHash)
+	return WriteMinhashSignature(sig, card, sketchFilename(dir, index, "minhash"))
+}
+
+// Saves the weighted minhash of the values of a column for the wset
+// measure.
+func (r *Repository) sketchWSet(values []string, dir string, index int) error {
+	if len(values) == 0 {
+		return ErrNoSketch
+	}
+	return WriteMinhashSignature(GetDomainWeightedMinhash(values, r.numHash), nil, sketchFilename(dir, index, "wminhash"))
+}
+
+// WeightedDomainSketch is the weighted minhash sketch of the value
+// frequencies of a domain.
+type WeightedDomainSketch struct {
+	Filename string
+	Index    int
+	Sketch   *minhashlsh.WeightedMinhash
+}
+
+// DoWeightedMinhashDomains sketches the value frequencies of domains with
+// weighted minhash, e.g. the ones of StreamValueFreqFromCache.
+func (r *Repository) DoWeightedMinhashDomains(fanout int, freqs <-chan *ValueFreq) <-chan *WeightedDomainSketch {
+	out := make(chan *WeightedDomainSketch)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < fanout; i++ {
+		wg.Add(1)
+		go func(id int) {
+			for vf := range freqs {
+				out <- &WeightedDomainSketch{
+					Filename: vf.Filename,
+					Index:    vf.Index,
+					Sketch:   weightedMinhash(vf.Values, vf.Freq, r.numHash),
+				}
+			}
+			wg.Done()
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// DoSaveWeightedDomainSketches saves the weighted domain sketches from an
+// input channel to disk. Returns a channel of progress counter.
+func (r *Repository) DoSaveWeightedDomainSketches(fanout int, sketches <-chan *WeightedDomainSketch, ext string) <-chan ProgressCounter {
+	progress := make(chan ProgressCounter)
+	wg := &sync.WaitGroup{}
+	wg.Add(fanout)
+	for i := 0; i < fanout; i++ {
+		go func(id int, sketches <-chan *WeightedDomainSketch) {
+			for domain := range sketches {
+				d := &Domain{
+					Filename: domain.Filename,
+					Index:    domain.Index,
+				}
+				err := WriteMinhashSignature(domain.Sketch.Signature(), nil, r.DomainFilename(d.Filename, d.Index, ext))
+				if err != nil {
+					log.Printf("Saving the sketch of %s:%d failed: %s", domain.Filename, domain.Index, err.Error())
+					continue
+				}
+				progress <- ProgressCounter{1}
+			}
+			wg.Done()
+		}(i, sketches)
+	}
+	go func() {
+		wg.Wait()
+		close(progress)
+	}()
+	return progress
+}
+
+// GetDomainWeightedMinhash returns the weighted minhash signature of the
+// value frequencies of a column.
+func GetDomainWeightedMinhash(column []string, numHash int) []uint64 {
+	freq := make(map[string]int)
+	for _, value := range column {
+		freq[value] += 1
+	}
+	values := make([]string, 0, len(freq))
+	freqs := make([]int, 0, len(freq))
+	for v, f := range freq {
+		values = append(values, v)
+		freqs = append(freqs, f)
+	}
+	return weightedMinhash(values, freqs, numHash).Signature()
+}
+
+// weightedMinhash sketches the normalized values of a domain weighted by
+// their relative frequencies, so the weighted Jaccard of two domains does
+// not depend on their sizes.
+func weightedMinhash(values []string, freqs []int, numHash int) *minhashlsh.WeightedMinhash {
+	weights := make(map[string]float64)
+	total := 0
+	for i, value := range values {
+		// the values files end with a newline
+		if value == "" {
+			continue
+		}
+		weights[normalize(value)] += float64(freqs[i])
+		total += freqs[i]
+	}
+	mh := minhashlsh.NewWeightedMinhash(seed, numHash)
+	for value, w := range weights {
+		mh.Push([]byte(value), w/float64(total))
+	}
+	return mh
 }
 
 // Produce a channel of values (tokenized)
@@ -281,19 +440,19 @@ func TokenizedValues(values []string, tokenFun func(string) []string, transFun f
 	return out
 }
 
-func StreamMinhashVectors(fanout int, ext string, filenames <-chan string) <-chan string {
+func (r *Repository) StreamMinhashVectors(fanout int, ext string, filenames <-chan string) <-chan string {
 	out := make(chan string)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
 	for i := 0; i < fanout; i++ {
 		go func(id int, out chan<- string) {
 			for filename := range filenames {
-				for _, index := range getTextDomains(filename) {
+				for _, index := range r.getTextDomains(filename) {
 					d := &Domain{
 						Filename: filename,
 						Index:    index,
 					}
-					minhashFilename := d.PhysicalFilename(ext)
+					minhashFilename := r.DomainFilename(d.Filename, d.Index, ext)
 					out <- minhashFilename
 				}
 			}
@@ -307,3 +466,30 @@ func StreamMinhashVectors(fanout int, ext string, filenames <-chan string) <-cha
 	}()
 	return out
 }
+
+// StreamNumericMinhashVectors streams the filenames of the minhash sketches
+// of the bins of numeric domains.
+func (r *Repository) StreamNumericMinhashVectors(fanout int, filenames <-chan string) <-chan string {
+	out := make(chan string)
+	wg := &sync.WaitGroup{}
+	wg.Add(fanout)
+	for i := 0; i < fanout; i++ {
+		go func(id int, out chan<- string) {
+			for filename := range filenames {
+				for _, index := range r.getNumericDomains(filename) {
+					d := &Domain{
+						Filename: filename,
+						Index:    index,
+					}
+					out <- r.DomainFilename(d.Filename, d.Index, "num-minhash")
+				}
+			}
+			wg.Done()
+		}(i, out)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,107 @@
+package opendata
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/RJMillerLab/table-union/embedding"
+)
+
+// sketchCache keeps the most recently read sketch files in memory, so that
+// the candidate columns shared by many queries are read from disk once.
+// The cached sketches are shared and must not be modified.
+type sketchCache struct {
+	lock     sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type sketchEntry struct {
+	key   string
+	value interface{}
+}
+
+// SetSketchCacheSize enables a cache of the given number of sketch files
+// of the repository, or disables it if size is 0. It is not safe to call
+// during queries.
+func (r *Repository) SetSketchCacheSize(size int) {
+	if size <= 0 {
+		r.sketches = nil
+		return
+	}
+	r.sketches = &sketchCache{
+		capacity: size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// InvalidateSketches removes the cached sketches of a table, once its
+// sketch files are removed or rewritten.
+func (r *Repository) InvalidateSketches(tableID string) {
+	c := r.sketches
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	dir := "/" + strings.Trim(tableID, "/") + "/"
+	for key, e := range c.entries {
+		if strings.Contains(key, dir) {
+			c.lru.Remove(e)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cachedSketch returns the cached sketch of key, or reads it and caches it.
+// Errors are not cached.
+func (r *Repository) cachedSketch(key string, read func() (interface{}, error)) (interface{}, error) {
+	c := r.sketches
+	if c == nil {
+		return read()
+	}
+	c.lock.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.lock.Unlock()
+		return e.Value.(*sketchEntry).value, nil
+	}
+	c.lock.Unlock()
+	value, err := read()
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.lru.PushFront(&sketchEntry{key, value})
+		if c.lru.Len() > c.capacity {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*sketchEntry).key)
+		}
+	}
+	return value, nil
+}
+
+func sketchKey(filename string, params ...interface{}) string {
+	if len(params) == 0 {
+		return filename
+	}
+	return fmt.Sprintf("%s%v", filename, params)
+}
+
+// readVec reads a vector sketch, e.g. ft-mean, through the cache.
+func (r *Repository) readVec(filename string) ([]float64, error) {
+	vec, err := r.cachedSketch(filename, func() (interface{}, error) {
+		return embedding.ReadVecFromDisk(filename, ByteOrder)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vec.([]float64), nil
+}
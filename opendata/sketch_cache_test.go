@@ -0,0 +1,80 @@
+package opendata
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_cachedSketch(t *testing.T) {
+	od := NewRepository(&Config{})
+	reads := 0
+	read := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			reads += 1
+			return key, nil
+		}
+	}
+	od.SetSketchCacheSize(2)
+	for _, key := range []string{"d/a.csv/0.minhash", "d/a.csv/0.minhash", "d/b.csv/0.minhash", "d/a.csv/0.minhash"} {
+		if v, err := od.cachedSketch(key, read(key)); err != nil || v.(string) != key {
+			t.Fatalf("cached %s is %v", key, v)
+		}
+	}
+	if reads != 2 {
+		t.Errorf("%d reads instead of 2", reads)
+	}
+	// the least recently used sketch of b.csv is evicted
+	od.cachedSketch("d/c.csv/0.minhash", read("d/c.csv/0.minhash"))
+	od.cachedSketch("d/a.csv/0.minhash", read("d/a.csv/0.minhash"))
+	if reads != 3 {
+		t.Errorf("%d reads instead of 3", reads)
+	}
+	od.cachedSketch("d/b.csv/0.minhash", read("d/b.csv/0.minhash"))
+	if reads != 4 {
+		t.Errorf("%d reads instead of 4", reads)
+	}
+	od.InvalidateSketches("b.csv")
+	od.cachedSketch("d/b.csv/0.minhash", read("d/b.csv/0.minhash"))
+	if reads != 5 {
+		t.Errorf("%d reads instead of 5", reads)
+	}
+	// errors are not cached
+	failing := func() (interface{}, error) {
+		reads += 1
+		return nil, fmt.Errorf("no sketch")
+	}
+	od.cachedSketch("d/e.csv/0.minhash", failing)
+	if _, err := od.cachedSketch("d/e.csv/0.minhash", failing); err == nil || reads != 7 {
+		t.Errorf("error is cached")
+	}
+	// disabled
+	od.SetSketchCacheSize(0)
+	od.cachedSketch("d/a.csv/0.minhash", read("d/a.csv/0.minhash"))
+	od.cachedSketch("d/a.csv/0.minhash", read("d/a.csv/0.minhash"))
+	if reads != 9 {
+		t.Errorf("%d reads instead of 9", reads)
+	}
+}
+
+func Test_InvalidateSketches(t *testing.T) {
+	od1, od2 := NewRepository(&Config{}), NewRepository(&Config{})
+	od1.SetSketchCacheSize(2)
+	od2.SetSketchCacheSize(2)
+	reads := 0
+	read := func() (interface{}, error) {
+		reads += 1
+		return "sketch", nil
+	}
+	od1.cachedSketch("d1/a.csv/0.minhash", read)
+	od2.cachedSketch("d2/a.csv/0.minhash", read)
+	// the sketches of a table of one repository are invalidated only
+	od1.InvalidateSketches("a.csv")
+	od2.cachedSketch("d2/a.csv/0.minhash", read)
+	if reads != 2 {
+		t.Errorf("%d reads instead of 2", reads)
+	}
+	od1.cachedSketch("d1/a.csv/0.minhash", read)
+	if reads != 3 {
+		t.Errorf("%d reads instead of 3", reads)
+	}
+}
@@ -1,5 +1,23 @@
 package opendata
 
+import "testing"
+
+func Test_GetDomainWeightedMinhash(t *testing.T) {
+	column := []string{"Toronto", "toronto", "Montreal", "Ottawa", "", "Ottawa"}
+	// the same frequencies relative to the size of the column
+	twice := append(append([]string{}, column...), column...)
+	sig1 := GetDomainWeightedMinhash(column, 128)
+	sig2 := GetDomainWeightedMinhash(twice, 128)
+	if estimateJaccard(sig1, sig2) != 1.0 {
+		t.Fatal("the signature depends on the size of the column")
+	}
+	// more frequent montreal values
+	skewed := append(append([]string{}, column...), "Montreal", "Montreal", "Montreal")
+	if j := estimateJaccard(sig1, GetDomainWeightedMinhash(skewed, 128)); j == 1.0 || j < 0.3 {
+		t.Fatalf("weighted jaccard %f of the skewed column", j)
+	}
+}
+
 /*
 func Test_MinhashSerialization(t *testing.T) {
 	seed = 1
@@ -10,7 +28,7 @@ func Test_MinhashSerialization(t *testing.T) {
 		mh.Push([]byte(word))
 	}
 	sig1 := mh.Signature()
-	err := writeMinhashSignature(mh, "words.minhash")
+	err := WriteMinhashSignature(mh.Signature(), nil, "words.minhash")
 	if err != nil {
 		log.Printf("error in writing sig to the disk.")
 		t.Fail()
@@ -70,72 +70,41 @@ type TableUnion struct {
 	score      float64
 }
 
-func ComputeAllAttUnionabilityScores(queryTable, candidateTable string) []AttributeUnion {
+func (r *Repository) ComputeAllAttUnionabilityScores(queryTable, candidateTable string) []AttributeUnion {
 	union := make([]AttributeUnion, 0)
-	queryTextDomains := getTextDomains(queryTable)
-	candTextDomains := getTextDomains(candidateTable)
-	for _, qindex := range queryTextDomains {
-		for _, cindex := range candTextDomains {
-			uSet, uSem, uSemSet, uNL := getAllAttUnionability(queryTable, candidateTable, qindex, cindex)
-			if uSet != -1.0 && uSet != 0.0 {
-				attunion := AttributeUnion{
-					queryTable:  queryTable,
-					candTable:   candidateTable,
-					queryColumn: qindex,
-					candColumn:  cindex,
-					score:       uSet,
-					measure:     []string{"set"},
-				}
-				union = append(union, attunion)
-			}
-			if uSem != -1.0 && uSem != 0.0 {
-				attunion := AttributeUnion{
-					queryTable:  queryTable,
-					candTable:   candidateTable,
-					queryColumn: qindex,
-					candColumn:  cindex,
-					score:       uSem,
-					measure:     []string{"sem"},
-				}
-				union = append(union, attunion)
-			}
-			if uSemSet != -1.0 && uSemSet != 0.0 {
-				attunion := AttributeUnion{
-					queryTable:  queryTable,
-					candTable:   candidateTable,
-					queryColumn: qindex,
-					candColumn:  cindex,
-					score:       uSemSet,
-					measure:     []string{"semset"},
+	for _, m := range r.Measures() {
+		queryDomains := r.getDomainsOfType(queryTable, m.ColumnType())
+		candDomains := r.getDomainsOfType(candidateTable, m.ColumnType())
+		for _, qindex := range queryDomains {
+			for _, cindex := range candDomains {
+				u := m.Unionability(queryTable, candidateTable, qindex, cindex)
+				if u == -1.0 || u == 0.0 {
+					continue
 				}
-				union = append(union, attunion)
-			}
-			if uNL != -1.0 && uNL != 0.0 {
 				attunion := AttributeUnion{
 					queryTable:  queryTable,
 					candTable:   candidateTable,
 					queryColumn: qindex,
 					candColumn:  cindex,
-					score:       uNL,
-					measure:     []string{"nl"},
+					score:       u,
+					measure:     []string{m.Name()},
 				}
 				union = append(union, attunion)
 			}
 		}
-
 	}
 	return union
 }
 
-func ComputeAttUnionabilityScores(queryTable, candidateTable string) ([]AttributeUnion, int, int) {
+func (r *Repository) ComputeAttUnionabilityScores(queryTable, candidateTable string) ([]AttributeUnion, int, int) {
 	union := make([]AttributeUnion, 0)
-	queryTextDomains := getTextDomains(queryTable)
-	candTextDomains := getTextDomains(candidateTable)
+	queryTextDomains := r.getTextDomains(queryTable)
+	candTextDomains := r.getTextDomains(candidateTable)
 	queryColNum := len(queryTextDomains)
 	candColNum := len(candTextDomains)
 	for _, qindex := range queryTextDomains {
 		for _, cindex := range candTextDomains {
-			score, measure := GetAttUnionability(queryTable, candidateTable, qindex, cindex)
+			score, measure := r.GetAttUnionability(queryTable, candidateTable, qindex, cindex)
 			if score == -1.0 {
 				continue
 			}
@@ -193,117 +162,45 @@ func ComputeTableUnionability(queryTable, candTable string, attunions []Attribut
 }
 */
 
-func getAllAttUnionability(queryTable, candidateTable string, queryIndex, candIndex int) (float64, float64, float64, float64) {
-	uSet := math.Min(1.0, setUnionability(queryTable, candidateTable, queryIndex, candIndex))
-	uNL := math.Min(1.0, nlUnionability(queryTable, candidateTable, queryIndex, candIndex))
-	uSem, uSemSet := semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	uSem = math.Min(1.0, uSem)
-	uSemSet = math.Min(1.0, uSemSet)
-	return uSet, uSem, uSemSet, uNL
-}
-
-func GetOneMeasureAttUnionabilityPercentile(queryTable, candidateTable string, queryIndex, candIndex int, attCDFs map[string]CDF, perturbationDelta float64, measure string) (float64, Percentile, []string) {
-	var attCDF CDF
-	if measure == "set" {
-		attCDF = attCDFs["set"]
-	}
-	if measure == "sem" {
-		attCDF = attCDFs["sem"]
-	}
-	if measure == "semset" {
-		attCDF = attCDFs["semset"]
-	}
-	if measure == "nl" {
-		attCDF = attCDFs["nl"]
-	}
-	uMeasure := make([]string, 0)
-	var u float64
-	var perc Percentile
-	if measure == "set" {
-		u = setUnionability(queryTable, candidateTable, queryIndex, candIndex)
-		if u == -1.0 {
-			return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
-		}
-		perc = GetPerturbedPercentile(attCDF, u, perturbationDelta)
-		uMeasure = append(uMeasure, "set")
+func (r *Repository) GetOneMeasureAttUnionabilityPercentile(queryTable, candidateTable string, queryIndex, candIndex int, attCDFs map[string]CDF, perturbationDelta float64, measure string) (float64, Percentile, []string) {
+	m := r.GetMeasure(measure)
+	if m == nil {
+		return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
 	}
-	if measure == "nl" {
-		u = nlUnionability(queryTable, candidateTable, queryIndex, candIndex)
-		if u == -1.0 {
-			return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
-		}
-		perc = GetPerturbedPercentile(attCDF, u, perturbationDelta)
-		uMeasure = append(uMeasure, "nl")
-	}
-	if measure == "sem" || measure == "semset" {
-		uSem, uSemSet := semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
-		if measure == "sem" {
-			if uSem == -1.0 {
-				return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
-			}
-			perc = GetPerturbedPercentile(attCDF, uSem, perturbationDelta)
-			uMeasure = append(uMeasure, "sem")
-		}
-		if measure == "semset" {
-			if uSemSet == -1.0 {
-				return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
-			}
-			perc = GetPerturbedPercentile(attCDF, uSemSet, perturbationDelta)
-			uMeasure = append(uMeasure, "semset")
-		}
+	u := m.Unionability(queryTable, candidateTable, queryIndex, candIndex)
+	if u == -1.0 {
+		return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
 	}
-	return u, perc, uMeasure
+	perc := GetPerturbedPercentile(attCDFs[measure], u, perturbationDelta)
+	return u, perc, []string{measure}
 }
 
-func GetAttUnionabilityPercentile(queryTable, candidateTable string, queryIndex, candIndex int, attCDFs map[string]CDF, perturbationDelta float64) (float64, Percentile, []string) {
-	setCDF := attCDFs["set"]
-	semCDF := attCDFs["sem"]
-	//semsetCDF := attCDFs["semset"]
-	nlCDF := attCDFs["nl"]
+// GetAttUnionabilityPercentile returns the score of the measure with the
+// highest percentile for a pair of columns. Measures with equal percentiles
+// are all returned. Ties are broken by the order of measures.
+func GetAttUnionabilityPercentile(queryTable, candidateTable string, queryIndex, candIndex int, measures []UnionabilityMeasure, attCDFs map[string]CDF, perturbationDelta float64) (float64, Percentile, []string) {
 	var uScore float64
 	var uPercentile Percentile
 	uMeasure := make([]string, 0)
-	uNL := nlUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	uNLPerc := GetPerturbedPercentile(nlCDF, uNL, perturbationDelta)
-	uScore = uNL
-	uPercentile = uNLPerc
-	uMeasure = append(uMeasure, "nl")
-	uSem, _ := semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	uSet := setUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	if uSet == -1.0 && uSem == -1.0 && uNL == -1.0 {
-		return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
-	}
-	uSetPerc := GetPerturbedPercentile(setCDF, uSet, perturbationDelta)
-	cmp := ComparePercentiles(uSetPerc, uPercentile)
-	if cmp == 1 {
-		uScore = uSet
-		uPercentile = uSetPerc
-		uMeasure = make([]string, 0)
-		uMeasure = append(uMeasure, "set")
-	} else if cmp == 0 {
-		uMeasure = append(uMeasure, "set")
-	}
-	uSemPerc := GetPerturbedPercentile(semCDF, uSem, perturbationDelta)
-	/*
-		uSemSetPerc := GetPerturbedPercentile(semsetCDF, uSemSet, perturbationDelta)
-		cmp = ComparePercentiles(uSemSetPerc, uPercentile)
-		if cmp == 1 {
-			uScore = uSemSet
-			uPercentile = uSemSetPerc
+	sketched := false
+	for i, m := range measures {
+		u := m.Unionability(queryTable, candidateTable, queryIndex, candIndex)
+		if u != -1.0 {
+			sketched = true
+		}
+		perc := GetPerturbedPercentile(attCDFs[m.Name()], u, perturbationDelta)
+		cmp := ComparePercentiles(perc, uPercentile)
+		if i == 0 || cmp == 1 {
+			uScore = u
+			uPercentile = perc
 			uMeasure = make([]string, 0)
-			uMeasure = append(uMeasure, "semset")
+			uMeasure = append(uMeasure, m.Name())
 		} else if cmp == 0 {
-			uMeasure = append(uMeasure, "semset")
+			uMeasure = append(uMeasure, m.Name())
 		}
-	*/
-	cmp = ComparePercentiles(uSemPerc, uPercentile)
-	if cmp == 1 {
-		uScore = uSem
-		uPercentile = uSemPerc
-		uMeasure = make([]string, 0)
-		uMeasure = append(uMeasure, "sem")
-	} else if cmp == 0 {
-		uMeasure = append(uMeasure, "sem")
+	}
+	if !sketched {
+		return -1.0, Percentile{0.0, 0.0, 0.0, 0.0}, []string{}
 	}
 	return uScore, uPercentile, uMeasure
 }
@@ -324,45 +221,29 @@ func ComparePercentiles(p1, p2 Percentile) int {
 	return 1
 }
 
-func GetAttUnionability(queryTable, candidateTable string, queryIndex, candIndex int) (float64, []string) {
+func (r *Repository) GetAttUnionability(queryTable, candidateTable string, queryIndex, candIndex int) (float64, []string) {
 	var uScore float64
 	uMeasure := make([]string, 0)
-	uSet := setUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	uScore = uSet
-	uMeasure = append(uMeasure, "set")
-	uNL := nlUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	if uNL > uScore {
-		uScore = uNL
-		uMeasure = make([]string, 0)
-		uMeasure = append(uMeasure, "nl")
-	} else if uNL == uScore {
-		uMeasure = append(uMeasure, "nl")
-	}
-	uSem, uSemSet := semSetUnionability(queryTable, candidateTable, queryIndex, candIndex)
-	if uSemSet > uScore {
-		uScore = uSemSet
-		uMeasure = make([]string, 0)
-		uMeasure = append(uMeasure, "semset")
-	} else if uSemSet == uScore {
-		uMeasure = append(uMeasure, "semset")
-	}
-	if uSem > uScore {
-		uScore = uSem
-		uMeasure = make([]string, 0)
-		uMeasure = append(uMeasure, "sem")
-	} else if uSem == uScore {
-		uMeasure = append(uMeasure, "sem")
+	for i, m := range r.Measures() {
+		u := m.Unionability(queryTable, candidateTable, queryIndex, candIndex)
+		if i == 0 || u > uScore {
+			uScore = u
+			uMeasure = make([]string, 0)
+			uMeasure = append(uMeasure, m.Name())
+		} else if u == uScore {
+			uMeasure = append(uMeasure, m.Name())
+		}
 	}
 	return uScore, uMeasure
 }
 
-func semSetUnionability(queryTable, candidateTable string, queryIndex, candIndex int) (float64, float64) {
+func (r *Repository) semSetUnionability(queryTable, candidateTable string, queryIndex, candIndex int) (float64, float64) {
 	/*
 		minhashFilename := getUnannotatedMinhashFilename(candidateTable, candIndex)
 		if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 			return -1.0, -1.0
 		}
-		cuaVec, err := ReadMinhashSignature(minhashFilename, numHash)
+		cuaVec, err := r.ReadMinhashSignature(minhashFilename, r.numHash)
 		if err != nil {
 			return -1.0, -1.0
 		}
@@ -370,26 +251,26 @@ func semSetUnionability(queryTable, candidateTable string, queryIndex, candIndex
 		if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 			return -1.0, -1.0
 		}
-		quaVec, err := ReadMinhashSignature(minhashFilename, numHash)
+		quaVec, err := r.ReadMinhashSignature(minhashFilename, r.numHash)
 		if err != nil {
 			return -1.0, -1.0
 		}
 		jaccard := estimateJaccard(quaVec, cuaVec)
 	*/
 	// computing ontology jaccard
-	ontMinhashFilename := getOntMinhashFilename(candidateTable, candIndex)
+	ontMinhashFilename := r.getOntMinhashFilename(candidateTable, candIndex)
 	if _, err := os.Stat(ontMinhashFilename); os.IsNotExist(err) {
 		return -1.0, -1.0
 	}
-	coVec, err := ReadMinhashSignature(ontMinhashFilename, numHash)
+	coVec, err := r.ReadMinhashSignature(ontMinhashFilename, r.numHash)
 	if err != nil {
 		return -1.0, -1.0
 	}
-	ontMinhashFilename = getOntMinhashFilename(queryTable, queryIndex)
+	ontMinhashFilename = r.getOntMinhashFilename(queryTable, queryIndex)
 	if _, err := os.Stat(ontMinhashFilename); os.IsNotExist(err) {
 		return -1.0, -1.0
 	}
-	qoVec, err := ReadMinhashSignature(ontMinhashFilename, numHash)
+	qoVec, err := r.ReadMinhashSignature(ontMinhashFilename, r.numHash)
 	if err != nil {
 		return -1.0, -1.0
 	}
@@ -408,20 +289,61 @@ func semSetUnionability(queryTable, candidateTable string, queryIndex, candIndex
 	//return ontProb, noOntProb + ontProb - ontProb*noOntProb
 }
 
-func nlUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
-	meanFilename := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", candidateTable, candIndex))
+// Saves the mean and the variance of the embeddings of the values of a
+// column, and the number of values with an embedding, for the nl measure.
+func (r *Repository) sketchEmbedding(values []string, dir string, index int) error {
+	if r.ft == nil {
+		return ErrCannotSketch
+	}
+	freq := make(map[string]int)
+	for _, v := range values {
+		freq[v] += 1
+	}
+	distinct := make([]string, 0, len(freq))
+	freqs := make([]int, 0, len(freq))
+	for v, f := range freq {
+		distinct = append(distinct, v)
+		freqs = append(freqs, f)
+	}
+	mean, covar, size, err := r.ft.GetDomainEmbMeanVar(distinct, freqs)
+	if err != nil {
+		return err
+	}
+	if size == 0 || containsNaN(mean) || containsNaN(covar) {
+		return ErrNoSketch
+	}
+	if err := embedding.WriteVecToDisk(mean, ByteOrder, sketchFilename(dir, index, "ft-mean")); err != nil {
+		return err
+	}
+	if err := embedding.WriteVecToDisk(covar, ByteOrder, sketchFilename(dir, index, "ft-covar")); err != nil {
+		return err
+	}
+	return writeNumbers(sketchFilename(dir, index, "size"), size)
+}
+
+func containsNaN(vec []float64) bool {
+	for _, v := range vec {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repository) nlUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	meanFilename := filepath.Join(r.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", candidateTable, candIndex))
 	if _, err := os.Stat(meanFilename); os.IsNotExist(err) {
 		return -1.0
 	}
-	cMean, err := embedding.ReadVecFromDisk(meanFilename, ByteOrder)
+	cMean, err := r.readVec(meanFilename)
 	if err != nil {
 		return -1.0
 	}
-	meanFilename = filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", queryTable, queryIndex))
+	meanFilename = filepath.Join(r.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-mean", queryTable, queryIndex))
 	if _, err := os.Stat(meanFilename); os.IsNotExist(err) {
 		return -1.0
 	}
-	qMean, err := embedding.ReadVecFromDisk(meanFilename, ByteOrder)
+	qMean, err := r.readVec(meanFilename)
 	if err != nil {
 		return -1.0
 	}
@@ -444,21 +366,21 @@ func nlUnionability(queryTable, candidateTable string, queryIndex, candIndex int
 	return uNL
 }
 
-func setUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+func (r *Repository) setUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
 	// getting the embedding of the candidate column
-	minhashFilename := getMinhashFilename(candidateTable, candIndex)
+	minhashFilename := r.getMinhashFilename(candidateTable, candIndex)
 	if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 		return -1.0
 	}
-	cVec, err := ReadMinhashSignature(minhashFilename, numHash)
+	cVec, err := r.ReadMinhashSignature(minhashFilename, r.numHash)
 	if err != nil {
 		return -1.0
 	}
-	minhashFilename = getMinhashFilename(queryTable, queryIndex)
+	minhashFilename = r.getMinhashFilename(queryTable, queryIndex)
 	if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 		return -1.0
 	}
-	qVec, err := ReadMinhashSignature(minhashFilename, numHash)
+	qVec, err := r.ReadMinhashSignature(minhashFilename, r.numHash)
 	if err != nil {
 		return -1.0
 	}
@@ -474,9 +396,27 @@ func setUnionability(queryTable, candidateTable string, queryIndex, candIndex in
 	return uSet
 }
 
-func getDomainCardinality(tableID string, index int) int {
-	cardpath := path.Join(OutputDir, "domains", tableID)
+// wsetUnionability is the weighted Jaccard of the value frequencies of two
+// columns, estimated from their weighted minhash signatures.
+func (r *Repository) wsetUnionability(queryTable, candidateTable string, queryIndex, candIndex int) float64 {
+	cVec, err := r.ReadMinhashSignature(r.getWeightedMinhashFilename(candidateTable, candIndex), r.numHash)
+	if err != nil {
+		return -1.0
+	}
+	qVec, err := r.ReadMinhashSignature(r.getWeightedMinhashFilename(queryTable, queryIndex), r.numHash)
+	if err != nil {
+		return -1.0
+	}
+	return estimateJaccard(cVec, qVec)
+}
+
+func (r *Repository) getDomainCardinality(tableID string, index int) int {
+	cardpath := path.Join(r.OutputDir, "domains", tableID)
 	cardpath = path.Join(cardpath, fmt.Sprintf("%d.%s", index, "card"))
+	return r.ReadCardinality(r.getMinhashFilename(tableID, index), cardpath)
+}
+
+func readDomainCardinality(cardpath string) int {
 	f, err := os.Open(cardpath)
 	defer f.Close()
 	if err != nil {
@@ -542,14 +482,20 @@ func logCombination(m, n int) float64 {
 
 }
 
-func getMinhashFilename(tableID string, index int) string {
-	fullpath := path.Join(OutputDir, "domains", tableID)
+func (r *Repository) getMinhashFilename(tableID string, index int) string {
+	fullpath := path.Join(r.OutputDir, "domains", tableID)
 	fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", index, "minhash"))
 	return fullpath
 }
 
-func getDomainSize(tableID string, index int) int {
-	cardpath := path.Join(OutputDir, "domains", tableID)
+func (r *Repository) getWeightedMinhashFilename(tableID string, index int) string {
+	fullpath := path.Join(r.OutputDir, "domains", tableID)
+	fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", index, "wminhash"))
+	return fullpath
+}
+
+func (r *Repository) getDomainSize(tableID string, index int) int {
+	cardpath := path.Join(r.OutputDir, "domains", tableID)
 	cardpath = path.Join(cardpath, fmt.Sprintf("%d.%s", index, "size"))
 	f, err := os.Open(cardpath)
 	defer f.Close()
@@ -571,86 +517,45 @@ func getDomainSize(tableID string, index int) int {
 	return card
 }
 
-func getUnannotatedMinhashFilename(tableID string, index int) string {
-	fullpath := path.Join(OutputDir, "domains", tableID)
+func (r *Repository) getUnannotatedMinhashFilename(tableID string, index int) string {
+	fullpath := path.Join(r.OutputDir, "domains", tableID)
 	fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", index, "noann-minhash"))
 	return fullpath
 }
 
-func getOntMinhashFilename(tableID string, index int) string {
-	fullpath := path.Join(OutputDir, "domains", tableID)
+func (r *Repository) getOntMinhashFilename(tableID string, index int) string {
+	fullpath := path.Join(r.OutputDir, "domains", tableID)
 	fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", index, "ont-minhash-l1"))
 	return fullpath
 }
 
-func getOntDomainCardinality(tableID string, index int) (int, int) {
-	cardpath := path.Join(OutputDir, "domains", tableID)
-	cardpath = path.Join(cardpath, fmt.Sprintf("%d.%s", index, "ont-noann-card"))
-	f, err := os.Open(cardpath)
-	defer f.Close()
-	if err != nil {
-		return -1.0, -1.0
-	}
-	card := 0
-	ocard := 0
-	scanner := bufio.NewScanner(f)
-	lineIndex := 0
-	for scanner.Scan() {
-		if lineIndex == 0 {
-			c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-			if err == nil {
-				card = c
-			}
-			lineIndex += 1
-		}
-		//if lineIndex == 1 {
-		//	c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-		//	if err == nil {
-		//		ocard = c
-		//	}
-		//	lineIndex += 1
-		//}
-	}
-	ontCardpath := path.Join(OutputDir, "domains", tableID)
-	ontCardpath = path.Join(ontCardpath, fmt.Sprintf("%d.%s", index, "ont-card"))
-	fo, err := os.Open(ontCardpath)
-	defer fo.Close()
-	if err != nil {
+func (r *Repository) getOntDomainCardinality(tableID string, index int) (int, int) {
+	cardpath := path.Join(r.OutputDir, "domains", tableID)
+	card := r.ReadCardinality(r.getUnannotatedMinhashFilename(tableID, index), path.Join(cardpath, fmt.Sprintf("%d.%s", index, "ont-noann-card")))
+	ocard := r.ReadCardinality(r.getOntMinhashFilename(tableID, index), path.Join(cardpath, fmt.Sprintf("%d.%s", index, "ont-card")))
+	if card == -1 || ocard == -1 {
 		return -1.0, -1.0
 	}
-	scanner = bufio.NewScanner(fo)
-	lineIndex = 0
-	for scanner.Scan() {
-		if lineIndex == 0 {
-			c, err := strconv.Atoi(strings.Replace(scanner.Text(), "\n", "", -1))
-			if err == nil {
-				ocard = c
-			} else {
-				panic(err)
-			}
-		}
-		lineIndex += 1
-	}
 	return card, ocard
 }
 
-func CountTextAtts(queryTable string, candidateTable string) int {
-	queryTextDomains := getTextDomains(queryTable)
-	candTextDomains := getTextDomains(candidateTable)
+func (r *Repository) CountTextAtts(queryTable string, candidateTable string) int {
+	queryTextDomains := r.getTextDomains(queryTable)
+	candTextDomains := r.getTextDomains(candidateTable)
 	return len(queryTextDomains) * len(candTextDomains)
 }
 
-func DoSaveAttScores(allScores chan []AttributeUnion, progress chan ProgressCounter) {
-	db, err := sql.Open("sqlite3", AttStatsDB)
+func (r *Repository) DoSaveAttScores(allScores chan []AttributeUnion, progress chan ProgressCounter) {
+	db, err := sql.Open("sqlite3", r.AttStatsDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text,query_column int, candidate_table text,candidate_column int, score real, measure text, percentile real);`, AllAttStatsTable, AllAttStatsTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text,query_column int, candidate_table text,candidate_column int, score real, measure text, percentile real);`, r.AllAttStatsTable, r.AllAttStatsTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, query_column, candidate_table, candidate_column, score, measure) values(?, ?, ?, ?, ?, ?);`, AllAttStatsTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, query_column, candidate_table, candidate_column, score, measure) values(?, ?, ?, ?, ?, ?);`, r.AllAttStatsTable))
 	if err != nil {
 		panic(err)
 	}
@@ -668,17 +573,17 @@ func DoSaveAttScores(allScores chan []AttributeUnion, progress chan ProgressCoun
 	db.Close()
 }
 
-func DoSaveTableScores(unions chan TableUnion, progress chan ProgressCounter) {
-	db, err := sql.Open("sqlite3", TableStatsDB)
+func (r *Repository) DoSaveTableScores(unions chan TableUnion, progress chan ProgressCounter) {
+	db, err := sql.Open("sqlite3", r.TableStatsDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column int, candidate_column int, score real, measure text);`, TableStatsTable, TableStatsTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column int, candidate_column int, score real, measure text);`, r.TableStatsTable, r.TableStatsTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column, candidate_column, score, measure) values(?, ?, ?, ?, ?, ?);`, TableStatsTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column, candidate_column, score, measure) values(?, ?, ?, ?, ?, ?);`, r.TableStatsTable))
 	if err != nil {
 		panic(err)
 	}
@@ -715,33 +620,28 @@ type attPairPerc struct {
 	percentile      float64
 }
 
-func SavePercentileAttUnionability() {
-	setCDF, semCDF, semsetCDF, nlCDF := LoadAttCDF()
-	cdfs := make(map[string]CDF)
-	cdfs["set"] = setCDF
-	cdfs["sem"] = semCDF
-	cdfs["semset"] = semsetCDF
-	cdfs["nl"] = nlCDF
-	db1, err := sql.Open("sqlite3", AttStatsDB)
+func (r *Repository) SavePercentileAttUnionability() {
+	cdfs := r.LoadAttCDF()
+	db1, err := sql.Open("sqlite3", r.AttStatsDB)
 	if err != nil {
 		panic(err)
 	}
 
-	db2, err := sql.Open("sqlite3", TableStatsDB)
+	db2, err := sql.Open("sqlite3", r.TableStatsDB)
 	if err != nil {
 		panic(err)
 	}
 
-	rows, err := db1.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table, query_column, candidate_column, score, measure FROM %s;`, AllAttStatsTable))
+	rows, err := db1.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table, query_column, candidate_column, score, measure FROM %s;`, r.AllAttStatsTable))
 	if err != nil {
 		panic(err)
 	}
 
-	_, err = db2.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column int, candidate_column int, score real, percentile real, measure text);`, AllAttPercentileTable, AllAttPercentileTable))
+	_, err = db2.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_column int, candidate_column int, score real, percentile real, measure text);`, r.AllAttPercentileTable, r.AllAttPercentileTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db2.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column,  candidate_column, score, percentile, measure) values(?, ?, ?, ?, ?, ?, ?);`, AllAttPercentileTable))
+	stmt, err := db2.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_column,  candidate_column, score, percentile, measure) values(?, ?, ?, ?, ?, ?, ?);`, r.AllAttPercentileTable))
 	if err != nil {
 		panic(err)
 	}
@@ -813,13 +713,13 @@ func SavePercentileAttUnionability() {
 	db2.Close()
 }
 
-func ComputeTableUnionabilityVariousC() {
+func (r *Repository) ComputeTableUnionabilityVariousC() {
 	tableUnions := make(chan tableCUnion)
-	db, err := sql.Open("sqlite3", AttStatsDB)
+	db, err := sql.Open("sqlite3", r.AttStatsDB)
 	if err != nil {
 		panic(err)
 	}
-	rows2, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, AllAttPercentileTable))
+	rows2, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, r.AllAttPercentileTable))
 	if err != nil {
 		panic(err)
 	}
@@ -848,7 +748,7 @@ func ComputeTableUnionabilityVariousC() {
 		go func() {
 			//for _, pair := range tablePairs {
 			for pair := range tablePairs {
-				rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_column, candidate_column, MAX(percentile) AS maxPercentile FROM %s WHERE query_table='%s' AND candidate_table='%s' AND percentile > 0.0 GROUP BY query_column, candidate_column ORDER BY maxPercentile desc;`, AllAttPercentileTable, strings.Split(pair, " ")[0], strings.Split(pair, " ")[1]))
+				rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_column, candidate_column, MAX(percentile) AS maxPercentile FROM %s WHERE query_table='%s' AND candidate_table='%s' AND percentile > 0.0 GROUP BY query_column, candidate_column ORDER BY maxPercentile desc;`, r.AllAttPercentileTable, strings.Split(pair, " ")[0], strings.Split(pair, " ")[1]))
 				if err != nil {
 					panic(err)
 				}
@@ -902,23 +802,23 @@ func ComputeTableUnionabilityVariousC() {
 		wwg.Done()
 	}()
 	go func() {
-		saveTableUnionabilityVariousC(tableUnions)
+		r.saveTableUnionabilityVariousC(tableUnions)
 		wwg.Done()
 	}()
 	wwg.Wait()
 }
 
-func saveTableUnionabilityVariousC(unions chan tableCUnion) {
-	db, err := sql.Open("sqlite3", TableStatsDB)
+func (r *Repository) saveTableUnionabilityVariousC(unions chan tableCUnion) {
+	db, err := sql.Open("sqlite3", r.TableStatsDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, c int, score real, difference real);`, CTableStatsTable, CTableStatsTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, c int, score real, difference real);`, r.CTableStatsTable, r.CTableStatsTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, c, score, difference) values(?, ?, ?, ?, ?);`, CTableStatsTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, c, score, difference) values(?, ?, ?, ?, ?);`, r.CTableStatsTable))
 	if err != nil {
 		panic(err)
 	}
@@ -940,35 +840,26 @@ func saveTableUnionabilityVariousC(unions chan tableCUnion) {
 	db.Close()
 }
 
-func ComputeTableUnionabilityCDF(numBins int) {
-	cCDFs := computeCUnionabilityCDFEquiDepth(numBins, TableStatsDB, CTableStatsTable)
-	saveMultCDF(cCDFs, TableStatsDB, TableCDFTable)
+func (r *Repository) ComputeTableUnionabilityCDF(numBins int) {
+	cCDFs := computeCUnionabilityCDFEquiDepth(numBins, r.TableStatsDB, r.CTableStatsTable)
+	saveMultCDF(cCDFs, r.TableStatsDB, r.TableCDFTable)
 }
 
-func ComputeAttUnionabilityCDF(numBins int) {
-	cdf := computeCDFEquiWidth(numBins, AttStatsDB, AttStatsTable)
-	saveCDF(cdf, AttStatsDB, AttCDFTable)
+func (r *Repository) ComputeAttUnionabilityCDF(numBins int) {
+	cdf := computeCDFEquiWidth(numBins, r.AttStatsDB, r.AttStatsTable)
+	saveCDF(cdf, r.AttStatsDB, r.AttCDFTable)
 }
 
-func ComputeAllAttUnionabilityCDF(numBins int) {
-	setCdf := computeAttCDFEquiDepth(numBins, AttStatsDB, AllAttStatsTable, "set")
-	saveCDF(setCdf, AttStatsDB, SetCDFTable)
-	semCdf := computeAttCDFEquiDepth(numBins, AttStatsDB, AllAttStatsTable, "sem")
-	saveCDF(semCdf, AttStatsDB, SemCDFTable)
-	semSetCdf := computeAttCDFEquiDepth(numBins, AttStatsDB, AllAttStatsTable, "semset")
-	saveCDF(semSetCdf, AttStatsDB, SemSetCDFTable)
-	nlCdf := computeAttCDFEquiDepth(numBins, AttStatsDB, AllAttStatsTable, "nl")
-	saveCDF(nlCdf, AttStatsDB, NlCDFTable)
-	/*
-		setCdf := computeAttCDFEquiWidth(numBins, AttStatsDB, AllAttStatsTable, "set")
-		saveCDF(setCdf, AttStatsDB, SetCDFTable)
-		semCdf := computeAttCDFEquiWidth(numBins, AttStatsDB, AllAttStatsTable, "sem")
-		saveCDF(semCdf, AttStatsDB, SemCDFTable)
-		semSetCdf := computeAttCDFEquiWidth(numBins, AttStatsDB, AllAttStatsTable, "semset")
-		saveCDF(semSetCdf, AttStatsDB, SemSetCDFTable)
-		nlCdf := computeAttCDFEquiWidth(numBins, AttStatsDB, AllAttStatsTable, "nl")
-		saveCDF(nlCdf, AttStatsDB, NlCDFTable)
-	*/
+func (r *Repository) ComputeAllAttUnionabilityCDF(numBins int) {
+	for _, m := range r.Measures() {
+		if m.CDFTable() == "" {
+			log.Printf("No CDF table for measure %s.", m.Name())
+			continue
+		}
+		cdf := computeAttCDFEquiDepth(numBins, r.AttStatsDB, r.AllAttStatsTable, m.Name())
+		//cdf := computeAttCDFEquiWidth(numBins, AttStatsDB, AllAttStatsTable, m.Name())
+		saveCDF(cdf, r.AttStatsDB, m.CDFTable())
+	}
 }
 
 func computeCUnionabilityCDFEquiWidth(numBins int, dbName, tableName string) map[int][]Bin {
@@ -1434,21 +1325,28 @@ func saveCDF(bins []Bin, dbName, tableName string) {
 	log.Printf("Finished saving: %s %s", dbName, tableName)
 }
 
-func LoadAttCDF() (CDF, CDF, CDF, CDF) {
-	setCDF := readCDFFromDB(AttStatsDB, SetCDFTable)
-	semCDF := readCDFFromDB(AttStatsDB, SemCDFTable)
-	semsetCDF := readCDFFromDB(AttStatsDB, SemSetCDFTable)
-	nlCDF := readCDFFromDB(AttStatsDB, NlCDFTable)
-	return setCDF, semCDF, semsetCDF, nlCDF
+// LoadAttCDF reads the CDFs of the registered measures.
+func (r *Repository) LoadAttCDF() map[string]CDF {
+	cdfs := make(map[string]CDF)
+	for _, m := range r.Measures() {
+		if m.CDFTable() == "" {
+			log.Printf("No CDF table for measure %s.", m.Name())
+			continue
+		}
+		cdfs[m.Name()] = r.LoadMeasureCDF(m)
+	}
+	return cdfs
+}
+
+// LoadMeasureCDF reads the CDF of a measure.
+func (r *Repository) LoadMeasureCDF(m UnionabilityMeasure) CDF {
+	return readCDFFromDB(r.AttStatsDB, m.CDFTable())
 }
 
-func LoadCDF() (CDF, CDF, CDF, CDF, map[int]CDF) {
-	setCDF := readCDFFromDB(AttStatsDB, SetCDFTable)
-	semCDF := readCDFFromDB(AttStatsDB, SemCDFTable)
-	semsetCDF := readCDFFromDB(AttStatsDB, SemSetCDFTable)
-	nlCDF := readCDFFromDB(AttStatsDB, NlCDFTable)
-	tableCDF := readMultCDFFromDB(TableStatsDB, TableCDFTable)
-	return setCDF, semCDF, semsetCDF, nlCDF, tableCDF
+func (r *Repository) LoadCDF() (map[string]CDF, map[int]CDF) {
+	attCDFs := r.LoadAttCDF()
+	tableCDF := readMultCDFFromDB(r.TableStatsDB, r.TableCDFTable)
+	return attCDFs, tableCDF
 }
 
 func readMultCDFFromDB(dbName, tableName string) map[int]CDF {
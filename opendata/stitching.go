@@ -15,13 +15,13 @@ type StitchedTable struct {
 	score      float64
 }
 
-func ComputeTableStitchingUnionability() {
+func (r *Repository) ComputeTableStitchingUnionability() {
 	tableUnions := make(chan StitchedTable)
-	db, err := sql.Open("sqlite3", AttStitchingDB)
+	db, err := sql.Open("sqlite3", r.AttStitchingDB)
 	if err != nil {
 		panic(err)
 	}
-	rows2, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, AttStitchingTable))
+	rows2, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_table, candidate_table FROM %s;`, r.AttStitchingTable))
 	if err != nil {
 		panic(err)
 	}
@@ -48,7 +48,7 @@ func ComputeTableStitchingUnionability() {
 		go func() {
 			for pair := range tablePairs {
 				alignment := make([]string, 0)
-				rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_col_name, candidate_col_name, MAX(score) AS maxScore FROM %s WHERE query_table='%s' AND candidate_table='%s' AND score > 0.0 GROUP BY query_col_name, candidate_col_name ORDER BY maxScore desc;`, AttStitchingTable, strings.Split(pair, " ")[0], strings.Split(pair, " ")[1]))
+				rows, err := db.Query(fmt.Sprintf(`SELECT DISTINCT query_col_name, candidate_col_name, MAX(score) AS maxScore FROM %s WHERE query_table='%s' AND candidate_table='%s' AND score > 0.0 GROUP BY query_col_name, candidate_col_name ORDER BY maxScore desc;`, r.AttStitchingTable, strings.Split(pair, " ")[0], strings.Split(pair, " ")[1]))
 				if err != nil {
 					panic(err)
 				}
@@ -98,23 +98,23 @@ func ComputeTableStitchingUnionability() {
 		wwg.Done()
 	}()
 	go func() {
-		saveTableUnionability(tableUnions)
+		r.saveTableUnionability(tableUnions)
 		wwg.Done()
 	}()
 	wwg.Wait()
 }
 
-func saveTableUnionability(unions chan StitchedTable) {
-	db, err := sql.Open("sqlite3", TableStitchingDB)
+func (r *Repository) saveTableUnionability(unions chan StitchedTable) {
+	db, err := sql.Open("sqlite3", r.TableStitchingDB)
 	if err != nil {
 		panic(err)
 	}
 	// Create table
-	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_col_name text, candidate_col_name text, score real);`, TableStitchingTable, TableStitchingTable))
+	_, err = db.Exec(fmt.Sprintf(`drop table if exists %s; create table if not exists %s (query_table text, candidate_table text, query_col_name text, candidate_col_name text, score real);`, r.TableStitchingTable, r.TableStitchingTable))
 	if err != nil {
 		panic(err)
 	}
-	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_col_name, candidate_col_name, score) values(?, ?, ?, ?, ?);`, TableStitchingTable))
+	stmt, err := db.Prepare(fmt.Sprintf(`insert into %s(query_table, candidate_table, query_col_name, candidate_col_name, score) values(?, ?, ?, ?, ?);`, r.TableStitchingTable))
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,185 @@
+package opendata
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/RJMillerLab/table-union/hll"
+	minhashlsh "github.com/RJMillerLab/table-union/minhashlsh"
+)
+
+// ValueDocFreq is the number of domains of the repository that contain
+// each normalized value.
+type ValueDocFreq struct {
+	NumDomains int
+	Freq       map[string]int
+	// the distinct values of the first domains, to measure the effect of
+	// stop values on the minhash LSH buckets
+	Sample [][]string
+}
+
+// ComputeValueDocFreq counts the domains containing each value in a stream
+// of value frequencies, e.g. the one of StreamValueFreqFromCache, and keeps
+// the values of the first sampleSize domains.
+func ComputeValueDocFreq(freqs <-chan *ValueFreq, sampleSize int) *ValueDocFreq {
+	df := &ValueDocFreq{
+		Freq:   make(map[string]int),
+		Sample: make([][]string, 0),
+	}
+	for vf := range freqs {
+		values := make(map[string]bool)
+		for _, value := range vf.Values {
+			// the values files end with a newline
+			if value == "" {
+				continue
+			}
+			values[normalize(value)] = true
+		}
+		if len(values) == 0 {
+			continue
+		}
+		df.NumDomains += 1
+		distinct := make([]string, 0, len(values))
+		for value := range values {
+			df.Freq[value] += 1
+			distinct = append(distinct, value)
+		}
+		if len(df.Sample) < sampleSize {
+			df.Sample = append(df.Sample, distinct)
+		}
+		if df.NumDomains%10000 == 0 {
+			fmt.Printf("Counted the values of %d domains\n", df.NumDomains)
+		}
+	}
+	return df
+}
+
+// StopValues returns the values found in more than the given fraction of
+// the domains, the most frequent first.
+func (df *ValueDocFreq) StopValues(fraction float64) []string {
+	minFreq := int(fraction * float64(df.NumDomains))
+	values := make([]string, 0)
+	for value, freq := range df.Freq {
+		if freq > minFreq {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if df.Freq[values[i]] != df.Freq[values[j]] {
+			return df.Freq[values[i]] > df.Freq[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// CompareBuckets indexes the minhash sketches of the sampled domains with
+// and without the stop values, and returns the bucket statistics of both
+// indexes. The sketches have numHash hash functions.
+func (df *ValueDocFreq) CompareBuckets(stopValues []string, numHash int, threshold float64) (before, after minhashlsh.BucketStats) {
+	stop := make(map[string]bool)
+	for _, value := range stopValues {
+		stop[value] = true
+	}
+	withStop := minhashlsh.NewMinhashLSH32(numHash, threshold)
+	withoutStop := minhashlsh.NewMinhashLSH32(numHash, threshold)
+	for i, values := range df.Sample {
+		key := strconv.Itoa(i)
+		mh := minhashlsh.NewMinhash(seed, numHash)
+		pushValues(mh, nil, values, nil)
+		withStop.Add(key, mh.Signature())
+		mh = minhashlsh.NewMinhash(seed, numHash)
+		pushValues(mh, nil, values, stop)
+		withoutStop.Add(key, mh.Signature())
+	}
+	withStop.Index()
+	withoutStop.Index()
+	return withStop.BucketStats(), withoutStop.BucketStats()
+}
+
+// WriteStopValues saves stop values, one per line.
+func WriteStopValues(filename string, values []string) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, value := range values {
+		if _, err := fmt.Fprintln(w, value); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// ReadStopValues reads the stop values saved by WriteStopValues.
+func ReadStopValues(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		values = append(values, scanner.Text())
+	}
+	return values, scanner.Err()
+}
+
+// SetStopValues replaces the stop values left out of the minhash sketches
+// of domains. It must be called before sketching.
+func (r *Repository) SetStopValues(values []string) {
+	r.stopValuesOnce.Do(func() {})
+	r.stopValues = make(map[string]bool)
+	for _, value := range values {
+		r.stopValues[normalize(value)] = true
+	}
+}
+
+// getStopValues returns the stop values, read from the STOP_VALUES file
+// on first use unless they are set.
+func (r *Repository) getStopValues() map[string]bool {
+	r.stopValuesOnce.Do(func() {
+		if r.StopValuesFile == "" {
+			return
+		}
+		values, err := ReadStopValues(r.StopValuesFile)
+		if err != nil {
+			panic(err)
+		}
+		r.stopValues = make(map[string]bool)
+		for _, value := range values {
+			r.stopValues[value] = true
+		}
+	})
+	return r.stopValues
+}
+
+// pushValues adds the normalized values that are not stop values to a
+// minhash. A domain of stop values only is sketched with all its values, so
+// that it does not collide with every other one. All the values, stop
+// values included, are added to the cardinality sketch of the domain if not
+// nil, so that it counts the distinct values like the card files.
+func pushValues(mh *minhashlsh.Minhash, card *hll.HLL, values []string, stop map[string]bool) {
+	skipped := make([]string, 0)
+	for _, value := range values {
+		if card != nil {
+			card.Add([]byte(value))
+		}
+		if stop[value] {
+			skipped = append(skipped, value)
+			continue
+		}
+		mh.Push([]byte(value))
+	}
+	if len(skipped) == len(values) {
+		for _, value := range skipped {
+			mh.Push([]byte(value))
+		}
+	}
+}
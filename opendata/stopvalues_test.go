@@ -0,0 +1,80 @@
+package opendata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// stopValueDomains returns domains of distinct values that all share the
+// same yes/no/n/a values.
+func stopValueDomains(n int) <-chan *ValueFreq {
+	out := make(chan *ValueFreq)
+	go func() {
+		for i := 0; i < n; i++ {
+			values := []string{"Yes", "No", "N/A", ""}
+			freq := []int{3, 2, 1, 1}
+			for j := 0; j < 5; j++ {
+				values = append(values, fmt.Sprintf("value-%d-%d", i, j))
+				freq = append(freq, 1)
+			}
+			out <- &ValueFreq{Filename: fmt.Sprintf("table%d", i), Values: values, Freq: freq}
+		}
+		close(out)
+	}()
+	return out
+}
+
+func Test_StopValues(t *testing.T) {
+	df := ComputeValueDocFreq(stopValueDomains(100), 50)
+	if df.NumDomains != 100 || len(df.Sample) != 50 {
+		t.Fatalf("%d domains, %d sampled", df.NumDomains, len(df.Sample))
+	}
+	stopValues := df.StopValues(0.5)
+	if len(stopValues) != 3 || stopValues[0] != "n/a" || stopValues[1] != "no" || stopValues[2] != "yes" {
+		t.Fatalf("stop values %v", stopValues)
+	}
+	dir, err := ioutil.TempDir("", "stopvalues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := path.Join(dir, "stop-values.txt")
+	if err := WriteStopValues(filename, stopValues); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadStopValues(filename)
+	if err != nil || len(read) != len(stopValues) {
+		t.Fatalf("read %v: %v", read, err)
+	}
+	before, after := df.CompareBuckets(stopValues, 256, 0.3)
+	if after.MeanCandidates >= before.MeanCandidates {
+		t.Errorf("%.2f candidates without stop values, %.2f with", after.MeanCandidates, before.MeanCandidates)
+	}
+}
+
+func Test_GetDomainMinhashStopValues(t *testing.T) {
+	od := NewRepository(&Config{})
+	a := []string{"Yes", "No", "N/A", "Toronto", "Montreal"}
+	b := []string{"yes", "no", "n/a", "Paris", "Lyon"}
+	od.SetStopValues(nil)
+	if j := estimateJaccard(od.GetDomainMinhash(nil, nil, a, 256), od.GetDomainMinhash(nil, nil, b, 256)); j < 0.2 {
+		t.Fatalf("jaccard %f with stop values", j)
+	}
+	od.SetStopValues([]string{"YES", "no", "n/a"})
+	if j := estimateJaccard(od.GetDomainMinhash(nil, nil, a, 256), od.GetDomainMinhash(nil, nil, b, 256)); j > 0.05 {
+		t.Fatalf("jaccard %f without stop values", j)
+	}
+	// the cardinality sketch counts the stop values
+	if _, card := od.GetDomainSketch(a, 256); card.Count() < 3.5 {
+		t.Fatalf("cardinality %f of %d values", card.Count(), len(a))
+	}
+	// a domain of stop values only is still sketched
+	c := od.GetDomainMinhash(nil, nil, []string{"Yes", "No"}, 256)
+	d := od.GetDomainMinhash(nil, nil, []string{"N/A"}, 256)
+	if estimateJaccard(c, d) > 0.05 {
+		t.Fatal("domains of stop values collide")
+	}
+}
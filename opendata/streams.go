@@ -50,10 +50,10 @@ func exists(filename string) bool {
 }
 
 // Creates a channel of filenames
-func StreamFilenames() <-chan string {
+func (r *Repository) StreamFilenames() <-chan string {
 	output := make(chan string)
 	go func() {
-		f, err := os.Open(OpendataList)
+		f, err := os.Open(r.OpendataList)
 		if err != nil {
 			panic(err)
 		}
@@ -84,9 +84,9 @@ type Domain struct {
 
 // Saves the domain values into its value file
 // and reports the status using the logger provided
-func (domain *Domain) Save(logger *log.Logger) int {
+func (r *Repository) SaveDomain(domain *Domain, logger *log.Logger) int {
 	var filepath string
-	dirPath := path.Join(OutputDir, "domains", domain.Filename)
+	dirPath := path.Join(r.OutputDir, "domains", domain.Filename)
 
 	if domain.Index < 0 {
 		// Encountered a file for the first time.
@@ -111,11 +111,13 @@ func (domain *Domain) Save(logger *log.Logger) int {
 	return len(domain.Values)
 }
 
-func (domain *Domain) PhysicalFilename(ext string) string {
-	fullpath := path.Join(OutputDir, "domains", domain.Filename)
+// DomainFilename returns the file of the extension ext of a domain of a
+// table, or the directory of the domains of the table if ext is empty.
+func (r *Repository) DomainFilename(table string, index int, ext string) string {
+	fullpath := path.Join(r.OutputDir, "domains", table)
 
 	if ext != "" {
-		fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", domain.Index, ext))
+		fullpath = path.Join(fullpath, fmt.Sprintf("%d.%s", index, ext))
 	}
 
 	return fullpath
@@ -127,8 +129,8 @@ func (domain *Domain) Id() string {
 
 // Loads the headers from the index file
 // of the csv file
-func GetDomainHeader(file string) *Domain {
-	filepath := path.Join(OutputDir, "domains", file, "index")
+func (r *Repository) GetDomainHeader(file string) *Domain {
+	filepath := path.Join(r.OutputDir, "domains", file, "index")
 	lines, err := readLines(filepath, -1)
 	if err != nil {
 		return &Domain{
@@ -170,62 +172,71 @@ func domainsFromCells(cells [][]string, filename string, width int) []*Domain {
 
 // A single worker function that "moves" filenames
 // for the input channel to domains of the output channel
-func makeDomains(filenames <-chan string, out chan *Domain) {
+func (r *Repository) makeDomains(filenames <-chan string, out chan *Domain) {
 	for filename := range filenames {
-		f, err := os.Open(Filepath(filename))
+		f, err := os.Open(r.Filepath(filename))
 		if err != nil {
 			panic(err)
 			f.Close()
 			continue
 		}
-		// Uses the csv parser to read
-		// the csv content line by line
-		// the first row is the headers of the domains
-		rdr := csv.NewReader(f)
-		header, err := rdr.Read()
-		if err != nil {
-			continue
-		}
-		width := len(header)
+		readDomains(f, filename, func(domain *Domain) {
+			out <- domain
+		})
+		f.Close()
+	}
+}
 
-		headerDomain := &Domain{
-			Filename: filename,
-			Index:    -1,
-			Values:   header,
-		}
+// Reads the header and the domain fragments of a csv file.
+// Returns an error if the header cannot be read.
+func readDomains(r io.Reader, filename string, emit func(*Domain)) error {
+	// Uses the csv parser to read
+	// the csv content line by line
+	// the first row is the headers of the domains
+	rdr := csv.NewReader(r)
+	header, err := rdr.Read()
+	if err != nil {
+		return err
+	}
+	width := len(header)
+
+	headerDomain := &Domain{
+		Filename: filename,
+		Index:    -1,
+		Values:   header,
+	}
 
-		out <- headerDomain
-		var cells [][]string
-		for {
-			// adding this line because US tables do not have headers
+	emit(headerDomain)
+	var cells [][]string
+	for {
+		// adding this line because US tables do not have headers
 
-			//
-			row, err := rdr.Read()
-			if err == io.EOF {
-				// at the end-of-file, we output the domains from the
-				// cells buffer
+		//
+		row, err := rdr.Read()
+		if err == io.EOF {
+			// at the end-of-file, we output the domains from the
+			// cells buffer
+			for _, domain := range domainsFromCells(cells, filename, width) {
+				emit(domain)
+			}
+			break
+		} else {
+			// read row by row into the cells buffer until there are over 1 million entries
+			cells = append(cells, row)
+			if len(cells)*width > 1000000 {
 				for _, domain := range domainsFromCells(cells, filename, width) {
-					out <- domain
-				}
-				break
-			} else {
-				// read row by row into the cells buffer until there are over 1 million entries
-				cells = append(cells, row)
-				if len(cells)*width > 1000000 {
-					for _, domain := range domainsFromCells(cells, filename, width) {
-						out <- domain
-					}
-					cells = nil
+					emit(domain)
 				}
+				cells = nil
 			}
 		}
-		f.Close()
 	}
+	return nil
 }
 
 // Maps makeDomain to the input channel of filenames
 // Uses fanout number of goroutines
-func StreamDomainsFromFilenames(fanout int, filenames <-chan string) <-chan *Domain {
+func (r *Repository) StreamDomainsFromFilenames(fanout int, filenames <-chan string) <-chan *Domain {
 	out := make(chan *Domain)
 
 	wg := &sync.WaitGroup{}
@@ -233,7 +244,7 @@ func StreamDomainsFromFilenames(fanout int, filenames <-chan string) <-chan *Dom
 	for id := 0; id < fanout; id++ {
 		wg.Add(1)
 		go func(id int) {
-			makeDomains(filenames, out)
+			r.makeDomains(filenames, out)
 			wg.Done()
 		}(id)
 	}
@@ -258,7 +269,7 @@ func Hash(s string) int {
 // Saves the domain fragments from an input channel to disk
 // using multiple goroutines specified by fanout.
 // Returns a channel of progress counter
-func DoSaveDomainValues(fanout int, domains <-chan *Domain) <-chan ProgressCounter {
+func (r *Repository) DoSaveDomainValues(fanout int, domains <-chan *Domain) <-chan ProgressCounter {
 	progress := make(chan ProgressCounter)
 	wg := &sync.WaitGroup{}
 
@@ -272,7 +283,7 @@ func DoSaveDomainValues(fanout int, domains <-chan *Domain) <-chan ProgressCount
 		// Start the goroutine and pass it
 		// its own input queue
 		go func(id int, queue chan *Domain) {
-			logf, err := os.OpenFile(path.Join(OutputDir, "logs", fmt.Sprintf("save_domain_values_%d.log", id)),
+			logf, err := os.OpenFile(path.Join(r.OutputDir, "logs", fmt.Sprintf("save_domain_values_%d.log", id)),
 				os.O_CREATE|os.O_WRONLY|os.O_APPEND,
 				0644)
 			defer logf.Close()
@@ -283,7 +294,7 @@ func DoSaveDomainValues(fanout int, domains <-chan *Domain) <-chan ProgressCount
 			logger := log.New(logf, fmt.Sprintf("[%d]", id), log.Lshortfile)
 
 			for domain := range queue {
-				n := domain.Save(logger)
+				n := r.SaveDomain(domain, logger)
 				progress <- ProgressCounter{n}
 			}
 			wg.Done()
@@ -365,9 +376,9 @@ func classifyValues(values []string) string {
 	return maxKey
 }
 
-func classifyDomains(file string) {
-	header := GetDomainHeader(file)
-	fout, err := os.OpenFile(path.Join(OutputDir, "domains", file, "types"), os.O_CREATE|os.O_WRONLY, 0644)
+func (r *Repository) classifyDomains(file string) {
+	header := r.GetDomainHeader(file)
+	fout, err := os.OpenFile(path.Join(r.OutputDir, "domains", file, "types"), os.O_CREATE|os.O_WRONLY, 0644)
 	defer fout.Close()
 
 	if err != nil {
@@ -375,7 +386,7 @@ func classifyDomains(file string) {
 	}
 
 	for i := 0; i < len(header.Values); i++ {
-		domain_file := path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.values", i))
+		domain_file := path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.values", i))
 		if !exists(domain_file) {
 			continue
 		}
@@ -389,7 +400,7 @@ func classifyDomains(file string) {
 	}
 }
 
-func DoClassifyDomainsFromFiles(fanout int, files <-chan string) <-chan int {
+func (r *Repository) DoClassifyDomainsFromFiles(fanout int, files <-chan string) <-chan int {
 	out := make(chan int)
 	wg := &sync.WaitGroup{}
 
@@ -398,7 +409,7 @@ func DoClassifyDomainsFromFiles(fanout int, files <-chan string) <-chan int {
 		go func(id int) {
 			n := 0
 			for file := range files {
-				classifyDomains(file)
+				r.classifyDomains(file)
 				n += 1
 				if n%100 == 0 {
 					out <- n
@@ -418,8 +429,8 @@ func DoClassifyDomainsFromFiles(fanout int, files <-chan string) <-chan int {
 	return out
 }
 
-func getColumnNames(file string) (names []string) {
-	indexFile := path.Join(OutputDir, "domains", file, "index")
+func (r *Repository) getColumnNames(file string) (names []string) {
+	indexFile := path.Join(r.OutputDir, "domains", file, "index")
 	f, err := os.Open(indexFile)
 	defer f.Close()
 	if err != nil {
@@ -433,8 +444,8 @@ func getColumnNames(file string) (names []string) {
 	return
 }
 
-func getNonNumericDomains(file string) (indices []int) {
-	typesFile := path.Join(OutputDir, "domains", file, "types")
+func (r *Repository) getNonNumericDomains(file string) (indices []int) {
+	typesFile := path.Join(r.OutputDir, "domains", file, "types")
 	f, err := os.Open(typesFile)
 	defer f.Close()
 	if err != nil {
@@ -460,8 +471,8 @@ func getNonNumericDomains(file string) (indices []int) {
 	return
 }
 
-func getAllDomains(file string) (indices []int) {
-	typesFile := path.Join(OutputDir, "domains", file, "types")
+func (r *Repository) getAllDomains(file string) (indices []int) {
+	typesFile := path.Join(r.OutputDir, "domains", file, "types")
 	f, err := os.Open(typesFile)
 	defer f.Close()
 	if err != nil {
@@ -489,8 +500,16 @@ func getAllDomains(file string) (indices []int) {
 	return
 }
 
-func getTextDomains(file string) (indices []int) {
-	typesFile := path.Join(OutputDir, "domains", file, "types")
+func (r *Repository) getTextDomains(file string) (indices []int) {
+	return r.getDomainsOfType(file, "text")
+}
+
+func (r *Repository) getNumericDomains(file string) (indices []int) {
+	return r.getDomainsOfType(file, "numeric")
+}
+
+func (r *Repository) getDomainsOfType(file, colType string) (indices []int) {
+	typesFile := path.Join(r.OutputDir, "domains", file, "types")
 	f, err := os.Open(typesFile)
 	defer f.Close()
 	if err != nil {
@@ -506,7 +525,7 @@ func getTextDomains(file string) (indices []int) {
 				log.Printf("error in types of file: %s", file)
 				panic(err)
 			}
-			if parts[1] == "text" {
+			if parts[1] == colType {
 				indices = append(indices, index)
 			}
 		} else {
@@ -533,8 +552,8 @@ func wordsFromLine(line string) []string {
 	return words
 }
 
-func streamDomainWords(file string, index int, out chan *Domain) {
-	filepath := path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.values", index))
+func (r *Repository) streamDomainWords(file string, index int, out chan *Domain) {
+	filepath := path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.values", index))
 	f, err := os.Open(filepath)
 	defer f.Close()
 	if err != nil {
@@ -565,15 +584,15 @@ func streamDomainWords(file string, index int, out chan *Domain) {
 	}
 }
 
-func StreamDomainValuesFromFiles(fanout int, files <-chan string) <-chan *Domain {
+func (r *Repository) StreamDomainValuesFromFiles(fanout int, files <-chan string) <-chan *Domain {
 	out := make(chan *Domain)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
 	for i := 0; i < fanout; i++ {
 		go func(id int) {
 			for file := range files {
-				for _, index := range getTextDomains(file) {
-					streamDomainWords(file, index, out)
+				for _, index := range r.getTextDomains(file) {
+					r.streamDomainWords(file, index, out)
 				}
 			}
 			wg.Done()
@@ -635,14 +654,14 @@ func getValueFreq(filename, datafilename string, index int) *ValueFreq {
 	return valuefreq
 }
 
-func StreamValueFreqFromCache(fanout int, filenames <-chan string) <-chan *ValueFreq {
+func (r *Repository) StreamValueFreqFromCache(fanout int, filenames <-chan string) <-chan *ValueFreq {
 	out := make(chan *ValueFreq)
 	wg := &sync.WaitGroup{}
 	for i := 0; i < fanout; i++ {
 		wg.Add(1)
 		go func(id int, out chan<- *ValueFreq) {
 			for filename := range filenames {
-				for _, index := range getTextDomains(filename) {
+				for _, index := range r.getTextDomains(filename) {
 					//fp := path.Join(OutputDir, "domains", filename, fmt.Sprintf("%d.ft-sum", index))
 					//f, err := os.Open(fp)
 					//f.Close()
@@ -653,7 +672,7 @@ func StreamValueFreqFromCache(fanout int, filenames <-chan string) <-chan *Value
 						Filename: filename,
 						Index:    index,
 					}
-					valueFilename := d.PhysicalFilename("values")
+					valueFilename := r.DomainFilename(d.Filename, d.Index, "values")
 					out <- getValueFreq(valueFilename, filename, index)
 					//}
 				}
@@ -670,7 +689,7 @@ func StreamValueFreqFromCache(fanout int, filenames <-chan string) <-chan *Value
 	return out
 }
 
-func StreamAllODEmbVectors(fanout int, filenames <-chan string) <-chan string {
+func (r *Repository) StreamAllODEmbVectors(fanout int, filenames <-chan string) <-chan string {
 	dfilename := "us.data.gov/t_0033d0e05a7f31a3.csv"
 	dindex := 0
 	out := make(chan string)
@@ -680,7 +699,7 @@ func StreamAllODEmbVectors(fanout int, filenames <-chan string) <-chan string {
 		go func(id int, out chan<- string) {
 			for filename := range filenames {
 				if strings.Contains(filename, "us.data.gov") == true {
-					for _, index := range getAllDomains(filename) {
+					for _, index := range r.getAllDomains(filename) {
 						d := &Domain{Filename: "", Index: 0}
 						if index == -1 {
 							d = &Domain{
@@ -693,16 +712,16 @@ func StreamAllODEmbVectors(fanout int, filenames <-chan string) <-chan string {
 								Index:    index,
 							}
 						}
-						embFilename := d.PhysicalFilename("ft-mean")
+						embFilename := r.DomainFilename(d.Filename, d.Index, "ft-mean")
 						out <- embFilename
 					}
 				} else {
-					for _, index := range getTextDomains(filename) {
+					for _, index := range r.getTextDomains(filename) {
 						d := &Domain{
 							Filename: filename,
 							Index:    index,
 						}
-						embFilename := d.PhysicalFilename("ft-mean")
+						embFilename := r.DomainFilename(d.Filename, d.Index, "ft-mean")
 						out <- embFilename
 					}
 				}
@@ -718,7 +737,7 @@ func StreamAllODEmbVectors(fanout int, filenames <-chan string) <-chan string {
 	return out
 }
 
-func StreamEmbVectors(fanout int, filenames <-chan string) <-chan string {
+func (r *Repository) StreamEmbVectors(fanout int, filenames <-chan string) <-chan string {
 	out := make(chan string)
 	wg := &sync.WaitGroup{}
 	wg.Add(fanout)
@@ -726,13 +745,13 @@ func StreamEmbVectors(fanout int, filenames <-chan string) <-chan string {
 		go func(id int, out chan<- string) {
 			for filename := range filenames {
 				//for _, index := range getNonNumericDomains(filename) {
-				for _, index := range getTextDomains(filename) {
+				for _, index := range r.getTextDomains(filename) {
 					d := &Domain{
 						Filename: filename,
 						Index:    index,
 					}
-					//embFilename := d.PhysicalFilename("ft-sum")
-					embFilename := d.PhysicalFilename("ft-mean")
+					//embFilename := DomainFilename(d.Filename, d.Index, "ft-sum")
+					embFilename := r.DomainFilename(d.Filename, d.Index, "ft-mean")
 					out <- embFilename
 				}
 			}
@@ -748,10 +767,10 @@ func StreamEmbVectors(fanout int, filenames <-chan string) <-chan string {
 }
 
 // to be removed
-func StreamQueryFilenames2() <-chan string {
+func (r *Repository) StreamQueryFilenames2() <-chan string {
 	output := make(chan string)
 	go func() {
-		f, err := os.Open(QueryList2)
+		f, err := os.Open(r.QueryList2)
 		if err != nil {
 			panic(err)
 		}
@@ -770,10 +789,10 @@ func StreamQueryFilenames2() <-chan string {
 }
 
 // Creates a channel of filenames
-func StreamQueryFilenames() <-chan string {
+func (r *Repository) StreamQueryFilenames() <-chan string {
 	output := make(chan string)
 	go func() {
-		f, err := os.Open(QueryList)
+		f, err := os.Open(r.QueryList)
 		if err != nil {
 			panic(err)
 		}
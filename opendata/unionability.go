@@ -20,13 +20,10 @@ import (
 )
 
 var (
-	lookup        map[string]map[string]bool
-	counts        map[string]int
-	notLetter     = regexp.MustCompile(`[^a-z]+`)
-	space         = []byte(" ")
-	minK          = 1
-	maxK          = 10
-	scoreFilename = "/home/fnargesian/TABLE_UNION_OUTPUT/scores/col_unionability"
+	notLetter = regexp.MustCompile(`[^a-z]+`)
+	space     = []byte(" ")
+	minK      = 1
+	maxK      = 10
 )
 
 type tableUnionability struct {
@@ -53,9 +50,9 @@ type tablePair struct {
 	codTable   string
 }
 
-func Init() {
-	lookup = LoadEntityWords()
-	counts = LoadEntityWordCount()
+func (r *Repository) Init() {
+	r.entityWords = r.LoadEntityWords()
+	r.entityWordCounts = r.LoadEntityWordCount()
 }
 
 func MkTablePairs(queries, files []string) <-chan *tablePair {
@@ -83,7 +80,7 @@ func MkTablePairs(queries, files []string) <-chan *tablePair {
 	return out
 }
 
-func DoComputeUnionability(queries, files []string, fanout int) <-chan *columnUnionability {
+func (r *Repository) DoComputeUnionability(queries, files []string, fanout int) <-chan *columnUnionability {
 	compare := MkTablePairs(queries, files)
 	out := make(chan *columnUnionability, 1000000)
 	wg := &sync.WaitGroup{}
@@ -93,7 +90,7 @@ func DoComputeUnionability(queries, files []string, fanout int) <-chan *columnUn
 		go func(id int) {
 			for pair := range compare {
 				count += 1
-				for score := range compareTables(pair.codTable, pair.queryTable) {
+				for score := range r.compareTables(pair.codTable, pair.queryTable) {
 					out <- score
 				}
 				if count%200 == 0 {
@@ -111,7 +108,7 @@ func DoComputeUnionability(queries, files []string, fanout int) <-chan *columnUn
 	return out
 }
 
-func ComputeAndSaveUnionability(queries, files []string, fanout int) <-chan ProgressCounter {
+func (r *Repository) ComputeAndSaveUnionability(queries, files []string, fanout int) <-chan ProgressCounter {
 	compare := MkTablePairs(queries, files)
 	progress := make(chan ProgressCounter)
 	wg := &sync.WaitGroup{}
@@ -119,7 +116,7 @@ func ComputeAndSaveUnionability(queries, files []string, fanout int) <-chan Prog
 	count := 0
 	for i := 0; i < fanout; i++ {
 		go func(id int) {
-			fname := scoreFilename + "_" + strconv.Itoa(id) + ".csv"
+			fname := path.Join(r.OutputDir, "scores", "col_unionability_"+strconv.Itoa(id)+".csv")
 			log.Printf("fname: %s", fname)
 			f, err := os.OpenFile(fname, os.O_CREATE|os.O_WRONLY, 0644)
 			if err != nil {
@@ -127,7 +124,7 @@ func ComputeAndSaveUnionability(queries, files []string, fanout int) <-chan Prog
 			}
 			for pair := range compare {
 				count += 1
-				for score := range compareTables(pair.codTable, pair.queryTable) {
+				for score := range r.compareTables(pair.codTable, pair.queryTable) {
 					_, err := f.WriteString(score.Table1 + "," + strconv.Itoa(score.Column1) + "," + score.Table2 + "," + strconv.Itoa(score.Column2) + "," + strconv.FormatFloat(score.Sarma, 'f', 6, 64) + "," + strconv.FormatFloat(score.Cosine, 'f', 6, 64) + ", " + strconv.FormatFloat(score.Jaccard, 'f', 6, 64) + "," + strconv.FormatFloat(score.Containment, 'f', 6, 64) + "\n")
 					if err != nil {
 						panic(err)
@@ -150,12 +147,12 @@ func ComputeAndSaveUnionability(queries, files []string, fanout int) <-chan Prog
 	return progress
 }
 
-func compareTables(codFilename, queryFilename string) <-chan *columnUnionability {
+func (r *Repository) compareTables(codFilename, queryFilename string) <-chan *columnUnionability {
 	out := make(chan *columnUnionability)
 	go func() {
-		for _, col1 := range getTextDomains(queryFilename) {
-			for _, col2 := range getTextDomains(codFilename) {
-				sarma, jaccard, containment, cosine := unionability(queryFilename, col1, codFilename, col2)
+		for _, col1 := range r.getTextDomains(queryFilename) {
+			for _, col2 := range r.getTextDomains(codFilename) {
+				sarma, jaccard, containment, cosine := r.unionability(queryFilename, col1, codFilename, col2)
 				out <- &columnUnionability{
 					Table1:      queryFilename,
 					Column1:     col1,
@@ -173,23 +170,23 @@ func compareTables(codFilename, queryFilename string) <-chan *columnUnionability
 	return out
 }
 
-func unionability(table1 string, column1 int, table2 string, column2 int) (float64, float64, float64, float64) {
-	domain1Values := unique(getDomainWords(table1, column1))
-	domain2Values := unique(getDomainWords(table2, column2))
+func (r *Repository) unionability(table1 string, column1 int, table2 string, column2 int) (float64, float64, float64, float64) {
+	domain1Values := unique(r.getDomainWords(table1, column1))
+	domain2Values := unique(r.getDomainWords(table2, column2))
 	//difference, intersection := differenceAndIntersection(domain2Values, domain1Values)
 	_, intersection := differenceAndIntersection(domain2Values, domain1Values)
 	// computing scores
-	//sarma := getSarma(domain1Values, difference)
+	//sarma := r.getSarma(domain1Values, difference)
 	sarma := -2.0
 	jaccard := getJaccard(domain1Values, domain1Values, intersection)
 	containment := getContainment(domain1Values, domain1Values, intersection)
-	cosine := getCosineEmbSum(table1, column1, table2, column2)
+	cosine := r.getCosineEmbSum(table1, column1, table2, column2)
 	return sarma, jaccard, containment, cosine
 }
 
-func getSarma(query, expansion []string) float64 {
-	annotations1 := annotate(query)
-	annotations2 := annotate(expansion)
+func (r *Repository) getSarma(query, expansion []string) float64 {
+	annotations1 := r.annotate(query)
+	annotations2 := r.annotate(expansion)
 	n1 := 1.0
 	n2 := 1.0
 	m1 := 1.0
@@ -217,7 +214,7 @@ func getContainment(query, candidate, intersection []string) float64 {
 	return float64(len(intersection)) / float64(len(query))
 }
 
-func DoAlign(queries, files []string, fanout int) <-chan *tableUnionability {
+func (r *Repository) DoAlign(queries, files []string, fanout int) <-chan *tableUnionability {
 	compare := MkTablePairs(queries, files)
 	out := make(chan *tableUnionability)
 	wg := &sync.WaitGroup{}
@@ -225,7 +222,7 @@ func DoAlign(queries, files []string, fanout int) <-chan *tableUnionability {
 	for i := 0; i < fanout; i++ {
 		go func(id int) {
 			for pair := range compare {
-				for score := range alignTables(pair.codTable, pair.queryTable) {
+				for score := range r.alignTables(pair.codTable, pair.queryTable) {
 					out <- score
 				}
 			}
@@ -240,15 +237,15 @@ func DoAlign(queries, files []string, fanout int) <-chan *tableUnionability {
 	return out
 }
 
-func alignTables(table1, table2 string) <-chan *tableUnionability {
-	db, err := sql.Open("sqlite3", ColumnUnionabilityDB)
+func (r *Repository) alignTables(table1, table2 string) <-chan *tableUnionability {
+	db, err := sql.Open("sqlite3", r.ColumnUnionabilityDB)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 	rows, err := db.Query(fmt.Sprintf(`SELECT column1, column2, %s 
 						FROM %s WHERE table1="%s" AND table2="%s" 
-						AND %s > %s ORDER BY %s;`, Measure, ColumnUnionabilityTable, table1, table2, Measure, Threshold, Measure))
+						AND %s > %s ORDER BY %s;`, r.Measure, r.ColumnUnionabilityTable, table1, table2, r.Measure, r.Threshold, r.Measure))
 	if err != nil {
 		panic(err)
 	}
@@ -275,7 +272,7 @@ func alignTables(table1, table2 string) <-chan *tableUnionability {
 						out <- &tableUnionability{
 							table1:  table1,
 							table2:  table2,
-							measure: Measure,
+							measure: r.Measure,
 							score:   score,
 							k:       k,
 						}
@@ -290,11 +287,11 @@ func alignTables(table1, table2 string) <-chan *tableUnionability {
 	return out
 }
 
-func DoSaveKUnionabilityScores(scores <-chan *tableUnionability, fanout int) <-chan ProgressCounter {
+func (r *Repository) DoSaveKUnionabilityScores(scores <-chan *tableUnionability, fanout int) <-chan ProgressCounter {
 	progress := make(chan ProgressCounter)
 	wg := &sync.WaitGroup{}
 	// output db
-	db, err := sql.Open("sqlite3", DatasetUnionabilityDB)
+	db, err := sql.Open("sqlite3", r.DatasetUnionabilityDB)
 	if err != nil {
 		panic(err)
 	}
@@ -305,13 +302,13 @@ func DoSaveKUnionabilityScores(scores <-chan *tableUnionability, fanout int) <-c
 			table2 text,
 			measure text, 
 			k int, 
-			score real);`, DatasetUnionabilityTable, DatasetUnionabilityTable))
+			score real);`, r.DatasetUnionabilityTable, r.DatasetUnionabilityTable))
 	if err != nil {
 		panic(err)
 	}
 	// Prepare insert stmt
 	stmt, err := db.Prepare(fmt.Sprintf(
-		`insert into %s(table1, table2, measure, k, score) values(?, ?, ?, ?, ?);`, DatasetUnionabilityTable))
+		`insert into %s(table1, table2, measure, k, score) values(?, ?, ?, ?, ?);`, r.DatasetUnionabilityTable))
 	if err != nil {
 		panic(err)
 	}
@@ -340,11 +337,11 @@ func DoSaveKUnionabilityScores(scores <-chan *tableUnionability, fanout int) <-c
 
 // Saves the domain pair scores from an input channel to database
 // Returns a channel of progress counter
-func DoSaveScores(scores <-chan *columnUnionability, fanout int) <-chan ProgressCounter {
+func (r *Repository) DoSaveScores(scores <-chan *columnUnionability, fanout int) <-chan ProgressCounter {
 	progress := make(chan ProgressCounter)
 	wg := &sync.WaitGroup{}
 	// output db
-	db, err := sql.Open("sqlite3", ColumnUnionabilityDB)
+	db, err := sql.Open("sqlite3", r.ColumnUnionabilityDB)
 	if err != nil {
 		panic(err)
 	}
@@ -358,13 +355,13 @@ func DoSaveScores(scores <-chan *columnUnionability, fanout int) <-chan Progress
 			sarma real,
 			sum_cosine real,
 			jaccard real,
-			containment real);`, ColumnUnionabilityTable, ColumnUnionabilityTable))
+			containment real);`, r.ColumnUnionabilityTable, r.ColumnUnionabilityTable))
 	if err != nil {
 		panic(err)
 	}
 	// Prepare insert stmt
 	stmt, err := db.Prepare(fmt.Sprintf(
-		`insert into %s(table1, column1, table2, column2, sarma, sum_cosine, jaccard, containment) values(?, ?, ?, ?, ?, ?, ?, ?);`, ColumnUnionabilityTable))
+		`insert into %s(table1, column1, table2, column2, sarma, sum_cosine, jaccard, containment) values(?, ?, ?, ?, ?, ?, ?, ?);`, r.ColumnUnionabilityTable))
 	if err != nil {
 		panic(err)
 	}
@@ -391,8 +388,8 @@ func DoSaveScores(scores <-chan *columnUnionability, fanout int) <-chan Progress
 	return progress
 }
 
-func getDomainWords(file string, index int) []string {
-	filepath := path.Join(OutputDir, "domains", file, fmt.Sprintf("%d.values", index))
+func (r *Repository) getDomainWords(file string, index int) []string {
+	filepath := path.Join(r.OutputDir, "domains", file, fmt.Sprintf("%d.values", index))
 	f, err := os.Open(filepath)
 	defer f.Close()
 	if err != nil {
@@ -442,11 +439,11 @@ func differenceAndIntersection(values1 []string, values2 []string) ([]string, []
 	return diff, intersect
 }
 
-func annotate(values []string) map[string]float64 {
+func (r *Repository) annotate(values []string) map[string]float64 {
 	annotations := make(map[string]float64)
 	for _, value := range values {
 		words := getWords(value)
-		for _, ent := range findEntities(words, lookup, counts) {
+		for _, ent := range findEntities(words, r.entityWords, r.entityWordCounts) {
 			if _, ok := annotations[ent]; !ok {
 				annotations[ent] = 1.0
 			} else {
@@ -493,9 +490,9 @@ func countWordEntity(words []string, ent string, lookup map[string]map[string]bo
 	return count
 }
 
-func getCosineEmbSum(table1 string, column1 int, table2 string, column2 int) float64 {
-	vecFilename1 := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-sum", table1, column1))
-	vecFilename2 := filepath.Join(OutputDir, "domains", fmt.Sprintf("%s/%d.ft-sum", table2, column2))
+func (r *Repository) getCosineEmbSum(table1 string, column1 int, table2 string, column2 int) float64 {
+	vecFilename1 := filepath.Join(r.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-sum", table1, column1))
+	vecFilename2 := filepath.Join(r.OutputDir, "domains", fmt.Sprintf("%s/%d.ft-sum", table2, column2))
 	if _, err := os.Stat(vecFilename1); err == nil {
 		if _, err := os.Stat(vecFilename2); err == nil {
 			emb1, err := embedding.ReadVecFromDisk(vecFilename1, binary.BigEndian)
@@ -513,9 +510,9 @@ func getCosineEmbSum(table1 string, column1 int, table2 string, column2 int) flo
 }
 
 // Creates an array of filenames
-func GetCODFilenames() []string {
+func (r *Repository) GetCODFilenames() []string {
 	output := make([]string, 0)
-	f, _ := os.Open(OpendataList)
+	f, _ := os.Open(r.OpendataList)
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
@@ -528,9 +525,9 @@ func GetCODFilenames() []string {
 }
 
 // Creates an array of query filenames
-func GetQueryFilenames() []string {
+func (r *Repository) GetQueryFilenames() []string {
 	output := make([]string, 0)
-	f, _ := os.Open(QueryList)
+	f, _ := os.Open(r.QueryList)
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
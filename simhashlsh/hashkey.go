@@ -0,0 +1,183 @@
+package simhashlsh
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// hashTableKey is the hash key of a point in a table: k bits packed into
+// uint64 words, most significant bit first. The order of the words is the
+// lexicographic order of the bits, and the unused bits of the last word
+// are zero.
+type hashTableKey []uint64
+
+func numWords(bits int) int {
+	return (bits + 63) / 64
+}
+
+func newHashTableKey(bits int) hashTableKey {
+	return make(hashTableKey, numWords(bits))
+}
+
+func (key hashTableKey) bit(i int) uint8 {
+	return uint8(key[i/64] >> uint(63-i%64) & 1)
+}
+
+func (key hashTableKey) set(i int) {
+	key[i/64] |= 1 << uint(63-i%64)
+}
+
+// flip returns a copy of the key with the bits flipped.
+func (key hashTableKey) flip(bits ...int) hashTableKey {
+	flipped := make(hashTableKey, len(key))
+	copy(flipped, key)
+	for _, i := range bits {
+		flipped[i/64] ^= 1 << uint(63-i%64)
+	}
+	return flipped
+}
+
+// prefixMask masks the bits of the last word of a prefix of prefixSize bits.
+func prefixMask(prefixSize int) uint64 {
+	if r := prefixSize % 64; r != 0 {
+		return ^uint64(0) << uint(64-r)
+	}
+	return ^uint64(0)
+}
+
+// comparePrefix compares the first prefixSize bits of two keys, and
+// returns -1, 0 or 1.
+func comparePrefix(a, b []uint64, prefixSize int) int {
+	w := numWords(prefixSize)
+	for i := 0; i < w; i++ {
+		x, y := a[i], b[i]
+		if i == w-1 {
+			mask := prefixMask(prefixSize)
+			x, y = x&mask, y&mask
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+func compareKeys(a, b []uint64) int {
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// mapKey is the key of a hash key in the bootstrapping tables.
+func (key hashTableKey) mapKey() string {
+	buf := make([]byte, 8*len(key))
+	for i, w := range key {
+		binary.BigEndian.PutUint64(buf[8*i:], w)
+	}
+	return string(buf)
+}
+
+func fromMapKey(s string) hashTableKey {
+	key := make(hashTableKey, len(s)/8)
+	for i := range key {
+		key[i] = binary.BigEndian.Uint64([]byte(s[8*i : 8*i+8]))
+	}
+	return key
+}
+
+// parseBitString reads a key written as a string of '0' and '1', the hash
+// keys of version 1 snapshots.
+func parseBitString(s string) (hashTableKey, error) {
+	key := newHashTableKey(len(s))
+	for i, c := range s {
+		switch c {
+		case '0':
+		case '1':
+			key.set(i)
+		default:
+			return nil, fmt.Errorf("hash value %q is not 0 or 1", c)
+		}
+	}
+	return key, nil
+}
+
+// hashTable is a table of buckets sorted by their hash keys. The keys of
+// all the buckets are stored in one slice, words per key.
+type hashTable struct {
+	words    int
+	hashKeys []uint64
+	buckets  []keys
+}
+
+func newHashTable(k int) hashTable {
+	return hashTable{
+		words:    numWords(k),
+		hashKeys: make([]uint64, 0),
+		buckets:  make([]keys, 0),
+	}
+}
+
+func (h hashTable) Len() int { return len(h.buckets) }
+
+func (h hashTable) Less(i, j int) bool { return compareKeys(h.key(i), h.key(j)) < 0 }
+
+func (h hashTable) Swap(i, j int) {
+	h.buckets[i], h.buckets[j] = h.buckets[j], h.buckets[i]
+	ki, kj := h.key(i), h.key(j)
+	for w := range ki {
+		ki[w], kj[w] = kj[w], ki[w]
+	}
+}
+
+// key returns the hash key of the i-th bucket.
+func (h hashTable) key(i int) hashTableKey {
+	return hashTableKey(h.hashKeys[i*h.words : (i+1)*h.words])
+}
+
+// search returns the first bucket whose hash key is not less than hk in the
+// first prefixSize bits.
+func (h hashTable) search(hk hashTableKey, prefixSize int) int {
+	lo, hi := 0, h.Len()
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if comparePrefix(h.key(mid), hk, prefixSize) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+func (h *hashTable) append(hk hashTableKey, ks keys) {
+	h.hashKeys = append(h.hashKeys, hk...)
+	h.buckets = append(h.buckets, ks)
+}
+
+// insertAt inserts a bucket before the j-th bucket.
+func (h *hashTable) insertAt(j int, hk hashTableKey, ks keys) {
+	h.hashKeys = append(h.hashKeys, hk...)
+	copy(h.hashKeys[(j+1)*h.words:], h.hashKeys[j*h.words:])
+	copy(h.hashKeys[j*h.words:], hk)
+	h.buckets = append(h.buckets, nil)
+	copy(h.buckets[j+1:], h.buckets[j:])
+	h.buckets[j] = ks
+}
+
+// removeAt removes the j-th bucket.
+func (h *hashTable) removeAt(j int) {
+	copy(h.hashKeys[j*h.words:], h.hashKeys[(j+1)*h.words:])
+	h.hashKeys = h.hashKeys[:len(h.hashKeys)-h.words]
+	copy(h.buckets[j:], h.buckets[j+1:])
+	h.buckets[len(h.buckets)-1] = nil
+	h.buckets = h.buckets[:len(h.buckets)-1]
+}
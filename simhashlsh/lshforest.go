@@ -11,9 +11,6 @@ const (
 	integrationPrecision = 0.01
 )
 
-// Key is a way to index into a table.
-type hashTableKey []uint8
-
 // Value is an index into the input dataset.
 type hashTableBucket []string
 
@@ -21,20 +18,9 @@ type hashTableBucket []string
 
 type keys []string
 
-// For initial bootstrapping
+// For initial bootstrapping, by the mapKey of the hash keys
 type initHashTable map[string]keys
 
-type bucket struct {
-	hashKey string
-	keys    keys
-}
-
-type hashTable []bucket
-
-func (h hashTable) Len() int           { return len(h) }
-func (h hashTable) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
-func (h hashTable) Less(i, j int) bool { return h[i].hashKey < h[j].hashKey }
-
 type cosineLSHParam struct {
 	// Dimensionality of the input data.
 	dim int
@@ -66,6 +52,12 @@ type CosineLSH struct {
 	// Tables
 	tables     []hashTable
 	initTables []initHashTable
+	// the points of the keys, if they are stored
+	vectors map[string][]float64
+	// predicts the thresholds of QueryPlusTuned
+	model *ThresholdModel
+	// guards tables and vectors for online updates
+	lock sync.RWMutex
 }
 
 // Compute the integral of function f, lower limit a, upper limit l, and
@@ -139,7 +131,7 @@ func NewCosineLSH(dim, numHash int, threshold float64) *CosineLSH {
 
 	tables := make([]hashTable, l)
 	for i := range tables {
-		tables[i] = make(hashTable, 0)
+		tables[i] = newHashTable(k)
 	}
 	initTables := make([]initHashTable, l)
 	for i := range initTables {
@@ -160,7 +152,7 @@ func (f *CosineLSH) Params() (k, l, dim int) {
 // Add a key with SimHash signature into the index.
 func (index *CosineLSH) Add(point []float64, key string) {
 	// Apply hash functions
-	Hs := index.toBasicHashTableKeys(index.hash(point))
+	Hs := index.hash(point)
 	// Insert keys into the bootstrapping tables
 	var wg sync.WaitGroup
 	wg.Add(len(index.initTables))
@@ -173,13 +165,16 @@ func (index *CosineLSH) Add(point []float64, key string) {
 				ht[hk][0] = key
 			}
 			wg.Done()
-		}(index.initTables[i], Hs[i], key)
+		}(index.initTables[i], Hs[i].mapKey(), key)
 	}
 	wg.Wait()
+	index.storeVector(key, point)
 }
 
 // Makes all the keys added searchable.
 func (index *CosineLSH) Index() {
+	index.lock.Lock()
+	defer index.lock.Unlock()
 	var wg sync.WaitGroup
 	wg.Add(len(index.tables))
 	for i := range index.tables {
@@ -187,12 +182,8 @@ func (index *CosineLSH) Index() {
 			// Build sorted hash table using buckets from init hash tables
 			initHt := *initHtPtr
 			ht := *htPtr
-			for hashKey := range initHt {
-				ks, _ := initHt[hashKey]
-				ht = append(ht, bucket{
-					hashKey: hashKey,
-					keys:    ks,
-				})
+			for hashKey, ks := range initHt {
+				ht.append(fromMapKey(hashKey), ks)
 			}
 			sort.Sort(ht)
 			*htPtr = ht
@@ -221,7 +212,7 @@ func (index *CosineLSH) query(point []float64, minK int, done <-chan struct{}) <
 	go func() {
 		defer close(out)
 		// Generate hash keys
-		Hs := index.toBasicHashTableKeys(index.hash(point))
+		Hs := index.hash(point)
 		seens := make(map[string]bool)
 		for K := index.cosineLSHParam.k; K >= minK; K-- {
 			prefixSize := K
@@ -230,23 +221,18 @@ func (index *CosineLSH) query(point []float64, minK int, done <-chan struct{}) <
 			var wg sync.WaitGroup
 			wg.Add(index.cosineLSHParam.l)
 			for i := 0; i < index.cosineLSHParam.l; i++ {
-				go func(ht hashTable, hk string) {
+				go func(i int, hk hashTableKey) {
 					defer wg.Done()
-					k := sort.Search(len(ht), func(x int) bool {
-						return ht[x].hashKey[:prefixSize] >= hk
-					})
-					if k < len(ht) && ht[k].hashKey[:prefixSize] == hk {
-						for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk; j++ {
-							for _, key := range ht[j].keys {
-								select {
-								case keyChan <- key:
-								case <-done:
-									return
-								}
+					for _, ks := range index.lookup(i, hk, prefixSize) {
+						for _, key := range ks {
+							select {
+							case keyChan <- key:
+							case <-done:
+								return
 							}
 						}
 					}
-				}(index.tables[i], Hs[i])
+				}(i, Hs[i])
 			}
 			go func() {
 				wg.Wait()
@@ -256,7 +242,11 @@ func (index *CosineLSH) query(point []float64, minK int, done <-chan struct{}) <
 				if _, seen := seens[key]; seen {
 					continue
 				}
-				out <- key
+				select {
+				case out <- key:
+				case <-done:
+					return
+				}
 				seens[key] = true
 			}
 		}
@@ -266,33 +256,16 @@ func (index *CosineLSH) query(point []float64, minK int, done <-chan struct{}) <
 
 // Hash returns all combined hash values for all hash tables.
 func (clsh *cosineLSHParam) hash(point []float64) []hashTableKey {
-	sig := newSimhash(clsh.hyperplanes, point).sig
+	sig := hashTableKey(newSimhash(clsh.hyperplanes, point).sig)
 	hvs := make([]hashTableKey, clsh.l)
 	for i := range hvs {
-		s := make(hashTableKey, clsh.k)
+		s := newHashTableKey(clsh.k)
 		for j := 0; j < clsh.k; j++ {
-			s[j] = uint8(sig[i*clsh.k+j])
+			if sig.bit(i*clsh.k+j) == 1 {
+				s.set(j)
+			}
 		}
 		hvs[i] = s
 	}
 	return hvs
 }
-
-func (index *CosineLSH) toBasicHashTableKeys(keys []hashTableKey) []string { // []uint64 {
-	basicKeys := make([]string, index.cosineLSHParam.l)
-	for i, key := range keys {
-		s := ""
-		for _, hashVal := range key {
-			switch hashVal {
-			case uint8(0):
-				s += "0"
-			case uint8(1):
-				s += "1"
-			default:
-				panic("Hash value is not 0 or 1")
-			}
-		}
-		basicKeys[i] = s
-	}
-	return basicKeys
-}
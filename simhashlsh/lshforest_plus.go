@@ -2,7 +2,6 @@ package simhashlsh
 
 import (
 	"log"
-	"sort"
 	"sync"
 )
 
@@ -24,9 +23,9 @@ func (index *CosineLSH) queryPlus(points [][]float64, done <-chan struct{}) <-ch
 		defer close(out)
 		seens := make(map[UnionPair]bool)
 		// Generate hash keys
-		Hs := make([][]string, len(points))
+		Hs := make([][]hashTableKey, len(points))
 		for i := 0; i < len(points); i++ {
-			Hs[i] = index.toBasicHashTableKeys(index.hash(points[i]))
+			Hs[i] = index.hash(points[i])
 		}
 		for K := index.cosineLSHParam.k; K > 0; K-- {
 			prefixSize := K
@@ -36,27 +35,22 @@ func (index *CosineLSH) queryPlus(points [][]float64, done <-chan struct{}) <-ch
 			wg.Add(index.cosineLSHParam.l * len(points))
 			for i := 0; i < index.cosineLSHParam.l; i++ {
 				for p := 0; p < len(Hs); p++ {
-					go func(ht hashTable, hk string, q int) {
+					go func(i int, hk hashTableKey, q int) {
 						defer wg.Done()
-						k := sort.Search(len(ht), func(x int) bool {
-							return ht[x].hashKey[:prefixSize] >= hk[:prefixSize]
-						})
-						if k < len(ht) && ht[k].hashKey[:prefixSize] == hk[:prefixSize] {
-							for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk[:prefixSize]; j++ {
-								for _, key := range ht[j].keys {
-									rp := UnionPair{
-										QueryIndex:   q,
-										CandidateKey: key,
-									}
-									select {
-									case keyChan <- rp:
-									case <-done:
-										return
-									}
+						for _, ks := range index.lookup(i, hk, prefixSize) {
+							for _, key := range ks {
+								rp := UnionPair{
+									QueryIndex:   q,
+									CandidateKey: key,
+								}
+								select {
+								case keyChan <- rp:
+								case <-done:
+									return
 								}
 							}
 						}
-					}(index.tables[i], Hs[p][i], p)
+					}(i, Hs[p][i], p)
 				}
 			}
 			go func() {
@@ -67,7 +61,11 @@ func (index *CosineLSH) queryPlus(points [][]float64, done <-chan struct{}) <-ch
 				if _, seen := seens[qk]; seen {
 					continue
 				}
-				out <- qk
+				select {
+				case out <- qk:
+				case <-done:
+					return
+				}
 				seens[qk] = true
 			}
 		}
@@ -78,9 +76,9 @@ func (index *CosineLSH) queryPlus(points [][]float64, done <-chan struct{}) <-ch
 func (index *CosineLSH) queryPlusPlus(points [][]float64, done <-chan struct{}) <-chan UnionPair {
 	out := make(chan UnionPair)
 	// Generate hash keys
-	Hs := make([][]string, len(points))
+	Hs := make([][]hashTableKey, len(points))
 	for i := 0; i < len(points); i++ {
-		Hs[i] = index.toBasicHashTableKeys(index.hash(points[i]))
+		Hs[i] = index.hash(points[i])
 	}
 	seens := make(map[UnionPair]bool)
 	var wg sync.WaitGroup
@@ -93,7 +91,11 @@ func (index *CosineLSH) queryPlusPlus(points [][]float64, done <-chan struct{})
 					continue
 				}
 				seens[rp] = true
-				out <- rp
+				select {
+				case out <- rp:
+				case <-done:
+					return
+				}
 			}
 		}
 	}()
@@ -104,7 +106,7 @@ func (index *CosineLSH) queryPlusPlus(points [][]float64, done <-chan struct{})
 	return out
 }
 
-func (index *CosineLSH) probe(Hs [][]string, prefixSize int, done <-chan struct{}) <-chan UnionPair {
+func (index *CosineLSH) probe(Hs [][]hashTableKey, prefixSize int, done <-chan struct{}) <-chan UnionPair {
 	log.Printf("prefix is now %d", prefixSize)
 	out := make(chan UnionPair)
 	var wg sync.WaitGroup
@@ -113,24 +115,17 @@ func (index *CosineLSH) probe(Hs [][]string, prefixSize int, done <-chan struct{
 		for i := 0; i < index.cosineLSHParam.l; i++ {
 			for p := 0; p < len(Hs); p++ {
 				go func(i, p int) {
-					hk := Hs[p][i]
-					ht := index.tables[i]
 					defer wg.Done()
-					k := sort.Search(len(ht), func(x int) bool {
-						return ht[x].hashKey[:prefixSize] >= hk[:prefixSize]
-					})
-					if k < len(ht) && ht[k].hashKey[:prefixSize] == hk[:prefixSize] {
-						for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk[:prefixSize]; j++ {
-							for _, key := range ht[j].keys {
-								rp := UnionPair{
-									QueryIndex:   p,
-									CandidateKey: key,
-								}
-								select {
-								case out <- rp:
-								case <-done:
-									return
-								}
+					for _, ks := range index.lookup(i, Hs[p][i], prefixSize) {
+						for _, key := range ks {
+							rp := UnionPair{
+								QueryIndex:   p,
+								CandidateKey: key,
+							}
+							select {
+							case out <- rp:
+							case <-done:
+								return
 							}
 						}
 					}
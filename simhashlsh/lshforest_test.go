@@ -3,8 +3,11 @@ package simhashlsh
 import (
 	"log"
 	"math/rand"
+	"runtime"
+	"sort"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func Test_LSHQuery(t *testing.T) {
@@ -58,11 +61,16 @@ func Test_LSHQueryPlus(t *testing.T) {
 		for i := 0; i < len(insertedVectors); i += 2 {
 			key1 := insertedVectors[i]
 			key2 := insertedVectors[i+1]
-			results := clsh.QueryPlus(vecs[i : i+2])
+			done := make(chan struct{})
+			results := make([]UnionPair, 0)
+			for foundPair := range clsh.QueryPlus(vecs[i:i+2], done) {
+				results = append(results, foundPair)
+			}
+			close(done)
 			avg += float64(len(results))
 			found := 0
 			for _, foundPair := range results {
-				if (foundPair.queryIndex == 0 && foundPair.candidateKey == key1) || (foundPair.queryIndex == 1 && foundPair.candidateKey == key2) {
+				if (foundPair.QueryIndex == 0 && foundPair.CandidateKey == key1) || (foundPair.QueryIndex == 1 && foundPair.CandidateKey == key2) {
 					found += 1
 				}
 			}
@@ -75,6 +83,63 @@ func Test_LSHQueryPlus(t *testing.T) {
 	}
 }
 
+func Test_QueryPlusCancel(t *testing.T) {
+	vecs := randomVectors(1, 300, 1.0)
+	clsh := NewCosineLSH(300, 100, 0.5)
+	for i := 0; i < 1000; i++ {
+		clsh.Add(vecs[0], strconv.Itoa(i))
+	}
+	clsh.Index()
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	results := clsh.QueryPlus([][]float64{vecs[0], vecs[0]}, done)
+	<-results
+	// the client leaves after the first result
+	close(done)
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are still running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_InsertDelete(t *testing.T) {
+	vecs := randomVectors(101, 300, 1.0)
+	clsh := NewCosineLSH(300, 64, 0.5)
+	for i, e := range vecs[:100] {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	clsh.Insert(vecs[100], "new")
+	found := false
+	for _, key := range clsh.Query(vecs[100]) {
+		if key == "new" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("unable to retrieve inserted key")
+	}
+	for i := range clsh.tables {
+		if !sort.IsSorted(clsh.tables[i]) {
+			t.Fatal("hash table is not sorted")
+		}
+	}
+	if !clsh.Delete(vecs[100], "new") {
+		t.Fatal("unable to delete inserted key")
+	}
+	for _, key := range clsh.Query(vecs[100]) {
+		if key == "new" {
+			t.Fatal("deleted key is retrieved")
+		}
+	}
+	if clsh.Delete(vecs[100], "new") {
+		t.Fail()
+	}
+}
+
 func randomVectors(n, dim int, max float64) [][]float64 {
 	random := rand.New(rand.NewSource(1))
 	vecs := make([][]float64, n)
@@ -86,3 +151,167 @@ func randomVectors(n, dim int, max float64) [][]float64 {
 	}
 	return vecs
 }
+
+// The hash tables of the index before the keys were packed, with the hash
+// keys as strings of '0' and '1', as a reference for the benchmarks.
+type stringBucket struct {
+	hashKey string
+	keys    keys
+}
+
+type stringHashTable []stringBucket
+
+func (index *CosineLSH) stringTables() []stringHashTable {
+	tables := make([]stringHashTable, len(index.tables))
+	for i, ht := range index.tables {
+		tables[i] = make(stringHashTable, ht.Len())
+		for j := range ht.buckets {
+			tables[i][j] = stringBucket{
+				hashKey: bitString(ht.key(j), index.k),
+				keys:    ht.buckets[j],
+			}
+		}
+	}
+	return tables
+}
+
+func bitString(key hashTableKey, bits int) string {
+	s := make([]byte, bits)
+	for i := range s {
+		s[i] = '0' + key.bit(i)
+	}
+	return string(s)
+}
+
+// stringHash is the hash of a point with a byte per bit of the signature.
+func (index *CosineLSH) stringHash(point []float64) []string {
+	sig := make([]uint8, len(index.hyperplanes))
+	for hix, h := range index.hyperplanes {
+		var dp float64
+		for k, v := range point {
+			dp += h[k] * v
+		}
+		if dp >= 0 {
+			sig[hix] = '1'
+		} else {
+			sig[hix] = '0'
+		}
+	}
+	hs := make([]string, index.l)
+	for i := range hs {
+		hs[i] = string(sig[i*index.k : (i+1)*index.k])
+	}
+	return hs
+}
+
+func (ht stringHashTable) lookup(hk string, prefixSize int) []keys {
+	hk = hk[:prefixSize]
+	k := sort.Search(len(ht), func(x int) bool {
+		return ht[x].hashKey[:prefixSize] >= hk
+	})
+	found := make([]keys, 0)
+	for j := k; j < len(ht) && ht[j].hashKey[:prefixSize] == hk; j++ {
+		found = append(found, ht[j].keys)
+	}
+	return found
+}
+
+func benchmarkIndex() (*CosineLSH, [][]float64) {
+	vecs := randomVectors(10000, 300, 1.0)
+	clsh := NewCosineLSH(300, 256, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	return clsh, randomVectors(100, 300, 1.0)
+}
+
+func Test_StringTables(t *testing.T) {
+	vecs := randomVectors(200, 300, 1.0)
+	clsh := NewCosineLSH(300, 256, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	tables := clsh.stringTables()
+	for _, prefixSize := range []int{clsh.k, clsh.k / 2, 1} {
+		for _, v := range vecs[:20] {
+			Hs, ss := clsh.hash(v), clsh.stringHash(v)
+			for i := range tables {
+				packed := clsh.lookup(i, Hs[i], prefixSize)
+				ref := tables[i].lookup(ss[i], prefixSize)
+				if len(packed) != len(ref) {
+					t.Fatalf("%d buckets in table %d with prefix %d, expected %d", len(packed), i, prefixSize, len(ref))
+				}
+				for j := range ref {
+					if len(packed[j]) != len(ref[j]) || packed[j][0] != ref[j][0] {
+						t.Fatal("buckets differ from the reference")
+					}
+				}
+			}
+		}
+	}
+}
+
+func Benchmark_HashString(b *testing.B) {
+	clsh, queries := benchmarkIndex()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clsh.stringHash(queries[n%len(queries)])
+	}
+}
+
+func Benchmark_HashPacked(b *testing.B) {
+	clsh, queries := benchmarkIndex()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clsh.hash(queries[n%len(queries)])
+	}
+}
+
+func benchmarkLookupString(b *testing.B, prefixSize int) {
+	clsh, queries := benchmarkIndex()
+	tables := clsh.stringTables()
+	hs := make([][]string, len(queries))
+	for i, q := range queries {
+		hs[i] = clsh.stringHash(q)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		q := n % len(queries)
+		for i := range tables {
+			tables[i].lookup(hs[q][i], prefixSize)
+		}
+	}
+}
+
+func benchmarkLookupPacked(b *testing.B, prefixSize int) {
+	clsh, queries := benchmarkIndex()
+	hs := make([][]hashTableKey, len(queries))
+	for i, q := range queries {
+		hs[i] = clsh.hash(q)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		q := n % len(queries)
+		for i := range clsh.tables {
+			clsh.lookup(i, hs[q][i], prefixSize)
+		}
+	}
+}
+
+func Benchmark_LookupString(b *testing.B) { benchmarkLookupString(b, 8) }
+
+func Benchmark_LookupPacked(b *testing.B) { benchmarkLookupPacked(b, 8) }
+
+func Benchmark_LookupStringShortPrefix(b *testing.B) { benchmarkLookupString(b, 2) }
+
+func Benchmark_LookupPackedShortPrefix(b *testing.B) { benchmarkLookupPacked(b, 2) }
+
+func Benchmark_Query(b *testing.B) {
+	clsh, queries := benchmarkIndex()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clsh.Query(queries[n%len(queries)])
+	}
+}
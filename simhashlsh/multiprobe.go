@@ -0,0 +1,217 @@
+package simhashlsh
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+)
+
+// perturbation is a set of bits of the hash key of a table to flip, with
+// its score.
+type perturbation struct {
+	table int
+	set   []int
+	score float64
+}
+
+type perturbationHeap []perturbation
+
+func (h perturbationHeap) Len() int            { return len(h) }
+func (h perturbationHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h perturbationHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *perturbationHeap) Push(x interface{}) { *h = append(*h, x.(perturbation)) }
+func (h *perturbationHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// margins returns the distance of a point to each hyperplane. The smaller
+// the margin, the more likely a close point is on the other side.
+func (clsh *cosineLSHParam) margins(point []float64) []float64 {
+	ms := make([]float64, len(clsh.hyperplanes))
+	for i, h := range clsh.hyperplanes {
+		var dp, norm float64
+		for j, v := range point {
+			dp += h[j] * v
+			norm += h[j] * h[j]
+		}
+		ms[i] = math.Abs(dp) / math.Sqrt(norm)
+	}
+	return ms
+}
+
+// perturbationSequence returns the first n bit flip sets of the first
+// prefixSize bits of the hash key of each table, in the order of increasing
+// sum of squared margins, as in multi-probe LSH (Lv et al., VLDB 2007).
+func (clsh *cosineLSHParam) perturbationSequence(point []float64, prefixSize, n int) []perturbation {
+	ms := clsh.margins(point)
+	// the bits of each table in the order of increasing margin, and their
+	// squared margins. The sets in the heap are positions in this order.
+	order := make([][]int, clsh.l)
+	sq := make([][]float64, clsh.l)
+	for i := 0; i < clsh.l; i++ {
+		order[i] = make([]int, prefixSize)
+		for j := range order[i] {
+			order[i][j] = j
+		}
+		tm := ms[i*clsh.k : i*clsh.k+prefixSize]
+		sort.Slice(order[i], func(a, b int) bool {
+			return tm[order[i][a]] < tm[order[i][b]]
+		})
+		sq[i] = make([]float64, prefixSize)
+		for j, b := range order[i] {
+			sq[i][j] = tm[b] * tm[b]
+		}
+	}
+	score := func(table int, set []int) float64 {
+		var s float64
+		for _, p := range set {
+			s += sq[table][p]
+		}
+		return s
+	}
+	h := make(perturbationHeap, 0)
+	for i := 0; i < clsh.l; i++ {
+		if prefixSize > 0 {
+			h = append(h, perturbation{table: i, set: []int{0}, score: sq[i][0]})
+		}
+	}
+	heap.Init(&h)
+	counts := make([]int, clsh.l)
+	flips := make([]perturbation, 0)
+	for h.Len() > 0 {
+		p := heap.Pop(&h).(perturbation)
+		if counts[p.table] < n {
+			counts[p.table] += 1
+			bits := make([]int, len(p.set))
+			for j, pos := range p.set {
+				bits[j] = order[p.table][pos]
+			}
+			flips = append(flips, perturbation{table: p.table, set: bits, score: p.score})
+		}
+		last := p.set[len(p.set)-1]
+		if last+1 >= prefixSize || counts[p.table] >= n {
+			continue
+		}
+		// shift replaces the last position with the next one, and expand
+		// adds the next one
+		shift := append(append([]int{}, p.set[:len(p.set)-1]...), last+1)
+		expand := append(append([]int{}, p.set...), last+1)
+		heap.Push(&h, perturbation{table: p.table, set: shift, score: score(p.table, shift)})
+		heap.Push(&h, perturbation{table: p.table, set: expand, score: score(p.table, expand)})
+	}
+	return flips
+}
+
+// probeKeys returns the tables and the hash keys of the query buckets,
+// followed by the buckets perturbed in the first prefixSize bits.
+func (index *CosineLSH) probeKeys(point []float64, prefixSize, probes int) ([]int, []hashTableKey) {
+	Hs := index.hash(point)
+	tables := make([]int, 0, len(Hs))
+	hks := make([]hashTableKey, 0, len(Hs))
+	for i, hk := range Hs {
+		tables = append(tables, i)
+		hks = append(hks, hk)
+	}
+	for _, flip := range index.perturbationSequence(point, prefixSize, probes) {
+		tables = append(tables, flip.table)
+		hks = append(hks, Hs[flip.table].flip(flip.set...))
+	}
+	return tables, hks
+}
+
+// QueryMultiProbe returns the keys in the bucket of the query point and in
+// up to probes neighbouring buckets of each table, which differ from the
+// query bucket in the bits of the hyperplanes closest to the point.
+func (index *CosineLSH) QueryMultiProbe(point []float64, probes int) []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	tables, hks := index.probeKeys(point, index.cosineLSHParam.k, probes)
+	for i := range tables {
+		for _, ks := range index.lookup(tables[i], hks[i], index.cosineLSHParam.k) {
+			for _, key := range ks {
+				if !seen[key] {
+					seen[key] = true
+					result = append(result, key)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// QueryPlusMultiProbe is QueryPlus that probes the query buckets and up to
+// probes neighbouring buckets of each table before shrinking the prefixes
+// of the hash keys.
+func (index *CosineLSH) QueryPlusMultiProbe(points [][]float64, probes int, done <-chan struct{}) <-chan UnionPair {
+	prefixSizes := make([]int, len(points))
+	numProbes := make([]int, len(points))
+	for p := range points {
+		prefixSizes[p] = index.cosineLSHParam.k
+		numProbes[p] = probes
+	}
+	return index.queryPlusProbes(points, prefixSizes, numProbes, done)
+}
+
+// queryPlusProbes probes the buckets of the first prefixSizes[p] bits of the
+// hash keys of each point p, and up to probes[p] neighbouring buckets, and
+// then falls back to shrinking the prefixes as QueryPlus.
+func (index *CosineLSH) queryPlusProbes(points [][]float64, prefixSizes, probes []int, done <-chan struct{}) <-chan UnionPair {
+	out := make(chan UnionPair)
+	go func() {
+		defer close(out)
+		seens := make(map[UnionPair]bool)
+		emit := func(rp UnionPair) bool {
+			if seens[rp] {
+				return true
+			}
+			seens[rp] = true
+			select {
+			case out <- rp:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		tables := make([][]int, len(points))
+		hks := make([][]hashTableKey, len(points))
+		var wg sync.WaitGroup
+		wg.Add(len(points))
+		for p := range points {
+			go func(p int) {
+				defer wg.Done()
+				tables[p], hks[p] = index.probeKeys(points[p], prefixSizes[p], probes[p])
+			}(p)
+		}
+		wg.Wait()
+		// the j-th probe of every point before the (j+1)-th probe
+		for j := 0; ; j++ {
+			probed := false
+			for p := range points {
+				if j >= len(tables[p]) {
+					continue
+				}
+				probed = true
+				for _, ks := range index.lookup(tables[p][j], hks[p][j], prefixSizes[p]) {
+					for _, key := range ks {
+						if !emit(UnionPair{QueryIndex: p, CandidateKey: key}) {
+							return
+						}
+					}
+				}
+			}
+			if !probed {
+				break
+			}
+		}
+		for rp := range index.queryPlusPlus(points, done) {
+			if !emit(rp) {
+				return
+			}
+		}
+	}()
+	return out
+}
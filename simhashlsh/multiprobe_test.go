@@ -0,0 +1,125 @@
+package simhashlsh
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func normalVectors(random *rand.Rand, n, dim int) [][]float64 {
+	vecs := make([][]float64, n)
+	for i := range vecs {
+		vecs[i] = make([]float64, dim)
+		for d := range vecs[i] {
+			vecs[i][d] = random.NormFloat64()
+		}
+	}
+	return vecs
+}
+
+func Test_perturbationSequence(t *testing.T) {
+	clsh := NewCosineLSH(50, 64, 0.9)
+	k, l, _ := clsh.Params()
+	point := normalVectors(rand.New(rand.NewSource(2)), 1, 50)[0]
+	ms := clsh.margins(point)
+	flips := clsh.perturbationSequence(point, k, 5)
+	counts := make([]int, l)
+	last := 0.0
+	for _, p := range flips {
+		if p.score < last {
+			t.Errorf("perturbation scores are not ascending: %f after %f", p.score, last)
+		}
+		last = p.score
+		var score float64
+		for _, b := range p.set {
+			if b < 0 || b >= k {
+				t.Fatalf("bit %d out of a key of %d bits", b, k)
+			}
+			m := ms[p.table*k+b]
+			score += m * m
+		}
+		if score-p.score > 1e-9 || p.score-score > 1e-9 {
+			t.Errorf("score of %v is %f, not %f", p.set, p.score, score)
+		}
+		counts[p.table] += 1
+	}
+	for i, c := range counts {
+		if c != 5 && k > 2 {
+			t.Errorf("%d perturbations of table %d", c, i)
+		}
+	}
+	// the first perturbation of a table flips the bit with the smallest margin
+	for _, p := range flips {
+		if counts[p.table] == 0 {
+			continue
+		}
+		counts[p.table] = 0
+		for b := 0; b < k; b++ {
+			if ms[p.table*k+b] < ms[p.table*k+p.set[0]] || len(p.set) != 1 {
+				t.Errorf("first perturbation of table %d is %v", p.table, p.set)
+				break
+			}
+		}
+	}
+}
+
+func Test_QueryMultiProbe(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	dim := 50
+	vecs := normalVectors(random, 1000, dim)
+	clsh := NewCosineLSH(dim, 64, 0.9)
+	for i, v := range vecs {
+		clsh.Add(v, strconv.Itoa(i))
+	}
+	clsh.Index()
+	// the queries are noisy copies of the indexed points
+	noise := normalVectors(random, len(vecs), dim)
+	recall := func(probes int) int {
+		found := 0
+		for i, v := range vecs {
+			q := make([]float64, dim)
+			for d := range q {
+				q[d] = v[d] + 0.3*noise[i][d]
+			}
+			for _, key := range clsh.QueryMultiProbe(q, probes) {
+				if key == strconv.Itoa(i) {
+					found += 1
+					break
+				}
+			}
+		}
+		return found
+	}
+	exact, probed := recall(0), recall(10)
+	if probed <= exact {
+		t.Errorf("recall with 10 probes is %d, %d without", probed, exact)
+	}
+	// without probes, only the query buckets
+	q := vecs[3]
+	plain := clsh.Query(q)
+	if len(clsh.QueryMultiProbe(q, 0)) != len(plain) {
+		t.Errorf("%d results without probes, %d with Query", len(clsh.QueryMultiProbe(q, 0)), len(plain))
+	}
+}
+
+func Test_QueryPlusMultiProbe(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	vecs := normalVectors(random, 200, 20)
+	clsh := NewCosineLSH(20, 32, 0.9)
+	for i, v := range vecs {
+		clsh.Add(v, strconv.Itoa(i))
+	}
+	clsh.Index()
+	// every key is found once for every query point, in the end by the
+	// empty prefix
+	seen := make(map[UnionPair]bool)
+	for pair := range clsh.QueryPlusMultiProbe(vecs[:2], 4, make(chan struct{})) {
+		if seen[pair] {
+			t.Fatalf("%v is returned twice", pair)
+		}
+		seen[pair] = true
+	}
+	if len(seen) != 2*len(vecs) {
+		t.Errorf("%d pairs instead of %d", len(seen), 2*len(vecs))
+	}
+}
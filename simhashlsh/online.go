@@ -0,0 +1,73 @@
+package simhashlsh
+
+// lookup returns the keys of the buckets in the i-th hash table whose hash
+// keys start with hk. The key slices are never modified in place, so they
+// remain valid after the lock is released.
+func (index *CosineLSH) lookup(i int, hk hashTableKey, prefixSize int) []keys {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	ht := index.tables[i]
+	found := make([]keys, 0)
+	for j := ht.search(hk, prefixSize); j < ht.Len() && comparePrefix(ht.key(j), hk, prefixSize) == 0; j++ {
+		found = append(found, ht.buckets[j])
+	}
+	return found
+}
+
+// Insert adds a key with its point into the index.
+// Unlike Add, the key is searchable right away.
+func (index *CosineLSH) Insert(point []float64, key string) {
+	Hs := index.hash(point)
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	for i, hk := range Hs {
+		ht := &index.tables[i]
+		j := ht.search(hk, index.cosineLSHParam.k)
+		if j < ht.Len() && compareKeys(ht.key(j), hk) == 0 {
+			ks := make(keys, len(ht.buckets[j]), len(ht.buckets[j])+1)
+			copy(ks, ht.buckets[j])
+			ht.buckets[j] = append(ks, key)
+			continue
+		}
+		ht.insertAt(j, hk, keys{key})
+	}
+	if index.vectors != nil {
+		index.vectors[key] = point
+	}
+}
+
+// Delete removes a key given the point it was added with.
+// It returns false if the key is not found.
+func (index *CosineLSH) Delete(point []float64, key string) bool {
+	Hs := index.hash(point)
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	deleted := false
+	for i, hk := range Hs {
+		ht := &index.tables[i]
+		j := ht.search(hk, index.cosineLSHParam.k)
+		if j == ht.Len() || compareKeys(ht.key(j), hk) != 0 {
+			continue
+		}
+		ks := make(keys, 0, len(ht.buckets[j]))
+		for _, k := range ht.buckets[j] {
+			if k != key {
+				ks = append(ks, k)
+			}
+		}
+		if len(ks) == len(ht.buckets[j]) {
+			continue
+		}
+		deleted = true
+		if len(ks) != 0 {
+			ht.buckets[j] = ks
+			continue
+		}
+		// drop the empty bucket
+		ht.removeAt(j)
+	}
+	if deleted && index.vectors != nil {
+		delete(index.vectors, key)
+	}
+	return deleted
+}
@@ -2,7 +2,8 @@ package simhashlsh
 
 import "math/rand"
 
-type signature []uint8
+// signature is the bits of a simhash, packed as in hashTableKey
+type signature []uint64
 
 // Represents a SinHash signature - an array of hash values
 type simhash struct {
@@ -20,19 +21,17 @@ func newSimhash(hs hyperplanes, e []float64) *simhash {
 }
 
 func newSignature(hyperplanes hyperplanes, e []float64) signature {
-	sigarr := make([]uint8, len(hyperplanes))
+	sig := newHashTableKey(len(hyperplanes))
 	for hix, h := range hyperplanes {
 		var dp float64
 		for k, v := range e {
 			dp += h[k] * float64(v)
 		}
 		if dp >= 0 {
-			sigarr[hix] = uint8(1)
-		} else {
-			sigarr[hix] = uint8(0)
+			sig.set(hix)
 		}
 	}
-	return sigarr
+	return signature(sig)
 }
 
 // the hyperplanes
@@ -0,0 +1,241 @@
+package simhashlsh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	snapshotMagic   = "SHLSHSNP"
+	snapshotVersion = uint32(3)
+)
+
+var (
+	byteOrder = binary.BigEndian
+	// ErrBadSnapshot is returned when loading a file that is not a snapshot
+	// of the index or was written by an unknown version.
+	ErrBadSnapshot = errors.New("simhashlsh: invalid snapshot")
+)
+
+// Save writes a snapshot of the index, including the hyperplanes, the
+// sorted hash tables and the stored points. Keys added after the last call
+// to Index() are not included.
+func (index *CosineLSH) Save(w io.Writer) error {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	param := index.cosineLSHParam
+	header := []uint32{snapshotVersion, uint32(param.dim), uint32(param.l), uint32(param.k), uint32(param.numHash), uint32(len(param.hyperplanes))}
+	if err := binary.Write(bw, byteOrder, header); err != nil {
+		return err
+	}
+	for _, h := range param.hyperplanes {
+		if err := binary.Write(bw, byteOrder, h); err != nil {
+			return err
+		}
+	}
+	for _, ht := range index.tables {
+		if err := binary.Write(bw, byteOrder, uint64(ht.Len())); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, byteOrder, ht.hashKeys); err != nil {
+			return err
+		}
+		for _, ks := range ht.buckets {
+			if err := binary.Write(bw, byteOrder, uint64(len(ks))); err != nil {
+				return err
+			}
+			for _, key := range ks {
+				if err := writeString(bw, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := writeVectors(bw, index.vectors); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Load reads an index from a snapshot written by Save.
+// The index is searchable right away.
+func Load(r io.Reader) (*CosineLSH, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrBadSnapshot
+	}
+	header := make([]uint32, 6)
+	if err := binary.Read(br, byteOrder, header); err != nil {
+		return nil, err
+	}
+	// version 1 snapshots have the hash keys as strings of bits, and
+	// versions 1 and 2 have no stored points
+	version := header[0]
+	if version < 1 || version > snapshotVersion {
+		return nil, ErrBadSnapshot
+	}
+	dim, l, k, numHash := int(header[1]), int(header[2]), int(header[3]), int(header[4])
+	hyperplanes := make([][]float64, header[5])
+	for i := range hyperplanes {
+		hyperplanes[i] = make([]float64, dim)
+		if err := binary.Read(br, byteOrder, hyperplanes[i]); err != nil {
+			return nil, err
+		}
+	}
+	index := &CosineLSH{
+		cosineLSHParam: newCosineLSHParam(dim, l, k, numHash, hyperplanes),
+		tables:         make([]hashTable, l),
+		initTables:     make([]initHashTable, l),
+	}
+	for i := range index.tables {
+		index.initTables[i] = make(initHashTable)
+		var numBuckets uint64
+		if err := binary.Read(br, byteOrder, &numBuckets); err != nil {
+			return nil, err
+		}
+		ht := hashTable{
+			words:    numWords(k),
+			hashKeys: make([]uint64, int(numBuckets)*numWords(k)),
+			buckets:  make([]keys, numBuckets),
+		}
+		if version > 1 {
+			if err := binary.Read(br, byteOrder, ht.hashKeys); err != nil {
+				return nil, err
+			}
+		}
+		for j := range ht.buckets {
+			if version == 1 {
+				s, err := readString(br)
+				if err != nil {
+					return nil, err
+				}
+				hashKey, err := parseBitString(s)
+				if err != nil || len(s) != k {
+					return nil, ErrBadSnapshot
+				}
+				copy(ht.key(j), hashKey)
+			}
+			var numKeys uint64
+			if err := binary.Read(br, byteOrder, &numKeys); err != nil {
+				return nil, err
+			}
+			ks := make(keys, numKeys)
+			for x := range ks {
+				var err error
+				if ks[x], err = readString(br); err != nil {
+					return nil, err
+				}
+			}
+			ht.buckets[j] = ks
+		}
+		index.tables[i] = ht
+	}
+	if version == snapshotVersion {
+		vectors, err := readVectors(br, dim)
+		if err != nil {
+			return nil, err
+		}
+		index.vectors = vectors
+	}
+	return index, nil
+}
+
+// writeVectors writes the number of points, or -1 if they are not stored,
+// followed by the keys and the points in the order of the keys.
+func writeVectors(w io.Writer, vectors map[string][]float64) error {
+	if vectors == nil {
+		return binary.Write(w, byteOrder, int64(-1))
+	}
+	keys := make([]string, 0, len(vectors))
+	for key := range vectors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if err := binary.Write(w, byteOrder, int64(len(keys))); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := writeString(w, key); err != nil {
+			return err
+		}
+		if err := binary.Write(w, byteOrder, vectors[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readVectors(r io.Reader, dim int) (map[string][]float64, error) {
+	var n int64
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, nil
+	}
+	vectors := make(map[string][]float64, n)
+	for i := int64(0); i < n; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		point := make([]float64, dim)
+		if err := binary.Read(r, byteOrder, point); err != nil {
+			return nil, err
+		}
+		vectors[key] = point
+	}
+	return vectors, nil
+}
+
+// SaveFile writes a snapshot of the index to filename.
+func (index *CosineLSH) SaveFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := index.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile reads an index from the snapshot in filename.
+func LoadFile(filename string) (*CosineLSH, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := binary.Write(w, byteOrder, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint32
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,69 @@
+package simhashlsh
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func Test_Snapshot(t *testing.T) {
+	vecs := randomVectors(100, 300, 1.0)
+	clsh := NewCosineLSH(300, 64, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	buf := new(bytes.Buffer)
+	if err := clsh.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clsh.cosineLSHParam, loaded.cosineLSHParam) {
+		t.Fatal("parameters do not match")
+	}
+	if !reflect.DeepEqual(clsh.tables, loaded.tables) {
+		t.Fatal("hash tables do not match")
+	}
+	// the hyperplanes are kept, so new points hash the same way
+	for _, e := range randomVectors(10, 300, 1.0) {
+		r1, r2 := clsh.Query(e), loaded.Query(e)
+		sort.Strings(r1)
+		sort.Strings(r2)
+		if !reflect.DeepEqual(r1, r2) {
+			t.Fail()
+		}
+	}
+	if _, err := Load(bytes.NewBufferString("not a snapshot")); err != ErrBadSnapshot {
+		t.Fail()
+	}
+}
+
+func Test_SnapshotVectors(t *testing.T) {
+	vecs := randomVectors(10, 300, 1.0)
+	clsh := NewCosineLSH(300, 64, 0.5)
+	clsh.StoreVectors()
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	buf := new(bytes.Buffer)
+	if err := clsh.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clsh.vectors, loaded.vectors) {
+		t.Fatal("stored vectors do not match")
+	}
+	// the candidates of the loaded index are scored
+	if result := loaded.QueryTopK(vecs[0], 1, 0.8); len(result) != 1 || result[0].Key != "0" {
+		t.Fatalf("top-k of the loaded index is %v", result)
+	}
+}
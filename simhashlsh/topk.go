@@ -0,0 +1,80 @@
+package simhashlsh
+
+import (
+	"math"
+	"sort"
+)
+
+// ScoredKey is a key with the exact cosine similarity of its point to the
+// query point.
+type ScoredKey struct {
+	Key   string
+	Score float64
+}
+
+// StoreVectors keeps the points of the keys added or inserted from now on,
+// so that candidates can be scored without reading them back. The points
+// are not copied and must not be modified. They are saved in snapshots.
+func (index *CosineLSH) StoreVectors() {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	if index.vectors == nil {
+		index.vectors = make(map[string][]float64)
+	}
+}
+
+func (index *CosineLSH) storeVector(key string, point []float64) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	if index.vectors != nil {
+		index.vectors[key] = point
+	}
+}
+
+// Vector returns the stored point of a key.
+func (index *CosineLSH) Vector(key string) ([]float64, bool) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	point, ok := index.vectors[key]
+	return point, ok
+}
+
+// QueryTopK returns the candidates of Query with a cosine similarity to the
+// point of at least threshold, in the order of decreasing similarity.
+// At most n keys are returned, or all of them if n is 0. Candidates without
+// a stored point are skipped.
+func (index *CosineLSH) QueryTopK(point []float64, n int, threshold float64) []ScoredKey {
+	result := make([]ScoredKey, 0)
+	for _, key := range index.Query(point) {
+		vec, ok := index.Vector(key)
+		if !ok {
+			continue
+		}
+		if score := cosine(point, vec); score >= threshold {
+			result = append(result, ScoredKey{Key: key, Score: score})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Key < result[j].Key
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+func cosine(x, y []float64) float64 {
+	var dp, nx, ny float64
+	for i := range x {
+		dp += x[i] * y[i]
+		nx += x[i] * x[i]
+		ny += y[i] * y[i]
+	}
+	if nx == 0 || ny == 0 {
+		return 0
+	}
+	return dp / (math.Sqrt(nx) * math.Sqrt(ny))
+}
@@ -0,0 +1,53 @@
+package simhashlsh
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_QueryTopK(t *testing.T) {
+	vecs := randomVectors(100, 300, 1.0)
+	clsh := NewCosineLSH(300, 64, 0.5)
+	clsh.StoreVectors()
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	for i, e := range vecs[:10] {
+		result := clsh.QueryTopK(e, 5, 0.8)
+		if len(result) == 0 || len(result) > 5 {
+			t.Fatalf("%d results", len(result))
+		}
+		if result[0].Key != strconv.Itoa(i) || result[0].Score < 0.9999 {
+			t.Fatalf("the query itself is not the first result: %v", result[0])
+		}
+		for j := range result {
+			if result[j].Score < 0.8 {
+				t.Fatal("result below the threshold")
+			}
+			if j > 0 && result[j].Score > result[j-1].Score {
+				t.Fatal("results are not sorted")
+			}
+		}
+	}
+	clsh.Insert(vecs[0], "new")
+	if _, ok := clsh.Vector("new"); !ok {
+		t.Fatal("the vector of the inserted key is not stored")
+	}
+	clsh.Delete(vecs[0], "new")
+	if _, ok := clsh.Vector("new"); ok {
+		t.Fatal("the vector of the deleted key is still stored")
+	}
+}
+
+func Test_QueryTopKWithoutVectors(t *testing.T) {
+	vecs := randomVectors(10, 300, 1.0)
+	clsh := NewCosineLSH(300, 64, 0.5)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	if len(clsh.QueryTopK(vecs[0], 0, 0)) != 0 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,221 @@
+package simhashlsh
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+)
+
+// the probability with which a point at the predicted threshold from the
+// query is to be found by the tuned queries
+const tunedRecall = 0.9
+
+// ThresholdModel is a ridge regression model that predicts, from the point
+// of a query column, the cosine similarity above which columns are
+// unionable with it.
+type ThresholdModel struct {
+	Weights []float64 `json:"weights"`
+	Bias    float64   `json:"bias"`
+}
+
+// FitThresholdModel fits a model to the thresholds of the points, with the
+// L2 penalty lambda on the weights.
+func FitThresholdModel(points [][]float64, thresholds []float64, lambda float64) (*ThresholdModel, error) {
+	if len(points) == 0 || len(points) != len(thresholds) {
+		return nil, errors.New("simhashlsh: no training samples")
+	}
+	if lambda <= 0 {
+		return nil, errors.New("simhashlsh: lambda must be positive")
+	}
+	dim := len(points[0])
+	// center the points and thresholds, so that the bias is not penalized
+	mean := make([]float64, dim)
+	var meanT float64
+	for i, point := range points {
+		if len(point) != dim {
+			return nil, errors.New("simhashlsh: points of different dimensions")
+		}
+		for d, v := range point {
+			mean[d] += v / float64(len(points))
+		}
+		meanT += thresholds[i] / float64(len(points))
+	}
+	// solve (X'X + lambda I) w = X'y
+	a := make([][]float64, dim)
+	for d := range a {
+		a[d] = make([]float64, dim)
+		a[d][d] = lambda
+	}
+	b := make([]float64, dim)
+	x := make([]float64, dim)
+	for i, point := range points {
+		for d, v := range point {
+			x[d] = v - mean[d]
+		}
+		y := thresholds[i] - meanT
+		for r := range x {
+			b[r] += x[r] * y
+			for c := 0; c <= r; c++ {
+				a[r][c] += x[r] * x[c]
+			}
+		}
+	}
+	w, err := solveCholesky(a, b)
+	if err != nil {
+		return nil, err
+	}
+	bias := meanT
+	for d := range w {
+		bias -= w[d] * mean[d]
+	}
+	return &ThresholdModel{
+		Weights: w,
+		Bias:    bias,
+	}, nil
+}
+
+// solveCholesky solves a x = b for a symmetric positive definite a, given
+// by its lower triangle.
+func solveCholesky(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, i+1)
+		for j := 0; j <= i; j++ {
+			s := a[i][j]
+			for k := 0; k < j; k++ {
+				s -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if s <= 0 {
+					return nil, errors.New("simhashlsh: matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(s)
+			} else {
+				l[i][j] = s / l[j][j]
+			}
+		}
+	}
+	// forward and back substitution
+	y := make([]float64, n)
+	for i := 0; i < n; i++ {
+		s := b[i]
+		for k := 0; k < i; k++ {
+			s -= l[i][k] * y[k]
+		}
+		y[i] = s / l[i][i]
+	}
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		s := y[i]
+		for k := i + 1; k < n; k++ {
+			s -= l[k][i] * x[k]
+		}
+		x[i] = s / l[i][i]
+	}
+	return x, nil
+}
+
+// Predict returns the threshold of a point, within [-1, 1].
+func (m *ThresholdModel) Predict(point []float64) float64 {
+	t := m.Bias
+	for d, v := range point {
+		if d < len(m.Weights) {
+			t += m.Weights[d] * v
+		}
+	}
+	return math.Max(-1.0, math.Min(1.0, t))
+}
+
+// SaveFile writes the model to filename as JSON.
+func (m *ThresholdModel) SaveFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(m); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadThresholdModel reads a model written by SaveFile.
+func LoadThresholdModel(filename string) (*ThresholdModel, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	m := &ThresholdModel{}
+	if err := json.NewDecoder(file).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TunedParams returns the longest prefix of the hash keys, and the fewest
+// probes per table for it, with which a point at cosine similarity threshold
+// to the query is found with probability at least tunedRecall. The probes
+// are counted as single bit flips of the prefix.
+func (index *CosineLSH) TunedParams(threshold float64) (prefixSize, probes int) {
+	k, l := index.cosineLSHParam.k, float64(index.cosineLSHParam.l)
+	// the probability that a bit of the hash keys of the points is equal
+	p := 1 - math.Acos(math.Max(-1.0, math.Min(1.0, threshold)))/math.Pi
+	for K := k; K > 0; K-- {
+		for m := 0; m <= K; m++ {
+			q := math.Pow(p, float64(K)) + float64(m)*math.Pow(p, float64(K-1))*(1-p)
+			if 1-math.Pow(1-math.Min(q, 1.0), l) >= tunedRecall {
+				return K, m
+			}
+		}
+	}
+	return 0, 0
+}
+
+// SetThresholdModel uses the model to tune QueryPlusTuned, or disables the
+// tuning if m is nil.
+func (index *CosineLSH) SetThresholdModel(m *ThresholdModel) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	index.model = m
+}
+
+// QueryTuned returns the keys found with the prefix length and probes tuned
+// to the threshold of the point.
+func (index *CosineLSH) QueryTuned(point []float64, threshold float64) []string {
+	prefixSize, probes := index.TunedParams(threshold)
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	tables, hks := index.probeKeys(point, prefixSize, probes)
+	for i := range tables {
+		for _, ks := range index.lookup(tables[i], hks[i], prefixSize) {
+			for _, key := range ks {
+				if !seen[key] {
+					seen[key] = true
+					result = append(result, key)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// QueryPlusTuned is QueryPlus that first probes the buckets tuned to the
+// threshold predicted by the model for each point. Without a model it is
+// QueryPlus.
+func (index *CosineLSH) QueryPlusTuned(points [][]float64, done <-chan struct{}) <-chan UnionPair {
+	index.lock.RLock()
+	model := index.model
+	index.lock.RUnlock()
+	if model == nil {
+		return index.QueryPlus(points, done)
+	}
+	prefixSizes := make([]int, len(points))
+	probes := make([]int, len(points))
+	for p, point := range points {
+		prefixSizes[p], probes[p] = index.TunedParams(model.Predict(point))
+	}
+	return index.queryPlusProbes(points, prefixSizes, probes, done)
+}
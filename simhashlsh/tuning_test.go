@@ -0,0 +1,109 @@
+package simhashlsh
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_FitThresholdModel(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	points := normalVectors(random, 200, 5)
+	weights := []float64{0.1, -0.05, 0.0, 0.2, 0.03}
+	thresholds := make([]float64, len(points))
+	for i, point := range points {
+		thresholds[i] = 0.5
+		for d, v := range point {
+			thresholds[i] += weights[d] * v
+		}
+	}
+	m, err := FitThresholdModel(points, thresholds, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.Bias-0.5) > 1e-4 {
+		t.Errorf("bias is %f", m.Bias)
+	}
+	for d := range weights {
+		if math.Abs(m.Weights[d]-weights[d]) > 1e-4 {
+			t.Errorf("weight %d is %f, expected %f", d, m.Weights[d], weights[d])
+		}
+	}
+	dir, err := ioutil.TempDir("", "simhashlsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "model.json")
+	if err := m.SaveFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadThresholdModel(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Predict(points[0]) != m.Predict(points[0]) {
+		t.Fail()
+	}
+	if _, err := FitThresholdModel(points, thresholds, 0); err == nil {
+		t.Fail()
+	}
+}
+
+func Test_TunedParams(t *testing.T) {
+	clsh := NewCosineLSH(50, 64, 0.7)
+	k, _, _ := clsh.Params()
+	lastPrefix, lastProbes := 0, 0
+	for _, threshold := range []float64{0.0, 0.3, 0.5, 0.7, 0.9, 0.99} {
+		prefixSize, probes := clsh.TunedParams(threshold)
+		if prefixSize < 0 || prefixSize > k || probes < 0 || probes > prefixSize {
+			t.Fatalf("prefix %d and %d probes for threshold %f", prefixSize, probes, threshold)
+		}
+		// higher thresholds are at least as selective
+		if prefixSize < lastPrefix || (prefixSize == lastPrefix && probes > lastProbes) {
+			t.Errorf("prefix %d and %d probes for threshold %f after prefix %d and %d probes", prefixSize, probes, threshold, lastPrefix, lastProbes)
+		}
+		lastPrefix, lastProbes = prefixSize, probes
+	}
+	if prefixSize, probes := clsh.TunedParams(1.0); prefixSize != k || probes != 0 {
+		t.Errorf("prefix %d and %d probes for identical points", prefixSize, probes)
+	}
+}
+
+func Test_QueryPlusTuned(t *testing.T) {
+	vecs := normalVectors(rand.New(rand.NewSource(4)), 100, 50)
+	clsh := NewCosineLSH(50, 64, 0.9)
+	for i, e := range vecs {
+		clsh.Add(e, strconv.Itoa(i))
+	}
+	clsh.Index()
+	for i, e := range vecs[:10] {
+		found := false
+		for _, key := range clsh.QueryTuned(e, 0.5) {
+			if key == strconv.Itoa(i) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("the query itself is not found")
+		}
+	}
+	clsh.SetThresholdModel(&ThresholdModel{Weights: make([]float64, 50), Bias: 0.5})
+	done := make(chan struct{})
+	defer close(done)
+	seen := make(map[UnionPair]bool)
+	for pair := range clsh.QueryPlusTuned(vecs[:2], done) {
+		if seen[pair] {
+			t.Fatalf("%v is returned twice", pair)
+		}
+		seen[pair] = true
+	}
+	// the fall back to QueryPlus returns every key
+	if len(seen) != 2*len(vecs) {
+		t.Errorf("%d pairs", len(seen))
+	}
+}
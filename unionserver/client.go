@@ -15,25 +15,24 @@ import (
 	fasttext "github.com/ekzhu/go-fasttext"
 )
 
-var (
-	domainDir = "/home/fnargesian/TABLE_UNION_OUTPUT/domains"
-)
-
 type Client struct {
-	ft       *fasttext.FastText
-	host     string
-	cli      *http.Client
-	transFun func(string) string
-	tokenFun func(string) []string
+	ft   *fasttext.FastText
+	host string
+	// the domains of the candidate tables, compared to the query columns
+	domainDir string
+	cli       *http.Client
+	transFun  func(string) string
+	tokenFun  func(string) []string
 }
 
-func NewClient(ft *fasttext.FastText, host string) (*Client, error) {
+func NewClient(ft *fasttext.FastText, host, domainDir string) (*Client, error) {
 	return &Client{
-		ft:       ft,
-		host:     host,
-		cli:      &http.Client{},
-		transFun: DefaultTransFun,
-		tokenFun: DefaultTokenFun,
+		ft:        ft,
+		host:      host,
+		domainDir: domainDir,
+		cli:       &http.Client{},
+		transFun:  DefaultTransFun,
+		tokenFun:  DefaultTokenFun,
 	}, nil
 }
 
@@ -117,7 +116,7 @@ func (c *Client) QueryAndPreviewResults(queryCSVFilename string, k, n int) []Que
 				selfUnion = false
 			}
 			log.Printf("%s -> %s: %f", queryTextHeaders[s], cand.TableUnion.CandHeader[d], score)
-			values, err := getDomainValues(domainDir, cand.TableUnion.CandTableID, d)
+			values, err := getDomainValues(c.domainDir, cand.TableUnion.CandTableID, d)
 			if err != nil {
 				panic(err)
 			}
@@ -15,6 +15,7 @@ import (
 )
 
 type JaccardClient struct {
+	od       *opendata.Repository
 	host     string
 	cli      *http.Client
 	transFun func(string) string
@@ -22,8 +23,9 @@ type JaccardClient struct {
 	numHash  int
 }
 
-func NewJaccardClient(host string, numHash int) (*JaccardClient, error) {
+func NewJaccardClient(od *opendata.Repository, host string, numHash int) (*JaccardClient, error) {
 	return &JaccardClient{
+		od:       od,
 		host:     host,
 		cli:      &http.Client{},
 		transFun: DefaultTransFun,
@@ -78,7 +80,7 @@ func (c *JaccardClient) QueryAndPreviewResults(queryCSVFilename string, k, n int
 	for i := 0; i < queryTable.NumCol(); i++ {
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
-			vec := opendata.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
+			vec := c.od.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
 			if len(vec) != 0 {
 				vecs = append(vecs, vec)
 				queryTextHeaders = append(queryTextHeaders, queryHeaders[i])
@@ -136,7 +138,7 @@ func (c *JaccardClient) Query(queryCSVFilename string, k, n int) []QueryResult {
 	for i := 0; i < queryTable.NumCol(); i++ {
 		col := queryTable.GetColumn(i)
 		if classifyValues(col) == "text" {
-			vec := opendata.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
+			vec := c.od.GetDomainMinhash(c.tokenFun, c.transFun, col, c.numHash)
 			if len(vec) != 0 {
 				vecs = append(vecs, vec)
 				queryTextHeaders = append(queryTextHeaders, queryHeaders[i])
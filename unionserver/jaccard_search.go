@@ -13,14 +13,16 @@ import (
 )
 
 type JaccardUnionIndex struct {
+	od        *opendata.Repository
 	lsh       *minhashlsh.MinhashLSH
 	domainDir string
 	byteOrder binary.ByteOrder
 	numHash   int
 }
 
-func NewJaccardUnionIndex(domainDir string, lsh *minhashlsh.MinhashLSH, numHash int) *JaccardUnionIndex {
+func NewJaccardUnionIndex(od *opendata.Repository, domainDir string, lsh *minhashlsh.MinhashLSH, numHash int) *JaccardUnionIndex {
 	index := &JaccardUnionIndex{
+		od:        od,
 		lsh:       lsh,
 		domainDir: domainDir,
 		byteOrder: ByteOrder,
@@ -30,8 +32,8 @@ func NewJaccardUnionIndex(domainDir string, lsh *minhashlsh.MinhashLSH, numHash
 }
 
 func (index *JaccardUnionIndex) Build() error {
-	domainfilenames := opendata.StreamFilenames()
-	minhashFilenames := opendata.StreamMinhashVectors(10, domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	minhashFilenames := index.od.StreamMinhashVectors(10, domainfilenames)
 	count := 0
 	for file := range minhashFilenames {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -42,7 +44,7 @@ func (index *JaccardUnionIndex) Build() error {
 		if count%1000 == 0 {
 			log.Printf("indexed %d domains", count)
 		}
-		vec, err := opendata.ReadMinhashSignature(file, index.numHash)
+		vec, err := index.od.ReadMinhashSignature(file, index.numHash)
 		if err != nil {
 			log.Printf("Error in reading minhash %s from disk.", file)
 			return err
@@ -74,7 +76,7 @@ func (index *JaccardUnionIndex) QueryOrderAll(query [][]uint64, N, K int) <-chan
 			if alignment.hasCompleted(tableID) {
 				continue
 			}
-			e := getColumnPairJaccard(tableID, index.domainDir, columnIndex, pair.QueryIndex, index.numHash, query)
+			e := getColumnPairJaccard(index.od, tableID, index.domainDir, columnIndex, pair.QueryIndex, index.numHash, query)
 			batch.Push(e, e.Sim)
 			if batch.Size() < batchSize {
 				continue
@@ -92,14 +94,14 @@ func (index *JaccardUnionIndex) QueryOrderAll(query [][]uint64, N, K int) <-chan
 	return results
 }
 
-func getColumnPairJaccard(candTableID, domainDir string, candColIndex, queryColIndex, numHash int, query [][]uint64) Pair {
+func getColumnPairJaccard(od *opendata.Repository, candTableID, domainDir string, candColIndex, queryColIndex, numHash int, query [][]uint64) Pair {
 	// getting the embedding of the candidate column
 	minhashFilename := getMinhashFilename(candTableID, domainDir, candColIndex)
 	if _, err := os.Stat(minhashFilename); os.IsNotExist(err) {
 		log.Printf("Embedding file %s does not exist.", minhashFilename)
 		panic(err)
 	}
-	vec, err := opendata.ReadMinhashSignature(minhashFilename, numHash)
+	vec, err := od.ReadMinhashSignature(minhashFilename, numHash)
 	if err != nil {
 		log.Printf("Error in reading %s from disk.", minhashFilename)
 		panic(err)
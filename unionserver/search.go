@@ -20,13 +20,15 @@ var (
 )
 
 type UnionIndex struct {
+	od        *opendata.Repository
 	lsh       *simhashlsh.CosineLSH
 	domainDir string
 	byteOrder binary.ByteOrder
 }
 
-func NewUnionIndex(domainDir string, lsh *simhashlsh.CosineLSH) *UnionIndex {
+func NewUnionIndex(od *opendata.Repository, domainDir string, lsh *simhashlsh.CosineLSH) *UnionIndex {
 	index := &UnionIndex{
+		od:        od,
 		lsh:       lsh,
 		domainDir: domainDir,
 		byteOrder: ByteOrder,
@@ -35,8 +37,8 @@ func NewUnionIndex(domainDir string, lsh *simhashlsh.CosineLSH) *UnionIndex {
 }
 
 func (index *UnionIndex) Build() error {
-	domainfilenames := opendata.StreamFilenames()
-	embfilenames := opendata.StreamEmbVectors(10, domainfilenames)
+	domainfilenames := index.od.StreamFilenames()
+	embfilenames := index.od.StreamEmbVectors(10, domainfilenames)
 	count := 0
 	for file := range embfilenames {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -113,7 +113,7 @@ func (w *WWT) ReadColumns() <-chan *WWTColumn {
 	out := make(chan *WWTColumn)
 	go func() {
 		for col := range readRaw(w.dir) {
-			vecs, err := embedding.GetDomainEmbPCA(w.ft, w.tokenFun, w.transFun, col.Column, 1)
+			vecs, _, err := embedding.GetDomainEmbPCA(w.ft, w.tokenFun, w.transFun, col.Column, 1)
 			if err != nil {
 				log.Printf("Error in table %s column %d: %s", col.TableID, col.ColumnIndex, err)
 				continue